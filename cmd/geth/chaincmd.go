@@ -38,6 +38,8 @@ import (
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/metrics"
 	"github.com/ethereum/go-ethereum/node"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/trie"
 	"gopkg.in/urfave/cli.v1"
 )
 
@@ -47,7 +49,7 @@ var (
 		Name:      "init",
 		Usage:     "Bootstrap and initialize a new genesis block",
 		ArgsUsage: "<genesisPath>",
-		Flags:     utils.DatabasePathFlags,
+		Flags:     append([]cli.Flag{utils.StateSchemeFlag}, utils.DatabasePathFlags...),
 		Category:  "BLOCKCHAIN COMMANDS",
 		Description: `
 The init command initializes a new genesis block and definition for the network.
@@ -75,6 +77,7 @@ The dumpgenesis command dumps the genesis block configuration in JSON format to
 			utils.CacheFlag,
 			utils.SyncModeFlag,
 			utils.GCModeFlag,
+			utils.StateSchemeFlag,
 			utils.SnapshotFlag,
 			utils.CacheDatabaseFlag,
 			utils.CacheGCFlag,
@@ -118,6 +121,56 @@ Optional second and third arguments control the first and
 last block to write. In this mode, the file will be appended
 if already existing. If the file ends with .gz, the output will
 be gzipped.`,
+	}
+	importHistoryCommand = cli.Command{
+		Action:    utils.MigrateFlags(importHistory),
+		Name:      "import-history",
+		Usage:     "Import an Era1 archive of blockchain history",
+		ArgsUsage: "<dir>",
+		Flags: append([]cli.Flag{
+			utils.CacheFlag,
+			utils.SyncModeFlag,
+			utils.TxLookupLimitFlag,
+		}, utils.DatabasePathFlags...),
+		Category: "BLOCKCHAIN COMMANDS",
+		Description: `
+The import-history command imports the blocks, receipts and total difficulties
+from the Era1 files of the selected network found in the given directory. Every
+file is checked against checksums.txt and against its own accumulator before its
+contents are written into the ancient store. The blocks are not executed, the
+state has to be synced separately afterwards.
+
+The import is only possible into a freshly initialised database, or one into which
+history was already imported, in which case it continues where it left off.`,
+	}
+	exportHistoryCommand = cli.Command{
+		Action:    utils.MigrateFlags(exportHistory),
+		Name:      "export-history",
+		Usage:     "Export blockchain history to an Era1 archive",
+		ArgsUsage: "<dir> <blockNumFirst> <blockNumLast>",
+		Flags: append([]cli.Flag{
+			utils.CacheFlag,
+			utils.SyncModeFlag,
+		}, utils.DatabasePathFlags...),
+		Category: "BLOCKCHAIN COMMANDS",
+		Description: `
+The export-history command exports blocks, receipts and total difficulties in
+the given range into Era1 files in the given directory. Every file holds a fixed
+epoch of 8192 blocks, indexed for random access and committed to by an
+accumulator root which is part of the file name. A checksums.txt file listing the
+sha256 checksums of the files is written alongside them.`,
+	}
+	verifyHistoryCommand = cli.Command{
+		Action:    utils.MigrateFlags(verifyHistory),
+		Name:      "verify-history",
+		Usage:     "Verify an Era1 archive of blockchain history",
+		ArgsUsage: "<dir>",
+		Flags:     utils.NetworkFlags,
+		Category:  "BLOCKCHAIN COMMANDS",
+		Description: `
+The verify-history command checks the Era1 files of the selected network found
+in the given directory against checksums.txt and their own accumulators, and
+ensures they form a single contiguous chain. It doesn't need a database.`,
 	}
 	importPreimagesCommand = cli.Command{
 		Action:    utils.MigrateFlags(importPreimages),
@@ -196,6 +249,9 @@ func initGenesis(ctx *cli.Context) error {
 		if err != nil {
 			utils.Fatalf("Failed to open database: %v", err)
 		}
+		if name == "chaindata" {
+			utils.MakeStateScheme(ctx, chaindb)
+		}
 		_, hash, err := core.SetupGenesisBlock(chaindb, genesis)
 		if err != nil {
 			utils.Fatalf("Failed to write genesis block: %v", err)
@@ -335,6 +391,74 @@ func exportChain(ctx *cli.Context) error {
 	return nil
 }
 
+// importHistory imports an Era1 archive into the chain database.
+func importHistory(ctx *cli.Context) error {
+	if len(ctx.Args()) != 1 {
+		utils.Fatalf("usage: %s", ctx.Command.ArgsUsage)
+	}
+	stack, _ := makeConfigNode(ctx)
+	defer stack.Close()
+
+	chain, db := utils.MakeChain(ctx, stack)
+	defer db.Close()
+
+	start := time.Now()
+	if err := utils.ImportHistory(chain, db, ctx.Args().First()); err != nil {
+		chain.Stop()
+		utils.Fatalf("Import error: %v\n", err)
+	}
+	chain.Stop()
+	fmt.Printf("Import done in %v\n", time.Since(start))
+	return nil
+}
+
+// exportHistory exports the chain history into an Era1 archive.
+func exportHistory(ctx *cli.Context) error {
+	if len(ctx.Args()) != 3 {
+		utils.Fatalf("usage: %s", ctx.Command.ArgsUsage)
+	}
+	stack, _ := makeConfigNode(ctx)
+	defer stack.Close()
+
+	chain, _ := utils.MakeChain(ctx, stack)
+	defer chain.Stop()
+
+	start := time.Now()
+	var (
+		dir         = ctx.Args().Get(0)
+		first, ferr = strconv.ParseUint(ctx.Args().Get(1), 10, 64)
+		last, lerr  = strconv.ParseUint(ctx.Args().Get(2), 10, 64)
+	)
+	if ferr != nil || lerr != nil {
+		utils.Fatalf("Export error in parsing parameters: block number not an integer\n")
+	}
+	if head := chain.CurrentFastBlock(); last > head.NumberU64() {
+		utils.Fatalf("Export error: block number %d larger than head block %d\n", last, head.NumberU64())
+	}
+	if err := utils.ExportHistory(chain, dir, first, last); err != nil {
+		utils.Fatalf("Export error: %v\n", err)
+	}
+	fmt.Printf("Export done in %v\n", time.Since(start))
+	return nil
+}
+
+// verifyHistory checks the integrity of an Era1 archive.
+func verifyHistory(ctx *cli.Context) error {
+	if len(ctx.Args()) != 1 {
+		utils.Fatalf("usage: %s", ctx.Command.ArgsUsage)
+	}
+	config := params.MainnetChainConfig
+	if genesis := utils.MakeGenesis(ctx); genesis != nil {
+		config = genesis.Config
+	}
+	start := time.Now()
+	if err := utils.VerifyHistory(ctx.Args().First(), config); err != nil {
+		utils.Fatalf("Verification error: %v\n", err)
+	}
+	fmt.Printf("Verification done in %v\n", time.Since(start))
+	return nil
+}
+
 // importPreimages imports preimage data from the specified file.
 func importPreimages(ctx *cli.Context) error {
 	if len(ctx.Args()) < 1 {
@@ -438,7 +562,7 @@ func dump(ctx *cli.Context) error {
 	if err != nil {
 		return err
 	}
-	state, err := state.New(root, state.NewDatabase(db), nil)
+	state, err := state.New(root, state.NewDatabaseWithConfig(db, &trie.Config{Scheme: rawdb.ReadStateScheme(db)}), nil)
 	if err != nil {
 		return err
 	}
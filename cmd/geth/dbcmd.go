@@ -72,7 +72,10 @@ Remove blockchain and state databases`,
 			dbExportCmd,
 			dbMetadataCmd,
 			dbMigrateFreezerCmd,
+			dbRecompressFreezerCmd,
 			dbCheckStateContentCmd,
+			dbPruneHistoryCmd,
+			dbVerifyCmd,
 		},
 	}
 	dbInspectCmd = cli.Command{
@@ -208,6 +211,58 @@ WARNING: This is a low-level operation which may cause database corruption!`,
 		Description: `The freezer-migrate command checks your database for receipts in a legacy format and updates those.
 WARNING: please back-up the receipt files in your ancients before running this command.`,
 	}
+	dbRecompressFreezerCmd = cli.Command{
+		Action:    utils.MigrateFlags(freezerRecompress),
+		Name:      "freezer-recompress",
+		Usage:     "Rewrite a freezer table with another compression codec (WARNING: may take a long time)",
+		ArgsUsage: "<type> <codec>",
+		Flags: utils.GroupFlags([]cli.Flag{
+			utils.SyncModeFlag,
+		}, utils.NetworkFlags, utils.DatabasePathFlags),
+		Description: `The freezer-recompress command rewrites the given freezer table in place with the given
+compression codec, which is one of 'raw', 'snappy' and 'zstd'. For zstd, a dictionary is built from items
+sampled across the table. The codec is recorded in the table metadata and retained from then on.
+WARNING: please back-up the ancients before running this command.`,
+	}
+	dbPruneBeforeFlag = cli.Uint64Flag{
+		Name:  "before",
+		Usage: "Block number below which the chain history is pruned",
+	}
+	dbPruneHistoryCmd = cli.Command{
+		Action:    utils.MigrateFlags(pruneHistory),
+		Name:      "prune-history",
+		Usage:     "Prune ancient block bodies and receipts below a block number",
+		ArgsUsage: "",
+		Flags: utils.GroupFlags([]cli.Flag{
+			utils.SyncModeFlag,
+			dbPruneBeforeFlag,
+		}, utils.NetworkFlags, utils.DatabasePathFlags),
+		Description: `The prune-history command drops the block bodies and receipts below the given
+block number from the ancient store. Headers are retained, so the chain can still be
+verified, but the pruned blocks, transactions, receipts and logs can't be served anymore.
+Only blocks already moved into the ancient store can be pruned.`,
+	}
+	dbRepairFlag = cli.BoolFlag{
+		Name:  "repair",
+		Usage: "Repair the recoverable inconsistencies found",
+	}
+	dbVerifyCmd = cli.Command{
+		Action:    utils.MigrateFlags(verifyDB),
+		Name:      "verify",
+		Usage:     "Verify the consistency of the chain data in the database",
+		ArgsUsage: "",
+		Flags: utils.GroupFlags([]cli.Flag{
+			utils.SyncModeFlag,
+			dbRepairFlag,
+		}, utils.NetworkFlags, utils.DatabasePathFlags),
+		Description: `The verify command walks the canonical chain across the key-value store and the
+ancient store, checking the presence of headers, bodies, receipts and total difficulties,
+the canonical hash mappings, the transaction lookups, the contiguity of the freezer and
+the snapshot generator marker.
+
+With --repair, the missing hash to number mappings and transaction lookups are rewritten,
+and a corrupted snapshot is dropped so it gets regenerated on the next startup.`,
+	}
 )
 
 func removeDB(ctx *cli.Context) error {
@@ -519,7 +574,7 @@ func dbDumpTrie(ctx *cli.Context) error {
 			return err
 		}
 	}
-	theTrie, err := trie.New(stRoot, trie.NewDatabase(db))
+	theTrie, err := trie.New(stRoot, utils.MakeTrieDatabase(db))
 	if err != nil {
 		return err
 	}
@@ -795,6 +850,95 @@ func freezerMigrate(ctx *cli.Context) error {
 	return nil
 }
 
+func freezerRecompress(ctx *cli.Context) error {
+	if ctx.NArg() != 2 {
+		return fmt.Errorf("required arguments: %v", ctx.Command.ArgsUsage)
+	}
+	kind := ctx.Args().Get(0)
+	codec, err := rawdb.ParseFreezerCodec(ctx.Args().Get(1))
+	if err != nil {
+		return err
+	}
+	stack, _ := makeConfigNode(ctx)
+	defer stack.Close()
+
+	ancient := ctx.GlobalString(utils.AncientFlag.Name)
+	switch {
+	case ancient == "":
+		ancient = filepath.Join(stack.ResolvePath("chaindata"), "ancient")
+	case !filepath.IsAbs(ancient):
+		ancient = stack.ResolvePath(ancient)
+	}
+	log.Info("Recompressing freezer table", "location", ancient, "name", kind, "codec", codec)
+	start := time.Now()
+	if err := rawdb.RecompressFreezerTable(ancient, kind, codec); err != nil {
+		return err
+	}
+	log.Info("Recompression finished", "elapsed", common.PrettyDuration(time.Since(start)))
+	return nil
+}
+
+func pruneHistory(ctx *cli.Context) error {
+	if !ctx.IsSet(dbPruneBeforeFlag.Name) {
+		return fmt.Errorf("missing --%s flag", dbPruneBeforeFlag.Name)
+	}
+	before := ctx.Uint64(dbPruneBeforeFlag.Name)
+
+	stack, _ := makeConfigNode(ctx)
+	defer stack.Close()
+
+	db := utils.MakeChainDatabase(ctx, stack, false)
+	defer db.Close()
+
+	frozen, err := db.Ancients()
+	if err != nil {
+		return err
+	}
+	tail, err := db.Tail()
+	if err != nil {
+		return err
+	}
+	log.Info("Pruning chain history", "before", before, "tail", tail, "ancients", frozen)
+	return rawdb.PruneChainHistory(db, before)
+}
+
+func verifyDB(ctx *cli.Context) error {
+	stack, _ := makeConfigNode(ctx)
+	defer stack.Close()
+
+	repair := ctx.Bool(dbRepairFlag.Name)
+	db := utils.MakeChainDatabase(ctx, stack, !repair)
+	defer db.Close()
+
+	issues, err := rawdb.VerifyDatabase(db, repair)
+	if err != nil {
+		return err
+	}
+	if len(issues) == 0 {
+		log.Info("No database inconsistencies found")
+		return nil
+	}
+	var (
+		data     [][]string
+		repaired int
+	)
+	for _, issue := range issues {
+		if issue.Repaired {
+			repaired++
+		}
+		data = append(data, []string{fmt.Sprint(issue.Number), issue.Kind, issue.Detail, fmt.Sprint(issue.Repaired)})
+	}
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"Block", "Kind", "Issue", "Repaired"})
+	table.AppendBulk(data)
+	table.Render()
+
+	if repaired < len(issues) {
+		return fmt.Errorf("found %d database inconsistencies, %d repaired", len(issues), repaired)
+	}
+	return nil
+}
+
 // dbHasLegacyReceipts checks freezer entries for legacy receipts. It stops at the first
 // non-empty receipt and checks its format. The index of this first non-empty element is
 // the second return parameter.
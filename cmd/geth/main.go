@@ -83,6 +83,7 @@ var (
 		utils.TxPoolNoLocalsFlag,
 		utils.TxPoolJournalFlag,
 		utils.TxPoolRejournalFlag,
+		utils.TxPoolRemoteJournalFlag,
 		utils.TxPoolPriceLimitFlag,
 		utils.TxPoolPriceBumpFlag,
 		utils.TxPoolAccountSlotsFlag,
@@ -93,8 +94,15 @@ var (
 		utils.SyncModeFlag,
 		utils.ExitWhenSyncedFlag,
 		utils.GCModeFlag,
+		utils.StateSchemeFlag,
 		utils.SnapshotFlag,
 		utils.TxLookupLimitFlag,
+		utils.LogIndexFlag,
+		utils.HistoryPruneFlag,
+		utils.StatePruneFlag,
+		utils.StatePruneIntervalFlag,
+		utils.StatePruneRateLimitFlag,
+		utils.StatePruneBloomSizeFlag,
 		utils.LightServeFlag,
 		utils.LightIngressFlag,
 		utils.LightEgressFlag,
@@ -131,6 +139,7 @@ var (
 		utils.MinerExtraDataFlag,
 		utils.MinerRecommitIntervalFlag,
 		utils.MinerNoVerifyFlag,
+		utils.MinerOrderingFlag,
 		utils.NATFlag,
 		utils.NoDiscoverFlag,
 		utils.DiscoveryV5Flag,
@@ -182,7 +191,12 @@ var (
 		utils.RPCGlobalGasCapFlag,
 		utils.RPCGlobalEVMTimeoutFlag,
 		utils.RPCGlobalTxFeeCapFlag,
+		utils.RPCLogQueryMaxBlockRangeFlag,
+		utils.RPCLogQueryMaxResultsFlag,
+		utils.RPCDBWriteFlag,
 		utils.AllowUnprotectedTxs,
+		utils.BatchRequestLimitFlag,
+		utils.BatchResponseMaxSizeFlag,
 	}
 
 	metricsFlags = []cli.Flag{
@@ -213,6 +227,9 @@ func init() {
 		initCommand,
 		importCommand,
 		exportCommand,
+		importHistoryCommand,
+		exportHistoryCommand,
+		verifyHistoryCommand,
 		importPreimagesCommand,
 		exportPreimagesCommand,
 		removedbCommand,
@@ -17,10 +17,14 @@
 package main
 
 import (
+	"bufio"
 	"bytes"
+	"compress/gzip"
 	"encoding/json"
 	"errors"
+	"io"
 	"os"
+	"strings"
 	"time"
 
 	"github.com/ethereum/go-ethereum/cmd/utils"
@@ -154,6 +158,39 @@ as the backend data source, making this command a lot faster.
 
 The argument is interpreted as block number or hash. If none is provided, the latest
 block is used.
+`,
+			},
+			{
+				Name:      "export",
+				Usage:     "Export the state at a given root into a flat file",
+				ArgsUsage: "<filename> [<root>]",
+				Action:    utils.MigrateFlags(exportSnapshot),
+				Category:  "MISCELLANEOUS COMMANDS",
+				Flags:     utils.GroupFlags(utils.NetworkFlags, utils.DatabasePathFlags),
+				Description: `
+geth snapshot export <filename> [<state-root>]
+will write all accounts, storage slots and contract codes of the specified state
+into a compact binary flat file, based on the state snapshot. The default export
+target is the HEAD state. If the file name ends with .gz, the output is gzipped.
+
+The file can be loaded into another database with 'geth snapshot import'.
+`,
+			},
+			{
+				Name:      "import",
+				Usage:     "Import the state from a flat file",
+				ArgsUsage: "<filename>",
+				Action:    utils.MigrateFlags(importSnapshot),
+				Category:  "MISCELLANEOUS COMMANDS",
+				Flags:     utils.GroupFlags(utils.NetworkFlags, utils.DatabasePathFlags),
+				Description: `
+geth snapshot import <filename>
+will load a state flat file written by 'geth snapshot export', rebuild the state
+trie, verify the state root and mark the snapshot as generated. Any previous
+snapshot in the database is dropped. The chain segment up to the block of the
+imported state should be present in the database, e.g. via 'geth import-history'.
+
+This command is only supported with the hash-based state scheme.
 `,
 			},
 		},
@@ -199,7 +236,7 @@ func verifyState(ctx *cli.Context) error {
 		log.Error("Failed to load head block")
 		return errors.New("no head block")
 	}
-	snaptree, err := snapshot.New(chaindb, trie.NewDatabase(chaindb), 256, headBlock.Root(), false, false, false)
+	snaptree, err := snapshot.New(chaindb, utils.MakeTrieDatabase(chaindb), 256, headBlock.Root(), false, false, false)
 	if err != nil {
 		log.Error("Failed to open snapshot tree", "err", err)
 		return err
@@ -265,7 +302,7 @@ func traverseState(ctx *cli.Context) error {
 		root = headBlock.Root()
 		log.Info("Start traversing the state", "root", root, "number", headBlock.NumberU64())
 	}
-	triedb := trie.NewDatabase(chaindb)
+	triedb := utils.MakeTrieDatabase(chaindb)
 	t, err := trie.NewSecure(root, triedb)
 	if err != nil {
 		log.Error("Failed to open trie", "root", root, "err", err)
@@ -287,7 +324,7 @@ func traverseState(ctx *cli.Context) error {
 			return err
 		}
 		if acc.Root != emptyRoot {
-			storageTrie, err := trie.NewSecure(acc.Root, triedb)
+			storageTrie, err := trie.NewSecureWithOwner(root, common.BytesToHash(accIter.Key), acc.Root, triedb)
 			if err != nil {
 				log.Error("Failed to open storage trie", "root", acc.Root, "err", err)
 				return err
@@ -354,7 +391,7 @@ func traverseRawState(ctx *cli.Context) error {
 		root = headBlock.Root()
 		log.Info("Start traversing the state", "root", root, "number", headBlock.NumberU64())
 	}
-	triedb := trie.NewDatabase(chaindb)
+	triedb := utils.MakeTrieDatabase(chaindb)
 	t, err := trie.NewSecure(root, triedb)
 	if err != nil {
 		log.Error("Failed to open trie", "root", root, "err", err)
@@ -391,7 +428,7 @@ func traverseRawState(ctx *cli.Context) error {
 				return errors.New("invalid account")
 			}
 			if acc.Root != emptyRoot {
-				storageTrie, err := trie.NewSecure(acc.Root, triedb)
+				storageTrie, err := trie.NewSecureWithOwner(root, common.BytesToHash(accIter.LeafKey()), acc.Root, triedb)
 				if err != nil {
 					log.Error("Failed to open storage trie", "root", acc.Root, "err", err)
 					return errors.New("missing storage trie")
@@ -456,7 +493,7 @@ func dumpState(ctx *cli.Context) error {
 	if err != nil {
 		return err
 	}
-	snaptree, err := snapshot.New(db, trie.NewDatabase(db), 256, root, false, false, false)
+	snaptree, err := snapshot.New(db, utils.MakeTrieDatabase(db), 256, root, false, false, false)
 	if err != nil {
 		return err
 	}
@@ -517,3 +554,87 @@ func dumpState(ctx *cli.Context) error {
 		"elapsed", common.PrettyDuration(time.Since(start)))
 	return nil
 }
+
+// exportSnapshot writes the state of the given root into a flat file.
+func exportSnapshot(ctx *cli.Context) error {
+	if ctx.NArg() < 1 || ctx.NArg() > 2 {
+		return errors.New("usage: geth snapshot export <filename> [<root>]")
+	}
+	stack, _ := makeConfigNode(ctx)
+	defer stack.Close()
+
+	chaindb := utils.MakeChainDatabase(ctx, stack, true)
+	headBlock := rawdb.ReadHeadBlock(chaindb)
+	if headBlock == nil {
+		log.Error("Failed to load head block")
+		return errors.New("no head block")
+	}
+	root := headBlock.Root()
+	if ctx.NArg() == 2 {
+		var err error
+		if root, err = parseRoot(ctx.Args()[1]); err != nil {
+			log.Error("Failed to resolve state root", "err", err)
+			return err
+		}
+	}
+	snaptree, err := snapshot.New(chaindb, utils.MakeTrieDatabase(chaindb), 256, headBlock.Root(), false, false, false)
+	if err != nil {
+		log.Error("Failed to open snapshot tree", "err", err)
+		return err
+	}
+	fn := ctx.Args()[0]
+	log.Info("Exporting state snapshot", "root", root, "file", fn)
+
+	// Open the file handle and potentially wrap with a gzip stream
+	fh, err := os.OpenFile(fn, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, os.ModePerm)
+	if err != nil {
+		return err
+	}
+	defer fh.Close()
+
+	writer := bufio.NewWriter(fh)
+	defer writer.Flush()
+
+	var w io.Writer = writer
+	if strings.HasSuffix(fn, ".gz") {
+		w = gzip.NewWriter(w)
+		defer w.(*gzip.Writer).Close()
+	}
+	return snapshot.Export(snaptree, root, chaindb, w)
+}
+
+// importSnapshot loads the state from a flat file written by exportSnapshot.
+func importSnapshot(ctx *cli.Context) error {
+	if ctx.NArg() != 1 {
+		return errors.New("usage: geth snapshot import <filename>")
+	}
+	stack, _ := makeConfigNode(ctx)
+	defer stack.Close()
+
+	chaindb := utils.MakeChainDatabase(ctx, stack, false)
+	fn := ctx.Args()[0]
+	log.Info("Importing state snapshot", "file", fn)
+
+	// Open the file handle and potentially unwrap the gzip stream
+	fh, err := os.Open(fn)
+	if err != nil {
+		return err
+	}
+	defer fh.Close()
+
+	var reader io.Reader = bufio.NewReader(fh)
+	if strings.HasSuffix(fn, ".gz") {
+		if reader, err = gzip.NewReader(reader); err != nil {
+			return err
+		}
+	}
+	root, err := snapshot.Import(chaindb, reader)
+	if err != nil {
+		log.Error("Failed to import state snapshot", "err", err)
+		return err
+	}
+	if head := rawdb.ReadHeadBlock(chaindb); head == nil || head.Root() != root {
+		log.Warn("Imported state doesn't belong to the head block, it will be regenerated", "root", root)
+	}
+	return nil
+}
@@ -42,7 +42,14 @@ var AppHelpFlagGroups = []flags.FlagGroup{
 			utils.SyncModeFlag,
 			utils.ExitWhenSyncedFlag,
 			utils.GCModeFlag,
+			utils.StateSchemeFlag,
 			utils.TxLookupLimitFlag,
+			utils.LogIndexFlag,
+			utils.HistoryPruneFlag,
+			utils.StatePruneFlag,
+			utils.StatePruneIntervalFlag,
+			utils.StatePruneRateLimitFlag,
+			utils.StatePruneBloomSizeFlag,
 			utils.EthStatsURLFlag,
 			utils.IdentityFlag,
 			utils.LightKDFFlag,
@@ -91,6 +98,7 @@ var AppHelpFlagGroups = []flags.FlagGroup{
 			utils.TxPoolNoLocalsFlag,
 			utils.TxPoolJournalFlag,
 			utils.TxPoolRejournalFlag,
+			utils.TxPoolRemoteJournalFlag,
 			utils.TxPoolPriceLimitFlag,
 			utils.TxPoolPriceBumpFlag,
 			utils.TxPoolAccountSlotsFlag,
@@ -152,7 +160,12 @@ var AppHelpFlagGroups = []flags.FlagGroup{
 			utils.RPCGlobalGasCapFlag,
 			utils.RPCGlobalEVMTimeoutFlag,
 			utils.RPCGlobalTxFeeCapFlag,
+			utils.RPCLogQueryMaxBlockRangeFlag,
+			utils.RPCLogQueryMaxResultsFlag,
+			utils.RPCDBWriteFlag,
 			utils.AllowUnprotectedTxs,
+			utils.BatchRequestLimitFlag,
+			utils.BatchResponseMaxSizeFlag,
 			utils.JSpathFlag,
 			utils.ExecFlag,
 			utils.PreloadJSFlag,
@@ -187,6 +200,7 @@ var AppHelpFlagGroups = []flags.FlagGroup{
 			utils.MinerExtraDataFlag,
 			utils.MinerRecommitIntervalFlag,
 			utils.MinerNoVerifyFlag,
+			utils.MinerOrderingFlag,
 		},
 	},
 	{
@@ -20,11 +20,16 @@ package utils
 import (
 	"bufio"
 	"compress/gzip"
+	"crypto/sha256"
 	"errors"
 	"fmt"
 	"io"
+	"io/ioutil"
+	"math"
+	"math/big"
 	"os"
 	"os/signal"
+	"path/filepath"
 	"runtime"
 	"strings"
 	"syscall"
@@ -38,14 +43,20 @@ import (
 	"github.com/ethereum/go-ethereum/eth/ethconfig"
 	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/internal/debug"
+	"github.com/ethereum/go-ethereum/internal/era"
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/node"
+	"github.com/ethereum/go-ethereum/params"
 	"github.com/ethereum/go-ethereum/rlp"
 	"gopkg.in/urfave/cli.v1"
 )
 
 const (
 	importBatchSize = 2500
+
+	// statsReportLimit is the time limit during import and export after
+	// which we always print out progress.
+	statsReportLimit = 8 * time.Second
 )
 
 // Fatalf formats a message to standard error and exits the program.
@@ -287,6 +298,324 @@ func ExportAppendChain(blockchain *core.BlockChain, fn string, first uint64, las
 	return nil
 }
 
+// historyNetworkName returns the name used in the Era1 file names for the
+// chain with the given config.
+func historyNetworkName(config *params.ChainConfig) string {
+	if name, ok := params.NetworkNames[config.ChainID.String()]; ok {
+		return name
+	}
+	return "unknown"
+}
+
+// ExportHistory exports blockchain history into the specified directory,
+// following the Era1 format. Every file holds the blocks of a single epoch of
+// era.MaxEra1Size blocks and a checksums.txt file listing the sha256 sums of
+// the files is written alongside them.
+func ExportHistory(bc *core.BlockChain, dir string, first, last uint64) error {
+	log.Info("Exporting blockchain history", "dir", dir)
+	if head := bc.CurrentFastBlock().NumberU64(); head < last {
+		log.Warn("Last block beyond head, setting last = head", "head", head, "last", last)
+		last = head
+	}
+	if first > last {
+		return fmt.Errorf("invalid range: first %d is after last %d", first, last)
+	}
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return fmt.Errorf("error creating output directory: %w", err)
+	}
+	var (
+		network   = historyNetworkName(bc.Config())
+		start     = time.Now()
+		reported  = time.Now()
+		checksums []string
+	)
+	for i := first; i <= last; i = (i/era.MaxEra1Size + 1) * era.MaxEra1Size {
+		epoch := int(i / era.MaxEra1Size)
+		err := func() error {
+			filename := filepath.Join(dir, era.Filename(network, epoch, common.Hash{}))
+			f, err := os.Create(filename)
+			if err != nil {
+				return fmt.Errorf("could not create era file: %w", err)
+			}
+			defer f.Close()
+
+			w := era.NewBuilder(f)
+			for n := i; n <= last && n/era.MaxEra1Size == uint64(epoch); n++ {
+				block := bc.GetBlockByNumber(n)
+				if block == nil {
+					return fmt.Errorf("export failed on #%d: not found", n)
+				}
+				receipts := bc.GetReceiptsByHash(block.Hash())
+				if receipts == nil {
+					return fmt.Errorf("export failed on #%d: receipts not found", n)
+				}
+				td := bc.GetTd(block.Hash(), n)
+				if td == nil {
+					return fmt.Errorf("export failed on #%d: total difficulty not found", n)
+				}
+				if err := w.Add(block, receipts, td); err != nil {
+					return err
+				}
+				if time.Since(reported) >= statsReportLimit {
+					log.Info("Exporting blocks", "exported", n-first, "elapsed", common.PrettyDuration(time.Since(start)))
+					reported = time.Now()
+				}
+			}
+			root, err := w.Finalize()
+			if err != nil {
+				return fmt.Errorf("export failed to finalize %d: %w", epoch, err)
+			}
+			if err := f.Close(); err != nil {
+				return err
+			}
+			// Set correct filename with root.
+			final := filepath.Join(dir, era.Filename(network, epoch, root))
+			if err := os.Rename(filename, final); err != nil {
+				return err
+			}
+			// Compute checksum of entire Era1.
+			checksum, err := fileChecksum(final)
+			if err != nil {
+				return err
+			}
+			checksums = append(checksums, checksum.Hex())
+			return nil
+		}()
+		if err != nil {
+			return err
+		}
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "checksums.txt"), []byte(strings.Join(checksums, "\n")), os.ModePerm); err != nil {
+		return err
+	}
+	log.Info("Exported blockchain history", "dir", dir, "elapsed", common.PrettyDuration(time.Since(start)))
+	return nil
+}
+
+// ImportHistory imports the Era1 files of the chain's network found in the
+// specified directory. Every file is checked against checksums.txt and its
+// own accumulator before its blocks and receipts are written into the
+// ancient store, without executing them. Blocks already present in the
+// database are skipped, so an interrupted import can be resumed.
+func ImportHistory(chain *core.BlockChain, db ethdb.Database, dir string) error {
+	network := historyNetworkName(chain.Config())
+	entries, checksums, err := readHistoryDir(dir, network)
+	if err != nil {
+		return err
+	}
+	head := chain.CurrentFastBlock().NumberU64()
+	if head != 0 {
+		if frozen, _ := db.Ancients(); frozen != head+1 {
+			return fmt.Errorf("history import only supported on a freshly initialised or history-imported database (head %d, ancients %d)", head, frozen)
+		}
+	}
+	var (
+		start    = time.Now()
+		reported = time.Now()
+		imported = 0
+	)
+	for i, filename := range entries {
+		err := func() error {
+			e, err := openHistoryFile(dir, filename, network, checksums[i])
+			if err != nil {
+				return err
+			}
+			defer e.Close()
+
+			if e.Start()+e.Count() <= head+1 {
+				log.Info("Skipping imported history", "file", filename)
+				return nil
+			}
+			if e.Start() > head+1 {
+				return fmt.Errorf("missing history: head is #%d, %s starts at #%d", head, filename, e.Start())
+			}
+			it, err := era.NewIterator(e)
+			if err != nil {
+				return err
+			}
+			var (
+				blocks   = make(types.Blocks, 0, importBatchSize)
+				receipts = make([]types.Receipts, 0, importBatchSize)
+				tds      = make([]*big.Int, 0, importBatchSize)
+			)
+			flush := func() error {
+				if len(blocks) == 0 {
+					return nil
+				}
+				headers := make([]*types.Header, len(blocks))
+				for j, block := range blocks {
+					headers[j] = block.Header()
+				}
+				if _, err := chain.InsertHeaderChain(headers, 0); err != nil {
+					return fmt.Errorf("error inserting headers: %w", err)
+				}
+				last := blocks[len(blocks)-1]
+				if td := chain.GetTd(last.Hash(), last.NumberU64()); td == nil || td.Cmp(tds[len(tds)-1]) != 0 {
+					return fmt.Errorf("total difficulty mismatch at #%d: have %v, want %v", last.NumberU64(), td, tds[len(tds)-1])
+				}
+				if _, err := chain.InsertReceiptChain(blocks, receipts, math.MaxUint64); err != nil {
+					return fmt.Errorf("error inserting body and receipts: %w", err)
+				}
+				imported += len(blocks)
+				head = last.NumberU64()
+				blocks, receipts, tds = blocks[:0], receipts[:0], tds[:0]
+				return nil
+			}
+			for it.Next() {
+				block := it.Block()
+				if block.NumberU64() <= head {
+					if hash := rawdb.ReadCanonicalHash(db, block.NumberU64()); hash != block.Hash() {
+						return fmt.Errorf("block #%d mismatch: have %x, want %x", block.NumberU64(), block.Hash(), hash)
+					}
+					continue
+				}
+				blocks = append(blocks, block)
+				receipts = append(receipts, it.Receipts())
+				tds = append(tds, it.TotalDifficulty())
+
+				if len(blocks) == importBatchSize {
+					if err := flush(); err != nil {
+						return err
+					}
+				}
+				if time.Since(reported) >= statsReportLimit {
+					log.Info("Importing history", "file", filename, "head", it.Number(), "imported", imported, "elapsed", common.PrettyDuration(time.Since(start)))
+					reported = time.Now()
+				}
+			}
+			if err := it.Error(); err != nil {
+				return err
+			}
+			return flush()
+		}()
+		if err != nil {
+			return err
+		}
+	}
+	log.Info("Imported blockchain history", "dir", dir, "blocks", imported, "elapsed", common.PrettyDuration(time.Since(start)))
+	return nil
+}
+
+// VerifyHistory checks the Era1 files of the network with the given chain
+// config found in the specified directory against checksums.txt and their own
+// accumulators, and ensures the files form a single contiguous chain. No
+// database is needed.
+func VerifyHistory(dir string, config *params.ChainConfig) error {
+	network := historyNetworkName(config)
+	entries, checksums, err := readHistoryDir(dir, network)
+	if err != nil {
+		return err
+	}
+	var (
+		start    = time.Now()
+		prevHash common.Hash
+		prevTd   *big.Int
+	)
+	for i, filename := range entries {
+		err := func() error {
+			e, err := openHistoryFile(dir, filename, network, checksums[i])
+			if err != nil {
+				return err
+			}
+			defer e.Close()
+
+			first, err := e.GetBlockByNumber(e.Start())
+			if err != nil {
+				return err
+			}
+			if prevTd != nil {
+				initial, err := e.InitialTD()
+				if err != nil {
+					return err
+				}
+				if first.ParentHash() != prevHash || initial.Cmp(prevTd) != 0 {
+					return fmt.Errorf("%s is not a continuation of the previous file", filename)
+				}
+			}
+			last := e.Start() + e.Count() - 1
+			lastBlock, err := e.GetBlockByNumber(last)
+			if err != nil {
+				return err
+			}
+			if prevTd, err = e.GetTotalDifficulty(last); err != nil {
+				return err
+			}
+			prevHash = lastBlock.Hash()
+
+			log.Info("Verified history file", "file", filename, "first", e.Start(), "last", last)
+			return nil
+		}()
+		if err != nil {
+			return err
+		}
+	}
+	log.Info("Verified blockchain history", "dir", dir, "files", len(entries), "elapsed", common.PrettyDuration(time.Since(start)))
+	return nil
+}
+
+// readHistoryDir lists the Era1 files of the given network in the directory
+// along with their expected checksums.
+func readHistoryDir(dir string, network string) ([]string, []string, error) {
+	entries, err := era.ReadDir(dir, network)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error reading %s: %w", dir, err)
+	}
+	if len(entries) == 0 {
+		return nil, nil, fmt.Errorf("no %s history files found in %s", network, dir)
+	}
+	blob, err := ioutil.ReadFile(filepath.Join(dir, "checksums.txt"))
+	if err != nil {
+		return nil, nil, fmt.Errorf("unable to read checksums.txt: %w", err)
+	}
+	checksums := strings.Split(strings.TrimSpace(string(blob)), "\n")
+	if len(checksums) != len(entries) {
+		return nil, nil, fmt.Errorf("expected equal number of checksums and entries, have: %d checksums, %d entries", len(checksums), len(entries))
+	}
+	return entries, checksums, nil
+}
+
+// openHistoryFile opens an Era1 file, validating its checksum, its content
+// against its accumulator and the accumulator against the file name.
+func openHistoryFile(dir string, filename string, network string, checksum string) (*era.Era, error) {
+	path := filepath.Join(dir, filename)
+	have, err := fileChecksum(path)
+	if err != nil {
+		return nil, err
+	}
+	if have.Hex() != strings.TrimSpace(checksum) {
+		return nil, fmt.Errorf("checksum mismatch for %s: have %s, want %s", filename, have.Hex(), checksum)
+	}
+	e, err := era.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("error opening %s: %w", filename, err)
+	}
+	root, err := e.Verify()
+	if err != nil {
+		e.Close()
+		return nil, fmt.Errorf("invalid history file %s: %w", filename, err)
+	}
+	if want := era.Filename(network, int(e.Start()/era.MaxEra1Size), root); want != filename {
+		e.Close()
+		return nil, fmt.Errorf("history file %s does not match its content, expected %s", filename, want)
+	}
+	return e, nil
+}
+
+// fileChecksum computes the sha256 checksum of the given file.
+func fileChecksum(path string) (common.Hash, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return common.Hash{}, fmt.Errorf("unable to checksum %s: %w", path, err)
+	}
+	return common.BytesToHash(h.Sum(nil)), nil
+}
+
 // ImportPreimages imports a batch of exported hash preimages into the database.
 // It's a part of the deprecated functionality, should be removed in the future.
 func ImportPreimages(db ethdb.Database, fn string) error {
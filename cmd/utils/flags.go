@@ -67,6 +67,7 @@ import (
 	"github.com/ethereum/go-ethereum/p2p/nat"
 	"github.com/ethereum/go-ethereum/p2p/netutil"
 	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/trie"
 	pcsclite "github.com/gballet/go-libpcsclite"
 	gopsutil "github.com/shirou/gopsutil/mem"
 	"gopkg.in/urfave/cli.v1"
@@ -118,10 +119,18 @@ var (
 		Name:  "remotedb",
 		Usage: "URL for remote database",
 	}
+	RemoteDBJWTSecretFlag = cli.StringFlag{
+		Name:  "remotedb.jwtsecret",
+		Usage: "Path to a JWT secret to authenticate against the remote database, required for writes",
+	}
 	AncientFlag = DirectoryFlag{
 		Name:  "datadir.ancient",
 		Usage: "Data directory for ancient chain segments (default = inside chaindata)",
 	}
+	DBEngineFlag = cli.StringFlag{
+		Name:  "db.engine",
+		Usage: "Backing database implementation to use ('leveldb' or 'pebble', default = engine of the existing database or leveldb)",
+	}
 	MinFreeDiskSpaceFlag = DirectoryFlag{
 		Name:  "datadir.minfreedisk",
 		Usage: "Minimum free disk space in MB, once reached triggers auto shut down (default = --cache.gc converted to MB, 0 = disabled)",
@@ -231,6 +240,10 @@ var (
 		Usage: `Blockchain garbage collection mode ("full", "archive")`,
 		Value: "full",
 	}
+	StateSchemeFlag = cli.StringFlag{
+		Name:  "state.scheme",
+		Usage: `Scheme to use for storing trie nodes ("hash" or "path", default = existing one or "hash")`,
+	}
 	SnapshotFlag = cli.BoolTFlag{
 		Name:  "snapshot",
 		Usage: `Enables snapshot-database mode (default = enable)`,
@@ -240,6 +253,33 @@ var (
 		Usage: "Number of recent blocks to maintain transactions index for (default = about one year, 0 = entire chain)",
 		Value: ethconfig.Defaults.TxLookupLimit,
 	}
+	LogIndexFlag = cli.BoolFlag{
+		Name:  "logindex",
+		Usage: "Maintain an exact address/topic index of the logs to speed up log queries",
+	}
+	HistoryPruneFlag = cli.Uint64Flag{
+		Name:  "history.prune",
+		Usage: "Block number below which ancient block bodies and receipts are pruned, transaction lookups are kept (0 = keep entire history)",
+	}
+	StatePruneFlag = cli.BoolFlag{
+		Name:  "state.prune",
+		Usage: "Delete stale state trie nodes in the background while the node is running (hash scheme only)",
+	}
+	StatePruneIntervalFlag = cli.DurationFlag{
+		Name:  "state.prune.interval",
+		Usage: "Time to wait between two online state pruning cycles",
+		Value: ethconfig.Defaults.StatePruneInterval,
+	}
+	StatePruneRateLimitFlag = cli.IntFlag{
+		Name:  "state.prune.ratelimit",
+		Usage: "Maximum number of stale trie nodes deleted per second by the online pruner (0 = unlimited)",
+		Value: ethconfig.Defaults.StatePruneRateLimit,
+	}
+	StatePruneBloomSizeFlag = cli.Uint64Flag{
+		Name:  "state.prune.bloomsize",
+		Usage: "Megabytes of memory allocated to the live state bloom filter of the online pruner",
+		Value: ethconfig.Defaults.StatePruneBloomSize,
+	}
 	LightKDFFlag = cli.BoolFlag{
 		Name:  "lightkdf",
 		Usage: "Reduce key-derivation RAM & CPU usage at some expense of KDF strength",
@@ -362,9 +402,14 @@ var (
 	}
 	TxPoolRejournalFlag = cli.DurationFlag{
 		Name:  "txpool.rejournal",
-		Usage: "Time interval to regenerate the local transaction journal",
+		Usage: "Time interval to regenerate the local and remote transaction journals",
 		Value: core.DefaultTxPoolConfig.Rejournal,
 	}
+	TxPoolRemoteJournalFlag = cli.StringFlag{
+		Name:  "txpool.remotejournal",
+		Usage: "Disk journal for remote transactions to survive node restarts (disabled if empty)",
+		Value: core.DefaultTxPoolConfig.RemoteJournal,
+	}
 	TxPoolPriceLimitFlag = cli.Uint64Flag{
 		Name:  "txpool.pricelimit",
 		Usage: "Minimum gas price limit to enforce for acceptance into the pool",
@@ -494,6 +539,11 @@ var (
 		Name:  "miner.noverify",
 		Usage: "Disable remote sealing verification",
 	}
+	MinerOrderingFlag = cli.StringFlag{
+		Name:  "miner.ordering",
+		Usage: "Transaction ordering policy of the built blocks (tip, fifo)",
+		Value: miner.TipOrdering,
+	}
 	// Account settings
 	UnlockedAccountFlag = cli.StringFlag{
 		Name:  "unlock",
@@ -533,6 +583,30 @@ var (
 		Usage: "Sets a cap on transaction fee (in ether) that can be sent via the RPC APIs (0 = no cap)",
 		Value: ethconfig.Defaults.RPCTxFeeCap,
 	}
+	RPCLogQueryMaxBlockRangeFlag = cli.Uint64Flag{
+		Name:  "rpc.logs.maxblockrange",
+		Usage: "Maximum number of blocks a single eth_getLogs query may span (0 = no limit)",
+		Value: ethconfig.Defaults.RPCLogQueryMaxBlockRange,
+	}
+	RPCDBWriteFlag = cli.BoolFlag{
+		Name:  "rpc.dbwrite",
+		Usage: "Expose raw database writes (debug_dbPut, debug_dbDelete, debug_dbWrite) on the authenticated RPC endpoints",
+	}
+	RPCLogQueryMaxResultsFlag = cli.IntFlag{
+		Name:  "rpc.logs.maxresults",
+		Usage: "Maximum number of logs a single eth_getLogs query may return (0 = no limit)",
+		Value: ethconfig.Defaults.RPCLogQueryMaxResults,
+	}
+	BatchRequestLimitFlag = cli.IntFlag{
+		Name:  "rpc.batch-request-limit",
+		Usage: "Maximum number of requests in a batch served over HTTP, WS and IPC (0 = no limit)",
+		Value: node.DefaultConfig.BatchRequestLimit,
+	}
+	BatchResponseMaxSizeFlag = cli.IntFlag{
+		Name:  "rpc.batch-response-max-size",
+		Usage: "Maximum number of bytes returned from a batched call over HTTP, WS and IPC (0 = no limit)",
+		Value: node.DefaultConfig.BatchResponseMaxSize,
+	}
 	// Authenticated RPC HTTP settings
 	AuthListenFlag = cli.StringFlag{
 		Name:  "authrpc.addr",
@@ -850,6 +924,8 @@ var (
 		DataDirFlag,
 		AncientFlag,
 		RemoteDBFlag,
+		RemoteDBJWTSecretFlag,
+		DBEngineFlag,
 	}
 )
 
@@ -1056,6 +1132,14 @@ func setHTTP(ctx *cli.Context, cfg *node.Config) {
 	if ctx.GlobalIsSet(AllowUnprotectedTxs.Name) {
 		cfg.AllowUnprotectedTxs = ctx.GlobalBool(AllowUnprotectedTxs.Name)
 	}
+
+	if ctx.GlobalIsSet(BatchRequestLimitFlag.Name) {
+		cfg.BatchRequestLimit = ctx.GlobalInt(BatchRequestLimitFlag.Name)
+	}
+
+	if ctx.GlobalIsSet(BatchResponseMaxSizeFlag.Name) {
+		cfg.BatchResponseMaxSize = ctx.GlobalInt(BatchResponseMaxSizeFlag.Name)
+	}
 }
 
 // setGraphQL creates the GraphQL listener interface string from the set
@@ -1318,6 +1402,14 @@ func SetNodeConfig(ctx *cli.Context, cfg *node.Config) {
 	setDataDir(ctx, cfg)
 	setSmartCard(ctx, cfg)
 
+	if ctx.GlobalIsSet(DBEngineFlag.Name) {
+		engine := ctx.GlobalString(DBEngineFlag.Name)
+		if engine != rawdb.DBLeveldb && engine != rawdb.DBPebble {
+			Fatalf("Invalid choice for db.engine '%s', allowed 'leveldb' or 'pebble'", engine)
+		}
+		cfg.DBEngine = engine
+	}
+
 	if ctx.GlobalIsSet(JWTSecretFlag.Name) {
 		cfg.JWTSecret = ctx.GlobalString(JWTSecretFlag.Name)
 	}
@@ -1435,6 +1527,9 @@ func setTxPool(ctx *cli.Context, cfg *core.TxPoolConfig) {
 	if ctx.GlobalIsSet(TxPoolRejournalFlag.Name) {
 		cfg.Rejournal = ctx.GlobalDuration(TxPoolRejournalFlag.Name)
 	}
+	if ctx.GlobalIsSet(TxPoolRemoteJournalFlag.Name) {
+		cfg.RemoteJournal = ctx.GlobalString(TxPoolRemoteJournalFlag.Name)
+	}
 	if ctx.GlobalIsSet(TxPoolPriceLimitFlag.Name) {
 		cfg.PriceLimit = ctx.GlobalUint64(TxPoolPriceLimitFlag.Name)
 	}
@@ -1505,6 +1600,12 @@ func setMiner(ctx *cli.Context, cfg *miner.Config) {
 	if ctx.GlobalIsSet(MinerNoVerifyFlag.Name) {
 		cfg.Noverify = ctx.GlobalBool(MinerNoVerifyFlag.Name)
 	}
+	if ctx.GlobalIsSet(MinerOrderingFlag.Name) {
+		cfg.Ordering = ctx.GlobalString(MinerOrderingFlag.Name)
+		if _, err := miner.LookupOrderingPolicy(cfg.Ordering); err != nil {
+			Fatalf("Invalid miner ordering policy: %v", err)
+		}
+	}
 	if ctx.GlobalIsSet(LegacyMinerGasTargetFlag.Name) {
 		log.Warn("The generic --miner.gastarget flag is deprecated and will be removed in the future!")
 	}
@@ -1644,6 +1745,12 @@ func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *ethconfig.Config) {
 	if ctx.GlobalIsSet(GCModeFlag.Name) {
 		cfg.NoPruning = ctx.GlobalString(GCModeFlag.Name) == "archive"
 	}
+	if ctx.GlobalIsSet(StateSchemeFlag.Name) {
+		cfg.StateScheme = ctx.GlobalString(StateSchemeFlag.Name)
+		if cfg.StateScheme == rawdb.PathScheme && cfg.NoPruning {
+			Fatalf("--%s=%s is incompatible with --%s=archive", StateSchemeFlag.Name, rawdb.PathScheme, GCModeFlag.Name)
+		}
+	}
 	if ctx.GlobalIsSet(CacheNoPrefetchFlag.Name) {
 		cfg.NoPrefetch = ctx.GlobalBool(CacheNoPrefetchFlag.Name)
 	}
@@ -1656,6 +1763,27 @@ func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *ethconfig.Config) {
 	if ctx.GlobalIsSet(TxLookupLimitFlag.Name) {
 		cfg.TxLookupLimit = ctx.GlobalUint64(TxLookupLimitFlag.Name)
 	}
+	if ctx.GlobalIsSet(LogIndexFlag.Name) {
+		cfg.LogIndex = ctx.GlobalBool(LogIndexFlag.Name)
+	}
+	if ctx.GlobalIsSet(HistoryPruneFlag.Name) {
+		cfg.HistoryPruneBefore = ctx.GlobalUint64(HistoryPruneFlag.Name)
+	}
+	if ctx.GlobalIsSet(StatePruneFlag.Name) {
+		cfg.StatePrune = ctx.GlobalBool(StatePruneFlag.Name)
+		if cfg.StatePrune && cfg.NoPruning {
+			Fatalf("--%s is incompatible with --%s=archive", StatePruneFlag.Name, GCModeFlag.Name)
+		}
+	}
+	if ctx.GlobalIsSet(StatePruneIntervalFlag.Name) {
+		cfg.StatePruneInterval = ctx.GlobalDuration(StatePruneIntervalFlag.Name)
+	}
+	if ctx.GlobalIsSet(StatePruneRateLimitFlag.Name) {
+		cfg.StatePruneRateLimit = ctx.GlobalInt(StatePruneRateLimitFlag.Name)
+	}
+	if ctx.GlobalIsSet(StatePruneBloomSizeFlag.Name) {
+		cfg.StatePruneBloomSize = ctx.GlobalUint64(StatePruneBloomSizeFlag.Name)
+	}
 	if ctx.GlobalIsSet(CacheFlag.Name) || ctx.GlobalIsSet(CacheTrieFlag.Name) {
 		cfg.TrieCleanCache = ctx.GlobalInt(CacheFlag.Name) * ctx.GlobalInt(CacheTrieFlag.Name) / 100
 	}
@@ -1702,6 +1830,15 @@ func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *ethconfig.Config) {
 	if ctx.GlobalIsSet(RPCGlobalTxFeeCapFlag.Name) {
 		cfg.RPCTxFeeCap = ctx.GlobalFloat64(RPCGlobalTxFeeCapFlag.Name)
 	}
+	if ctx.GlobalIsSet(RPCLogQueryMaxBlockRangeFlag.Name) {
+		cfg.RPCLogQueryMaxBlockRange = ctx.GlobalUint64(RPCLogQueryMaxBlockRangeFlag.Name)
+	}
+	if ctx.GlobalIsSet(RPCLogQueryMaxResultsFlag.Name) {
+		cfg.RPCLogQueryMaxResults = ctx.GlobalInt(RPCLogQueryMaxResultsFlag.Name)
+	}
+	if ctx.GlobalIsSet(RPCDBWriteFlag.Name) {
+		cfg.RPCDBWrite = ctx.GlobalBool(RPCDBWriteFlag.Name)
+	}
 	if ctx.GlobalIsSet(NoDiscoverFlag.Name) {
 		cfg.EthDiscoveryURLs, cfg.SnapDiscoveryURLs = []string{}, []string{}
 	} else if ctx.GlobalIsSet(DNSDiscoveryFlag.Name) {
@@ -1975,7 +2112,14 @@ func MakeChainDatabase(ctx *cli.Context, stack *node.Node, readonly bool) ethdb.
 	switch {
 	case ctx.GlobalIsSet(RemoteDBFlag.Name):
 		log.Info("Using remote db", "url", ctx.GlobalString(RemoteDBFlag.Name))
-		chainDb, err = remotedb.New(ctx.GlobalString(RemoteDBFlag.Name))
+		if !ctx.GlobalIsSet(RemoteDBJWTSecretFlag.Name) {
+			chainDb, err = remotedb.New(ctx.GlobalString(RemoteDBFlag.Name))
+			break
+		}
+		var secret []byte
+		if secret, err = os.ReadFile(ctx.GlobalString(RemoteDBJWTSecretFlag.Name)); err == nil {
+			chainDb, err = remotedb.NewAuthenticated(ctx.GlobalString(RemoteDBFlag.Name), common.FromHex(strings.TrimSpace(string(secret))))
+		}
 	case ctx.GlobalString(SyncModeFlag.Name) == "light":
 		chainDb, err = stack.OpenDatabase("lightchaindata", cache, handles, "", readonly)
 	default:
@@ -2008,10 +2152,34 @@ func MakeGenesis(ctx *cli.Context) *core.Genesis {
 	return genesis
 }
 
+// MakeStateScheme resolves the trie node storage scheme from the command line
+// flag and the existing database, marking the database if the path scheme is
+// selected. It must be called before any state is written.
+func MakeStateScheme(ctx *cli.Context, disk ethdb.Database) string {
+	scheme, err := rawdb.ParseStateScheme(ctx.GlobalString(StateSchemeFlag.Name), disk)
+	if err != nil {
+		Fatalf("%v", err)
+	}
+	if scheme == rawdb.PathScheme {
+		if ctx.GlobalString(GCModeFlag.Name) == "archive" {
+			Fatalf("--%s=%s is incompatible with --%s=archive", StateSchemeFlag.Name, rawdb.PathScheme, GCModeFlag.Name)
+		}
+		rawdb.WriteStateScheme(disk, scheme)
+	}
+	return scheme
+}
+
+// MakeTrieDatabase constructs a trie database on top of the given chain database,
+// running with the trie node storage scheme the database was marked with.
+func MakeTrieDatabase(disk ethdb.Database) *trie.Database {
+	return trie.NewDatabaseWithConfig(disk, &trie.Config{Scheme: rawdb.ReadStateScheme(disk)})
+}
+
 // MakeChain creates a chain manager from set command line flags.
 func MakeChain(ctx *cli.Context, stack *node.Node) (chain *core.BlockChain, chainDb ethdb.Database) {
 	var err error
 	chainDb = MakeChainDatabase(ctx, stack, false) // TODO(rjl493456442) support read-only database
+	MakeStateScheme(ctx, chainDb)
 	config, _, err := core.SetupGenesisBlock(chainDb, MakeGenesis(ctx))
 	if err != nil {
 		Fatalf("%v", err)
@@ -0,0 +1,195 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package utils
+
+import (
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/internal/era"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/trie"
+)
+
+var (
+	historyKey, _ = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+	historyAddr   = crypto.PubkeyToAddress(historyKey.PublicKey)
+)
+
+func TestHistoryImportAndExport(t *testing.T) {
+	var (
+		count = era.MaxEra1Size + 128
+		gspec = &core.Genesis{
+			Config: params.TestChainConfig,
+			Alloc:  core.GenesisAlloc{historyAddr: {Balance: big.NewInt(1_000_000_000_000_000)}},
+		}
+		db      = rawdb.NewMemoryDatabase()
+		genesis = gspec.MustCommit(db)
+		signer  = types.LatestSigner(gspec.Config)
+	)
+	blocks, _ := core.GenerateChain(gspec.Config, genesis, ethash.NewFaker(), db, count, func(i int, g *core.BlockGen) {
+		if i%64 != 0 {
+			return
+		}
+		tx, _ := types.SignTx(types.NewTx(&types.DynamicFeeTx{
+			ChainID:   gspec.Config.ChainID,
+			Nonce:     g.TxNonce(historyAddr),
+			To:        &common.Address{0xaa},
+			Gas:       21000,
+			GasFeeCap: g.BaseFee(),
+			Value:     big.NewInt(1),
+		}), signer, historyKey)
+		g.AddTx(tx)
+	})
+	chain, err := core.NewBlockChain(db, nil, gspec.Config, ethash.NewFaker(), vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatalf("unable to initialize chain: %v", err)
+	}
+	defer chain.Stop()
+
+	if _, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("error inserting chain: %v", err)
+	}
+	// Export the history into two epochs
+	dir := t.TempDir()
+	if err := ExportHistory(chain, dir, 0, uint64(count)); err != nil {
+		t.Fatalf("error exporting history: %v", err)
+	}
+	entries, err := era.ReadDir(dir, historyNetworkName(gspec.Config))
+	if err != nil {
+		t.Fatalf("error reading era dir: %v", err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("wrong number of era files: have %d, want 2", len(entries))
+	}
+	if err := VerifyHistory(dir, gspec.Config); err != nil {
+		t.Fatalf("error verifying history: %v", err)
+	}
+	// Check random access into the exported files
+	for _, entry := range entries {
+		e, err := era.Open(filepath.Join(dir, entry))
+		if err != nil {
+			t.Fatalf("error opening era: %v", err)
+		}
+		for _, n := range []uint64{e.Start(), e.Start() + e.Count()/2, e.Start() + e.Count() - 1} {
+			want := chain.GetBlockByNumber(n)
+			block, err := e.GetBlockByNumber(n)
+			if err != nil {
+				t.Fatalf("error reading block %d: %v", n, err)
+			}
+			if block.Hash() != want.Hash() {
+				t.Fatalf("block %d mismatch", n)
+			}
+			td, err := e.GetTotalDifficulty(n)
+			if err != nil {
+				t.Fatalf("error reading total difficulty %d: %v", n, err)
+			}
+			if td.Cmp(chain.GetTd(want.Hash(), n)) != 0 {
+				t.Fatalf("total difficulty %d mismatch", n)
+			}
+		}
+		e.Close()
+	}
+	// Import the history into a fresh chain
+	ancient, err := ioutil.TempDir("", "history-import")
+	if err != nil {
+		t.Fatalf("error creating ancient dir: %v", err)
+	}
+	defer os.RemoveAll(ancient)
+
+	db2, err := rawdb.NewDatabaseWithFreezer(rawdb.NewMemoryDatabase(), ancient, "", false)
+	if err != nil {
+		t.Fatalf("error creating database: %v", err)
+	}
+	defer db2.Close()
+
+	gspec.MustCommit(db2)
+	imported, err := core.NewBlockChain(db2, nil, gspec.Config, ethash.NewFaker(), vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatalf("unable to initialize chain: %v", err)
+	}
+	defer imported.Stop()
+
+	if err := ImportHistory(imported, db2, dir); err != nil {
+		t.Fatalf("failed to import history: %v", err)
+	}
+	if head := imported.CurrentFastBlock().NumberU64(); head != uint64(count) {
+		t.Fatalf("imported head mismatch: have %d, want %d", head, count)
+	}
+	for _, block := range blocks {
+		n := block.NumberU64()
+		have := imported.GetBlockByNumber(n)
+		if have == nil || have.Hash() != block.Hash() {
+			t.Fatalf("imported block %d mismatch", n)
+		}
+		if len(block.Transactions()) == 0 {
+			continue
+		}
+		want := chain.GetReceiptsByHash(block.Hash())
+		got := imported.GetReceiptsByHash(block.Hash())
+		if types.DeriveSha(got, trie.NewStackTrie(nil)) != types.DeriveSha(want, trie.NewStackTrie(nil)) {
+			t.Fatalf("imported receipts %d mismatch", n)
+		}
+	}
+	// Importing again is a no-op
+	if err := ImportHistory(imported, db2, dir); err != nil {
+		t.Fatalf("failed to reimport history: %v", err)
+	}
+}
+
+func TestHistoryImportTampered(t *testing.T) {
+	var (
+		gspec   = &core.Genesis{Config: params.TestChainConfig}
+		db      = rawdb.NewMemoryDatabase()
+		genesis = gspec.MustCommit(db)
+	)
+	blocks, _ := core.GenerateChain(gspec.Config, genesis, ethash.NewFaker(), db, 16, nil)
+	chain, err := core.NewBlockChain(db, nil, gspec.Config, ethash.NewFaker(), vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatalf("unable to initialize chain: %v", err)
+	}
+	defer chain.Stop()
+
+	if _, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("error inserting chain: %v", err)
+	}
+	dir := t.TempDir()
+	if err := ExportHistory(chain, dir, 0, 16); err != nil {
+		t.Fatalf("error exporting history: %v", err)
+	}
+	entries, _ := era.ReadDir(dir, historyNetworkName(gspec.Config))
+
+	// Flip a byte in the middle of the file
+	path := filepath.Join(dir, entries[0])
+	blob, _ := ioutil.ReadFile(path)
+	blob[len(blob)/2] ^= 0xff
+	ioutil.WriteFile(path, blob, 0600)
+
+	if err := VerifyHistory(dir, gspec.Config); err == nil {
+		t.Fatalf("tampered history verified")
+	}
+}
@@ -130,6 +130,7 @@ type CacheConfig struct {
 	TrieTimeLimit       time.Duration // Time limit after which to flush the current in-memory trie to disk
 	SnapshotLimit       int           // Memory allowance (MB) to use for caching snapshot entries in memory
 	Preimages           bool          // Whether to store preimage of trie key to the disk
+	HistoryPruneBefore  uint64        // Block number below which ancient bodies and receipts are pruned (0 = keep all)
 
 	SnapshotWait bool // Wait for snapshot construction on startup. TODO(karalabe): This is a dirty hack for testing, nuke it
 }
@@ -236,6 +237,7 @@ func NewBlockChain(db ethdb.Database, cacheConfig *CacheConfig, chainConfig *par
 			Cache:     cacheConfig.TrieCleanLimit,
 			Journal:   cacheConfig.TrieCleanJournal,
 			Preimages: cacheConfig.Preimages,
+			Scheme:    rawdb.ReadStateScheme(db),
 		}),
 		quit:          make(chan struct{}),
 		chainmu:       syncx.NewClosableMutex(),
@@ -392,6 +394,11 @@ func NewBlockChain(db ethdb.Database, cacheConfig *CacheConfig, chainConfig *par
 		bc.wg.Add(1)
 		go bc.maintainTxIndex(txIndexBlock)
 	}
+	// Start the chain history pruner if requested.
+	if bc.cacheConfig.HistoryPruneBefore > 0 {
+		bc.wg.Add(1)
+		go bc.maintainHistory()
+	}
 
 	// If periodic cache journal is required, spin it up.
 	if bc.cacheConfig.TrieCleanRejournal > 0 {
@@ -550,6 +557,13 @@ func (bc *BlockChain) setHeadBeyondRoot(head uint64, root common.Hash, repair bo
 					if root != (common.Hash{}) && !beyondRoot && newHeadBlock.Root() == root {
 						beyondRoot, rootNumber = true, newHeadBlock.NumberU64()
 					}
+					// The path scheme can revert the persistent state to
+					// older blocks, as long as the reverse diffs are retained
+					if triedb := bc.stateCache.TrieDB(); !bc.HasState(newHeadBlock.Root()) && triedb.Recoverable(newHeadBlock.Root()) {
+						if err := triedb.Recover(newHeadBlock.Root()); err != nil {
+							log.Error("Failed to recover state", "number", newHeadBlock.NumberU64(), "hash", newHeadBlock.Hash(), "err", err)
+						}
+					}
 					if _, err := state.New(newHeadBlock.Root(), bc.stateCache, bc.snaps); err != nil {
 						log.Trace("Block state missing, rewinding further", "number", newHeadBlock.NumberU64(), "hash", newHeadBlock.Hash())
 						if pivot == nil || newHeadBlock.NumberU64() > *pivot {
@@ -832,7 +846,17 @@ func (bc *BlockChain) Stop() {
 	//  - HEAD:     So we don't need to reprocess any blocks in the general case
 	//  - HEAD-1:   So we don't do large reorgs if our HEAD becomes an uncle
 	//  - HEAD-127: So we have a hard limit on the number of blocks reexecuted
-	if !bc.cacheConfig.TrieDirtyDisabled {
+	//
+	// The path scheme only has room for a single persistent state, write the
+	// HEAD state there, older ones remain reachable through the reverse diffs.
+	if triedb := bc.stateCache.TrieDB(); triedb.Scheme() == rawdb.PathScheme {
+		recent := bc.CurrentBlock()
+
+		log.Info("Writing cached state to disk", "block", recent.Number(), "hash", recent.Hash(), "root", recent.Root())
+		if err := triedb.Commit(recent.Root(), true, nil); err != nil {
+			log.Error("Failed to commit recent state trie", "err", err)
+		}
+	} else if !bc.cacheConfig.TrieDirtyDisabled {
 		triedb := bc.stateCache.TrieDB()
 
 		for _, offset := range []uint64{0, 1, TriesInMemory - 1} {
@@ -879,6 +903,17 @@ func (bc *BlockChain) insertStopped() bool {
 	return atomic.LoadInt32(&bc.procInterrupt) == 1
 }
 
+// FlushState persists the state trie of the given root from the in-memory trie
+// database into the disk database.
+func (bc *BlockChain) FlushState(root common.Hash) error {
+	if !bc.chainmu.TryLock() {
+		return errChainStopped
+	}
+	defer bc.chainmu.Unlock()
+
+	return bc.stateCache.TrieDB().Commit(root, false, nil)
+}
+
 func (bc *BlockChain) procFutureBlocks() {
 	blocks := make([]*types.Block, 0, bc.futureBlocks.Len())
 	for _, hash := range bc.futureBlocks.Keys() {
@@ -1237,6 +1272,11 @@ func (bc *BlockChain) writeBlockWithState(block *types.Block, receipts []*types.
 	}
 	triedb := bc.stateCache.TrieDB()
 
+	// If we're running with the path scheme, the state is overwritten in place
+	// and only the most recent transitions are kept in memory
+	if triedb.Scheme() == rawdb.PathScheme {
+		return triedb.CapLayers(root, TriesInMemory)
+	}
 	// If we're running an archive node, always flush
 	if bc.cacheConfig.TrieDirtyDisabled {
 		return triedb.Commit(root, false, nil)
@@ -2309,6 +2349,52 @@ func (bc *BlockChain) maintainTxIndex(ancients uint64) {
 	}
 }
 
+// maintainHistory is responsible for pruning the ancient block bodies and
+// receipts below the configured cutoff. As the chain freezer only moves blocks
+// into the ancient store gradually, the pruning is retried on every new head
+// until the cutoff is reached.
+func (bc *BlockChain) maintainHistory() {
+	defer bc.wg.Done()
+
+	cutoff := bc.cacheConfig.HistoryPruneBefore
+	prune := func() bool {
+		frozen, err := bc.db.Ancients()
+		if err != nil {
+			log.Warn("History pruning not supported", "err", err)
+			return true
+		}
+		before := cutoff
+		if before > frozen {
+			before = frozen
+		}
+		if err := rawdb.PruneChainHistory(bc.db, before); err != nil {
+			log.Error("Failed to prune chain history", "before", before, "err", err)
+			return true
+		}
+		return before == cutoff
+	}
+	if prune() {
+		return
+	}
+	headCh := make(chan ChainHeadEvent, 1) // Buffered to avoid locking up the event feed
+	sub := bc.SubscribeChainHeadEvent(headCh)
+	if sub == nil {
+		return
+	}
+	defer sub.Unsubscribe()
+
+	for {
+		select {
+		case <-headCh:
+			if prune() {
+				return
+			}
+		case <-bc.quit:
+			return
+		}
+	}
+}
+
 // reportBlock logs a bad block error.
 func (bc *BlockChain) reportBlock(block *types.Block, receipts types.Receipts, err error) {
 	rawdb.WriteBadBlock(bc.db, block)
@@ -257,6 +257,13 @@ func (bc *BlockChain) GetTransactionLookup(hash common.Hash) *rawdb.LegacyTxLook
 	return lookup
 }
 
+// HistoryPruned reports whether the bodies and receipts of the given block were
+// pruned from the ancient store. The genesis block is never pruned.
+func (bc *BlockChain) HistoryPruned(number uint64) bool {
+	tail, err := bc.db.Tail()
+	return err == nil && number != 0 && number < tail
+}
+
 // GetTd retrieves a block's total difficulty in the canonical chain from the
 // database by hash and number, caching it if found.
 func (bc *BlockChain) GetTd(hash common.Hash, number uint64) *big.Int {
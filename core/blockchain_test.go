@@ -3758,3 +3758,207 @@ func TestSetCanonical(t *testing.T) {
 	chain.SetCanonical(canon[TriesInMemory-1])
 	verify(canon[TriesInMemory-1])
 }
+
+// Tests that a chain running with the path-based state scheme keeps the recent
+// states in memory, persists the head state on shutdown and can rewind below
+// the persistent state using the reverse diffs.
+func TestPathSchemeChain(t *testing.T) {
+	var (
+		gendb   = rawdb.NewMemoryDatabase()
+		key, _  = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		address = crypto.PubkeyToAddress(key.PublicKey)
+		storer  = common.HexToAddress("0xc0de")
+		funds   = big.NewInt(100000000000000000)
+		gspec   = &Genesis{
+			Config: params.TestChainConfig,
+			Alloc: GenesisAlloc{
+				address: {Balance: funds},
+				// NUMBER NUMBER SSTORE: store the block number in its own slot
+				storer: {Code: []byte{byte(vm.NUMBER), byte(vm.NUMBER), byte(vm.SSTORE)}, Balance: big.NewInt(0)},
+			},
+			BaseFee: big.NewInt(params.InitialBaseFee),
+		}
+		genesis = gspec.MustCommit(gendb)
+		signer  = types.LatestSigner(gspec.Config)
+	)
+	blocks, _ := GenerateChain(gspec.Config, genesis, ethash.NewFaker(), gendb, 2*TriesInMemory, func(i int, block *BlockGen) {
+		tx, _ := types.SignTx(types.NewTransaction(block.TxNonce(address), storer, big.NewInt(1), 100000, block.header.BaseFee, nil), signer, key)
+		block.AddTx(tx)
+	})
+	db := rawdb.NewMemoryDatabase()
+	rawdb.WriteStateScheme(db, rawdb.PathScheme)
+	gspec.MustCommit(db)
+
+	chain, err := NewBlockChain(db, nil, gspec.Config, ethash.NewFaker(), vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to create tester chain: %v", err)
+	}
+	if scheme := chain.StateCache().TrieDB().Scheme(); scheme != rawdb.PathScheme {
+		t.Fatalf("state scheme mismatch: have %s, want %s", scheme, rawdb.PathScheme)
+	}
+	if n, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("block %d: failed to insert into chain: %v", n, err)
+	}
+	// The most recent states are available, the older ones are overwritten
+	for _, block := range blocks[len(blocks)-TriesInMemory-1:] {
+		if !chain.HasState(block.Root()) {
+			t.Fatalf("block %d: state missing", block.NumberU64())
+		}
+	}
+	if chain.HasState(blocks[len(blocks)-TriesInMemory-2].Root()) {
+		t.Fatalf("block %d: state not overwritten", len(blocks)-TriesInMemory-1)
+	}
+	chain.Stop()
+
+	// Reopen the chain, the head state must be persisted
+	chain, err = NewBlockChain(db, nil, gspec.Config, ethash.NewFaker(), vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to recreate chain: %v", err)
+	}
+	defer chain.Stop()
+
+	head := chain.CurrentBlock()
+	if head.Hash() != blocks[len(blocks)-1].Hash() {
+		t.Fatalf("head block mismatch: have %d, want %d", head.NumberU64(), len(blocks))
+	}
+	statedb, err := chain.State()
+	if err != nil {
+		t.Fatalf("failed to open head state: %v", err)
+	}
+	slot := common.BigToHash(head.Number())
+	if have := statedb.GetState(storer, slot); have != slot {
+		t.Fatalf("storage mismatch: have %x, want %x", have, slot)
+	}
+	// Rewind below the persistent state
+	target := uint64(TriesInMemory / 2)
+	if err := chain.SetHead(target); err != nil {
+		t.Fatalf("failed to rewind chain: %v", err)
+	}
+	if head := chain.CurrentBlock(); head.NumberU64() != target {
+		t.Fatalf("rewound head mismatch: have %d, want %d", head.NumberU64(), target)
+	}
+	if statedb, err = chain.State(); err != nil {
+		t.Fatalf("failed to open rewound state: %v", err)
+	}
+	slot = common.BigToHash(new(big.Int).SetUint64(target))
+	if have := statedb.GetState(storer, slot); have != slot {
+		t.Fatalf("storage mismatch: have %x, want %x", have, slot)
+	}
+	if have := statedb.GetState(storer, common.BigToHash(new(big.Int).SetUint64(target+1))); have != (common.Hash{}) {
+		t.Fatalf("reverted storage still present: %x", have)
+	}
+	// Continue the chain on top of the recovered state
+	if n, err := chain.InsertChain(blocks[target:]); err != nil {
+		t.Fatalf("block %d: failed to reinsert into chain: %v", n, err)
+	}
+	if head := chain.CurrentBlock(); head.Hash() != blocks[len(blocks)-1].Hash() {
+		t.Fatalf("head block mismatch after reinsertion: have %d, want %d", head.NumberU64(), len(blocks))
+	}
+}
+
+func TestHistoryPruning(t *testing.T) {
+	// Configure and generate a sample block chain
+	var (
+		gendb   = rawdb.NewMemoryDatabase()
+		key, _  = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		address = crypto.PubkeyToAddress(key.PublicKey)
+		funds   = big.NewInt(100000000000000000)
+		gspec   = &Genesis{
+			Config:  params.TestChainConfig,
+			Alloc:   GenesisAlloc{address: {Balance: funds}},
+			BaseFee: big.NewInt(params.InitialBaseFee),
+		}
+		genesis = gspec.MustCommit(gendb)
+		signer  = types.LatestSigner(gspec.Config)
+	)
+	blocks, receipts := GenerateChain(gspec.Config, genesis, ethash.NewFaker(), gendb, 128, func(i int, block *BlockGen) {
+		tx, err := types.SignTx(types.NewTransaction(block.TxNonce(address), common.Address{0x00}, big.NewInt(1000), params.TxGas, block.header.BaseFee, nil), signer, key)
+		if err != nil {
+			panic(err)
+		}
+		block.AddTx(tx)
+	})
+	frdir := t.TempDir()
+	ancientDb, err := rawdb.NewDatabaseWithFreezer(rawdb.NewMemoryDatabase(), frdir, "", false)
+	if err != nil {
+		t.Fatalf("failed to create temp freezer db: %v", err)
+	}
+	defer ancientDb.Close()
+	gspec.MustCommit(ancientDb)
+
+	chain, err := NewBlockChain(ancientDb, nil, params.TestChainConfig, ethash.NewFaker(), vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to create tester chain: %v", err)
+	}
+	headers := make([]*types.Header, len(blocks))
+	for i, block := range blocks {
+		headers[i] = block.Header()
+	}
+	if n, err := chain.InsertHeaderChain(headers, 0); err != nil {
+		t.Fatalf("failed to insert header %d: %v", n, err)
+	}
+	if n, err := chain.InsertReceiptChain(blocks, receipts, 128); err != nil {
+		t.Fatalf("block %d: failed to insert into chain: %v", n, err)
+	}
+	chain.Stop()
+
+	// Pruning beyond the ancient store should fail, pruning below it succeed
+	frozen, _ := ancientDb.Ancients()
+	if err := rawdb.PruneChainHistory(ancientDb, frozen+1); err == nil {
+		t.Fatalf("pruning beyond ancients succeeded")
+	}
+	if err := rawdb.PruneChainHistory(ancientDb, 64); err != nil {
+		t.Fatalf("failed to prune history: %v", err)
+	}
+	check := func(chain *BlockChain, tail uint64) {
+		t.Helper()
+
+		if chain.GetBlockByNumber(0) == nil {
+			t.Fatalf("genesis block pruned")
+		}
+		for _, block := range blocks {
+			number, hash := block.NumberU64(), block.Hash()
+			if chain.GetHeaderByNumber(number) == nil {
+				t.Fatalf("header %d pruned", number)
+			}
+			if pruned := chain.HistoryPruned(number); pruned != (number < tail) {
+				t.Fatalf("block %d pruned status mismatch: have %v", number, pruned)
+			}
+			if have := chain.GetBlockByNumber(number) != nil; have == (number < tail) {
+				t.Fatalf("block %d availability mismatch: have %v", number, have)
+			}
+			if have := chain.GetReceiptsByHash(hash) != nil; have == (number < tail) {
+				t.Fatalf("receipts %d availability mismatch: have %v", number, have)
+			}
+			// Transaction lookups are retained to report pruned history
+			if rawdb.ReadTxLookupEntry(chain.db, block.Transactions()[0].Hash()) == nil {
+				t.Fatalf("transaction lookup of block %d deleted", number)
+			}
+		}
+	}
+	chain, err = NewBlockChain(ancientDb, nil, params.TestChainConfig, ethash.NewFaker(), vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to create tester chain: %v", err)
+	}
+	check(chain, 64)
+	chain.Stop()
+
+	// Prune further via the runtime option
+	cacheConfig := *defaultCacheConfig
+	cacheConfig.HistoryPruneBefore = 100
+
+	chain, err = NewBlockChain(ancientDb, &cacheConfig, params.TestChainConfig, ethash.NewFaker(), vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to create tester chain: %v", err)
+	}
+	defer chain.Stop()
+
+	// Wait for the pruner to run in the background
+	for deadline := time.Now().Add(5 * time.Second); !chain.HistoryPruned(99); {
+		if time.Now().After(deadline) {
+			t.Fatalf("history not pruned in time")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	check(chain, 100)
+}
@@ -32,6 +32,10 @@ var (
 	// ErrNoGenesis is returned when there is no Genesis Block.
 	ErrNoGenesis = errors.New("genesis not found in chain")
 
+	// ErrHistoryPruned is returned when the requested block bodies or receipts
+	// were pruned from the local database.
+	ErrHistoryPruned = errors.New("history pruned")
+
 	errSideChainReceipts = errors.New("side blocks can't be accepted as ancient chain data")
 )
 
@@ -24,6 +24,10 @@ import (
 // NewTxsEvent is posted when a batch of transactions enter the transaction pool.
 type NewTxsEvent struct{ Txs []*types.Transaction }
 
+// TxLifecycleEvent is posted when a batch of transactions change state in the
+// transaction pool.
+type TxLifecycleEvent struct{ Changes []TxLifecycle }
+
 // NewMinedBlockEvent is posted when a block has been imported.
 type NewMinedBlockEvent struct{ Block *types.Block }
 
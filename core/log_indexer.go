@@ -0,0 +1,135 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// LogIndexer implements a core.ChainIndexer, building an exact index from the
+// addresses and topics of logs to their positions in the canonical chain.
+//
+// Every section is stored along with the hash of its last block. Sections which
+// are re-indexed after a reorg replace the previous data, stale sections are
+// recognized by their head hash not being canonical.
+type LogIndexer struct {
+	db        ethdb.Database                         // database instance to write index data and metadata into
+	section   uint64                                 // Section is the section number being processed currently
+	head      common.Hash                            // Head is the hash of the last header processed
+	addresses map[common.Address][]rawdb.LogPosition // Log positions by emitting address
+	topics    []map[common.Hash][]rawdb.LogPosition  // Log positions by topic, per topic position
+}
+
+// NewLogIndexer returns a chain indexer that generates the log index for the
+// canonical chain.
+func NewLogIndexer(db ethdb.Database, size, confirms uint64) *ChainIndexer {
+	backend := &LogIndexer{db: db}
+	table := rawdb.NewTable(db, string(rawdb.LogIndexPrefix))
+
+	return NewChainIndexer(db, table, backend, size, confirms, bloomThrottling, "logindex")
+}
+
+// Reset implements core.ChainIndexerBackend, starting a new log index section.
+func (l *LogIndexer) Reset(ctx context.Context, section uint64, lastSectionHead common.Hash) error {
+	l.section, l.head = section, common.Hash{}
+	l.addresses = make(map[common.Address][]rawdb.LogPosition)
+	l.topics = nil
+	return nil
+}
+
+// Process implements core.ChainIndexerBackend, adding the logs of a new header
+// into the index. Receipts are read from the key-value store or the ancients.
+//
+// Blocks below the history tail have their receipts pruned, they are skipped
+// without contributing to the index. Log queries into pruned history are
+// rejected before consulting the index anyway.
+func (l *LogIndexer) Process(ctx context.Context, header *types.Header) error {
+	var (
+		hash     = header.Hash()
+		number   = header.Number.Uint64()
+		receipts = rawdb.ReadRawReceipts(l.db, hash, number)
+	)
+	if receipts == nil && header.ReceiptHash != types.EmptyRootHash {
+		if tail, err := l.db.Tail(); err != nil || number >= tail {
+			return fmt.Errorf("missing receipts of block #%d [%x]", number, hash)
+		}
+	}
+	var index uint
+	for _, receipt := range receipts {
+		for _, entry := range receipt.Logs {
+			pos := rawdb.LogPosition{Block: number, Index: index}
+			l.addresses[entry.Address] = append(l.addresses[entry.Address], pos)
+			for i, topic := range entry.Topics {
+				if i == len(l.topics) {
+					l.topics = append(l.topics, make(map[common.Hash][]rawdb.LogPosition))
+				}
+				l.topics[i][topic] = append(l.topics[i][topic], pos)
+			}
+			index++
+		}
+	}
+	l.head = hash
+	return nil
+}
+
+// Commit implements core.ChainIndexerBackend, replacing any previous data of
+// the section with the freshly built index.
+func (l *LogIndexer) Commit() error {
+	rawdb.DeleteLogIndexSection(l.db, l.section)
+
+	batch := l.db.NewBatch()
+	flush := func() error {
+		if batch.ValueSize() < ethdb.IdealBatchSize {
+			return nil
+		}
+		if err := batch.Write(); err != nil {
+			return err
+		}
+		batch.Reset()
+		return nil
+	}
+	for address, positions := range l.addresses {
+		rawdb.WriteLogIndexAddress(batch, l.section, address, positions)
+		if err := flush(); err != nil {
+			return err
+		}
+	}
+	for i, topics := range l.topics {
+		for topic, positions := range topics {
+			rawdb.WriteLogIndexTopic(batch, l.section, i, topic, positions)
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+	}
+	// Write the head last, marking the section as complete.
+	rawdb.WriteLogIndexHead(batch, l.section, l.head)
+	log.Debug("Committed log index section", "section", l.section, "head", l.head, "addresses", len(l.addresses))
+	return batch.Write()
+}
+
+// Prune returns an empty error since we don't support pruning here.
+func (l *LogIndexer) Prune(threshold uint64) error {
+	return nil
+}
@@ -0,0 +1,181 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"context"
+	"math/big"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// indexSection runs the log indexer over the given blocks as a single section.
+func indexSection(t *testing.T, indexer *LogIndexer, section uint64, blocks []*types.Block) {
+	if err := indexer.Reset(context.Background(), section, common.Hash{}); err != nil {
+		t.Fatal(err)
+	}
+	for _, block := range blocks {
+		if err := indexer.Process(context.Background(), block.Header()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := indexer.Commit(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLogIndexer(t *testing.T) {
+	var (
+		db     = rawdb.NewMemoryDatabase()
+		addr1  = common.HexToAddress("0x1111")
+		addr2  = common.HexToAddress("0x2222")
+		topic1 = common.HexToHash("0x01")
+		topic2 = common.HexToHash("0x02")
+	)
+	genesis := (&Genesis{BaseFee: big.NewInt(params.InitialBaseFee)}).MustCommit(db)
+	blocks, receipts := GenerateChain(params.TestChainConfig, genesis, ethash.NewFaker(), db, 4, func(i int, gen *BlockGen) {
+		if i%2 == 1 {
+			return // leave every second block without logs
+		}
+		receipt := types.NewReceipt(nil, false, 0)
+		receipt.Logs = []*types.Log{
+			{Address: addr1, Topics: []common.Hash{topic1}},
+			{Address: addr2, Topics: []common.Hash{topic2, topic1}},
+		}
+		gen.AddUncheckedReceipt(receipt)
+		gen.AddUncheckedTx(types.NewTransaction(uint64(i), common.Address{}, big.NewInt(1), 21000, gen.BaseFee(), nil))
+	})
+	for i, block := range blocks {
+		rawdb.WriteBlock(db, block)
+		rawdb.WriteCanonicalHash(db, block.Hash(), block.NumberU64())
+		rawdb.WriteReceipts(db, block.Hash(), block.NumberU64(), receipts[i])
+	}
+	indexer := &LogIndexer{db: db}
+	indexSection(t, indexer, 0, blocks)
+
+	if head := rawdb.ReadLogIndexHead(db, 0); head != blocks[3].Hash() {
+		t.Fatalf("wrong section head: have %x, want %x", head, blocks[3].Hash())
+	}
+	tests := []struct {
+		have []rawdb.LogPosition
+		want []rawdb.LogPosition
+	}{
+		{rawdb.ReadLogIndexAddress(db, 0, addr1), []rawdb.LogPosition{{Block: 1, Index: 0}, {Block: 3, Index: 0}}},
+		{rawdb.ReadLogIndexAddress(db, 0, addr2), []rawdb.LogPosition{{Block: 1, Index: 1}, {Block: 3, Index: 1}}},
+		{rawdb.ReadLogIndexTopic(db, 0, 0, topic1), []rawdb.LogPosition{{Block: 1, Index: 0}, {Block: 3, Index: 0}}},
+		{rawdb.ReadLogIndexTopic(db, 0, 0, topic2), []rawdb.LogPosition{{Block: 1, Index: 1}, {Block: 3, Index: 1}}},
+		{rawdb.ReadLogIndexTopic(db, 0, 1, topic1), []rawdb.LogPosition{{Block: 1, Index: 1}, {Block: 3, Index: 1}}},
+		{rawdb.ReadLogIndexTopic(db, 0, 1, topic2), nil},
+	}
+	for i, test := range tests {
+		if !reflect.DeepEqual(test.have, test.want) {
+			t.Errorf("test %d: wrong positions: have %v, want %v", i, test.have, test.want)
+		}
+	}
+	// Re-index the section with fewer blocks and check stale entries are removed.
+	indexSection(t, indexer, 0, blocks[:2])
+
+	if head := rawdb.ReadLogIndexHead(db, 0); head != blocks[1].Hash() {
+		t.Fatalf("wrong section head after reindex: have %x, want %x", head, blocks[1].Hash())
+	}
+	if have, want := rawdb.ReadLogIndexAddress(db, 0, addr1), []rawdb.LogPosition{{Block: 1, Index: 0}}; !reflect.DeepEqual(have, want) {
+		t.Errorf("wrong positions after reindex: have %v, want %v", have, want)
+	}
+	// Deleting the section removes all of its entries.
+	rawdb.DeleteLogIndexSection(db, 0)
+	if head := rawdb.ReadLogIndexHead(db, 0); head != (common.Hash{}) {
+		t.Errorf("section head not deleted: %x", head)
+	}
+	if positions := rawdb.ReadLogIndexTopic(db, 0, 0, topic2); positions != nil {
+		t.Errorf("section entries not deleted: %v", positions)
+	}
+}
+
+// Tests that the log indexer skips the blocks with pruned receipts instead of
+// getting stuck on them.
+func TestLogIndexerPrunedHistory(t *testing.T) {
+	var (
+		gendb   = rawdb.NewMemoryDatabase()
+		addr    = common.HexToAddress("0x1111")
+		gspec   = &Genesis{Config: params.TestChainConfig, BaseFee: big.NewInt(params.InitialBaseFee)}
+		genesis = gspec.MustCommit(gendb)
+	)
+	blocks, receipts := GenerateChain(gspec.Config, genesis, ethash.NewFaker(), gendb, 128, func(i int, gen *BlockGen) {
+		receipt := types.NewReceipt(nil, false, 0)
+		receipt.Logs = []*types.Log{{Address: addr}}
+		gen.AddUncheckedReceipt(receipt)
+		gen.AddUncheckedTx(types.NewTransaction(uint64(i), common.Address{}, big.NewInt(1), 21000, gen.BaseFee(), nil))
+	})
+	db, err := rawdb.NewDatabaseWithFreezer(rawdb.NewMemoryDatabase(), t.TempDir(), "", false)
+	if err != nil {
+		t.Fatalf("failed to create temp freezer db: %v", err)
+	}
+	defer db.Close()
+	gspec.MustCommit(db)
+
+	chain, err := NewBlockChain(db, nil, gspec.Config, ethash.NewFaker(), vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to create tester chain: %v", err)
+	}
+	defer chain.Stop()
+
+	headers := make([]*types.Header, len(blocks))
+	for i, block := range blocks {
+		headers[i] = block.Header()
+	}
+	if n, err := chain.InsertHeaderChain(headers, 0); err != nil {
+		t.Fatalf("failed to insert header %d: %v", n, err)
+	}
+	if n, err := chain.InsertReceiptChain(blocks, receipts, 128); err != nil {
+		t.Fatalf("block %d: failed to insert into chain: %v", n, err)
+	}
+	if err := rawdb.PruneChainHistory(db, 48); err != nil {
+		t.Fatalf("failed to prune history: %v", err)
+	}
+	indexer := NewLogIndexer(db, 32, 0)
+	defer indexer.Close()
+	indexer.Start(chain)
+
+	for start := time.Now(); ; time.Sleep(10 * time.Millisecond) {
+		if sections, _, _ := indexer.Sections(); sections == 4 {
+			break
+		}
+		if time.Since(start) > 5*time.Second {
+			t.Fatal("log index not built in time")
+		}
+	}
+	// Only the logs of the blocks above the history tail are indexed
+	for section := uint64(0); section < 4; section++ {
+		var want []rawdb.LogPosition
+		for number := section * 32; number < (section+1)*32; number++ {
+			if number >= 48 {
+				want = append(want, rawdb.LogPosition{Block: number, Index: 0})
+			}
+		}
+		if have := rawdb.ReadLogIndexAddress(db, section, addr); !reflect.DeepEqual(have, want) {
+			t.Errorf("section %d: wrong positions: have %v, want %v", section, have, want)
+		}
+	}
+}
@@ -445,9 +445,12 @@ func ReadBodyRLP(db ethdb.Reader, hash common.Hash, number uint64) rlp.RawValue
 		// Check if the data is in ancients
 		if isCanon(reader, number, hash) {
 			data, _ = reader.Ancient(freezerBodiesTable, number)
-			return nil
+			if len(data) > 0 {
+				return nil
+			}
 		}
-		// If not, try reading from leveldb
+		// If not, try reading from leveldb. The genesis block is always
+		// kept there, even if the history was pruned from the ancients.
 		data, _ = db.Get(blockBodyKey(number, hash))
 		return nil
 	})
@@ -483,7 +486,10 @@ func WriteBodyRLP(db ethdb.KeyValueWriter, hash common.Hash, number uint64, rlp
 // HasBody verifies the existence of a block body corresponding to the hash.
 func HasBody(db ethdb.Reader, hash common.Hash, number uint64) bool {
 	if isCanon(db, number, hash) {
-		return true
+		// Pruned history is only marked as canonical, but not present
+		if has, _ := db.HasAncient(freezerBodiesTable, number); has {
+			return true
+		}
 	}
 	if has, err := db.Has(blockBodyKey(number, hash)); !has || err != nil {
 		return false
@@ -573,7 +579,10 @@ func DeleteTd(db ethdb.KeyValueWriter, hash common.Hash, number uint64) {
 // to a block.
 func HasReceipts(db ethdb.Reader, hash common.Hash, number uint64) bool {
 	if isCanon(db, number, hash) {
-		return true
+		// Pruned history is only marked as canonical, but not present
+		if has, _ := db.HasAncient(freezerReceiptTable, number); has {
+			return true
+		}
 	}
 	if has, err := db.Has(blockReceiptsKey(number, hash)); !has || err != nil {
 		return false
@@ -588,9 +597,12 @@ func ReadReceiptsRLP(db ethdb.Reader, hash common.Hash, number uint64) rlp.RawVa
 		// Check if the data is in ancients
 		if isCanon(reader, number, hash) {
 			data, _ = reader.Ancient(freezerReceiptTable, number)
-			return nil
+			if len(data) > 0 {
+				return nil
+			}
 		}
-		// If not, try reading from leveldb
+		// If not, try reading from leveldb. The genesis block is always
+		// kept there, even if the history was pruned from the ancients.
 		data, _ = db.Get(blockReceiptsKey(number, hash))
 		return nil
 	})
@@ -175,3 +175,104 @@ func DeleteBloombits(db ethdb.Database, bit uint, from uint64, to uint64) {
 		log.Crit("Failed to delete bloom bits", "err", it.Error())
 	}
 }
+
+// LogPosition is the location of a log in the canonical chain.
+type LogPosition struct {
+	Block uint64 // Number of the block containing the log
+	Index uint   // Index of the log within the block
+}
+
+// Fields of the log index. Topics are indexed per position, the topic at
+// position i is stored under logIndexTopicField+i.
+const (
+	logIndexAddressField byte = 0
+	logIndexTopicField   byte = 1
+)
+
+// ReadLogIndexHead retrieves the hash of the last block of a log index section,
+// identifying the chain the section was built from.
+func ReadLogIndexHead(db ethdb.KeyValueReader, section uint64) common.Hash {
+	data, _ := db.Get(logIndexHeadKey(section))
+	if len(data) != common.HashLength {
+		return common.Hash{}
+	}
+	return common.BytesToHash(data)
+}
+
+// WriteLogIndexHead stores the hash of the last block of a log index section.
+func WriteLogIndexHead(db ethdb.KeyValueWriter, section uint64, head common.Hash) {
+	if err := db.Put(logIndexHeadKey(section), head.Bytes()); err != nil {
+		log.Crit("Failed to store log index head", "err", err)
+	}
+}
+
+// ReadLogIndexAddress retrieves the positions of all logs emitted by the given
+// address within a log index section.
+func ReadLogIndexAddress(db ethdb.KeyValueReader, section uint64, address common.Address) []LogPosition {
+	return readLogPositions(db, logIndexKey(section, logIndexAddressField, address.Bytes()))
+}
+
+// WriteLogIndexAddress stores the positions of all logs emitted by the given
+// address within a log index section.
+func WriteLogIndexAddress(db ethdb.KeyValueWriter, section uint64, address common.Address, positions []LogPosition) {
+	writeLogPositions(db, logIndexKey(section, logIndexAddressField, address.Bytes()), positions)
+}
+
+// ReadLogIndexTopic retrieves the positions of all logs within a log index section
+// which have the given topic at the given topic position.
+func ReadLogIndexTopic(db ethdb.KeyValueReader, section uint64, position int, topic common.Hash) []LogPosition {
+	return readLogPositions(db, logIndexKey(section, logIndexTopicField+byte(position), topic.Bytes()))
+}
+
+// WriteLogIndexTopic stores the positions of all logs within a log index section
+// which have the given topic at the given topic position.
+func WriteLogIndexTopic(db ethdb.KeyValueWriter, section uint64, position int, topic common.Hash, positions []LogPosition) {
+	writeLogPositions(db, logIndexKey(section, logIndexTopicField+byte(position), topic.Bytes()), positions)
+}
+
+func readLogPositions(db ethdb.KeyValueReader, key []byte) []LogPosition {
+	data, _ := db.Get(key)
+	if len(data) == 0 {
+		return nil
+	}
+	var positions []LogPosition
+	if err := rlp.DecodeBytes(data, &positions); err != nil {
+		log.Error("Invalid log index entry RLP", "key", key, "err", err)
+		return nil
+	}
+	return positions
+}
+
+func writeLogPositions(db ethdb.KeyValueWriter, key []byte, positions []LogPosition) {
+	data, err := rlp.EncodeToBytes(positions)
+	if err != nil {
+		log.Crit("Failed to encode log index entry", "err", err)
+	}
+	if err := db.Put(key, data); err != nil {
+		log.Crit("Failed to store log index entry", "err", err)
+	}
+}
+
+// DeleteLogIndexSection removes all log index entries of the given section.
+func DeleteLogIndexSection(db ethdb.KeyValueStore, section uint64) {
+	batch := db.NewBatch()
+	it := db.NewIterator(append(logIndexPrefix, encodeBlockNumber(section)...), nil)
+	defer it.Release()
+
+	for it.Next() {
+		batch.Delete(it.Key())
+		if batch.ValueSize() >= ethdb.IdealBatchSize {
+			if err := batch.Write(); err != nil {
+				log.Crit("Failed to delete log index section", "err", err)
+			}
+			batch.Reset()
+		}
+	}
+	if it.Error() != nil {
+		log.Crit("Failed to iterate log index section", "err", it.Error())
+	}
+	batch.Delete(logIndexHeadKey(section))
+	if err := batch.Write(); err != nil {
+		log.Crit("Failed to delete log index section", "err", err)
+	}
+}
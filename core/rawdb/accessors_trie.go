@@ -0,0 +1,185 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rawdb
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// The list of supported trie node storage schemes.
+const (
+	// HashScheme stores every trie node keyed by its hash. Stale nodes are
+	// never overwritten and can only be removed by offline pruning.
+	HashScheme = "hash"
+
+	// PathScheme stores every trie node keyed by its owner and path in the
+	// trie, overwriting the previous version in place.
+	PathScheme = "path"
+)
+
+// ReadStateScheme retrieves the trie node storage scheme of the database. An
+// empty string is returned if the database hasn't been marked yet.
+func ReadStateScheme(db ethdb.KeyValueReader) string {
+	data, _ := db.Get(stateSchemeKey)
+	return string(data)
+}
+
+// WriteStateScheme stores the trie node storage scheme of the database.
+func WriteStateScheme(db ethdb.KeyValueWriter, scheme string) {
+	if err := db.Put(stateSchemeKey, []byte(scheme)); err != nil {
+		log.Crit("Failed to store state scheme", "err", err)
+	}
+}
+
+// ParseStateScheme checks the requested trie node storage scheme against the
+// one used by the given database and returns the scheme to run with. A path
+// scheme can only be selected for a database without any existing state.
+func ParseStateScheme(provided string, db ethdb.Database) (string, error) {
+	stored := ReadStateScheme(db)
+	if stored == "" && ReadCanonicalHash(db, 0) != (common.Hash{}) {
+		stored = HashScheme // legacy database created before the marker
+	}
+	switch {
+	case provided == "" && stored == "":
+		return HashScheme, nil
+	case provided == "":
+		return stored, nil
+	case provided != HashScheme && provided != PathScheme:
+		return "", fmt.Errorf("unknown state scheme %q", provided)
+	case stored != "" && stored != provided:
+		return "", fmt.Errorf("state.scheme choice was %s but found pre-existing %s database", provided, stored)
+	}
+	return provided, nil
+}
+
+// ReadAccountTrieNode retrieves the account trie node stored at the given path.
+func ReadAccountTrieNode(db ethdb.KeyValueReader, path []byte) []byte {
+	data, _ := db.Get(accountTrieNodeKey(path))
+	return data
+}
+
+// WriteAccountTrieNode stores the account trie node at the given path.
+func WriteAccountTrieNode(db ethdb.KeyValueWriter, path []byte, node []byte) {
+	if err := db.Put(accountTrieNodeKey(path), node); err != nil {
+		log.Crit("Failed to store account trie node", "err", err)
+	}
+}
+
+// DeleteAccountTrieNode deletes the account trie node stored at the given path.
+func DeleteAccountTrieNode(db ethdb.KeyValueWriter, path []byte) {
+	if err := db.Delete(accountTrieNodeKey(path)); err != nil {
+		log.Crit("Failed to delete account trie node", "err", err)
+	}
+}
+
+// ReadStorageTrieNode retrieves the storage trie node of the given account
+// stored at the given path.
+func ReadStorageTrieNode(db ethdb.KeyValueReader, accountHash common.Hash, path []byte) []byte {
+	data, _ := db.Get(storageTrieNodeKey(accountHash, path))
+	return data
+}
+
+// WriteStorageTrieNode stores the storage trie node of the given account at
+// the given path.
+func WriteStorageTrieNode(db ethdb.KeyValueWriter, accountHash common.Hash, path []byte, node []byte) {
+	if err := db.Put(storageTrieNodeKey(accountHash, path), node); err != nil {
+		log.Crit("Failed to store storage trie node", "err", err)
+	}
+}
+
+// DeleteStorageTrieNode deletes the storage trie node of the given account
+// stored at the given path.
+func DeleteStorageTrieNode(db ethdb.KeyValueWriter, accountHash common.Hash, path []byte) {
+	if err := db.Delete(storageTrieNodeKey(accountHash, path)); err != nil {
+		log.Crit("Failed to delete storage trie node", "err", err)
+	}
+}
+
+// IterateStorageTrieNodes returns an iterator for walking all the storage trie
+// nodes of a specific account.
+func IterateStorageTrieNodes(db ethdb.Iteratee, accountHash common.Hash) ethdb.Iterator {
+	return db.NewIterator(storageTrieNodeKey(accountHash, nil), nil)
+}
+
+// ReadPersistentStateID retrieves the id of the latest state flushed into the
+// path-based trie node storage.
+func ReadPersistentStateID(db ethdb.KeyValueReader) uint64 {
+	data, _ := db.Get(persistentStateIDKey)
+	if len(data) != 8 {
+		return 0
+	}
+	return binary.BigEndian.Uint64(data)
+}
+
+// WritePersistentStateID stores the id of the latest state flushed into the
+// path-based trie node storage.
+func WritePersistentStateID(db ethdb.KeyValueWriter, id uint64) {
+	if err := db.Put(persistentStateIDKey, encodeBlockNumber(id)); err != nil {
+		log.Crit("Failed to store the persistent state ID", "err", err)
+	}
+}
+
+// ReadStateID retrieves the state id of the given state root, or nil if the
+// state is not tracked.
+func ReadStateID(db ethdb.KeyValueReader, root common.Hash) *uint64 {
+	data, _ := db.Get(stateIDKey(root))
+	if len(data) != 8 {
+		return nil
+	}
+	id := binary.BigEndian.Uint64(data)
+	return &id
+}
+
+// WriteStateID stores the state id of the given state root.
+func WriteStateID(db ethdb.KeyValueWriter, root common.Hash, id uint64) {
+	if err := db.Put(stateIDKey(root), encodeBlockNumber(id)); err != nil {
+		log.Crit("Failed to store state ID", "err", err)
+	}
+}
+
+// DeleteStateID deletes the state id of the given state root.
+func DeleteStateID(db ethdb.KeyValueWriter, root common.Hash) {
+	if err := db.Delete(stateIDKey(root)); err != nil {
+		log.Crit("Failed to delete state ID", "err", err)
+	}
+}
+
+// ReadReverseDiff retrieves the RLP encoded reverse diff which reverts the
+// state with the given id to its parent.
+func ReadReverseDiff(db ethdb.KeyValueReader, id uint64) []byte {
+	data, _ := db.Get(reverseDiffKey(id))
+	return data
+}
+
+// WriteReverseDiff stores the RLP encoded reverse diff of the given state id.
+func WriteReverseDiff(db ethdb.KeyValueWriter, id uint64, blob []byte) {
+	if err := db.Put(reverseDiffKey(id), blob); err != nil {
+		log.Crit("Failed to store reverse diff", "err", err)
+	}
+}
+
+// DeleteReverseDiff deletes the reverse diff of the given state id.
+func DeleteReverseDiff(db ethdb.KeyValueWriter, id uint64) {
+	if err := db.Delete(reverseDiffKey(id)); err != nil {
+		log.Crit("Failed to delete reverse diff", "err", err)
+	}
+}
@@ -56,7 +56,7 @@ type chainFreezer struct {
 
 // newChainFreezer initializes the freezer for ancient chain data.
 func newChainFreezer(datadir string, namespace string, readonly bool, maxTableSize uint32, tables map[string]bool) (*chainFreezer, error) {
-	freezer, err := NewFreezer(datadir, namespace, readonly, maxTableSize, tables)
+	freezer, err := newFreezer(datadir, namespace, readonly, maxTableSize, tables, freezerPrunable)
 	if err != nil {
 		return nil, err
 	}
@@ -68,6 +68,21 @@ func newChainFreezer(datadir string, namespace string, readonly bool, maxTableSi
 	}, nil
 }
 
+// RecompressFreezerTable rewrites a table of the chain freezer in the given
+// directory with the given compression codec. The freezer must not be in use.
+func RecompressFreezerTable(datadir string, kind string, codec FreezerCodec) error {
+	if _, ok := FreezerNoSnappy[kind]; !ok {
+		return errUnknownTable
+	}
+	freezer, err := newChainFreezer(datadir, "", false, freezerTableSize, FreezerNoSnappy)
+	if err != nil {
+		return err
+	}
+	defer freezer.Close()
+
+	return freezer.RecompressTable(kind, codec)
+}
+
 // Close closes the chain freezer instance and terminates the background thread.
 func (f *chainFreezer) Close() error {
 	err := f.Freezer.Close()
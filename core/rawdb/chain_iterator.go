@@ -17,6 +17,7 @@
 package rawdb
 
 import (
+	"fmt"
 	"runtime"
 	"sync/atomic"
 	"time"
@@ -178,6 +179,10 @@ func iterateTransactions(db ethdb.Database, from uint64, to uint64, reverse bool
 // There is a passed channel, the whole procedure will be interrupted if any
 // signal received.
 func indexTransactions(db ethdb.Database, from uint64, to uint64, interrupt chan struct{}, hook func(uint64) bool) {
+	// Block bodies below the history tail are pruned, skip them
+	if tail, err := db.Tail(); err == nil && from < tail {
+		from = tail
+	}
 	// short circuit for invalid range
 	if from >= to {
 		return
@@ -270,6 +275,14 @@ func indexTransactionsForTesting(db ethdb.Database, from uint64, to uint64, inte
 // There is a passed channel, the whole procedure will be interrupted if any
 // signal received.
 func unindexTransactions(db ethdb.Database, from uint64, to uint64, interrupt chan struct{}, hook func(uint64) bool) {
+	// Block bodies below the history tail are pruned, so the hashes of their
+	// transactions are unknown. The lookup entries of those blocks can't be
+	// found without scanning the entire index and are left in place, even
+	// if they fall out of the requested range. They are still useful to tell
+	// pruned history apart from unknown transactions.
+	if tail, err := db.Tail(); err == nil && from < tail {
+		from = tail
+	}
 	// short circuit for invalid range
 	if from >= to {
 		return
@@ -352,3 +365,31 @@ func UnindexTransactions(db ethdb.Database, from uint64, to uint64, interrupt ch
 func unindexTransactionsForTesting(db ethdb.Database, from uint64, to uint64, interrupt chan struct{}, hook func(uint64) bool) {
 	unindexTransactions(db, from, to, interrupt, hook)
 }
+
+// PruneChainHistory drops the ancient block bodies and receipts below the given
+// block number, keeping the headers in place. The transaction indices of the
+// pruned blocks are retained, so lookups can tell pruned history apart from
+// unknown transactions. Note, these are never unindexed afterwards, not even if
+// they fall out of the transaction lookup limit.
+func PruneChainHistory(db ethdb.Database, before uint64) error {
+	frozen, err := db.Ancients()
+	if err != nil {
+		return err
+	}
+	if before > frozen {
+		return fmt.Errorf("history pruning beyond ancient limit, before %d, ancients %d", before, frozen)
+	}
+	tail, err := db.Tail()
+	if err != nil {
+		return err
+	}
+	if before <= tail {
+		return nil
+	}
+	start := time.Now()
+	if err := db.TruncateTail(before); err != nil {
+		return err
+	}
+	log.Info("Pruned chain history", "tail", before, "elapsed", common.PrettyDuration(time.Since(start)))
+	return nil
+}
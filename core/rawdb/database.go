@@ -21,6 +21,8 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"path/filepath"
+	"strings"
 	"sync/atomic"
 	"time"
 
@@ -235,7 +237,9 @@ func NewDatabaseWithFreezer(db ethdb.KeyValueStore, freezer string, namespace st
 			// freezer.
 		}
 	}
-	// Freezer is consistent with the key-value database, permit combining the two
+	// Freezer is consistent with the key-value database, permit combining the two.
+	// Track the writes into the key-value store from here on.
+	db = newStatsStore(db, namespace)
 	if !frdb.readonly {
 		frdb.wg.Add(1)
 		go func() {
@@ -265,21 +269,78 @@ func NewMemoryDatabaseWithCap(size int) ethdb.Database {
 // NewLevelDBDatabase creates a persistent key-value database without a freezer
 // moving immutable chain segments into cold storage.
 func NewLevelDBDatabase(file string, cache int, handles int, namespace string, readonly bool) (ethdb.Database, error) {
-	db, err := leveldb.New(file, cache, handles, namespace, readonly)
-	if err != nil {
-		return nil, err
-	}
-	return NewDatabase(db), nil
+	return Open(OpenOptions{
+		Type:      DBLeveldb,
+		Directory: file,
+		Namespace: namespace,
+		Cache:     cache,
+		Handles:   handles,
+		ReadOnly:  readonly,
+	})
 }
 
 // NewLevelDBDatabaseWithFreezer creates a persistent key-value database with a
 // freezer moving immutable chain segments into cold storage.
 func NewLevelDBDatabaseWithFreezer(file string, cache int, handles int, freezer string, namespace string, readonly bool) (ethdb.Database, error) {
-	kvdb, err := leveldb.New(file, cache, handles, namespace, readonly)
+	return Open(OpenOptions{
+		Type:              DBLeveldb,
+		Directory:         file,
+		AncientsDirectory: freezer,
+		Namespace:         namespace,
+		Cache:             cache,
+		Handles:           handles,
+		ReadOnly:          readonly,
+	})
+}
+
+// NewPebbleDBDatabase creates a persistent key-value database without a freezer
+// moving immutable chain segments into cold storage.
+func NewPebbleDBDatabase(file string, cache int, handles int, namespace string, readonly bool) (ethdb.Database, error) {
+	return Open(OpenOptions{
+		Type:      DBPebble,
+		Directory: file,
+		Namespace: namespace,
+		Cache:     cache,
+		Handles:   handles,
+		ReadOnly:  readonly,
+	})
+}
+
+const (
+	// DBLeveldb is the identifier of the LevelDB key-value store.
+	DBLeveldb = "leveldb"
+
+	// DBPebble is the identifier of the Pebble key-value store.
+	DBPebble = "pebble"
+
+	// engineFile is the name of the file within a database directory which
+	// records the key-value store the database was created with.
+	engineFile = "ENGINE"
+)
+
+// OpenOptions contains the options to apply when opening a database.
+type OpenOptions struct {
+	Type              string // Key-value store to use, empty for the one of an existing database
+	Directory         string // Directory of the key-value store
+	AncientsDirectory string // Directory of the freezer, empty to open without one
+	Namespace         string // Prefix of the metrics reported by the database
+	Cache             int    // Memory allowance of the key-value store in megabytes
+	Handles           int    // Number of file handles of the key-value store
+	ReadOnly          bool   // Whether to open the database in read-only mode
+}
+
+// Open opens a persistent database with the configured key-value store, also
+// attaching a freezer to it if an ancients directory is given. Databases are
+// only opened with the key-value store they were created with.
+func Open(o OpenOptions) (ethdb.Database, error) {
+	kvdb, err := openKeyValueDatabase(o)
 	if err != nil {
 		return nil, err
 	}
-	frdb, err := NewDatabaseWithFreezer(kvdb, freezer, namespace, readonly)
+	if o.AncientsDirectory == "" {
+		return NewDatabase(kvdb), nil
+	}
+	frdb, err := NewDatabaseWithFreezer(kvdb, o.AncientsDirectory, o.Namespace, o.ReadOnly)
 	if err != nil {
 		kvdb.Close()
 		return nil, err
@@ -287,6 +348,68 @@ func NewLevelDBDatabaseWithFreezer(file string, cache int, handles int, freezer
 	return frdb, nil
 }
 
+// openKeyValueDatabase opens the key-value store of a database, refusing to do
+// so if the directory contains a database of a different type.
+func openKeyValueDatabase(o OpenOptions) (ethdb.KeyValueStore, error) {
+	existing, err := ReadDatabaseEngine(o.Directory)
+	if err != nil {
+		return nil, err
+	}
+	engine := o.Type
+	switch {
+	case engine == "" && existing != "":
+		engine = existing
+	case engine == "":
+		engine = DBLeveldb
+	case existing != "" && engine != existing:
+		return nil, fmt.Errorf("db.engine choice was %s but found pre-existing %s database in %s", engine, existing, o.Directory)
+	}
+	var kvdb ethdb.KeyValueStore
+	switch engine {
+	case DBLeveldb:
+		log.Info("Using leveldb as the backing database")
+		kvdb, err = leveldb.New(o.Directory, o.Cache, o.Handles, o.Namespace, o.ReadOnly)
+	case DBPebble:
+		log.Info("Using pebble as the backing database")
+		kvdb, err = newPebbleDBDatabase(o.Directory, o.Cache, o.Handles, o.Namespace, o.ReadOnly)
+	default:
+		return nil, fmt.Errorf("unknown db.engine %q", engine)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if existing == "" && !o.ReadOnly {
+		if err := writeDatabaseEngine(o.Directory, engine); err != nil {
+			kvdb.Close()
+			return nil, err
+		}
+	}
+	return kvdb, nil
+}
+
+// ReadDatabaseEngine returns the key-value store the database in the given
+// directory was created with, or an empty string if there is no database.
+func ReadDatabaseEngine(dir string) (string, error) {
+	blob, err := os.ReadFile(filepath.Join(dir, engineFile))
+	if err == nil {
+		return strings.TrimSpace(string(blob)), nil
+	}
+	if !os.IsNotExist(err) {
+		return "", err
+	}
+	// Databases created before the engine was recorded are all LevelDB ones.
+	if _, err := os.Stat(filepath.Join(dir, "CURRENT")); err == nil {
+		return DBLeveldb, nil
+	}
+	return "", nil
+}
+
+// writeDatabaseEngine records the key-value store of the database in the
+// given directory.
+func writeDatabaseEngine(dir string, engine string) error {
+	return os.WriteFile(filepath.Join(dir, engineFile), []byte(engine+"\n"), 0644)
+}
+
 type counter uint64
 
 func (c counter) String() string {
@@ -324,6 +447,8 @@ func InspectDatabase(db ethdb.Database, keyPrefix, keyStart []byte) error {
 	defer it.Release()
 
 	var (
+		pathScheme = ReadStateScheme(db) == PathScheme
+
 		count  int64
 		start  = time.Now()
 		logged = time.Now()
@@ -336,12 +461,14 @@ func InspectDatabase(db ethdb.Database, keyPrefix, keyStart []byte) error {
 		numHashPairings stat
 		hashNumPairings stat
 		tries           stat
+		stateHistory    stat
 		codes           stat
 		txLookups       stat
 		accountSnaps    stat
 		storageSnaps    stat
 		preimages       stat
 		bloomBits       stat
+		logIndex        stat
 		beaconHeaders   stat
 		cliqueSnaps     stat
 
@@ -385,6 +512,12 @@ func InspectDatabase(db ethdb.Database, keyPrefix, keyStart []byte) error {
 			hashNumPairings.Add(size)
 		case len(key) == common.HashLength:
 			tries.Add(size)
+		case pathScheme && (bytes.HasPrefix(key, TrieNodeAccountPrefix) || bytes.HasPrefix(key, TrieNodeStoragePrefix) && len(key) >= len(TrieNodeStoragePrefix)+common.HashLength):
+			tries.Add(size)
+		case pathScheme && bytes.HasPrefix(key, stateIDPrefix) && len(key) == len(stateIDPrefix)+common.HashLength:
+			stateHistory.Add(size)
+		case pathScheme && bytes.HasPrefix(key, reverseDiffPrefix) && len(key) == len(reverseDiffPrefix)+8:
+			stateHistory.Add(size)
 		case bytes.HasPrefix(key, CodePrefix) && len(key) == len(CodePrefix)+common.HashLength:
 			codes.Add(size)
 		case bytes.HasPrefix(key, txLookupPrefix) && len(key) == (len(txLookupPrefix)+common.HashLength):
@@ -403,6 +536,12 @@ func InspectDatabase(db ethdb.Database, keyPrefix, keyStart []byte) error {
 			bloomBits.Add(size)
 		case bytes.HasPrefix(key, BloomBitsIndexPrefix):
 			bloomBits.Add(size)
+		case bytes.HasPrefix(key, logIndexPrefix) && (len(key) == len(logIndexPrefix)+9+common.AddressLength || len(key) == len(logIndexPrefix)+9+common.HashLength):
+			logIndex.Add(size)
+		case bytes.HasPrefix(key, logIndexHeadPrefix) && len(key) == len(logIndexHeadPrefix)+8:
+			logIndex.Add(size)
+		case bytes.HasPrefix(key, LogIndexPrefix):
+			logIndex.Add(size)
 		case bytes.HasPrefix(key, skeletonHeaderPrefix) && len(key) == (len(skeletonHeaderPrefix)+8):
 			beaconHeaders.Add(size)
 		case bytes.HasPrefix(key, []byte("clique-")) && len(key) == 7+common.HashLength:
@@ -422,6 +561,7 @@ func InspectDatabase(db ethdb.Database, keyPrefix, keyStart []byte) error {
 				lastPivotKey, fastTrieProgressKey, snapshotDisabledKey, SnapshotRootKey, snapshotJournalKey,
 				snapshotGeneratorKey, snapshotRecoveryKey, txIndexTailKey, fastTxLookupLimitKey,
 				uncleanShutdownKey, badBlockKey, transitionStatusKey, skeletonSyncStatusKey,
+				stateSchemeKey, persistentStateIDKey,
 			} {
 				if bytes.Equal(key, meta) {
 					metadata.Add(size)
@@ -462,8 +602,10 @@ func InspectDatabase(db ethdb.Database, keyPrefix, keyStart []byte) error {
 		{"Key-Value store", "Block hash->number", hashNumPairings.Size(), hashNumPairings.Count()},
 		{"Key-Value store", "Transaction index", txLookups.Size(), txLookups.Count()},
 		{"Key-Value store", "Bloombit index", bloomBits.Size(), bloomBits.Count()},
+		{"Key-Value store", "Log index", logIndex.Size(), logIndex.Count()},
 		{"Key-Value store", "Contract codes", codes.Size(), codes.Count()},
 		{"Key-Value store", "Trie nodes", tries.Size(), tries.Count()},
+		{"Key-Value store", "State history", stateHistory.Size(), stateHistory.Count()},
 		{"Key-Value store", "Trie preimages", preimages.Size(), preimages.Count()},
 		{"Key-Value store", "Account snapshot", accountSnaps.Size(), accountSnaps.Count()},
 		{"Key-Value store", "Storage snapshot", storageSnaps.Size(), storageSnaps.Count()},
@@ -0,0 +1,87 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+//go:build arm64 || amd64
+// +build arm64 amd64
+
+package rawdb
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// Tests that the key-value store of a database is recorded on creation and that
+// databases are only opened with the engine they were created with.
+func TestDatabaseEngine(t *testing.T) {
+	for _, engine := range []string{DBLeveldb, DBPebble} {
+		dir := filepath.Join(t.TempDir(), "chaindata")
+		open := func(typ string) error {
+			db, err := Open(OpenOptions{Type: typ, Directory: dir, AncientsDirectory: filepath.Join(dir, "ancient")})
+			if err != nil {
+				return err
+			}
+			defer db.Close()
+			if err := db.Put([]byte("key"), []byte("value")); err != nil {
+				t.Fatalf("%s: failed to write: %v", engine, err)
+			}
+			if blob, err := db.Get([]byte("key")); err != nil || !bytes.Equal(blob, []byte("value")) {
+				t.Fatalf("%s: wrong value %q: %v", engine, blob, err)
+			}
+			return nil
+		}
+		if err := open(engine); err != nil {
+			t.Fatalf("%s: failed to create database: %v", engine, err)
+		}
+		if have, err := ReadDatabaseEngine(dir); err != nil || have != engine {
+			t.Fatalf("%s: wrong recorded engine %q: %v", engine, have, err)
+		}
+		// Reopening without an explicit engine must use the recorded one.
+		if err := open(""); err != nil {
+			t.Fatalf("%s: failed to reopen database: %v", engine, err)
+		}
+		other := DBPebble
+		if engine == DBPebble {
+			other = DBLeveldb
+		}
+		if err := open(other); err == nil {
+			t.Fatalf("%s: opened with mismatching engine %s", engine, other)
+		}
+	}
+}
+
+// Tests that LevelDB databases created before the engine was recorded are
+// recognized as such.
+func TestDatabaseEngineLegacy(t *testing.T) {
+	dir := t.TempDir()
+	db, err := Open(OpenOptions{Type: DBLeveldb, Directory: dir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.Close()
+	if err := os.Remove(filepath.Join(dir, engineFile)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewPebbleDBDatabase(dir, 0, 0, "", false); err == nil {
+		t.Fatal("opened legacy leveldb database with pebble")
+	}
+	if db, err = NewLevelDBDatabase(dir, 0, 0, "", false); err != nil {
+		t.Fatalf("failed to open legacy leveldb database: %v", err)
+	}
+	db.Close()
+}
@@ -0,0 +1,383 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rawdb
+
+import (
+	"bytes"
+	"encoding/json"
+	"sync/atomic"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/metrics"
+)
+
+// Categories of key-value data tracked by the database statistics.
+const (
+	statsHeaders = iota
+	statsBodies
+	statsReceipts
+	statsDifficulties
+	statsCanonicalHashes
+	statsHeaderNumbers
+	statsTxLookups
+	statsBloomBits
+	statsLogIndex
+	statsCodes
+	statsTrieNodes
+	statsStateHistory
+	statsSnapshot
+	statsPreimages
+	statsSkeletonHeaders
+	statsOther
+	statsCategories
+)
+
+// statsCategoryNames are the names of the tracked categories, also used in the
+// metric names.
+var statsCategoryNames = [statsCategories]string{
+	"headers", "bodies", "receipts", "difficulties", "canonical", "numbers", "txlookups",
+	"bloombits", "logindex", "codes", "trienodes", "statehistory", "snapshot", "preimages",
+	"skeleton", "other",
+}
+
+// statsCategory classifies a key into the category of data it belongs to. It
+// is a coarser and cheaper version of the classification of InspectDatabase,
+// as it's done on every write.
+func statsCategory(key []byte) int {
+	if len(key) == 0 {
+		return statsOther
+	}
+	// Single byte prefixes are only matched together with the key length, some
+	// of the metadata keys start with the same letters
+	var (
+		number = 1 + 8                     // prefix + num (uint64 big endian)
+		block  = 1 + 8 + common.HashLength // prefix + num (uint64 big endian) + hash
+		hash   = 1 + common.HashLength     // prefix + hash
+	)
+	switch {
+	case key[0] == headerPrefix[0] && len(key) == block:
+		return statsHeaders
+	case key[0] == headerPrefix[0] && len(key) == block+len(headerTDSuffix) && bytes.HasSuffix(key, headerTDSuffix):
+		return statsDifficulties
+	case key[0] == headerPrefix[0] && len(key) == number+len(headerHashSuffix) && bytes.HasSuffix(key, headerHashSuffix):
+		return statsCanonicalHashes
+	case key[0] == headerNumberPrefix[0] && len(key) == hash:
+		return statsHeaderNumbers
+	case key[0] == blockBodyPrefix[0] && len(key) == block:
+		return statsBodies
+	case key[0] == blockReceiptsPrefix[0] && len(key) == block:
+		return statsReceipts
+	case key[0] == txLookupPrefix[0] && len(key) == hash:
+		return statsTxLookups
+	case key[0] == bloomBitsPrefix[0] && len(key) == 1+10+common.HashLength, bytes.HasPrefix(key, BloomBitsIndexPrefix):
+		return statsBloomBits
+	case key[0] == logIndexPrefix[0] && (len(key) == number+1+common.AddressLength || len(key) == number+1+common.HashLength),
+		key[0] == logIndexHeadPrefix[0] && len(key) == number, bytes.HasPrefix(key, LogIndexPrefix):
+		return statsLogIndex
+	case key[0] == CodePrefix[0] && len(key) == hash:
+		return statsCodes
+	case key[0] == TrieNodeAccountPrefix[0], key[0] == TrieNodeStoragePrefix[0] && len(key) >= hash:
+		return statsTrieNodes
+	case key[0] == stateIDPrefix[0] && len(key) == hash, key[0] == reverseDiffPrefix[0] && len(key) == number:
+		return statsStateHistory
+	case key[0] == SnapshotAccountPrefix[0] && len(key) == hash, key[0] == SnapshotStoragePrefix[0] && len(key) == hash+common.HashLength:
+		return statsSnapshot
+	case key[0] == skeletonHeaderPrefix[0] && len(key) == number:
+		return statsSkeletonHeaders
+	case bytes.HasPrefix(key, PreimagePrefix):
+		return statsPreimages
+	case len(key) == common.HashLength:
+		// Unprefixed hashes are the trie nodes of the hash scheme
+		return statsTrieNodes
+	}
+	return statsOther
+}
+
+// categoryStats are the write statistics of a category of data.
+type categoryStats struct {
+	written uint64 // Bytes of keys and values written
+	puts    uint64 // Number of entries written
+	deletes uint64 // Number of entries deleted
+	growth  int64  // Net change of the stored bytes, only tracked with expensive metrics
+}
+
+// dbStats tracks the writes into a key-value store per category of data since
+// the database was opened.
+type dbStats [statsCategories]categoryStats
+
+// newDBStats creates the statistics of a database, exposing them through the
+// metrics registry under the given namespace. The gauges of a database opened
+// earlier with the same namespace are replaced, reporting the latest one.
+func newDBStats(namespace string, growth bool) *dbStats {
+	stats := new(dbStats)
+	for i, name := range statsCategoryNames {
+		cat := &stats[i]
+		registerStatsGauge(namespace+"stats/"+name+"/written", func() int64 { return int64(atomic.LoadUint64(&cat.written)) })
+		registerStatsGauge(namespace+"stats/"+name+"/puts", func() int64 { return int64(atomic.LoadUint64(&cat.puts)) })
+		registerStatsGauge(namespace+"stats/"+name+"/deletes", func() int64 { return int64(atomic.LoadUint64(&cat.deletes)) })
+		if growth {
+			registerStatsGauge(namespace+"stats/"+name+"/growth", func() int64 { return atomic.LoadInt64(&cat.growth) })
+		}
+	}
+	return stats
+}
+
+// registerStatsGauge registers a functional gauge in the default registry,
+// replacing any metric registered under the same name.
+func registerStatsGauge(name string, f func() int64) {
+	metrics.DefaultRegistry.Unregister(name)
+	metrics.NewRegisteredFunctionalGauge(name, nil, f)
+}
+
+// add merges the given pending statistics into the database ones.
+func (s *dbStats) add(pending *dbStats) {
+	for i := range pending {
+		if pending[i] == (categoryStats{}) {
+			continue
+		}
+		atomic.AddUint64(&s[i].written, pending[i].written)
+		atomic.AddUint64(&s[i].puts, pending[i].puts)
+		atomic.AddUint64(&s[i].deletes, pending[i].deletes)
+		atomic.AddInt64(&s[i].growth, pending[i].growth)
+	}
+}
+
+// storedSize returns the size of the entry stored under the given key, or zero
+// if it doesn't exist.
+func storedSize(db ethdb.KeyValueReader, key []byte) int {
+	blob, err := db.Get(key)
+	if err != nil {
+		return 0
+	}
+	return len(key) + len(blob)
+}
+
+// statsStore is a key-value store wrapper tracking the writes per category of
+// data. Batched writes are accounted for when the batch is written.
+//
+// The net size change of the categories is only tracked if expensive metrics
+// are enabled, as the size of the previous value needs to be read on every
+// write and deletion. The reads are not atomic with the writes, so the net
+// change is approximate if the same keys are written concurrently.
+type statsStore struct {
+	ethdb.KeyValueStore
+	stats  *dbStats
+	growth bool // Whether the net size change is tracked
+}
+
+// statsProperty is the database property under which the write statistics are
+// reported by statsStore. The statistics are retrieved via Stat, so that they're
+// accessible through any database wrapper.
+const statsProperty = "rawdb.writestats"
+
+// newStatsStore wraps a key-value store with write statistics.
+func newStatsStore(db ethdb.KeyValueStore, namespace string) *statsStore {
+	growth := metrics.EnabledExpensive
+	return &statsStore{KeyValueStore: db, stats: newDBStats(namespace, growth), growth: growth}
+}
+
+// Put inserts the given value into the key-value store.
+func (s *statsStore) Put(key []byte, value []byte) error {
+	var old int
+	if s.growth {
+		old = storedSize(s.KeyValueStore, key)
+	}
+	if err := s.KeyValueStore.Put(key, value); err != nil {
+		return err
+	}
+	cat := &s.stats[statsCategory(key)]
+	atomic.AddUint64(&cat.written, uint64(len(key)+len(value)))
+	atomic.AddUint64(&cat.puts, 1)
+	if s.growth {
+		atomic.AddInt64(&cat.growth, int64(len(key)+len(value)-old))
+	}
+	return nil
+}
+
+// Delete removes the key from the key-value store.
+func (s *statsStore) Delete(key []byte) error {
+	var old int
+	if s.growth {
+		old = storedSize(s.KeyValueStore, key)
+	}
+	if err := s.KeyValueStore.Delete(key); err != nil {
+		return err
+	}
+	cat := &s.stats[statsCategory(key)]
+	atomic.AddUint64(&cat.deletes, 1)
+	if s.growth {
+		atomic.AddInt64(&cat.growth, -int64(old))
+	}
+	return nil
+}
+
+// Stat returns a particular internal stat of the database. The write statistics
+// are returned JSON encoded for the statsProperty.
+func (s *statsStore) Stat(property string) (string, error) {
+	if property != statsProperty {
+		return s.KeyValueStore.Stat(property)
+	}
+	var stats []*CategoryStats
+	for i, name := range statsCategoryNames {
+		cat := &s.stats[i]
+		stats = append(stats, &CategoryStats{
+			Store:    "key-value",
+			Category: name,
+			Written:  atomic.LoadUint64(&cat.written),
+			Puts:     atomic.LoadUint64(&cat.puts),
+			Deletes:  atomic.LoadUint64(&cat.deletes),
+			Growth:   atomic.LoadInt64(&cat.growth),
+		})
+	}
+	blob, err := json.Marshal(stats)
+	return string(blob), err
+}
+
+// NewBatch creates a write-only database that buffers changes to its host db
+// until a final write is called.
+func (s *statsStore) NewBatch() ethdb.Batch {
+	return &statsBatch{Batch: s.KeyValueStore.NewBatch(), db: s.KeyValueStore, stats: s.stats, growth: s.growth}
+}
+
+// NewBatchWithSize creates a write-only database batch with pre-allocated buffer.
+func (s *statsStore) NewBatchWithSize(size int) ethdb.Batch {
+	return &statsBatch{Batch: s.KeyValueStore.NewBatchWithSize(size), db: s.KeyValueStore, stats: s.stats, growth: s.growth}
+}
+
+// statsBatch is a batch wrapper collecting the write statistics of the batched
+// operations, merging them into the database ones on write.
+type statsBatch struct {
+	ethdb.Batch
+	db      ethdb.KeyValueReader // Store to read the sizes of overwritten entries from
+	stats   *dbStats
+	pending dbStats
+	growth  bool           // Whether the net size change is tracked
+	sizes   map[string]int // Sizes of the entries written by the batch, 0 if deleted, only if growth is tracked
+}
+
+// oldSize returns the size of the entry currently stored under the key, taking
+// the earlier operations of the batch into account.
+func (b *statsBatch) oldSize(key []byte) int {
+	if size, ok := b.sizes[string(key)]; ok {
+		return size
+	}
+	return storedSize(b.db, key)
+}
+
+// trackSize records the size of the entry the batch writes under the key.
+func (b *statsBatch) trackSize(key []byte, size int) {
+	if b.sizes == nil {
+		b.sizes = make(map[string]int)
+	}
+	b.sizes[string(key)] = size
+}
+
+// Put inserts the given value into the batch.
+func (b *statsBatch) Put(key []byte, value []byte) error {
+	var old int
+	if b.growth {
+		old = b.oldSize(key)
+	}
+	if err := b.Batch.Put(key, value); err != nil {
+		return err
+	}
+	cat := &b.pending[statsCategory(key)]
+	cat.written += uint64(len(key) + len(value))
+	cat.puts++
+	if b.growth {
+		b.trackSize(key, len(key)+len(value))
+		cat.growth += int64(len(key) + len(value) - old)
+	}
+	return nil
+}
+
+// Delete inserts the key removal into the batch.
+func (b *statsBatch) Delete(key []byte) error {
+	var old int
+	if b.growth {
+		old = b.oldSize(key)
+	}
+	if err := b.Batch.Delete(key); err != nil {
+		return err
+	}
+	cat := &b.pending[statsCategory(key)]
+	cat.deletes++
+	if b.growth {
+		b.trackSize(key, 0)
+		cat.growth -= int64(old)
+	}
+	return nil
+}
+
+// Write flushes the batch and accounts for its operations.
+func (b *statsBatch) Write() error {
+	if err := b.Batch.Write(); err != nil {
+		return err
+	}
+	b.stats.add(&b.pending)
+	b.pending, b.sizes = dbStats{}, nil
+	return nil
+}
+
+// Reset resets the batch for reuse.
+func (b *statsBatch) Reset() {
+	b.Batch.Reset()
+	b.pending, b.sizes = dbStats{}, nil
+}
+
+// CategoryStats is the usage of a category of data in the database.
+type CategoryStats struct {
+	Store    string `json:"store"`             // Store holding the data, key-value or ancient
+	Category string `json:"category"`          // Name of the data category
+	Written  uint64 `json:"written,omitempty"` // Bytes written into the key-value store since startup
+	Puts     uint64 `json:"puts,omitempty"`    // Number of entries written into the key-value store since startup
+	Deletes  uint64 `json:"deletes,omitempty"` // Number of entries deleted from the key-value store since startup
+	Growth   int64  `json:"growth,omitempty"`  // Net change of the bytes stored in the key-value store since startup, only with expensive metrics
+	Size     uint64 `json:"size,omitempty"`    // Total size of the ancient table
+	Items    uint64 `json:"items,omitempty"`   // Number of items in the ancient table
+}
+
+// ReadDatabaseStats returns the per-category write statistics of the key-value
+// store, and the sizes of the ancient tables. The key-value store statistics
+// are only tracked for databases opened with a freezer, they are not computed
+// by scanning the database.
+func ReadDatabaseStats(db ethdb.Database) []*CategoryStats {
+	var stats []*CategoryStats
+	if blob, err := db.Stat(statsProperty); err == nil {
+		if err := json.Unmarshal([]byte(blob), &stats); err != nil {
+			stats = nil
+		}
+	}
+	items, err := db.Ancients()
+	if err != nil {
+		return stats
+	}
+	tail, _ := db.Tail()
+	for _, table := range []string{freezerHeaderTable, freezerHashTable, freezerBodiesTable, freezerReceiptTable, freezerDifficultyTable} {
+		size, err := db.AncientSize(table)
+		if err != nil {
+			continue
+		}
+		count := items
+		if freezerPrunable[table] {
+			count -= tail
+		}
+		stats = append(stats, &CategoryStats{Store: "ancient", Category: table, Size: size, Items: count})
+	}
+	return stats
+}
@@ -0,0 +1,152 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rawdb
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethdb/memorydb"
+	"github.com/ethereum/go-ethereum/metrics"
+)
+
+func TestStatsCategory(t *testing.T) {
+	hash := common.Hash{0x01}
+	tests := []struct {
+		key  []byte
+		want int
+	}{
+		{headerKey(1, hash), statsHeaders},
+		{headerTDKey(1, hash), statsDifficulties},
+		{headerHashKey(1), statsCanonicalHashes},
+		{headerNumberKey(hash), statsHeaderNumbers},
+		{blockBodyKey(1, hash), statsBodies},
+		{blockReceiptsKey(1, hash), statsReceipts},
+		{txLookupKey(hash), statsTxLookups},
+		{bloomBitsKey(1, 1, hash), statsBloomBits},
+		{codeKey(hash), statsCodes},
+		{hash.Bytes(), statsTrieNodes},
+		{append(common.CopyBytes(BloomBitsIndexPrefix), make([]byte, common.HashLength-len(BloomBitsIndexPrefix))...), statsBloomBits},
+		{append(common.CopyBytes(LogIndexPrefix), make([]byte, common.HashLength-len(LogIndexPrefix))...), statsLogIndex},
+		{accountSnapshotKey(hash), statsSnapshot},
+		{storageSnapshotKey(hash, hash), statsSnapshot},
+		{preimageKey(hash), statsPreimages},
+		{skeletonHeaderKey(1), statsSkeletonHeaders},
+		{headHeaderKey, statsOther},
+		{databaseVersionKey, statsOther},
+		{SnapshotRootKey, statsOther},
+	}
+	for i, tt := range tests {
+		if have := statsCategory(tt.key); have != tt.want {
+			t.Errorf("test %d: category mismatch for %q: have %s, want %s", i, tt.key, statsCategoryNames[have], statsCategoryNames[tt.want])
+		}
+	}
+}
+
+func TestDatabaseStats(t *testing.T) {
+	defer func(enabled bool) { metrics.EnabledExpensive = enabled }(metrics.EnabledExpensive)
+	metrics.EnabledExpensive = true
+
+	db, err := NewDatabaseWithFreezer(memorydb.New(), t.TempDir(), "", false)
+	if err != nil {
+		t.Fatalf("failed to create database: %v", err)
+	}
+	defer db.Close()
+
+	header := &types.Header{Number: big.NewInt(1), Extra: []byte("stats test")}
+	WriteHeader(db, header) // direct writes
+
+	batch := db.NewBatch()
+	WriteCanonicalHash(batch, header.Hash(), 1)
+	DeleteCanonicalHash(batch, 2)
+	if stats := lookupStats(ReadDatabaseStats(db), "key-value", "canonical"); stats.Puts != 0 {
+		t.Fatalf("unwritten batch accounted for")
+	}
+	batch.Write()
+
+	headers := lookupStats(ReadDatabaseStats(db), "key-value", "headers")
+	if headers.Puts != 1 || headers.Written == 0 {
+		t.Fatalf("header writes mismatch: have %d puts of %d bytes, want 1 put", headers.Puts, headers.Written)
+	}
+	canonical := lookupStats(ReadDatabaseStats(db), "key-value", "canonical")
+	if canonical.Puts != 1 || canonical.Deletes != 1 {
+		t.Fatalf("canonical hash writes mismatch: have %d puts, %d deletes, want 1 and 1", canonical.Puts, canonical.Deletes)
+	}
+	if ancients := lookupStats(ReadDatabaseStats(db), "ancient", freezerHeaderTable); ancients == nil {
+		t.Fatalf("ancient table statistics missing")
+	}
+	// Overwrites and deletions are accounted for in the net size change
+	key := headerKey(1, header.Hash())
+	size := int64(len(key) + len(ReadHeaderRLP(db, header.Hash(), 1)))
+	if headers.Growth != size {
+		t.Fatalf("header growth mismatch: have %d, want %d", headers.Growth, size)
+	}
+	WriteHeader(db, header)
+	if have := lookupStats(ReadDatabaseStats(db), "key-value", "headers").Growth; have != size {
+		t.Fatalf("header growth mismatch after overwrite: have %d, want %d", have, size)
+	}
+	batch.Reset()
+	batch.Put(key, []byte{0x01})
+	batch.Delete(key)
+	batch.Delete(key)
+	batch.Write()
+	if have := lookupStats(ReadDatabaseStats(db), "key-value", "headers").Growth; have != 0 {
+		t.Fatalf("header growth mismatch after deletion: have %d, want 0", have)
+	}
+	if have := lookupStats(ReadDatabaseStats(db), "key-value", "canonical").Growth; have != int64(len(headerHashKey(1))+common.HashLength) {
+		t.Fatalf("canonical hash growth mismatch: have %d, want %d", have, len(headerHashKey(1))+common.HashLength)
+	}
+}
+
+// Tests that the net size change is not tracked without expensive metrics, so
+// that writes don't need to read the previous values.
+func TestDatabaseStatsNoGrowth(t *testing.T) {
+	defer func(enabled bool) { metrics.EnabledExpensive = enabled }(metrics.EnabledExpensive)
+	metrics.EnabledExpensive = false
+
+	db, err := NewDatabaseWithFreezer(memorydb.New(), t.TempDir(), "", false)
+	if err != nil {
+		t.Fatalf("failed to create database: %v", err)
+	}
+	defer db.Close()
+
+	header := &types.Header{Number: big.NewInt(1), Extra: []byte("stats test")}
+	WriteHeader(db, header)
+
+	batch := db.NewBatch()
+	WriteCanonicalHash(batch, header.Hash(), 1)
+	if sizes := batch.(*statsBatch).sizes; sizes != nil {
+		t.Fatalf("batch tracked %d entry sizes", len(sizes))
+	}
+	batch.Write()
+
+	headers := lookupStats(ReadDatabaseStats(db), "key-value", "headers")
+	if headers.Puts != 1 || headers.Growth != 0 {
+		t.Fatalf("header stats mismatch: have %d puts, growth %d, want 1 put, no growth", headers.Puts, headers.Growth)
+	}
+}
+
+func lookupStats(stats []*CategoryStats, store string, category string) *CategoryStats {
+	for _, stat := range stats {
+		if stat.Store == store && stat.Category == category {
+			return stat
+		}
+	}
+	return nil
+}
@@ -0,0 +1,234 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rawdb
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// Inconsistency describes a single problem found while verifying the database.
+type Inconsistency struct {
+	Number   uint64 // Block number affected, the chain head for global data
+	Kind     string // Category of the data affected
+	Detail   string // Human readable description of the problem
+	Repaired bool   // Whether the problem was fixed in the database
+}
+
+// String implements fmt.Stringer.
+func (i *Inconsistency) String() string {
+	return fmt.Sprintf("#%d %s: %s", i.Number, i.Kind, i.Detail)
+}
+
+// VerifyDatabase walks the canonical chain across the key-value store and the
+// ancient store, checking that headers, bodies, receipts, total difficulties,
+// canonical hash mappings and transaction lookups are present and linked up.
+// It also checks the contiguity of the chain freezer and the snapshot generator
+// marker.
+//
+// If repair is set, the recoverable inconsistencies are fixed in place. These
+// are missing hash to number mappings and transaction lookups, which are
+// rewritten, and a corrupted snapshot generator marker, which is dropped so
+// the snapshot is regenerated on the next startup.
+func VerifyDatabase(db ethdb.Database, repair bool) ([]*Inconsistency, error) {
+	var (
+		issues []*Inconsistency
+		batch  = db.NewBatch()
+		start  = time.Now()
+		logged = start
+	)
+	report := func(number uint64, kind string, detail string, repairable bool) {
+		issue := &Inconsistency{Number: number, Kind: kind, Detail: detail, Repaired: repair && repairable}
+		log.Warn("Database inconsistency", "number", number, "kind", kind, "detail", detail, "repaired", issue.Repaired)
+		issues = append(issues, issue)
+	}
+	flush := func(force bool) error {
+		if !repair || (!force && batch.ValueSize() < ethdb.IdealBatchSize) {
+			return nil
+		}
+		if err := batch.Write(); err != nil {
+			return err
+		}
+		batch.Reset()
+		return nil
+	}
+	// Resolve the chain heads, the header head is required to walk the chain
+	headHash := ReadHeadHeaderHash(db)
+	if headHash == (common.Hash{}) {
+		return nil, errors.New("head header hash missing")
+	}
+	number := ReadHeaderNumber(db, headHash)
+	if number == nil {
+		return nil, fmt.Errorf("head header number missing, hash %x", headHash)
+	}
+	head := *number
+
+	// Bodies and receipts are expected up to the highest of the block heads
+	var bodyHead uint64
+	for _, marker := range []struct {
+		kind string
+		hash common.Hash
+	}{
+		{"head block", ReadHeadBlockHash(db)},
+		{"head fast block", ReadHeadFastBlockHash(db)},
+	} {
+		kind, hash := marker.kind, marker.hash
+		if hash == (common.Hash{}) {
+			report(head, "heads", fmt.Sprintf("%s hash missing", kind), false)
+			continue
+		}
+		number := ReadHeaderNumber(db, hash)
+		if number == nil || !HasHeader(db, hash, *number) {
+			report(head, "heads", fmt.Sprintf("%s %x unknown", kind, hash), false)
+			continue
+		}
+		if *number > head {
+			report(head, "heads", fmt.Sprintf("%s %d above head header", kind, *number), false)
+			continue
+		}
+		if *number > bodyHead {
+			bodyHead = *number
+		}
+	}
+	// Check that the freezer doesn't go beyond the chain head. A missing
+	// freezer is not an error, the database might be freezer-less.
+	frozen, _ := db.Ancients()
+	tail, _ := db.Tail()
+	if frozen > head+1 {
+		report(head, "freezer", fmt.Sprintf("ancients %d beyond head header", frozen), false)
+	}
+	txTail := uint64(0)
+	if number := ReadTxIndexTail(db); number != nil {
+		txTail = *number
+	}
+	var parent common.Hash
+	for n := uint64(0); n <= head; n++ {
+		if err := flush(false); err != nil {
+			return issues, err
+		}
+		if time.Since(logged) > 8*time.Second {
+			log.Info("Verifying database", "number", n, "head", head, "issues", len(issues), "elapsed", common.PrettyDuration(time.Since(start)))
+			logged = time.Now()
+		}
+		// Check the contiguity of the ancient store, each table needs to have
+		// the item unless it was pruned.
+		if n < frozen {
+			for _, table := range []string{freezerHashTable, freezerHeaderTable, freezerDifficultyTable, freezerBodiesTable, freezerReceiptTable} {
+				if freezerPrunable[table] && n < tail {
+					continue
+				}
+				if has, _ := db.HasAncient(table, n); !has {
+					report(n, "freezer", fmt.Sprintf("missing item in %s table", table), false)
+				}
+			}
+		}
+		// Check the canonical header and its linkage to the parent
+		hash := ReadCanonicalHash(db, n)
+		if hash == (common.Hash{}) {
+			report(n, "canonical", "missing canonical hash", false)
+			parent = common.Hash{}
+			continue
+		}
+		header := ReadHeader(db, hash, n)
+		switch {
+		case header == nil:
+			report(n, "header", fmt.Sprintf("missing header %x", hash), false)
+		case header.Hash() != hash:
+			report(n, "header", fmt.Sprintf("header hash mismatch, have %x, want %x", header.Hash(), hash), false)
+		case n > 0 && parent != (common.Hash{}) && header.ParentHash != parent:
+			report(n, "canonical", fmt.Sprintf("parent hash mismatch, have %x, want %x", header.ParentHash, parent), false)
+		}
+		parent = hash
+
+		if number := ReadHeaderNumber(db, hash); number == nil || *number != n {
+			report(n, "header", "missing hash to number mapping", true)
+			if repair {
+				WriteHeaderNumber(batch, hash, n)
+			}
+		}
+		if len(ReadTdRLP(db, hash, n)) == 0 {
+			report(n, "td", "missing total difficulty", false)
+		}
+		// Check the block data if it's expected to be present
+		if n > bodyHead || (n != 0 && n < tail) {
+			continue
+		}
+		body := ReadBody(db, hash, n)
+		switch {
+		case body == nil:
+			report(n, "body", "missing block body", false)
+		case header != nil && types.CalcUncleHash(body.Uncles) != header.UncleHash:
+			report(n, "body", "uncle hash mismatch", false)
+		}
+		if !HasReceipts(db, hash, n) {
+			report(n, "receipts", "missing receipts", false)
+		}
+		// Check the transaction lookups above the index tail
+		if body == nil || n < txTail {
+			continue
+		}
+		for _, tx := range body.Transactions {
+			if number := ReadTxLookupEntry(db, tx.Hash()); number == nil || *number != n {
+				report(n, "txlookup", fmt.Sprintf("missing lookup for transaction %x", tx.Hash()), true)
+				if repair {
+					WriteTxLookupEntries(batch, n, []common.Hash{tx.Hash()})
+				}
+			}
+		}
+	}
+	// Check the snapshot generator marker, if the snapshot is enabled
+	if root := ReadSnapshotRoot(db); root != (common.Hash{}) && !ReadSnapshotDisabled(db) {
+		var (
+			blob      = ReadSnapshotGenerator(db)
+			generator struct {
+				Wiping bool
+				Done   bool
+				Marker []byte
+				Rest   []rlp.RawValue `rlp:"tail"`
+			}
+			detail string
+		)
+		switch {
+		case len(blob) == 0:
+			detail = "missing generator marker"
+		case rlp.DecodeBytes(blob, &generator) != nil:
+			detail = "corrupted generator marker"
+		case generator.Done && len(generator.Marker) != 0:
+			detail = "completed generator with pending marker"
+		case len(generator.Marker) != 0 && len(generator.Marker) != common.HashLength && len(generator.Marker) != 2*common.HashLength:
+			detail = fmt.Sprintf("invalid generator marker length %d", len(generator.Marker))
+		}
+		if detail != "" {
+			report(head, "snapshot", detail, true)
+			if repair {
+				DeleteSnapshotRoot(batch)
+			}
+		}
+	}
+	if err := flush(true); err != nil {
+		return issues, err
+	}
+	log.Info("Verified database", "head", head, "issues", len(issues), "elapsed", common.PrettyDuration(time.Since(start)))
+	return issues, nil
+}
@@ -0,0 +1,118 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rawdb
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethdb"
+)
+
+// writeVerifyTestChain writes a small canonical chain with a transaction in each
+// non-genesis block into the database.
+func writeVerifyTestChain(db ethdb.Database, count int) []*types.Block {
+	var (
+		blocks []*types.Block
+		parent common.Hash
+	)
+	for i := 0; i < count; i++ {
+		header := &types.Header{
+			ParentHash: parent,
+			Number:     big.NewInt(int64(i)),
+			Difficulty: big.NewInt(1),
+			Extra:      []byte("verify test"),
+		}
+		var txs []*types.Transaction
+		if i > 0 {
+			txs = append(txs, types.NewTransaction(uint64(i), common.Address{0x01}, big.NewInt(1), 21000, big.NewInt(1), nil))
+		}
+		block := types.NewBlock(header, txs, nil, nil, newHasher())
+
+		WriteBlock(db, block)
+		WriteCanonicalHash(db, block.Hash(), block.NumberU64())
+		WriteTd(db, block.Hash(), block.NumberU64(), big.NewInt(int64(i+1)))
+		WriteReceipts(db, block.Hash(), block.NumberU64(), nil)
+		WriteTxLookupEntriesByBlock(db, block)
+
+		blocks = append(blocks, block)
+		parent = block.Hash()
+	}
+	head := blocks[len(blocks)-1].Hash()
+	WriteHeadHeaderHash(db, head)
+	WriteHeadBlockHash(db, head)
+	WriteHeadFastBlockHash(db, head)
+	return blocks
+}
+
+func TestVerifyDatabase(t *testing.T) {
+	db := NewMemoryDatabase()
+	blocks := writeVerifyTestChain(db, 8)
+
+	issues, err := VerifyDatabase(db, false)
+	if err != nil {
+		t.Fatalf("failed to verify database: %v", err)
+	}
+	if len(issues) != 0 {
+		t.Fatalf("unexpected issues in consistent database: %v", issues)
+	}
+	// Corrupt the database in recoverable and unrecoverable ways
+	DeleteHeaderNumber(db, blocks[2].Hash())
+	DeleteTxLookupEntry(db, blocks[3].Transactions()[0].Hash())
+	DeleteReceipts(db, blocks[4].Hash(), 4)
+	WriteSnapshotRoot(db, common.Hash{0x01})
+	WriteSnapshotGenerator(db, []byte{0xff})
+
+	check := func(repair bool, want int, repaired int) {
+		t.Helper()
+
+		issues, err := VerifyDatabase(db, repair)
+		if err != nil {
+			t.Fatalf("failed to verify database: %v", err)
+		}
+		if len(issues) != want {
+			t.Fatalf("issue count mismatch: have %d, want %d: %v", len(issues), want, issues)
+		}
+		var fixed int
+		for _, issue := range issues {
+			if issue.Repaired {
+				fixed++
+			}
+		}
+		if fixed != repaired {
+			t.Fatalf("repaired issue count mismatch: have %d, want %d", fixed, repaired)
+		}
+	}
+	check(false, 4, 0)
+	check(true, 4, 3)
+	check(false, 1, 0)
+
+	if number := ReadHeaderNumber(db, blocks[2].Hash()); number == nil || *number != 2 {
+		t.Fatalf("hash to number mapping not repaired")
+	}
+	if number := ReadTxLookupEntry(db, blocks[3].Transactions()[0].Hash()); number == nil || *number != 3 {
+		t.Fatalf("transaction lookup not repaired")
+	}
+	if root := ReadSnapshotRoot(db); root != (common.Hash{}) {
+		t.Fatalf("corrupted snapshot not dropped")
+	}
+	// Break the canonical chain, which is unrecoverable
+	DeleteCanonicalHash(db, 5)
+	check(true, 2, 0)
+}
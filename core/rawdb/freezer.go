@@ -77,6 +77,7 @@ type Freezer struct {
 
 	readonly     bool
 	tables       map[string]*freezerTable // Data tables for storing everything
+	prunable     map[string]bool          // Data tables affected by tail truncation, all if nil
 	instanceLock fileutil.Releaser        // File-system lock to prevent double opens
 	closeOnce    sync.Once
 }
@@ -87,6 +88,13 @@ type Freezer struct {
 // The 'tables' argument defines the data tables. If the value of a map
 // entry is true, snappy compression is disabled for the table.
 func NewFreezer(datadir string, namespace string, readonly bool, maxTableSize uint32, tables map[string]bool) (*Freezer, error) {
+	return newFreezer(datadir, namespace, readonly, maxTableSize, tables, nil)
+}
+
+// newFreezer creates a freezer instance in which only the tables marked in the
+// 'prunable' argument are affected by tail truncation. If the argument is nil,
+// all tables are truncated together.
+func newFreezer(datadir string, namespace string, readonly bool, maxTableSize uint32, tables map[string]bool, prunable map[string]bool) (*Freezer, error) {
 	// Create the initial freezer object
 	var (
 		readMeter  = metrics.NewRegisteredMeter(namespace+"ancient/read", nil)
@@ -110,6 +118,7 @@ func NewFreezer(datadir string, namespace string, readonly bool, maxTableSize ui
 	freezer := &Freezer{
 		readonly:     readonly,
 		tables:       make(map[string]*freezerTable),
+		prunable:     prunable,
 		instanceLock: lock,
 		datadir:      datadir,
 	}
@@ -206,7 +215,8 @@ func (f *Freezer) Ancients() (uint64, error) {
 	return atomic.LoadUint64(&f.frozen), nil
 }
 
-// Tail returns the number of first stored item in the freezer.
+// Tail returns the number of first stored item in the freezer. If only some of
+// the tables are prunable, it's the first item stored in those.
 func (f *Freezer) Tail() (uint64, error) {
 	return atomic.LoadUint64(&f.tail), nil
 }
@@ -288,6 +298,7 @@ func (f *Freezer) TruncateHead(items uint64) error {
 }
 
 // TruncateTail discards any recent data below the provided threshold number.
+// Tables which are not prunable are left untouched.
 func (f *Freezer) TruncateTail(tail uint64) error {
 	if f.readonly {
 		return errReadOnly
@@ -298,7 +309,10 @@ func (f *Freezer) TruncateTail(tail uint64) error {
 	if atomic.LoadUint64(&f.tail) >= tail {
 		return nil
 	}
-	for _, table := range f.tables {
+	for kind, table := range f.tables {
+		if !f.isPrunable(kind) {
+			continue
+		}
 		if err := table.truncateTail(tail); err != nil {
 			return err
 		}
@@ -338,36 +352,48 @@ func (f *Freezer) validate() error {
 		break
 	}
 	// Now check every table against that length
+	var tail uint64
 	for kind, table := range f.tables {
 		items := atomic.LoadUint64(&table.items)
 		if length != items {
 			return fmt.Errorf("freezer tables %s and %s have differing lengths: %d != %d", kind, name, items, length)
 		}
+		if hidden := atomic.LoadUint64(&table.itemHidden); f.isPrunable(kind) && hidden > tail {
+			tail = hidden
+		}
 	}
 	atomic.StoreUint64(&f.frozen, length)
+	atomic.StoreUint64(&f.tail, tail)
 	return nil
 }
 
-// repair truncates all data tables to the same length.
+// repair truncates all data tables to the same length, and all prunable tables
+// to the same tail.
 func (f *Freezer) repair() error {
 	var (
 		head = uint64(math.MaxUint64)
 		tail = uint64(0)
 	)
-	for _, table := range f.tables {
+	for kind, table := range f.tables {
 		items := atomic.LoadUint64(&table.items)
 		if head > items {
 			head = items
 		}
+		if !f.isPrunable(kind) {
+			continue
+		}
 		hidden := atomic.LoadUint64(&table.itemHidden)
 		if hidden > tail {
 			tail = hidden
 		}
 	}
-	for _, table := range f.tables {
+	for kind, table := range f.tables {
 		if err := table.truncateHead(head); err != nil {
 			return err
 		}
+		if !f.isPrunable(kind) {
+			continue
+		}
 		if err := table.truncateTail(tail); err != nil {
 			return err
 		}
@@ -377,6 +403,11 @@ func (f *Freezer) repair() error {
 	return nil
 }
 
+// isPrunable reports whether the given table is affected by tail truncation.
+func (f *Freezer) isPrunable(kind string) bool {
+	return f.prunable == nil || f.prunable[kind]
+}
+
 // convertLegacyFn takes a raw freezer entry in an older format and
 // returns it in the new format.
 type convertLegacyFn = func([]byte) ([]byte, error)
@@ -394,6 +425,38 @@ func (f *Freezer) MigrateTable(kind string, convert convertLegacyFn) error {
 	if !ok {
 		return errUnknownTable
 	}
+	return f.migrateTable(table, convert, table.codec, table.dict)
+}
+
+// RecompressTable rewrites the given table with the given compression codec.
+// For zstd, a dictionary is built from items sampled across the table. Same as
+// MigrateTable, it must not be called while the table is being read.
+func (f *Freezer) RecompressTable(kind string, codec FreezerCodec) error {
+	if f.readonly {
+		return errReadOnly
+	}
+	f.writeLock.Lock()
+	defer f.writeLock.Unlock()
+
+	table, ok := f.tables[kind]
+	if !ok {
+		return errUnknownTable
+	}
+	var dict []byte
+	if codec == FreezerZstd {
+		var err error
+		if dict, err = buildZstdDict(table); err != nil {
+			return err
+		}
+	}
+	log.Info("Recompressing freezer table", "table", kind, "from", table.codec, "to", codec, "dictionary", len(dict))
+	return f.migrateTable(table, func(blob []byte) ([]byte, error) { return blob, nil }, codec, dict)
+}
+
+// migrateTable rewrites all entries of the given table with the converter into
+// a new table using the given codec, which replaces the original one.
+func (f *Freezer) migrateTable(table *freezerTable, convert convertLegacyFn, codec FreezerCodec, dict []byte) error {
+	kind := table.name
 	// forEach iterates every entry in the table serially and in order, calling `fn`
 	// with the item as argument. If `fn` returns an error the iteration stops
 	// and that error will be returned.
@@ -420,19 +483,37 @@ func (f *Freezer) MigrateTable(kind string, convert convertLegacyFn) error {
 		}
 		return nil
 	}
-	// TODO(s1na): This is a sanity-check since as of now no process does tail-deletion. But the migration
-	// process assumes no deletion at tail and needs to be modified to account for that.
-	if table.itemOffset > 0 || table.itemHidden > 0 {
-		return fmt.Errorf("migration not supported for tail-deleted freezers")
-	}
+	// Items deleted from the tail are not migrated, the new table starts at the
+	// first item still retrievable.
+	tail := atomic.LoadUint64(&table.itemHidden)
+
 	ancientsPath := filepath.Dir(table.index.Name())
 	// Set up new dir for the migrated table, the content of which
 	// we'll at the end move over to the ancients dir.
 	migrationPath := filepath.Join(ancientsPath, "migration")
-	newTable, err := NewFreezerTable(migrationPath, kind, table.noCompression, false)
+	newTable, err := openTable(migrationPath, kind, metrics.NilMeter{}, metrics.NilMeter{}, metrics.NilGauge{}, table.maxFileSize, codec, dict, false)
 	if err != nil {
 		return err
 	}
+	if newTable.codec != codec {
+		newTable.Close()
+		return fmt.Errorf("previous migration attempt used codec %v", newTable.codec)
+	}
+	if atomic.LoadUint64(&newTable.items) == 0 && tail > 0 {
+		if err := newTable.initTail(tail); err != nil {
+			newTable.Close()
+			return err
+		}
+	}
+	if have := atomic.LoadUint64(&newTable.itemHidden); have != tail {
+		newTable.Close()
+		return fmt.Errorf("previous migration attempt used tail %d, have %d", have, tail)
+	}
+	oldSize, err := table.size()
+	if err != nil {
+		newTable.Close()
+		return err
+	}
 	var (
 		batch  = newTable.newBatch()
 		out    []byte
@@ -468,6 +549,12 @@ func (f *Freezer) MigrateTable(kind string, convert convertLegacyFn) error {
 	// delete the index file.
 	table.releaseFilesAfter(0, true)
 
+	// The first data file and the index file are replaced by the migrated
+	// ones, unless the codec and thus the file names are changed.
+	if codec != table.codec {
+		table.releaseFilesBefore(1, true)
+	}
+
 	if err := newTable.Close(); err != nil {
 		return err
 	}
@@ -486,7 +573,20 @@ func (f *Freezer) MigrateTable(kind string, convert convertLegacyFn) error {
 	if err := os.Remove(migrationPath); err != nil {
 		return err
 	}
+	if codec != table.codec {
+		if err := os.Remove(table.index.Name()); err != nil {
+			return err
+		}
+	}
+	table.Close()
+	table.sizeGauge.Dec(int64(oldSize))
 
+	reopened, err := openTable(ancientsPath, kind, table.readMeter, table.writeMeter, table.sizeGauge, table.maxFileSize, codec, dict, false)
+	if err != nil {
+		return err
+	}
+	f.tables[kind] = reopened
+	f.writeBatch = newFreezerBatch(f)
 	return nil
 }
 
@@ -0,0 +1,179 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rawdb
+
+import (
+	"fmt"
+	"sync/atomic"
+
+	"github.com/golang/snappy"
+	"github.com/klauspost/compress/zstd"
+)
+
+const (
+	// zstdDictID is the identifier of the dictionary in the zstd frames. It's
+	// non-zero, so that frames compressed without the dictionary can't be
+	// mistaken for ones using it.
+	zstdDictID = 1
+
+	// zstdDictSize is the size of the dictionaries built for zstd tables.
+	zstdDictSize = 64 * 1024
+
+	// zstdDictSamples is the number of items sampled to build a dictionary.
+	zstdDictSamples = 256
+)
+
+// freezerCompressor compresses and decompresses the items of a freezer table
+// with the codec of the table. It's safe for concurrent use.
+type freezerCompressor struct {
+	codec FreezerCodec
+	enc   *zstd.Encoder // Zstd encoder, nil for other codecs
+	dec   *zstd.Decoder // Zstd decoder, nil for other codecs
+}
+
+// newFreezerCompressor creates the compressor of the given codec. The dictionary
+// is only used by zstd.
+func newFreezerCompressor(codec FreezerCodec, dict []byte) (*freezerCompressor, error) {
+	c := &freezerCompressor{codec: codec}
+	switch codec {
+	case FreezerRaw, FreezerSnappy:
+		return c, nil
+
+	case FreezerZstd:
+		var (
+			eopts = []zstd.EOption{zstd.WithEncoderCRC(false), zstd.WithEncoderLevel(zstd.SpeedBetterCompression)}
+			dopts = []zstd.DOption{zstd.WithDecoderConcurrency(0)}
+			err   error
+		)
+		if len(dict) > 0 {
+			eopts = append(eopts, zstd.WithEncoderDictRaw(zstdDictID, dict))
+			dopts = append(dopts, zstd.WithDecoderDictRaw(zstdDictID, dict))
+		}
+		if c.enc, err = zstd.NewWriter(nil, eopts...); err != nil {
+			return nil, err
+		}
+		if c.dec, err = zstd.NewReader(nil, dopts...); err != nil {
+			c.enc.Close()
+			return nil, err
+		}
+		return c, nil
+
+	default:
+		return nil, fmt.Errorf("unsupported freezer codec %v", codec)
+	}
+}
+
+// compress returns the compressed form of the data, reusing the given buffer
+// if it's large enough.
+func (c *freezerCompressor) compress(dst, data []byte) []byte {
+	switch c.codec {
+	case FreezerSnappy:
+		// The snappy library does not care what the capacity of the buffer is,
+		// but only checks the length. If the length is too small, it will
+		// allocate a brand new buffer.
+		// To avoid that, we check the required size here, and grow the size of the
+		// buffer to utilize the full capacity.
+		if n := snappy.MaxEncodedLen(len(data)); len(dst) < n {
+			if cap(dst) < n {
+				dst = make([]byte, n)
+			}
+			dst = dst[:n]
+		}
+		return snappy.Encode(dst, data)
+
+	case FreezerZstd:
+		return c.enc.EncodeAll(data, dst[:0])
+
+	default:
+		return data
+	}
+}
+
+// decodedLen returns the length of the decompressed item, or -1 if it can't
+// be determined without decompressing it.
+func (c *freezerCompressor) decodedLen(item []byte) int {
+	switch c.codec {
+	case FreezerSnappy:
+		n, err := snappy.DecodedLen(item)
+		if err != nil {
+			return -1
+		}
+		return n
+
+	case FreezerZstd:
+		var header zstd.Header
+		if err := header.Decode(item); err != nil || !header.HasFCS {
+			return -1
+		}
+		return int(header.FrameContentSize)
+
+	default:
+		return len(item)
+	}
+}
+
+// decompress returns the decompressed form of the item. Raw items are returned
+// as is, without copying.
+func (c *freezerCompressor) decompress(item []byte) ([]byte, error) {
+	switch c.codec {
+	case FreezerSnappy:
+		return snappy.Decode(nil, item)
+
+	case FreezerZstd:
+		return c.dec.DecodeAll(item, nil)
+
+	default:
+		return item, nil
+	}
+}
+
+// close releases the resources held by the compressor.
+func (c *freezerCompressor) close() {
+	if c.enc != nil {
+		c.enc.Close()
+	}
+	if c.dec != nil {
+		c.dec.Close()
+	}
+}
+
+// buildZstdDict builds a raw zstd dictionary from items sampled evenly across
+// the given freezer table. The dictionary primes the compression of every item
+// with the encoding structure repeating across them, which small items like
+// receipts and bodies compress much better with.
+func buildZstdDict(t *freezerTable) ([]byte, error) {
+	var (
+		dict []byte
+		head = atomic.LoadUint64(&t.items)
+		tail = atomic.LoadUint64(&t.itemHidden)
+		step = (head - tail) / zstdDictSamples
+	)
+	if step == 0 {
+		step = 1
+	}
+	for i := tail; i < head && len(dict) < zstdDictSize; i += step {
+		item, err := t.Retrieve(i)
+		if err != nil {
+			return nil, err
+		}
+		if len(item) > zstdDictSize/zstdDictSamples {
+			item = item[:zstdDictSize/zstdDictSamples]
+		}
+		dict = append(dict, item...)
+	}
+	return dict, nil
+}
@@ -17,6 +17,7 @@
 package rawdb
 
 import (
+	"fmt"
 	"io"
 	"os"
 
@@ -24,7 +25,44 @@ import (
 	"github.com/ethereum/go-ethereum/rlp"
 )
 
-const freezerVersion = 1 // The initial version tag of freezer table metadata
+const (
+	freezerTableV1 = 1              // The initial version tag of freezer table metadata
+	freezerTableV2 = 2              // Adds the compression codec of the table items
+	freezerVersion = freezerTableV2 // The current version tag of freezer table metadata
+)
+
+// FreezerCodec is the compression codec of the items in a freezer table.
+type FreezerCodec uint8
+
+const (
+	FreezerRaw    FreezerCodec = iota // Items are stored uncompressed
+	FreezerSnappy                     // Items are snappy compressed
+	FreezerZstd                       // Items are zstd compressed, optionally with a dictionary
+)
+
+// String implements fmt.Stringer.
+func (c FreezerCodec) String() string {
+	switch c {
+	case FreezerRaw:
+		return "raw"
+	case FreezerSnappy:
+		return "snappy"
+	case FreezerZstd:
+		return "zstd"
+	default:
+		return fmt.Sprintf("unknown(%d)", uint8(c))
+	}
+}
+
+// ParseFreezerCodec parses the name of a freezer compression codec.
+func ParseFreezerCodec(name string) (FreezerCodec, error) {
+	for _, codec := range []FreezerCodec{FreezerRaw, FreezerSnappy, FreezerZstd} {
+		if codec.String() == name {
+			return codec, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown freezer codec %q", name)
+}
 
 // freezerTableMeta wraps all the metadata of the freezer table.
 type freezerTableMeta struct {
@@ -36,13 +74,33 @@ type freezerTableMeta struct {
 	// plus the number of items hidden in the table, so it should never
 	// be lower than the "actual tail".
 	VirtualTail uint64
+
+	// Codec is the compression codec of the table items. It's only valid
+	// from version 2 on, older tables are identified by their file names.
+	Codec FreezerCodec `rlp:"optional"`
+
+	// Dictionary is the zstd dictionary the table items are compressed with,
+	// or empty if no dictionary is used.
+	Dictionary []byte `rlp:"optional"`
 }
 
-// newMetadata initializes the metadata object with the given virtual tail.
-func newMetadata(tail uint64) *freezerTableMeta {
+// newMetadata initializes the metadata object with the given virtual tail and
+// compression settings. Tables with a legacy codec (raw or snappy) keep using
+// the legacy metadata version, as their codec can be inferred from the file
+// names, so that older releases are still able to open them. Only the codecs
+// unknown to older releases need the version 2 metadata.
+func newMetadata(tail uint64, codec FreezerCodec, dict []byte) *freezerTableMeta {
+	if codec == FreezerRaw || codec == FreezerSnappy {
+		return &freezerTableMeta{
+			Version:     freezerTableV1,
+			VirtualTail: tail,
+		}
+	}
 	return &freezerTableMeta{
 		Version:     freezerVersion,
 		VirtualTail: tail,
+		Codec:       codec,
+		Dictionary:  dict,
 	}
 }
 
@@ -71,9 +129,9 @@ func writeMetadata(file *os.File, meta *freezerTableMeta) error {
 }
 
 // loadMetadata loads the metadata from the given metadata file.
-// Initializes the metadata file with the given "actual tail" if
-// it's empty.
-func loadMetadata(file *os.File, tail uint64) (*freezerTableMeta, error) {
+// Initializes the metadata file with the given "actual tail" and
+// compression settings if it's empty. Legacy metadata is left as is.
+func loadMetadata(file *os.File, tail uint64, codec FreezerCodec, dict []byte) (*freezerTableMeta, error) {
 	stat, err := file.Stat()
 	if err != nil {
 		return nil, err
@@ -85,7 +143,7 @@ func loadMetadata(file *os.File, tail uint64) (*freezerTableMeta, error) {
 	// In both cases, write the meta into the file with the actual tail
 	// as the virtual tail.
 	if stat.Size() == 0 {
-		m := newMetadata(tail)
+		m := newMetadata(tail, codec, dict)
 		if err := writeMetadata(file, m); err != nil {
 			return nil, err
 		}
@@ -107,3 +165,24 @@ func loadMetadata(file *os.File, tail uint64) (*freezerTableMeta, error) {
 	}
 	return m, nil
 }
+
+// readCodec returns the compression settings recorded in the given metadata
+// file. False is returned if the metadata is missing or legacy, in which case
+// the codec needs to be inferred from the table files.
+func readCodec(file *os.File) (FreezerCodec, []byte, bool, error) {
+	stat, err := file.Stat()
+	if err != nil {
+		return 0, nil, false, err
+	}
+	if stat.Size() == 0 {
+		return 0, nil, false, nil
+	}
+	m, err := readMetadata(file)
+	if err != nil {
+		return 0, nil, false, err
+	}
+	if m.Version < freezerTableV2 {
+		return 0, nil, false, nil
+	}
+	return m.Codec, m.Dictionary, true, nil
+}
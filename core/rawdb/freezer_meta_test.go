@@ -17,6 +17,7 @@
 package rawdb
 
 import (
+	"bytes"
 	"os"
 	"testing"
 )
@@ -26,7 +27,28 @@ func TestReadWriteFreezerTableMeta(t *testing.T) {
 	if err != nil {
 		t.Fatalf("Failed to create file %v", err)
 	}
-	err = writeMetadata(f, newMetadata(100))
+	err = writeMetadata(f, newMetadata(100, FreezerSnappy, nil))
+	if err != nil {
+		t.Fatalf("Failed to write metadata %v", err)
+	}
+	meta, err := readMetadata(f)
+	if err != nil {
+		t.Fatalf("Failed to read metadata %v", err)
+	}
+	if meta.Version != freezerTableV1 {
+		t.Fatalf("Unexpected version field")
+	}
+	if meta.VirtualTail != uint64(100) {
+		t.Fatalf("Unexpected virtual tail field")
+	}
+}
+
+func TestReadWriteZstdFreezerTableMeta(t *testing.T) {
+	f, err := os.CreateTemp(os.TempDir(), "*")
+	if err != nil {
+		t.Fatalf("Failed to create file %v", err)
+	}
+	err = writeMetadata(f, newMetadata(100, FreezerZstd, []byte{0x1, 0x2}))
 	if err != nil {
 		t.Fatalf("Failed to write metadata %v", err)
 	}
@@ -37,6 +59,9 @@ func TestReadWriteFreezerTableMeta(t *testing.T) {
 	if meta.Version != freezerVersion {
 		t.Fatalf("Unexpected version field")
 	}
+	if meta.Codec != FreezerZstd || !bytes.Equal(meta.Dictionary, []byte{0x1, 0x2}) {
+		t.Fatalf("Unexpected compression fields")
+	}
 	if meta.VirtualTail != uint64(100) {
 		t.Fatalf("Unexpected virtual tail field")
 	}
@@ -47,11 +72,11 @@ func TestInitializeFreezerTableMeta(t *testing.T) {
 	if err != nil {
 		t.Fatalf("Failed to create file %v", err)
 	}
-	meta, err := loadMetadata(f, uint64(100))
+	meta, err := loadMetadata(f, uint64(100), FreezerSnappy, nil)
 	if err != nil {
 		t.Fatalf("Failed to read metadata %v", err)
 	}
-	if meta.Version != freezerVersion {
+	if meta.Version != freezerTableV1 {
 		t.Fatalf("Unexpected version field")
 	}
 	if meta.VirtualTail != uint64(100) {
@@ -22,6 +22,7 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"math"
 	"os"
 	"path/filepath"
 	"sync"
@@ -30,7 +31,6 @@ import (
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/metrics"
-	"github.com/golang/snappy"
 )
 
 var (
@@ -85,8 +85,8 @@ func (i *indexEntry) bounds(end *indexEntry) (startOffset, endOffset, fileId uin
 }
 
 // freezerTable represents a single chained data table within the freezer (e.g. blocks).
-// It consists of a data file (snappy encoded arbitrary data blobs) and an indexEntry
-// file (uncompressed 64 bit indices into the data file).
+// It consists of a data file (arbitrary data blobs, compressed with the codec of the
+// table) and an indexEntry file (uncompressed 64 bit indices into the data file).
 type freezerTable struct {
 	// WARNING: The `items` field is accessed atomically. On 32 bit platforms, only
 	// 64-bit aligned fields can be atomic. The struct is guaranteed to be so aligned,
@@ -101,11 +101,13 @@ type freezerTable struct {
 	// should never be lower than itemOffset.
 	itemHidden uint64
 
-	noCompression bool // if true, disables snappy compression. Note: does not work retroactively
-	readonly      bool
-	maxFileSize   uint32 // Max file size for data-files
-	name          string
-	path          string
+	codec       FreezerCodec       // Compression codec of the items, recorded in the metadata
+	dict        []byte             // Compression dictionary of the items, recorded in the metadata
+	compressor  *freezerCompressor // Compressor of the items with the table codec
+	readonly    bool
+	maxFileSize uint32 // Max file size for data-files
+	name        string
+	path        string
 
 	head   *os.File            // File descriptor for the data head of the table
 	index  *os.File            // File descriptor for the indexEntry file of the table
@@ -123,72 +125,91 @@ type freezerTable struct {
 	lock   sync.RWMutex // Mutex protecting the data file descriptors
 }
 
-// NewFreezerTable opens the given path as a freezer table.
+// NewFreezerTable opens the given path as a freezer table. The compression flag
+// only applies if the table is created, existing tables retain their codec.
 func NewFreezerTable(path, name string, disableSnappy, readonly bool) (*freezerTable, error) {
 	return newTable(path, name, metrics.NilMeter{}, metrics.NilMeter{}, metrics.NilGauge{}, freezerTableSize, disableSnappy, readonly)
 }
 
-// newTable opens a freezer table, creating the data and index files if they are
+// newTable opens a freezer table, creating it snappy compressed or raw if it's
+// non-existent.
+func newTable(path string, name string, readMeter metrics.Meter, writeMeter metrics.Meter, sizeGauge metrics.Gauge, maxFilesize uint32, noCompression, readonly bool) (*freezerTable, error) {
+	codec := FreezerSnappy
+	if noCompression {
+		codec = FreezerRaw
+	}
+	return openTable(path, name, readMeter, writeMeter, sizeGauge, maxFilesize, codec, nil, readonly)
+}
+
+// openTable opens a freezer table, creating the data and index files if they are
 // non-existent. Both files are truncated to the shortest common length to ensure
 // they don't go out of sync.
-func newTable(path string, name string, readMeter metrics.Meter, writeMeter metrics.Meter, sizeGauge metrics.Gauge, maxFilesize uint32, noCompression, readonly bool) (*freezerTable, error) {
-	// Ensure the containing directory exists and open the indexEntry file
+//
+// The given codec and dictionary are only used if the table is created, existing
+// tables are opened with the codec recorded in their metadata.
+func openTable(path string, name string, readMeter metrics.Meter, writeMeter metrics.Meter, sizeGauge metrics.Gauge, maxFilesize uint32, codec FreezerCodec, dict []byte, readonly bool) (*freezerTable, error) {
+	// Ensure the containing directory exists and open the metadata file.
 	if err := os.MkdirAll(path, 0755); err != nil {
 		return nil, err
 	}
-	var idxName string
-	if noCompression {
-		idxName = fmt.Sprintf("%s.ridx", name) // raw index file
+	// TODO(rjl493456442) change it to read-only mode. Open the metadata file
+	// in rw mode. It's a temporary solution for now and should be changed
+	// whenever the tail deletion is actually used. The reason for this hack is
+	// the additional meta file for each freezer table is added in order to support
+	// tail deletion, but for most legacy nodes this file is missing. This check
+	// will suddenly break lots of database relevant commands. So the metadata file
+	// is always opened for mutation and nothing else will be written except
+	// the initialization.
+	meta, err := openFreezerFileForAppend(filepath.Join(path, fmt.Sprintf("%s.meta", name)))
+	if err != nil {
+		return nil, err
+	}
+	// Resolve the codec of the table, which determines the names of the index
+	// and data files.
+	recorded, recordedDict, ok, err := readCodec(meta)
+	if err != nil {
+		meta.Close()
+		return nil, err
+	}
+	if ok {
+		codec, dict = recorded, recordedDict
 	} else {
-		idxName = fmt.Sprintf("%s.cidx", name) // compressed index file
+		codec = legacyCodec(path, name, codec)
 	}
-	var (
-		err   error
-		index *os.File
-		meta  *os.File
-	)
+	compressor, err := newFreezerCompressor(codec, dict)
+	if err != nil {
+		meta.Close()
+		return nil, err
+	}
+	// Open the indexEntry file
+	var index *os.File
 	if readonly {
 		// Will fail if table doesn't exist
-		index, err = openFreezerFileForReadOnly(filepath.Join(path, idxName))
-		if err != nil {
-			return nil, err
-		}
-		// TODO(rjl493456442) change it to read-only mode. Open the metadata file
-		// in rw mode. It's a temporary solution for now and should be changed
-		// whenever the tail deletion is actually used. The reason for this hack is
-		// the additional meta file for each freezer table is added in order to support
-		// tail deletion, but for most legacy nodes this file is missing. This check
-		// will suddenly break lots of database relevant commands. So the metadata file
-		// is always opened for mutation and nothing else will be written except
-		// the initialization.
-		meta, err = openFreezerFileForAppend(filepath.Join(path, fmt.Sprintf("%s.meta", name)))
-		if err != nil {
-			return nil, err
-		}
+		index, err = openFreezerFileForReadOnly(filepath.Join(path, indexFileName(name, codec)))
 	} else {
-		index, err = openFreezerFileForAppend(filepath.Join(path, idxName))
-		if err != nil {
-			return nil, err
-		}
-		meta, err = openFreezerFileForAppend(filepath.Join(path, fmt.Sprintf("%s.meta", name)))
-		if err != nil {
-			return nil, err
-		}
+		index, err = openFreezerFileForAppend(filepath.Join(path, indexFileName(name, codec)))
+	}
+	if err != nil {
+		compressor.close()
+		meta.Close()
+		return nil, err
 	}
 	// Create the table and repair any past inconsistency
 	tab := &freezerTable{
-		index:         index,
-		meta:          meta,
-		files:         make(map[uint32]*os.File),
-		readMeter:     readMeter,
-		writeMeter:    writeMeter,
-		sizeGauge:     sizeGauge,
-		name:          name,
-		path:          path,
-		logger:        log.New("database", path, "table", name),
-		noCompression: noCompression,
-		readonly:      readonly,
-		maxFileSize:   maxFilesize,
+		index:       index,
+		meta:        meta,
+		files:       make(map[uint32]*os.File),
+		readMeter:   readMeter,
+		writeMeter:  writeMeter,
+		sizeGauge:   sizeGauge,
+		name:        name,
+		path:        path,
+		logger:      log.New("database", path, "table", name),
+		codec:       codec,
+		dict:        dict,
+		compressor:  compressor,
+		readonly:    readonly,
+		maxFileSize: maxFilesize,
 	}
 	if err := tab.repair(); err != nil {
 		tab.Close()
@@ -205,6 +226,42 @@ func newTable(path string, name string, readMeter metrics.Meter, writeMeter metr
 	return tab, nil
 }
 
+// indexFileName returns the name of the index file of a table.
+func indexFileName(name string, codec FreezerCodec) string {
+	switch codec {
+	case FreezerRaw:
+		return fmt.Sprintf("%s.ridx", name) // raw index file
+	case FreezerZstd:
+		return fmt.Sprintf("%s.zidx", name) // zstd compressed index file
+	default:
+		return fmt.Sprintf("%s.cidx", name) // snappy compressed index file
+	}
+}
+
+// dataFileName returns the name of a data file of a table.
+func dataFileName(name string, num uint32, codec FreezerCodec) string {
+	switch codec {
+	case FreezerRaw:
+		return fmt.Sprintf("%s.%04d.rdat", name, num)
+	case FreezerZstd:
+		return fmt.Sprintf("%s.%04d.zdat", name, num)
+	default:
+		return fmt.Sprintf("%s.%04d.cdat", name, num)
+	}
+}
+
+// legacyCodec infers the codec of a table without the codec recorded in its
+// metadata from the existing index files. The given codec is preferred if the
+// table exists in multiple codecs, and used if the table doesn't exist yet.
+func legacyCodec(path, name string, codec FreezerCodec) FreezerCodec {
+	for _, c := range []FreezerCodec{codec, FreezerSnappy, FreezerRaw} {
+		if _, err := os.Stat(filepath.Join(path, indexFileName(name, c))); err == nil {
+			return c
+		}
+	}
+	return codec
+}
+
 // repair cross-checks the head and the index file and truncates them to
 // be in sync with each other after a potential crash / data loss.
 func (t *freezerTable) repair() error {
@@ -251,7 +308,7 @@ func (t *freezerTable) repair() error {
 	t.itemOffset = uint64(firstIndex.offset)
 
 	// Load metadata from the file
-	meta, err := loadMetadata(t.meta, t.itemOffset)
+	meta, err := loadMetadata(t.meta, t.itemOffset, t.codec, t.dict)
 	if err != nil {
 		return err
 	}
@@ -481,7 +538,7 @@ func (t *freezerTable) truncateTail(items uint64) error {
 	}
 	// Update the virtual tail marker and hidden these entries in table.
 	atomic.StoreUint64(&t.itemHidden, items)
-	if err := writeMetadata(t.meta, newMetadata(items)); err != nil {
+	if err := writeMetadata(t.meta, newMetadata(items, t.codec, t.dict)); err != nil {
 		return err
 	}
 	// Hidden items still fall in the current tail file, no data file
@@ -561,6 +618,7 @@ func (t *freezerTable) Close() error {
 	defer t.lock.Unlock()
 
 	var errs []error
+	t.compressor.close()
 	if err := t.index.Close(); err != nil {
 		errs = append(errs, err)
 	}
@@ -588,13 +646,7 @@ func (t *freezerTable) Close() error {
 func (t *freezerTable) openFile(num uint32, opener func(string) (*os.File, error)) (f *os.File, err error) {
 	var exist bool
 	if f, exist = t.files[num]; !exist {
-		var name string
-		if t.noCompression {
-			name = fmt.Sprintf("%s.%04d.rdat", t.name, num)
-		} else {
-			name = fmt.Sprintf("%s.%04d.cdat", t.name, num)
-		}
-		f, err = opener(filepath.Join(t.path, name))
+		f, err = opener(filepath.Join(t.path, dataFileName(t.name, num, t.codec)))
 		if err != nil {
 			return nil, err
 		}
@@ -612,6 +664,36 @@ func (t *freezerTable) releaseFile(num uint32) {
 	}
 }
 
+// initTail sets the tail of an empty table to the given item, as if all items
+// before it had been deleted. It's used to migrate tail-deleted tables.
+func (t *freezerTable) initTail(tail uint64) error {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if atomic.LoadUint64(&t.items) != 0 || t.headBytes != 0 {
+		return errors.New("tail initialization of non-empty table")
+	}
+	if tail > math.MaxUint32 {
+		return fmt.Errorf("tail %d out of range", tail)
+	}
+	// Commit the virtual tail first, then replace the only index entry with
+	// the one pointing to the new tail.
+	if err := writeMetadata(t.meta, newMetadata(tail, t.codec, t.dict)); err != nil {
+		return err
+	}
+	if err := t.meta.Sync(); err != nil {
+		return err
+	}
+	entry := indexEntry{filenum: t.headId, offset: uint32(tail)}
+	if _, err := t.index.WriteAt(entry.append(nil), 0); err != nil {
+		return err
+	}
+	atomic.StoreUint64(&t.itemOffset, tail)
+	atomic.StoreUint64(&t.itemHidden, tail)
+	atomic.StoreUint64(&t.items, tail)
+	return nil
+}
+
 // releaseFilesAfter closes all open files with a higher number, and optionally also deletes the files
 func (t *freezerTable) releaseFilesAfter(num uint32, remove bool) {
 	for fnum, f := range t.files {
@@ -703,23 +785,19 @@ func (t *freezerTable) RetrieveItems(start, count, maxBytes uint64) ([][]byte, e
 	for i, diskSize := range sizes {
 		item := diskData[offset : offset+diskSize]
 		offset += diskSize
-		decompressedSize := diskSize
-		if !t.noCompression {
-			decompressedSize, _ = snappy.DecodedLen(item)
-		}
-		if i > 0 && uint64(outputSize+decompressedSize) > maxBytes {
+		decompressedSize := t.compressor.decodedLen(item)
+		if i > 0 && decompressedSize >= 0 && uint64(outputSize+decompressedSize) > maxBytes {
 			break
 		}
-		if !t.noCompression {
-			data, err := snappy.Decode(nil, item)
-			if err != nil {
-				return nil, err
-			}
-			output = append(output, data)
-		} else {
-			output = append(output, item)
+		data, err := t.compressor.decompress(item)
+		if err != nil {
+			return nil, err
+		}
+		if i > 0 && uint64(outputSize+len(data)) > maxBytes {
+			break
 		}
-		outputSize += decompressedSize
+		output = append(output, data)
+		outputSize += len(data)
 	}
 	return output, nil
 }
@@ -251,13 +251,15 @@ func TestFreezerRepairDanglingHeadLarge(t *testing.T) {
 	}
 }
 
-// TestSnappyDetection tests that we fail to open a snappy database and vice versa
+// TestSnappyDetection tests that tables are opened with the codec they were
+// created with, regardless of the requested one, both if the codec is recorded
+// in the metadata and if it's inferred from the files of a legacy table.
 func TestSnappyDetection(t *testing.T) {
 	t.Parallel()
 	rm, wm, sg := metrics.NewMeter(), metrics.NewMeter(), metrics.NewGauge()
 	fname := fmt.Sprintf("snappytest-%d", rand.Uint64())
 
-	// Open with snappy
+	// Open without snappy
 	{
 		f, err := newTable(os.TempDir(), fname, rm, wm, sg, 50, true, false)
 		if err != nil {
@@ -268,29 +270,90 @@ func TestSnappyDetection(t *testing.T) {
 		f.Close()
 	}
 
-	// Open without snappy
+	// Open with snappy, the recorded codec is used
 	{
 		f, err := newTable(os.TempDir(), fname, rm, wm, sg, 50, false, false)
 		if err != nil {
 			t.Fatal(err)
 		}
-		if _, err = f.Retrieve(0); err == nil {
+		if f.codec != FreezerRaw {
+			t.Fatalf("codec mismatch: have %v, want %v", f.codec, FreezerRaw)
+		}
+		// There should be 255 items
+		if _, err = f.Retrieve(0xfe); err != nil {
 			f.Close()
-			t.Fatalf("expected empty table")
+			t.Fatalf("expected no error, got %v", err)
 		}
+		// Downgrade the metadata to the legacy version without codec
+		if err := writeMetadata(f.meta, &freezerTableMeta{Version: freezerTableV1}); err != nil {
+			t.Fatal(err)
+		}
+		f.Close()
 	}
 
-	// Open with snappy
+	// Open with snappy, the codec is inferred from the files
 	{
-		f, err := newTable(os.TempDir(), fname, rm, wm, sg, 50, true, false)
+		f, err := newTable(os.TempDir(), fname, rm, wm, sg, 50, false, false)
 		if err != nil {
 			t.Fatal(err)
 		}
-		// There should be 255 items
+		if f.codec != FreezerRaw {
+			t.Fatalf("codec mismatch: have %v, want %v", f.codec, FreezerRaw)
+		}
 		if _, err = f.Retrieve(0xfe); err != nil {
 			f.Close()
 			t.Fatalf("expected no error, got %v", err)
 		}
+		meta, err := readMetadata(f.meta)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if meta.Version != freezerTableV1 {
+			t.Fatalf("legacy metadata rewritten: version %d, codec %v", meta.Version, meta.Codec)
+		}
+		f.Close()
+	}
+}
+
+// Tests that zstd tables, with and without dictionary, store and retrieve
+// items correctly.
+func TestZstdTable(t *testing.T) {
+	t.Parallel()
+
+	for _, dict := range [][]byte{nil, getChunk(4096, 0x42)} {
+		fname := fmt.Sprintf("zstdtest-%d", rand.Uint64())
+		f, err := openTable(os.TempDir(), fname, metrics.NilMeter{}, metrics.NilMeter{}, metrics.NilGauge{}, 50, FreezerZstd, dict, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		writeChunks(t, f, 255, 15)
+		f.Close()
+
+		// Reopen with another codec requested, zstd should be retained
+		f, err = newTable(os.TempDir(), fname, metrics.NilMeter{}, metrics.NilMeter{}, metrics.NilGauge{}, 50, true, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if f.codec != FreezerZstd || !bytes.Equal(f.dict, dict) {
+			t.Fatalf("compression settings mismatch: codec %v, dictionary %d bytes", f.codec, len(f.dict))
+		}
+		for y := 0; y < 255; y++ {
+			got, err := f.Retrieve(uint64(y))
+			if err != nil {
+				t.Fatalf("item %d: %v", y, err)
+			}
+			if exp := getChunk(15, y); !bytes.Equal(got, exp) {
+				t.Fatalf("item %d: have %x, want %x", y, got, exp)
+			}
+		}
+		items, err := f.RetrieveItems(0, 255, 100)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(items) == 0 || len(items)*15 > 100 {
+			t.Fatalf("wrong number of items retrieved: %d", len(items))
+		}
+		f.Close()
 	}
 }
 
@@ -283,6 +283,191 @@ func TestFreezerReadonlyValidate(t *testing.T) {
 	}
 }
 
+func TestFreezerPrunableTail(t *testing.T) {
+	tables := map[string]bool{"a": true, "b": true}
+	prunable := map[string]bool{"b": true}
+	dir := t.TempDir()
+
+	f, err := newFreezer(dir, "", false, 2049, tables, prunable)
+	if err != nil {
+		t.Fatal("can't open freezer", err)
+	}
+	var item = make([]byte, 256)
+	_, err = f.ModifyAncients(func(op ethdb.AncientWriteOp) error {
+		for i := uint64(0); i < 100; i++ {
+			if err := op.AppendRaw("a", i, item); err != nil {
+				return err
+			}
+			if err := op.AppendRaw("b", i, item); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	require.NoError(t, f.TruncateTail(50))
+
+	check := func(f *Freezer) {
+		t.Helper()
+		if tail, _ := f.Tail(); tail != 50 {
+			t.Fatalf("Tail() returned %d, want 50", tail)
+		}
+		if ok, _ := f.HasAncient("a", 0); !ok {
+			t.Fatal("non-prunable table was truncated")
+		}
+		if ok, _ := f.HasAncient("b", 49); ok {
+			t.Fatal("prunable table was not truncated")
+		}
+		if ok, _ := f.HasAncient("b", 50); !ok {
+			t.Fatal("prunable table was truncated too far")
+		}
+		checkAncientCount(t, f, "a", 100)
+	}
+	check(f)
+	require.NoError(t, f.Close())
+
+	// Reopen the freezer, both in writable and readonly mode, and ensure the
+	// tail is restored without touching the non-prunable tables.
+	f, err = newFreezer(dir, "", false, 2049, tables, prunable)
+	if err != nil {
+		t.Fatal("can't reopen freezer", err)
+	}
+	check(f)
+	require.NoError(t, f.Close())
+
+	f, err = newFreezer(dir, "", true, 2049, tables, prunable)
+	if err != nil {
+		t.Fatal("can't reopen readonly freezer", err)
+	}
+	check(f)
+	require.NoError(t, f.Close())
+}
+
+// Tests that tables can be recompressed in place with another codec, which is
+// retained when reopening the freezer.
+func TestFreezerRecompress(t *testing.T) {
+	tables := map[string]bool{"a": false, "b": true}
+	f, dir := newFreezerForTesting(t, tables)
+
+	item := func(i uint64) []byte {
+		return []byte(fmt.Sprintf("receipt of transaction %d in block %d", i%7, i))
+	}
+	write := func(from, to uint64) {
+		_, err := f.ModifyAncients(func(op ethdb.AncientWriteOp) error {
+			for i := from; i < to; i++ {
+				require.NoError(t, op.AppendRaw("a", i, item(i)))
+				require.NoError(t, op.AppendRaw("b", i, item(i)))
+			}
+			return nil
+		})
+		require.NoError(t, err)
+	}
+	check := func(codec FreezerCodec, n uint64) {
+		t.Helper()
+		if have := f.tables["a"].codec; have != codec {
+			t.Fatalf("codec mismatch: have %v, want %v", have, codec)
+		}
+		checkAncientCount(t, f, "a", n)
+		for i := uint64(0); i < n; i++ {
+			blob, err := f.Ancient("a", i)
+			require.NoError(t, err)
+			if !bytes.Equal(blob, item(i)) {
+				t.Fatalf("item %d mismatch: have %q, want %q", i, blob, item(i))
+			}
+		}
+	}
+	write(0, 100)
+	require.NoError(t, f.RecompressTable("a", FreezerZstd))
+	if len(f.tables["a"].dict) == 0 {
+		t.Fatal("no dictionary built")
+	}
+	check(FreezerZstd, 100)
+	for _, name := range []string{indexFileName("a", FreezerSnappy), dataFileName("a", 0, FreezerSnappy)} {
+		if _, err := os.Stat(path.Join(dir, name)); !os.IsNotExist(err) {
+			t.Fatalf("old table file %s not removed", name)
+		}
+	}
+	// Ensure the recompressed table can be appended to and is reopened with
+	// its new codec
+	write(100, 150)
+	require.NoError(t, f.Close())
+
+	var err error
+	f, err = NewFreezer(dir, "", false, 2049, tables)
+	require.NoError(t, err)
+	check(FreezerZstd, 150)
+
+	require.NoError(t, f.RecompressTable("a", FreezerRaw))
+	check(FreezerRaw, 150)
+	require.NoError(t, f.Close())
+}
+
+// Tests that tables with deleted tail items can be recompressed, retaining
+// their tail.
+func TestFreezerRecompressTailDeleted(t *testing.T) {
+	tables := map[string]bool{"a": true}
+	f, dir := newFreezerForTesting(t, tables)
+
+	item := func(i uint64) []byte {
+		return []byte(fmt.Sprintf("receipt of transaction %d in block %d", i%7, i))
+	}
+	write := func(from, to uint64) {
+		_, err := f.ModifyAncients(func(op ethdb.AncientWriteOp) error {
+			for i := from; i < to; i++ {
+				require.NoError(t, op.AppendRaw("a", i, item(i)))
+			}
+			return nil
+		})
+		require.NoError(t, err)
+	}
+	check := func(codec FreezerCodec, tail, n uint64) {
+		t.Helper()
+		if have := f.tables["a"].codec; have != codec {
+			t.Fatalf("codec mismatch: have %v, want %v", have, codec)
+		}
+		checkAncientCount(t, f, "a", n)
+		if have, _ := f.Tail(); have != tail {
+			t.Fatalf("tail mismatch: have %d, want %d", have, tail)
+		}
+		for i := uint64(0); i < n; i++ {
+			blob, err := f.Ancient("a", i)
+			if i < tail {
+				if err == nil {
+					t.Fatalf("deleted item %d retrievable", i)
+				}
+				continue
+			}
+			require.NoError(t, err)
+			if !bytes.Equal(blob, item(i)) {
+				t.Fatalf("item %d mismatch: have %q, want %q", i, blob, item(i))
+			}
+		}
+	}
+	write(0, 200)
+	require.NoError(t, f.TruncateTail(120))
+	require.NoError(t, f.RecompressTable("a", FreezerZstd))
+	check(FreezerZstd, 120, 200)
+
+	// Ensure the tail is retained after reopening, and the table can still be
+	// appended to and recompressed again
+	write(200, 250)
+	require.NoError(t, f.Close())
+
+	var err error
+	f, err = NewFreezer(dir, "", false, 2049, tables)
+	require.NoError(t, err)
+	check(FreezerZstd, 120, 250)
+
+	require.NoError(t, f.RecompressTable("a", FreezerSnappy))
+	check(FreezerSnappy, 120, 250)
+	require.NoError(t, f.Close())
+
+	f, err = NewFreezer(dir, "", false, 2049, tables)
+	require.NoError(t, err)
+	check(FreezerSnappy, 120, 250)
+	require.NoError(t, f.Close())
+}
+
 func newFreezerForTesting(t *testing.T, tables map[string]bool) (*Freezer, string) {
 	t.Helper()
 
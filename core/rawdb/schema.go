@@ -84,6 +84,13 @@ var (
 	// transitionStatusKey tracks the eth2 transition status.
 	transitionStatusKey = []byte("eth2-transition")
 
+	// stateSchemeKey tracks the trie node storage scheme of the database.
+	stateSchemeKey = []byte("StateScheme")
+
+	// persistentStateIDKey tracks the id of the latest state flushed into the
+	// path-based trie node storage.
+	persistentStateIDKey = []byte("LastStateID")
+
 	// Data item prefixes (use single byte to avoid mixing data types, avoid `i`, used for indexes).
 	headerPrefix       = []byte("h") // headerPrefix + num (uint64 big endian) + hash -> header
 	headerTDSuffix     = []byte("t") // headerPrefix + num (uint64 big endian) + hash + headerTDSuffix -> td
@@ -99,6 +106,13 @@ var (
 	SnapshotStoragePrefix = []byte("o") // SnapshotStoragePrefix + account hash + storage hash -> storage trie value
 	CodePrefix            = []byte("c") // CodePrefix + code hash -> account code
 	skeletonHeaderPrefix  = []byte("S") // skeletonHeaderPrefix + num (uint64 big endian) -> header
+	logIndexPrefix        = []byte("x") // logIndexPrefix + section (uint64 big endian) + field + address/topic -> log positions
+	logIndexHeadPrefix    = []byte("X") // logIndexHeadPrefix + section (uint64 big endian) -> section head hash
+
+	TrieNodeAccountPrefix = []byte("A") // TrieNodeAccountPrefix + hexPath -> trie node
+	TrieNodeStoragePrefix = []byte("O") // TrieNodeStoragePrefix + accountHash + hexPath -> trie node
+	stateIDPrefix         = []byte("L") // stateIDPrefix + state root -> state id
+	reverseDiffPrefix     = []byte("D") // reverseDiffPrefix + state id (uint64 big endian) -> reverse diff
 
 	PreimagePrefix = []byte("secure-key-")       // PreimagePrefix + hash -> preimage
 	configPrefix   = []byte("ethereum-config-")  // config prefix for the db
@@ -106,6 +120,7 @@ var (
 
 	// Chain index prefixes (use `i` + single byte to avoid mixing data types).
 	BloomBitsIndexPrefix = []byte("iB") // BloomBitsIndexPrefix is the data table of a chain indexer to track its progress
+	LogIndexPrefix       = []byte("iL") // LogIndexPrefix is the data table of the log indexer to track its progress
 
 	preimageCounter    = metrics.NewRegisteredCounter("db/preimage/total", nil)
 	preimageHitCounter = metrics.NewRegisteredCounter("db/preimage/hits", nil)
@@ -138,6 +153,14 @@ var FreezerNoSnappy = map[string]bool{
 	freezerDifficultyTable: true,
 }
 
+// freezerPrunable configures which ancient-tables are dropped when truncating the
+// tail of the chain freezer. Headers, hashes and difficulties are always kept so
+// the header chain stays intact.
+var freezerPrunable = map[string]bool{
+	freezerBodiesTable:  true,
+	freezerReceiptTable: true,
+}
+
 // LegacyTxLookupEntry is the legacy TxLookupEntry definition with some unnecessary
 // fields.
 type LegacyTxLookupEntry struct {
@@ -218,11 +241,45 @@ func bloomBitsKey(bit uint, section uint64, hash common.Hash) []byte {
 	return key
 }
 
+// logIndexKey = logIndexPrefix + section (uint64 big endian) + field + value
+func logIndexKey(section uint64, field byte, value []byte) []byte {
+	key := make([]byte, 0, len(logIndexPrefix)+9+len(value))
+	key = append(append(key, logIndexPrefix...), encodeBlockNumber(section)...)
+	return append(append(key, field), value...)
+}
+
+// logIndexHeadKey = logIndexHeadPrefix + section (uint64 big endian)
+func logIndexHeadKey(section uint64) []byte {
+	return append(logIndexHeadPrefix, encodeBlockNumber(section)...)
+}
+
 // skeletonHeaderKey = skeletonHeaderPrefix + num (uint64 big endian)
 func skeletonHeaderKey(number uint64) []byte {
 	return append(skeletonHeaderPrefix, encodeBlockNumber(number)...)
 }
 
+// accountTrieNodeKey = TrieNodeAccountPrefix + nodePath.
+func accountTrieNodeKey(path []byte) []byte {
+	key := make([]byte, 0, len(TrieNodeAccountPrefix)+len(path))
+	return append(append(key, TrieNodeAccountPrefix...), path...)
+}
+
+// storageTrieNodeKey = TrieNodeStoragePrefix + accountHash + nodePath.
+func storageTrieNodeKey(accountHash common.Hash, path []byte) []byte {
+	key := make([]byte, 0, len(TrieNodeStoragePrefix)+common.HashLength+len(path))
+	return append(append(append(key, TrieNodeStoragePrefix...), accountHash.Bytes()...), path...)
+}
+
+// stateIDKey = stateIDPrefix + root (32 bytes)
+func stateIDKey(root common.Hash) []byte {
+	return append(stateIDPrefix, root.Bytes()...)
+}
+
+// reverseDiffKey = reverseDiffPrefix + id (uint64 big endian)
+func reverseDiffKey(id uint64) []byte {
+	return append(reverseDiffPrefix, encodeBlockNumber(id)...)
+}
+
 // preimageKey = PreimagePrefix + hash
 func preimageKey(hash common.Hash) []byte {
 	return append(PreimagePrefix, hash.Bytes()...)
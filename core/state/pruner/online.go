@@ -0,0 +1,340 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package pruner
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/state/snapshot"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+const (
+	// onlinePruneBatch is the number of stale trie nodes collected before they
+	// are deleted in one batch.
+	onlinePruneBatch = 4096
+
+	// onlinePruneMinInterval is the minimal time allowed between two pruning
+	// cycles.
+	onlinePruneMinInterval = time.Minute
+)
+
+var (
+	// onlinePruneRecheck is the time to wait between checking whether the chain
+	// progressed far enough to start deleting stale trie nodes. It's a variable
+	// to allow tests to speed it up.
+	onlinePruneRecheck = 10 * time.Second
+
+	// errInterrupted is returned if the pruning was aborted by a shutdown.
+	errInterrupted = errors.New("pruning interrupted")
+)
+
+// Chain defines the blockchain methods needed by the online pruner.
+type Chain interface {
+	// CurrentBlock retrieves the current head block of the canonical chain.
+	CurrentBlock() *types.Block
+
+	// StateCache returns the caching database underpinning the chain state.
+	StateCache() state.Database
+
+	// Snapshots returns the snapshot tree of the chain state, nil if disabled.
+	Snapshots() *snapshot.Tree
+
+	// FlushState persists the state trie of the given root into the disk.
+	FlushState(root common.Hash) error
+}
+
+// OnlineConfig contains the settings of the online pruner.
+type OnlineConfig struct {
+	BloomSize uint64        // Megabytes of memory allocated to the live state bloom filter
+	Interval  time.Duration // Time to wait between two pruning cycles
+	RateLimit int           // Maximum number of trie nodes deleted per second, 0 for unlimited
+}
+
+// OnlinePruner is a background service deleting stale trie nodes from the
+// database of a live node. A pruning cycle works as follows:
+//
+//   - start protecting all trie nodes flushed to disk, and persist the state of
+//     the current head, which becomes the pruning target
+//   - regenerate the target state from the snapshot tree and iterate the
+//     genesis state, recording their trie nodes in a bloom filter
+//   - wait until the target is deeper than the tries kept in memory, so every
+//     state still in use derives from it
+//   - iterate the database, deleting all trie nodes neither recorded nor
+//     protected, throttled by the configured rate limit
+//
+// States older than the pruning target become unavailable after a cycle, same
+// as with the offline pruner.
+type OnlinePruner struct {
+	db     ethdb.Database
+	chain  Chain
+	config OnlineConfig
+	synced func() bool // Whether the node is synced, no pruning is done until then
+
+	bloom *stateBloom // Live state filter, non-nil while a pruning cycle is running
+	lock  sync.Mutex  // Lock protecting the live state filter from concurrent deletions
+
+	quit chan struct{}
+	wg   sync.WaitGroup
+
+	startHook func() // Hook invoked once a cycle protects flushed nodes (used by tests)
+}
+
+// NewOnlinePruner creates an online pruner for the given chain. The pruner needs
+// to be created before the chain starts importing blocks, so that no trie node
+// is flushed to disk unnoticed.
+func NewOnlinePruner(db ethdb.Database, chain Chain, config OnlineConfig, synced func() bool) (*OnlinePruner, error) {
+	// The path scheme overwrites trie nodes in place, there's nothing to prune
+	if rawdb.ReadStateScheme(db) == rawdb.PathScheme {
+		return nil, errors.New("online pruning is not needed with the path-based state scheme")
+	}
+	if config.BloomSize < 256 {
+		log.Warn("Sanitizing bloomfilter size", "provided(MB)", config.BloomSize, "updated(MB)", 256)
+		config.BloomSize = 256
+	}
+	if config.Interval < onlinePruneMinInterval {
+		log.Warn("Sanitizing online pruning interval", "provided", config.Interval, "updated", onlinePruneMinInterval)
+		config.Interval = onlinePruneMinInterval
+	}
+	p := &OnlinePruner{
+		db:     db,
+		chain:  chain,
+		config: config,
+		synced: synced,
+		quit:   make(chan struct{}),
+	}
+	chain.StateCache().TrieDB().SetFlushHook(p.protect)
+	return p, nil
+}
+
+// Start launches the background pruning loop.
+func (p *OnlinePruner) Start() {
+	p.wg.Add(1)
+	go p.loop()
+}
+
+// Stop terminates the background pruning loop, aborting any running cycle.
+func (p *OnlinePruner) Stop() {
+	close(p.quit)
+	p.wg.Wait()
+}
+
+// protect is invoked by the trie database for every trie node flushed to disk,
+// marking it as live if a pruning cycle is running.
+func (p *OnlinePruner) protect(hash common.Hash) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if p.bloom != nil {
+		p.bloom.Put(hash.Bytes(), nil)
+	}
+}
+
+// loop runs the pruning cycles once the node is synced, waiting the configured
+// interval in between.
+func (p *OnlinePruner) loop() {
+	defer p.wg.Done()
+
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-timer.C:
+			if !p.synced() {
+				timer.Reset(onlinePruneRecheck)
+				continue
+			}
+			if err := p.prune(); err != nil {
+				if err == errInterrupted {
+					return
+				}
+				log.Error("Online state pruning failed", "err", err)
+			}
+			timer.Reset(p.config.Interval)
+
+		case <-p.quit:
+			return
+		}
+	}
+}
+
+// prune runs a single pruning cycle.
+func (p *OnlinePruner) prune() error {
+	bloom, err := newStateBloomWithSize(p.config.BloomSize)
+	if err != nil {
+		return err
+	}
+	// Start protecting flushed trie nodes before persisting the target state,
+	// otherwise nodes of newer states could slip through.
+	p.lock.Lock()
+	p.bloom = bloom
+	p.lock.Unlock()
+
+	if p.startHook != nil {
+		p.startHook()
+	}
+	defer func() {
+		p.lock.Lock()
+		p.bloom = nil
+		p.lock.Unlock()
+	}()
+	start := time.Now()
+	target := p.chain.CurrentBlock()
+	if err := p.chain.FlushState(target.Root()); err != nil {
+		return err
+	}
+	log.Info("Started online state pruning", "number", target.NumberU64(), "root", target.Root())
+
+	// Record the live state. The genesis state is always retained, same as
+	// with the offline pruner.
+	if err := extractGenesis(p.db, bloom); err != nil {
+		return err
+	}
+	if err := p.markLive(target.Root(), bloom); err != nil {
+		return err
+	}
+	log.Info("Recorded live state", "number", target.NumberU64(), "elapsed", common.PrettyDuration(time.Since(start)))
+
+	// Wait until all the in-memory tries derive from the target state, and
+	// ensure it wasn't reorged out meanwhile.
+	for p.chain.CurrentBlock().NumberU64() < target.NumberU64()+core.TriesInMemory {
+		select {
+		case <-time.After(onlinePruneRecheck):
+		case <-p.quit:
+			return errInterrupted
+		}
+	}
+	if rawdb.ReadCanonicalHash(p.db, target.NumberU64()) != target.Hash() {
+		log.Warn("Online pruning target reorged out", "number", target.NumberU64(), "hash", target.Hash())
+		return nil
+	}
+	count, size, err := p.sweep(bloom)
+	if err != nil {
+		return err
+	}
+	log.Info("Pruned stale state", "nodes", count, "size", size, "elapsed", common.PrettyDuration(time.Since(start)))
+	return nil
+}
+
+// markLive records the trie nodes and contract codes of the given state in the
+// live state filter. The state is regenerated from the snapshot tree, which is
+// a lot faster than iterating the trie on disk. If the snapshot is unavailable,
+// or its layers get flattened below the target during the regeneration, the
+// persisted trie is iterated instead.
+func (p *OnlinePruner) markLive(root common.Hash, bloom *stateBloom) error {
+	if snaptree := p.chain.Snapshots(); snaptree != nil {
+		err := snapshot.GenerateTrieWithInterrupt(snaptree, root, p.db, bloom, p.quit)
+		if err == nil {
+			return nil
+		}
+		if err == snapshot.ErrGenerationInterrupted {
+			return errInterrupted
+		}
+		log.Warn("Failed to regenerate live state from snapshot, iterating trie", "root", root, "err", err)
+	}
+	return markState(p.chain.StateCache().TrieDB(), root, bloom, p.quit)
+}
+
+// sweep iterates the database and deletes all trie nodes which are not in the
+// live state filter.
+func (p *OnlinePruner) sweep(bloom *stateBloom) (int, common.StorageSize, error) {
+	var (
+		count  int
+		size   common.StorageSize
+		keys   [][]byte
+		start  = time.Now()
+		logged = time.Now()
+		iter   = p.db.NewIterator(nil, nil)
+	)
+	defer func() { iter.Release() }()
+
+	// flush deletes the collected stale nodes. The filter is rechecked under the
+	// lock, so that nodes flushed by the trie database since their collection
+	// are retained.
+	flush := func() error {
+		batch := p.db.NewBatch()
+
+		p.lock.Lock()
+		defer p.lock.Unlock()
+
+		for _, key := range keys {
+			if ok, _ := bloom.Contain(key); ok {
+				continue
+			}
+			batch.Delete(key)
+			count++
+		}
+		keys = keys[:0]
+		return batch.Write()
+	}
+	for iter.Next() {
+		key := iter.Key()
+		if len(key) != common.HashLength {
+			continue
+		}
+		if ok, _ := bloom.Contain(key); ok {
+			continue
+		}
+		keys = append(keys, common.CopyBytes(key))
+		size += common.StorageSize(len(key) + len(iter.Value()))
+		if len(keys) < onlinePruneBatch {
+			continue
+		}
+		if err := flush(); err != nil {
+			return count, size, err
+		}
+		// Recreate the iterator after every batch in order to allow the
+		// underlying compactor to delete the entries.
+		next := common.CopyBytes(key)
+		iter.Release()
+		iter = p.db.NewIterator(nil, next)
+
+		if time.Since(logged) > 8*time.Second {
+			log.Info("Pruning stale state", "nodes", count, "size", size, "elapsed", common.PrettyDuration(time.Since(start)))
+			logged = time.Now()
+		}
+		// Throttle the deletions to the configured rate
+		var wait time.Duration
+		if p.config.RateLimit > 0 {
+			wait = time.Duration(count)*time.Second/time.Duration(p.config.RateLimit) - time.Since(start)
+		}
+		if wait < 0 {
+			wait = 0
+		}
+		select {
+		case <-time.After(wait):
+		case <-p.quit:
+			return count, size, errInterrupted
+		}
+	}
+	if err := iter.Error(); err != nil {
+		return count, size, err
+	}
+	if err := flush(); err != nil {
+		return count, size, err
+	}
+	return count, size, nil
+}
@@ -0,0 +1,147 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package pruner
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/trie"
+)
+
+// Tests that the online pruner deletes stale trie nodes from a live chain while
+// it's importing blocks, without affecting the states still in use. The live
+// state is either regenerated from the snapshot tree, or the trie is iterated
+// if snapshots are disabled.
+func TestOnlinePruning(t *testing.T) {
+	t.Run("snapshot", func(t *testing.T) { testOnlinePruning(t, 256) })
+	t.Run("trie", func(t *testing.T) { testOnlinePruning(t, 0) })
+}
+
+func testOnlinePruning(t *testing.T, snapshotLimit int) {
+	defer func(recheck time.Duration) {
+		onlinePruneRecheck = recheck
+	}(onlinePruneRecheck)
+	onlinePruneRecheck = 10 * time.Millisecond
+
+	var (
+		db      = rawdb.NewMemoryDatabase()
+		gendb   = rawdb.NewMemoryDatabase()
+		key, _  = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		address = crypto.PubkeyToAddress(key.PublicKey)
+		counter = common.HexToAddress("0xc0ffee")
+		gspec   = &core.Genesis{
+			Config:  params.TestChainConfig,
+			BaseFee: big.NewInt(params.InitialBaseFee),
+			Alloc: core.GenesisAlloc{
+				address: {Balance: big.NewInt(params.Ether)},
+				// Stores the current block number into slot 0 on every call
+				counter: {Balance: new(big.Int), Code: common.FromHex("0x43600055")},
+			},
+		}
+		genesis = gspec.MustCommit(gendb)
+		signer  = types.LatestSigner(gspec.Config)
+	)
+	blocks, _ := core.GenerateChain(gspec.Config, genesis, ethash.NewFaker(), gendb, 600, func(i int, gen *core.BlockGen) {
+		for _, tx := range []*types.Transaction{
+			types.NewTransaction(gen.TxNonce(address), common.BigToAddress(big.NewInt(int64(0x1000+i))), big.NewInt(1), params.TxGas, gen.BaseFee(), nil),
+			types.NewTransaction(gen.TxNonce(address)+1, counter, nil, 50000, gen.BaseFee(), nil),
+		} {
+			signed, err := types.SignTx(tx, signer, key)
+			if err != nil {
+				t.Fatal(err)
+			}
+			gen.AddTx(signed)
+		}
+	})
+	gspec.MustCommit(db)
+
+	// Flush every trie to disk once it's out of the in-memory window, so that
+	// plenty of stale nodes accumulate.
+	chain, err := core.NewBlockChain(db, &core.CacheConfig{
+		TrieCleanLimit: 256,
+		TrieDirtyLimit: 0,
+		TrieTimeLimit:  5 * time.Minute,
+		SnapshotLimit:  snapshotLimit,
+		SnapshotWait:   true,
+	}, gspec.Config, ethash.NewFaker(), vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	defer chain.Stop()
+
+	pruner, err := NewOnlinePruner(db, chain, OnlineConfig{}, func() bool { return true })
+	if err != nil {
+		t.Fatalf("failed to create pruner: %v", err)
+	}
+	pruner.config.BloomSize = 16 // Keep the test lightweight
+
+	if n, err := chain.InsertChain(blocks[:300]); err != nil {
+		t.Fatalf("failed to import block %d: %v", n, err)
+	}
+	// Start a pruning cycle and keep importing blocks while it's running
+	started := make(chan struct{})
+	pruner.startHook = func() { close(started) }
+
+	done := make(chan error, 1)
+	go func() { done <- pruner.prune() }()
+
+	select {
+	case <-started:
+	case err := <-done:
+		t.Fatalf("pruning cycle finished before starting: %v", err)
+	case <-time.After(10 * time.Second):
+		t.Fatalf("pruning cycle not started")
+	}
+	for i := 300; i < len(blocks); i++ {
+		if _, err := chain.InsertChain(blocks[i : i+1]); err != nil {
+			t.Fatalf("failed to import block %d: %v", i, err)
+		}
+	}
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("failed to prune state: %v", err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatalf("pruning cycle not finished")
+	}
+	// The states in use and the genesis state must be complete on disk
+	triedb := trie.NewDatabase(db)
+	head := chain.CurrentBlock().NumberU64()
+	for _, number := range []uint64{0, head - core.TriesInMemory + 1, head} {
+		bloom, _ := newStateBloomWithSize(1)
+		if err := markState(triedb, chain.GetBlockByNumber(number).Root(), bloom, nil); err != nil {
+			t.Fatalf("state of block %d not accessible: %v", number, err)
+		}
+	}
+	// The stale states older than the pruning target must be gone
+	for number := uint64(1); number < 300; number++ {
+		if root := chain.GetBlockByNumber(number).Root(); rawdb.HasTrieNode(db, root) {
+			t.Fatalf("stale state root of block %d not deleted", number)
+		}
+	}
+}
@@ -85,6 +85,10 @@ type Pruner struct {
 
 // NewPruner creates the pruner instance.
 func NewPruner(db ethdb.Database, datadir, trieCachePath string, bloomSize uint64) (*Pruner, error) {
+	// The path scheme overwrites trie nodes in place, there's nothing to prune
+	if rawdb.ReadStateScheme(db) == rawdb.PathScheme {
+		return nil, errors.New("offline pruning is not needed with the path-based state scheme")
+	}
 	headBlock := rawdb.ReadHeadBlock(db)
 	if headBlock == nil {
 		return nil, errors.New("Failed to load head block")
@@ -410,12 +414,24 @@ func extractGenesis(db ethdb.Database, stateBloom *stateBloom) error {
 	if genesis == nil {
 		return errors.New("missing genesis block")
 	}
-	t, err := trie.NewSecure(genesis.Root(), trie.NewDatabase(db))
+	return markState(trie.NewDatabase(db), genesis.Root(), stateBloom, nil)
+}
+
+// markState iterates the state trie of the given root along with all storage
+// tries, and commits the trie nodes and contract codes into the given bloom
+// filter. The iteration is aborted if the interrupt channel is closed.
+func markState(triedb *trie.Database, root common.Hash, stateBloom *stateBloom, interrupt <-chan struct{}) error {
+	t, err := trie.NewSecure(root, triedb)
 	if err != nil {
 		return err
 	}
 	accIter := t.NodeIterator(nil)
 	for accIter.Next(true) {
+		select {
+		case <-interrupt:
+			return errInterrupted
+		default:
+		}
 		hash := accIter.Hash()
 
 		// Embedded nodes don't have hash.
@@ -430,7 +446,7 @@ func extractGenesis(db ethdb.Database, stateBloom *stateBloom) error {
 				return err
 			}
 			if acc.Root != emptyRoot {
-				storageTrie, err := trie.NewSecure(acc.Root, trie.NewDatabase(db))
+				storageTrie, err := trie.NewSecure(acc.Root, triedb)
 				if err != nil {
 					return err
 				}
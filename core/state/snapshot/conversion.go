@@ -60,12 +60,130 @@ func GenerateStorageTrieRoot(account common.Hash, it StorageIterator) (common.Ha
 	return generateTrieRoot(nil, it, account, stackTrieGenerate, nil, newGenerateStats(), true)
 }
 
+// ErrGenerationInterrupted is returned if the trie regeneration from the snapshot
+// was aborted via the interrupt channel.
+var ErrGenerationInterrupted = errors.New("trie generation interrupted")
+
+// liveIterator is a snapshot iterator over the state of a live snapshot tree.
+// If the layers below the iterated state get flattened during the iteration,
+// the iterator is reopened at the next position. As the iterated layer itself
+// is unchanged, the returned entries remain consistent. The iteration is also
+// terminated early once the interrupt channel is closed.
+type liveIterator struct {
+	it        Iterator                                 // Currently open snapshot iterator
+	open      func(seek common.Hash) (Iterator, error) // Reopens the iterator at the given position
+	next      []byte                                   // Position to reopen the iterator at
+	interrupt <-chan struct{}                          // Channel to abort the iteration
+	err       error                                    // Failure set in case of reopening error
+}
+
+// Next steps the iterator forward one element, returning false if exhausted or
+// interrupted.
+func (it *liveIterator) Next() bool {
+	if it.err != nil {
+		return false
+	}
+	select {
+	case <-it.interrupt:
+		return false
+	default:
+	}
+	for {
+		if it.it.Next() {
+			it.next = increaseKey(common.CopyBytes(it.it.Hash().Bytes()))
+			return true
+		}
+		if it.it.Error() != ErrSnapshotStale || it.next == nil {
+			return false
+		}
+		it.it.Release()
+		if it.it, it.err = it.open(common.BytesToHash(it.next)); it.err != nil {
+			return false
+		}
+	}
+}
+
+// Error returns any failure that occurred during iteration.
+func (it *liveIterator) Error() error {
+	if it.err != nil {
+		return it.err
+	}
+	return it.it.Error()
+}
+
+// Hash returns the hash of the entry the iterator is currently at.
+func (it *liveIterator) Hash() common.Hash {
+	return it.it.Hash()
+}
+
+// Release releases the currently open snapshot iterator.
+func (it *liveIterator) Release() {
+	if it.err == nil {
+		it.it.Release()
+	}
+}
+
+// liveAccountIterator is a liveIterator over the accounts of a state.
+type liveAccountIterator struct {
+	*liveIterator
+}
+
+// Account returns the RLP encoded slim account the iterator is currently at.
+func (it liveAccountIterator) Account() []byte {
+	return it.it.(AccountIterator).Account()
+}
+
+// liveStorageIterator is a liveIterator over the storage slots of an account.
+type liveStorageIterator struct {
+	*liveIterator
+}
+
+// Slot returns the storage slot the iterator is currently at.
+func (it liveStorageIterator) Slot() []byte {
+	return it.it.(StorageIterator).Slot()
+}
+
+// newLiveAccountIterator creates an account iterator over the given state of a
+// live snapshot tree.
+func newLiveAccountIterator(snaptree *Tree, root common.Hash, interrupt <-chan struct{}) (AccountIterator, error) {
+	open := func(seek common.Hash) (Iterator, error) {
+		return snaptree.AccountIterator(root, seek)
+	}
+	it, err := open(common.Hash{})
+	if err != nil {
+		return nil, err
+	}
+	return liveAccountIterator{&liveIterator{it: it, open: open, next: common.Hash{}.Bytes(), interrupt: interrupt}}, nil
+}
+
+// newLiveStorageIterator creates a storage iterator over the given account of a
+// live snapshot tree.
+func newLiveStorageIterator(snaptree *Tree, root common.Hash, account common.Hash, interrupt <-chan struct{}) (StorageIterator, error) {
+	open := func(seek common.Hash) (Iterator, error) {
+		return snaptree.StorageIterator(root, account, seek)
+	}
+	it, err := open(common.Hash{})
+	if err != nil {
+		return nil, err
+	}
+	return liveStorageIterator{&liveIterator{it: it, open: open, next: common.Hash{}.Bytes(), interrupt: interrupt}}, nil
+}
+
 // GenerateTrie takes the whole snapshot tree as the input, traverses all the
 // accounts as well as the corresponding storages and regenerate the whole state
 // (account trie + all storage tries).
 func GenerateTrie(snaptree *Tree, root common.Hash, src ethdb.Database, dst ethdb.KeyValueWriter) error {
+	return GenerateTrieWithInterrupt(snaptree, root, src, dst, nil)
+}
+
+// GenerateTrieWithInterrupt is like GenerateTrie, but aborts the regeneration
+// once the interrupt channel is closed. It can be used on the snapshot tree of
+// a live node, where the layers below the given state might get flattened
+// during the regeneration. An error is only returned if the layer of the state
+// itself is flattened.
+func GenerateTrieWithInterrupt(snaptree *Tree, root common.Hash, src ethdb.Database, dst ethdb.KeyValueWriter, interrupt <-chan struct{}) error {
 	// Traverse all state by snapshot, re-generate the whole state trie
-	acctIt, err := snaptree.AccountIterator(root, common.Hash{})
+	acctIt, err := newLiveAccountIterator(snaptree, root, interrupt)
 	if err != nil {
 		return err // The required snapshot might not exist.
 	}
@@ -81,7 +199,7 @@ func GenerateTrie(snaptree *Tree, root common.Hash, src ethdb.Database, dst ethd
 			rawdb.WriteCode(dst, codeHash, code)
 		}
 		// Then migrate all storage trie nodes into the tmp db.
-		storageIt, err := snaptree.StorageIterator(root, accountHash, common.Hash{})
+		storageIt, err := newLiveStorageIterator(snaptree, root, accountHash, nil)
 		if err != nil {
 			return common.Hash{}, err
 		}
@@ -91,12 +209,23 @@ func GenerateTrie(snaptree *Tree, root common.Hash, src ethdb.Database, dst ethd
 		if err != nil {
 			return common.Hash{}, err
 		}
+		if err := storageIt.Error(); err != nil {
+			return common.Hash{}, err
+		}
 		return hash, nil
 	}, newGenerateStats(), true)
 
 	if err != nil {
 		return err
 	}
+	select {
+	case <-interrupt:
+		return ErrGenerationInterrupted
+	default:
+	}
+	if err := acctIt.Error(); err != nil {
+		return err // The state itself might have been flattened.
+	}
 	if got != root {
 		return fmt.Errorf("state root hash mismatch: got %x, want %x", got, root)
 	}
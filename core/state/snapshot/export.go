@@ -0,0 +1,325 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package snapshot
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/trie"
+)
+
+// The snapshot flat file is an RLP stream starting with a flatHeader, followed
+// by the state entries in ascending hash order. Every account entry is directly
+// followed by the entries of its storage slots, and preceded by the entry of its
+// contract code if the code wasn't exported yet.
+const (
+	flatMagic   = "gethsnapshot"
+	flatVersion = 1
+)
+
+// Kinds of entries in the snapshot flat file.
+const (
+	flatAccount = iota // Account in slim RLP format, keyed by account hash
+	flatStorage        // Storage slot of the last account, keyed by slot hash
+	flatCode           // Contract code, keyed by code hash
+)
+
+// flatHeader is the first item of a snapshot flat file.
+type flatHeader struct {
+	Magic   string // Always set to 'gethsnapshot' for disambiguation
+	Version uint64
+	Root    common.Hash // State root the file was exported at
+}
+
+// flatEntry is a single state item in a snapshot flat file.
+type flatEntry struct {
+	Kind uint8
+	Hash common.Hash
+	Blob []byte
+}
+
+// Export writes the state of the given root from the snapshot tree into w as a
+// flat file, which can be loaded into another database with Import. Contract
+// codes are read from the given database.
+func Export(snaptree *Tree, root common.Hash, db ethdb.KeyValueReader, w io.Writer) error {
+	accIt, err := snaptree.AccountIterator(root, common.Hash{})
+	if err != nil {
+		return err // The required snapshot might not exist.
+	}
+	defer accIt.Release()
+
+	if err := rlp.Encode(w, &flatHeader{Magic: flatMagic, Version: flatVersion, Root: root}); err != nil {
+		return err
+	}
+	var (
+		start    = time.Now()
+		logged   = time.Now()
+		codes    = make(map[common.Hash]struct{})
+		accounts uint64
+		slots    uint64
+	)
+	for accIt.Next() {
+		account, err := FullAccount(accIt.Account())
+		if err != nil {
+			return err
+		}
+		// Export the contract code ahead of the first account using it
+		if codeHash := common.BytesToHash(account.CodeHash); codeHash != emptyCode {
+			if _, ok := codes[codeHash]; !ok {
+				code := rawdb.ReadCode(db, codeHash)
+				if len(code) == 0 {
+					return fmt.Errorf("missing code %x of account %x", codeHash, accIt.Hash())
+				}
+				if err := rlp.Encode(w, &flatEntry{Kind: flatCode, Hash: codeHash, Blob: code}); err != nil {
+					return err
+				}
+				codes[codeHash] = struct{}{}
+			}
+		}
+		if err := rlp.Encode(w, &flatEntry{Kind: flatAccount, Hash: accIt.Hash(), Blob: accIt.Account()}); err != nil {
+			return err
+		}
+		accounts++
+
+		if common.BytesToHash(account.Root) != emptyRoot {
+			stIt, err := snaptree.StorageIterator(root, accIt.Hash(), common.Hash{})
+			if err != nil {
+				return err
+			}
+			for stIt.Next() {
+				if err := rlp.Encode(w, &flatEntry{Kind: flatStorage, Hash: stIt.Hash(), Blob: stIt.Slot()}); err != nil {
+					stIt.Release()
+					return err
+				}
+				slots++
+			}
+			err = stIt.Error()
+			stIt.Release()
+			if err != nil {
+				return err
+			}
+		}
+		if time.Since(logged) > 8*time.Second {
+			log.Info("Exporting state snapshot", "at", accIt.Hash(), "accounts", accounts, "slots", slots,
+				"elapsed", common.PrettyDuration(time.Since(start)))
+			logged = time.Now()
+		}
+	}
+	if err := accIt.Error(); err != nil {
+		return err
+	}
+	log.Info("Exported state snapshot", "root", root, "accounts", accounts, "slots", slots, "codes", len(codes),
+		"elapsed", common.PrettyDuration(time.Since(start)))
+	return nil
+}
+
+// Import loads a snapshot flat file produced by Export into the database. The
+// state tries are rebuilt from the flat state and the resulting root verified
+// against the one in the file, after which the snapshot is marked as generated
+// for the root. Any previous snapshot in the database is wiped.
+//
+// The tries are written with the hash-based node scheme.
+func Import(db ethdb.KeyValueStore, r io.Reader) (common.Hash, error) {
+	if rawdb.ReadStateScheme(db) == rawdb.PathScheme {
+		return common.Hash{}, errors.New("snapshot import is not supported with the path-based state scheme")
+	}
+	stream := rlp.NewStream(r, 0)
+
+	var header flatHeader
+	if err := stream.Decode(&header); err != nil {
+		return common.Hash{}, fmt.Errorf("invalid snapshot file header: %v", err)
+	}
+	if header.Magic != flatMagic {
+		return common.Hash{}, errors.New("not a snapshot file")
+	}
+	if header.Version != flatVersion {
+		return common.Hash{}, fmt.Errorf("unsupported snapshot file version %d", header.Version)
+	}
+	// Drop the previous snapshot, its entries would mix with the imported ones
+	if err := wipeSnapshot(db); err != nil {
+		return common.Hash{}, err
+	}
+	var (
+		batch   = db.NewBatch()
+		accTrie = trie.NewStackTrie(batch)
+		stTrie  *trie.StackTrie
+
+		account  common.Hash // Hash of the last imported account
+		slot     common.Hash // Hash of the last imported slot of the account
+		stRoot   common.Hash // Expected storage root of the account
+		codes    = make(map[common.Hash]struct{})
+		accounts uint64
+		slots    uint64
+		size     common.StorageSize
+
+		start  = time.Now()
+		logged = time.Now()
+	)
+	// finish verifies the storage trie of the last imported account
+	finish := func() error {
+		if stTrie == nil {
+			return nil
+		}
+		root, err := stTrie.Commit()
+		if err != nil {
+			return err
+		}
+		if root != stRoot {
+			return fmt.Errorf("storage root mismatch of account %x: have %x, want %x", account, root, stRoot)
+		}
+		stTrie = nil
+		return nil
+	}
+	for {
+		var entry flatEntry
+		if err := stream.Decode(&entry); err != nil {
+			if err == io.EOF {
+				break
+			}
+			return common.Hash{}, err
+		}
+		switch entry.Kind {
+		case flatCode:
+			if crypto.Keccak256Hash(entry.Blob) != entry.Hash {
+				return common.Hash{}, fmt.Errorf("code hash mismatch %x", entry.Hash)
+			}
+			rawdb.WriteCode(batch, entry.Hash, entry.Blob)
+			codes[entry.Hash] = struct{}{}
+
+		case flatAccount:
+			if accounts > 0 && bytes.Compare(entry.Hash[:], account[:]) <= 0 {
+				return common.Hash{}, fmt.Errorf("account %x out of order", entry.Hash)
+			}
+			if err := finish(); err != nil {
+				return common.Hash{}, err
+			}
+			full, err := FullAccount(entry.Blob)
+			if err != nil {
+				return common.Hash{}, fmt.Errorf("invalid account %x: %v", entry.Hash, err)
+			}
+			if codeHash := common.BytesToHash(full.CodeHash); codeHash != emptyCode {
+				if _, ok := codes[codeHash]; !ok {
+					return common.Hash{}, fmt.Errorf("missing code %x of account %x", codeHash, entry.Hash)
+				}
+			}
+			blob, err := rlp.EncodeToBytes(full)
+			if err != nil {
+				return common.Hash{}, err
+			}
+			rawdb.WriteAccountSnapshot(batch, entry.Hash, entry.Blob)
+			accTrie.Update(entry.Hash[:], blob)
+			size += common.StorageSize(1 + common.HashLength + len(entry.Blob))
+
+			account, slot, stRoot = entry.Hash, common.Hash{}, common.BytesToHash(full.Root)
+			if stRoot != emptyRoot {
+				stTrie = trie.NewStackTrie(batch)
+			}
+			accounts++
+
+		case flatStorage:
+			if stTrie == nil {
+				return common.Hash{}, fmt.Errorf("unexpected storage slot %x of account %x", entry.Hash, account)
+			}
+			if slot != (common.Hash{}) && bytes.Compare(entry.Hash[:], slot[:]) <= 0 {
+				return common.Hash{}, fmt.Errorf("storage slot %x of account %x out of order", entry.Hash, account)
+			}
+			rawdb.WriteStorageSnapshot(batch, account, entry.Hash, entry.Blob)
+			stTrie.Update(entry.Hash[:], entry.Blob)
+			size += common.StorageSize(1 + 2*common.HashLength + len(entry.Blob))
+			slot = entry.Hash
+			slots++
+
+		default:
+			return common.Hash{}, fmt.Errorf("unknown snapshot entry kind %d", entry.Kind)
+		}
+		if batch.ValueSize() > ethdb.IdealBatchSize {
+			if err := batch.Write(); err != nil {
+				return common.Hash{}, err
+			}
+			batch.Reset()
+		}
+		if time.Since(logged) > 8*time.Second {
+			log.Info("Importing state snapshot", "at", account, "accounts", accounts, "slots", slots,
+				"elapsed", common.PrettyDuration(time.Since(start)))
+			logged = time.Now()
+		}
+	}
+	if err := finish(); err != nil {
+		return common.Hash{}, err
+	}
+	root, err := accTrie.Commit()
+	if err != nil {
+		return common.Hash{}, err
+	}
+	if root != header.Root {
+		return common.Hash{}, fmt.Errorf("state root mismatch: have %x, want %x", root, header.Root)
+	}
+	// The state is complete, mark the snapshot as generated
+	rawdb.WriteSnapshotRoot(batch, root)
+	journalProgress(batch, nil, &generatorStats{accounts: accounts, slots: slots, storage: size})
+	if err := batch.Write(); err != nil {
+		return common.Hash{}, err
+	}
+	log.Info("Imported state snapshot", "root", root, "accounts", accounts, "slots", slots, "codes", len(codes), "size", size,
+		"elapsed", common.PrettyDuration(time.Since(start)))
+	return root, nil
+}
+
+// wipeSnapshot deletes the snapshot metadata and all the flat state entries
+// from the database.
+func wipeSnapshot(db ethdb.KeyValueStore) error {
+	batch := db.NewBatch()
+	rawdb.DeleteSnapshotRoot(batch)
+	rawdb.DeleteSnapshotJournal(batch)
+	rawdb.DeleteSnapshotGenerator(batch)
+	rawdb.DeleteSnapshotRecoveryNumber(batch)
+	rawdb.DeleteSnapshotDisabled(batch)
+	if err := batch.Write(); err != nil {
+		return err
+	}
+	batch.Reset()
+
+	for _, prefix := range [][]byte{rawdb.SnapshotAccountPrefix, rawdb.SnapshotStoragePrefix} {
+		it := db.NewIterator(prefix, nil)
+		for it.Next() {
+			batch.Delete(it.Key())
+			if batch.ValueSize() > ethdb.IdealBatchSize {
+				if err := batch.Write(); err != nil {
+					it.Release()
+					return err
+				}
+				batch.Reset()
+			}
+		}
+		err := it.Error()
+		it.Release()
+		if err != nil {
+			return err
+		}
+	}
+	return batch.Write()
+}
@@ -0,0 +1,107 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package snapshot
+
+import (
+	"bytes"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb/memorydb"
+	"github.com/ethereum/go-ethereum/trie"
+)
+
+// Tests that a snapshot exported into a flat file can be imported into another
+// database, recreating both the state tries and the snapshot.
+func TestExportImport(t *testing.T) {
+	var (
+		helper = newHelper()
+		code   = []byte{0x60, 0x00, 0x60, 0x00, 0xf3}
+		stRoot = helper.makeStorageTrie([]string{"key-1", "key-2", "key-3"}, []string{"val-1", "val-2", "val-3"})
+	)
+	rawdb.WriteCode(helper.diskdb, crypto.Keccak256Hash(code), code)
+
+	helper.addTrieAccount("acc-1", &Account{Balance: big.NewInt(1), Root: stRoot, CodeHash: crypto.Keccak256(code)})
+	helper.addTrieAccount("acc-2", &Account{Balance: big.NewInt(2), Root: emptyRoot.Bytes(), CodeHash: emptyCode.Bytes()})
+	helper.addTrieAccount("acc-3", &Account{Balance: big.NewInt(3), Root: stRoot, CodeHash: crypto.Keccak256(code)})
+
+	root, snap := helper.Generate()
+	select {
+	case <-snap.genPending:
+	case <-time.After(3 * time.Second):
+		t.Fatalf("Snapshot generation failed")
+	}
+	stop := make(chan *generatorStats)
+	snap.genAbort <- stop
+	<-stop
+
+	snaps, err := New(helper.diskdb, helper.triedb, 16, root, false, false, false)
+	if err != nil {
+		t.Fatalf("Failed to open snapshot tree: %v", err)
+	}
+	var buf bytes.Buffer
+	if err := Export(snaps, root, helper.diskdb, &buf); err != nil {
+		t.Fatalf("Failed to export snapshot: %v", err)
+	}
+	blob := buf.Bytes()
+
+	// Import the snapshot into a database with a stale snapshot and ensure both
+	// the tries and the snapshot are restored
+	db := memorydb.New()
+	rawdb.WriteAccountSnapshot(db, common.Hash{0x01}, []byte{0x01})
+
+	imported, err := Import(db, bytes.NewReader(blob))
+	if err != nil {
+		t.Fatalf("Failed to import snapshot: %v", err)
+	}
+	if imported != root {
+		t.Fatalf("Imported root mismatch: have %x, want %x", imported, root)
+	}
+	if have := rawdb.ReadSnapshotRoot(db); have != root {
+		t.Fatalf("Snapshot root mismatch: have %x, want %x", have, root)
+	}
+	if data := rawdb.ReadAccountSnapshot(db, common.Hash{0x01}); len(data) != 0 {
+		t.Fatalf("Stale snapshot entry not wiped")
+	}
+	accTrie, err := trie.NewSecure(root, trie.NewDatabase(db))
+	if err != nil {
+		t.Fatalf("Failed to open imported trie: %v", err)
+	}
+	it := accTrie.NodeIterator(nil)
+	for it.Next(true) {
+	}
+	if it.Error() != nil {
+		t.Fatalf("Imported account trie incomplete: %v", it.Error())
+	}
+	imports, err := New(db, trie.NewDatabase(db), 16, root, false, false, false)
+	if err != nil {
+		t.Fatalf("Failed to open imported snapshot: %v", err)
+	}
+	if err := imports.Verify(root); err != nil {
+		t.Fatalf("Imported snapshot invalid: %v", err)
+	}
+	// Corrupt the file and ensure the import is rejected
+	corrupt := common.CopyBytes(blob)
+	corrupt[len(corrupt)-1]++
+	if _, err := Import(memorydb.New(), bytes.NewReader(corrupt)); err == nil {
+		t.Fatalf("Corrupted snapshot imported")
+	}
+}
@@ -248,7 +248,7 @@ func (dl *diskLayer) proveRange(ctx *generatorContext, root common.Hash, prefix
 		return &proofResult{keys: keys, vals: vals}, nil
 	}
 	// Snap state is chunked, generate edge proofs for verification.
-	tr, err := trie.New(root, dl.triedb)
+	tr, err := trie.NewWithOwner(dl.root, trieOwner(prefix), root, dl.triedb)
 	if err != nil {
 		ctx.stats.Log("Trie missing, state snapshotting paused", dl.root, dl.genMarker)
 		return nil, errMissingTrie
@@ -310,6 +310,15 @@ func (dl *diskLayer) proveRange(ctx *generatorContext, root common.Hash, prefix
 // be cleaned up.
 type onStateCallback func(key []byte, val []byte, write bool, delete bool) error
 
+// trieOwner returns the owner of the trie backing the snapshot segment with the
+// given prefix: the account hash for storage segments, empty for accounts.
+func trieOwner(prefix []byte) common.Hash {
+	if len(prefix) == len(rawdb.SnapshotStoragePrefix)+common.HashLength {
+		return common.BytesToHash(prefix[len(rawdb.SnapshotStoragePrefix):])
+	}
+	return common.Hash{}
+}
+
 // generateRange generates the state segment with particular prefix. Generation can
 // either verify the correctness of existing state through range-proof and skip
 // generation, or iterate trie to regenerate state on demand.
@@ -374,7 +383,7 @@ func (dl *diskLayer) generateRange(ctx *generatorContext, root common.Hash, pref
 	// if it's already opened with some nodes resolved.
 	tr := result.tr
 	if tr == nil {
-		tr, err = trie.New(root, dl.triedb)
+		tr, err = trie.NewWithOwner(dl.root, trieOwner(prefix), root, dl.triedb)
 		if err != nil {
 			ctx.stats.Log("Trie missing, state snapshotting paused", dl.root, dl.genMarker)
 			return false, nil, errMissingTrie
@@ -204,7 +204,10 @@ func (fi *fastIterator) Next() bool {
 	//  - or exhaust the iterator
 	for {
 		if !fi.next(0) {
-			return false // exhausted
+			return false // exhausted or failed
+		}
+		if fi.fail != nil {
+			return false // a cascaded iterator failed
 		}
 		if fi.account {
 			fi.curAccount = fi.iterators[0].it.(AccountIterator).Account()
@@ -233,9 +236,16 @@ func (fi *fastIterator) next(idx int) bool {
 	// next one is surely not exhausted yet, otherwise it would have been removed
 	// already).
 	if it := fi.iterators[idx].it; !it.Next() {
+		// If the iterator was aborted instead, e.g. the layer became stale,
+		// the whole iteration fails, otherwise entries would be skipped.
+		err := it.Error()
 		it.Release()
 
 		fi.iterators = append(fi.iterators[:idx], fi.iterators[idx+1:]...)
+		if err != nil {
+			fi.fail = err
+			return false
+		}
 		return len(fi.iterators) > 0
 	}
 	// If there's no one left to cascade into, return
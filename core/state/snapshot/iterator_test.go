@@ -585,6 +585,96 @@ func TestAccountIteratorFlattening(t *testing.T) {
 	//verifyIterator(t, 7, it)
 }
 
+// TestAccountIteratorStaleLayer tests that the iteration fails if a layer below
+// the iterated one gets flattened, instead of silently skipping its entries.
+func TestAccountIteratorStaleLayer(t *testing.T) {
+	// Create an empty base layer and a snapshot tree out of it
+	base := &diskLayer{
+		diskdb: rawdb.NewMemoryDatabase(),
+		root:   common.HexToHash("0x01"),
+		cache:  fastcache.New(1024 * 500),
+	}
+	snaps := &Tree{
+		layers: map[common.Hash]snapshot{
+			base.root: base,
+		},
+	}
+	// Create a stack of diffs on top
+	snaps.Update(common.HexToHash("0x02"), common.HexToHash("0x01"), nil,
+		randomAccountSet("0xaa", "0xee"), nil)
+
+	snaps.Update(common.HexToHash("0x03"), common.HexToHash("0x02"), nil,
+		randomAccountSet(), nil)
+
+	snaps.Update(common.HexToHash("0x04"), common.HexToHash("0x03"), nil,
+		randomAccountSet("0xbb", "0xcc"), nil)
+
+	// Step into the bottom diff layer and flatten it from underneath
+	it, _ := snaps.AccountIterator(common.HexToHash("0x04"), common.Hash{})
+	defer it.Release()
+
+	if !it.Next() {
+		t.Fatalf("iterator exhausted prematurely: %v", it.Error())
+	}
+	if err := snaps.Cap(common.HexToHash("0x04"), 1); err != nil {
+		t.Fatalf("failed to flatten snapshot stack: %v", err)
+	}
+	for it.Next() {
+	}
+	if err := it.Error(); err != ErrSnapshotStale {
+		t.Fatalf("iterator error mismatch: have %v, want %v", err, ErrSnapshotStale)
+	}
+}
+
+// TestLiveAccountIterator tests that the iterator over a live snapshot tree is
+// reopened if a layer below the iterated one gets flattened.
+func TestLiveAccountIterator(t *testing.T) {
+	// Create an empty base layer and a snapshot tree out of it
+	base := &diskLayer{
+		diskdb: rawdb.NewMemoryDatabase(),
+		root:   common.HexToHash("0x01"),
+		cache:  fastcache.New(1024 * 500),
+	}
+	snaps := &Tree{
+		layers: map[common.Hash]snapshot{
+			base.root: base,
+		},
+	}
+	// Create a stack of diffs on top
+	snaps.Update(common.HexToHash("0x02"), common.HexToHash("0x01"), nil,
+		randomAccountSet("0xaa", "0xee"), nil)
+
+	snaps.Update(common.HexToHash("0x03"), common.HexToHash("0x02"), nil,
+		randomAccountSet(), nil)
+
+	snaps.Update(common.HexToHash("0x04"), common.HexToHash("0x03"), nil,
+		randomAccountSet("0xbb", "0xcc"), nil)
+
+	// Step into the bottom diff layer and flatten it from underneath
+	it, err := newLiveAccountIterator(snaps, common.HexToHash("0x04"), nil)
+	if err != nil {
+		t.Fatalf("failed to create iterator: %v", err)
+	}
+	defer it.Release()
+
+	if !it.Next() {
+		t.Fatalf("iterator exhausted prematurely: %v", it.Error())
+	}
+	if err := snaps.Cap(common.HexToHash("0x04"), 1); err != nil {
+		t.Fatalf("failed to flatten snapshot stack: %v", err)
+	}
+	count := 1
+	for it.Next() {
+		count++
+	}
+	if err := it.Error(); err != nil {
+		t.Fatalf("iteration failed: %v", err)
+	}
+	if count != 4 {
+		t.Fatalf("account count mismatch: have %d, want 4", count)
+	}
+}
+
 func TestAccountIteratorSeek(t *testing.T) {
 	// Create a snapshot stack with some initial data
 	base := &diskLayer{
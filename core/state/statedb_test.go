@@ -32,6 +32,7 @@ import (
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/rawdb"
 	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/trie"
 )
 
 // Tests that updating a state trie does not leak any database writes prior to
@@ -915,3 +916,55 @@ func TestStateDBAccessList(t *testing.T) {
 		t.Fatalf("expected empty, got %d", got)
 	}
 }
+
+// Tests that consecutive commits of the path based scheme keep the storage of
+// every committed state readable, including the storage of destructed accounts.
+func TestPathSchemeCommits(t *testing.T) {
+	db := NewDatabaseWithConfig(rawdb.NewMemoryDatabase(), &trie.Config{Scheme: rawdb.PathScheme})
+	state, _ := New(common.Hash{}, db, nil)
+
+	var (
+		addrA = common.Address{0xa}
+		addrB = common.Address{0xb}
+		key   = common.Hash{0x1}
+	)
+	state.SetState(addrA, key, common.Hash{0x1})
+	state.SetState(addrB, key, common.Hash{0x1})
+	root1, err := state.Commit(false)
+	if err != nil {
+		t.Fatalf("failed to commit first state: %v", err)
+	}
+	// Update the storage through the same state, its tries must resolve their
+	// nodes from the newly committed state
+	state.SetState(addrA, key, common.Hash{0x2})
+	state.Suicide(addrB)
+	root2, err := state.Commit(false)
+	if err != nil {
+		t.Fatalf("failed to commit second state: %v", err)
+	}
+	state.SetState(addrA, common.Hash{0x2}, common.Hash{0x3})
+	if _, err := state.Commit(false); err != nil {
+		t.Fatalf("failed to commit third state: %v", err)
+	}
+	for i, tt := range []struct {
+		root common.Hash
+		a, b common.Hash
+	}{
+		{root1, common.Hash{0x1}, common.Hash{0x1}},
+		{root2, common.Hash{0x2}, common.Hash{}},
+	} {
+		state, err := New(tt.root, db, nil)
+		if err != nil {
+			t.Fatalf("test %d: failed to open state: %v", i, err)
+		}
+		if have := state.GetState(addrA, key); have != tt.a {
+			t.Errorf("test %d: storage of A mismatch: have %x, want %x", i, have, tt.a)
+		}
+		if have := state.GetState(addrB, key); have != tt.b {
+			t.Errorf("test %d: storage of B mismatch: have %x, want %x", i, have, tt.b)
+		}
+		if err := state.Error(); err != nil {
+			t.Errorf("test %d: state error: %v", i, err)
+		}
+	}
+}
@@ -0,0 +1,242 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"errors"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+const (
+	// maxBundles is the maximum number of bundles the pool keeps at the same
+	// time, protecting it from being flooded with bundles.
+	maxBundles = 1024
+
+	// maxBundleFutureBlocks is how many blocks ahead of the current head a
+	// bundle may target. Bundles for far future blocks would otherwise linger
+	// in the pool for a long time.
+	maxBundleFutureBlocks = 25
+
+	// maxBundleTxs is the maximum number of transactions in a single bundle.
+	maxBundleTxs = 64
+
+	// maxBundlesPerSender is the maximum number of bundles the pool keeps with
+	// transactions of the same sender, so no single account can fill the pool.
+	maxBundlesPerSender = 16
+)
+
+var (
+	// ErrEmptyBundle is returned if a bundle without any transactions is submitted.
+	ErrEmptyBundle = errors.New("empty bundle")
+
+	// ErrBundleOutdated is returned if the target block of a bundle is already
+	// part of the chain.
+	ErrBundleOutdated = errors.New("bundle target block already mined")
+
+	// ErrBundleTooLarge is returned if a bundle contains more transactions than
+	// allowed.
+	ErrBundleTooLarge = errors.New("bundle too large")
+
+	// ErrBundleTooFarAhead is returned if the target block of a bundle is too
+	// far ahead of the current head.
+	ErrBundleTooFarAhead = errors.New("bundle target block too far ahead")
+
+	// ErrBundleNonceGap is returned if the transactions of a sender in a bundle
+	// don't have consecutive nonces.
+	ErrBundleNonceGap = errors.New("bundle transaction nonces not consecutive")
+
+	// ErrBundleSenderLimit is returned if a sender of the bundle has the maximum
+	// number of bundles in the pool already.
+	ErrBundleSenderLimit = errors.New("bundle sender limit reached")
+
+	// ErrBundleTimestamp is returned if the validity window of a bundle ends
+	// before it starts.
+	ErrBundleTimestamp = errors.New("bundle max timestamp before min timestamp")
+
+	// ErrBundlePoolFull is returned if the pool holds the maximum number of
+	// bundles already.
+	ErrBundlePoolFull = errors.New("bundle pool full")
+)
+
+// TxBundle is an ordered group of transactions which must be included together,
+// in order, at the top of the target block or not at all.
+type TxBundle struct {
+	Txs               types.Transactions // Transactions to include, in order
+	BlockNumber       uint64             // Number of the block the bundle targets
+	MinTimestamp      uint64             // Earliest block timestamp the bundle is valid at (0 = unbounded)
+	MaxTimestamp      uint64             // Latest block timestamp the bundle is valid at (0 = unbounded)
+	RevertingTxHashes []common.Hash      // Transactions allowed to revert without invalidating the bundle
+
+	senders []common.Address // Distinct senders of the transactions, set when added to the pool
+}
+
+// AllowsRevert reports whether the transaction with the given hash may revert
+// without invalidating the bundle.
+func (b *TxBundle) AllowsRevert(hash common.Hash) bool {
+	for _, h := range b.RevertingTxHashes {
+		if h == hash {
+			return true
+		}
+	}
+	return false
+}
+
+// validAt reports whether the bundle can be included into the block with the
+// given number and timestamp.
+func (b *TxBundle) validAt(number, timestamp uint64) bool {
+	if b.BlockNumber != number {
+		return false
+	}
+	if b.MinTimestamp != 0 && timestamp < b.MinTimestamp {
+		return false
+	}
+	if b.MaxTimestamp != 0 && timestamp > b.MaxTimestamp {
+		return false
+	}
+	return true
+}
+
+// AddBundle validates a bundle of transactions and stores it until its target
+// block is mined. The transactions of each sender must have consecutive nonces
+// not yet used, and the sender must be able to pay for all of them.
+func (pool *TxPool) AddBundle(bundle *TxBundle) error {
+	if len(bundle.Txs) == 0 {
+		return ErrEmptyBundle
+	}
+	if len(bundle.Txs) > maxBundleTxs {
+		return ErrBundleTooLarge
+	}
+	if bundle.MaxTimestamp != 0 && bundle.MaxTimestamp < bundle.MinTimestamp {
+		return ErrBundleTimestamp
+	}
+	var (
+		senders []common.Address
+		txs     = make(map[common.Address]types.Transactions)
+	)
+	for _, tx := range bundle.Txs {
+		from, err := types.Sender(pool.signer, tx)
+		if err != nil {
+			return ErrInvalidSender
+		}
+		if _, ok := txs[from]; !ok {
+			senders = append(senders, from)
+		}
+		txs[from] = append(txs[from], tx)
+	}
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+
+	head := pool.chain.CurrentBlock().NumberU64()
+	if bundle.BlockNumber <= head {
+		return ErrBundleOutdated
+	}
+	if bundle.BlockNumber > head+maxBundleFutureBlocks {
+		return ErrBundleTooFarAhead
+	}
+	for _, from := range senders {
+		var (
+			nonce = pool.currentState.GetNonce(from)
+			cost  = new(big.Int)
+		)
+		for i, tx := range txs[from] {
+			if i == 0 && tx.Nonce() < nonce {
+				return ErrNonceTooLow
+			}
+			if i > 0 && tx.Nonce() != txs[from][i-1].Nonce()+1 {
+				return ErrBundleNonceGap
+			}
+			cost.Add(cost, tx.Cost())
+		}
+		if pool.currentState.GetBalance(from).Cmp(cost) < 0 {
+			return ErrInsufficientFunds
+		}
+		if pool.senderBundles(from) >= maxBundlesPerSender {
+			return ErrBundleSenderLimit
+		}
+	}
+	if len(pool.bundles) >= maxBundles {
+		return ErrBundlePoolFull
+	}
+	bundle.senders = senders
+	pool.bundles = append(pool.bundles, bundle)
+	log.Trace("Added transaction bundle", "number", bundle.BlockNumber, "txs", len(bundle.Txs))
+	return nil
+}
+
+// senderBundles returns the number of bundles in the pool with transactions of
+// the given sender. The caller must hold pool.mu.
+func (pool *TxPool) senderBundles(from common.Address) int {
+	var count int
+	for _, bundle := range pool.bundles {
+		for _, sender := range bundle.senders {
+			if sender == from {
+				count++
+				break
+			}
+		}
+	}
+	return count
+}
+
+// RemoveBundle drops a bundle from the pool, e.g. because it can't be included
+// into its target block.
+func (pool *TxPool) RemoveBundle(bundle *TxBundle) {
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+
+	pool.filterBundles(func(b *TxBundle) bool { return b != bundle })
+}
+
+// Bundles retrieves the bundles which can be included into the block with the
+// given number and timestamp, in the order they were submitted.
+func (pool *TxPool) Bundles(number, timestamp uint64) []*TxBundle {
+	pool.mu.RLock()
+	defer pool.mu.RUnlock()
+
+	var bundles []*TxBundle
+	for _, bundle := range pool.bundles {
+		if bundle.validAt(number, timestamp) {
+			bundles = append(bundles, bundle)
+		}
+	}
+	return bundles
+}
+
+// pruneBundles drops all the bundles targeting blocks already in the chain. The
+// caller must hold pool.mu.
+func (pool *TxPool) pruneBundles(head uint64) {
+	pool.filterBundles(func(bundle *TxBundle) bool { return bundle.BlockNumber > head })
+}
+
+// filterBundles retains the bundles the given function returns true for. The
+// caller must hold pool.mu.
+func (pool *TxPool) filterBundles(keep func(*TxBundle) bool) {
+	bundles := pool.bundles[:0]
+	for _, bundle := range pool.bundles {
+		if keep(bundle) {
+			bundles = append(bundles, bundle)
+		}
+	}
+	for i := len(bundles); i < len(pool.bundles); i++ {
+		pool.bundles[i] = nil
+	}
+	pool.bundles = bundles
+}
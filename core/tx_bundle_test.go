@@ -0,0 +1,145 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// Tests that bundles are validated on submission, and only returned for the
+// block number and timestamps they target.
+func TestTransactionBundles(t *testing.T) {
+	t.Parallel()
+
+	pool, key := setupTxPool()
+	defer pool.Stop()
+
+	testAddBalance(pool, crypto.PubkeyToAddress(key.PublicKey), big.NewInt(1000000))
+	txs := types.Transactions{transaction(0, 100000, key), transaction(1, 100000, key)}
+
+	// Accounts with a used nonce, and without funds
+	used, _ := crypto.GenerateKey()
+	testAddBalance(pool, crypto.PubkeyToAddress(used.PublicKey), big.NewInt(1000000))
+	testSetNonce(pool, crypto.PubkeyToAddress(used.PublicKey), 1)
+	unfunded, _ := crypto.GenerateKey()
+
+	// Ensure invalid bundles are rejected
+	tests := []struct {
+		bundle *TxBundle
+		err    error
+	}{
+		{&TxBundle{BlockNumber: 1}, ErrEmptyBundle},
+		{&TxBundle{Txs: make(types.Transactions, maxBundleTxs+1), BlockNumber: 1}, ErrBundleTooLarge},
+		{&TxBundle{Txs: txs, BlockNumber: 0}, ErrBundleOutdated},
+		{&TxBundle{Txs: txs, BlockNumber: maxBundleFutureBlocks + 1}, ErrBundleTooFarAhead},
+		{&TxBundle{Txs: types.Transactions{txs[0], transaction(0, 100000, used)}, BlockNumber: 1}, ErrNonceTooLow},
+		{&TxBundle{Txs: types.Transactions{txs[0], transaction(2, 100000, key)}, BlockNumber: 1}, ErrBundleNonceGap},
+		{&TxBundle{Txs: types.Transactions{transaction(0, 100000, unfunded)}, BlockNumber: 1}, ErrInsufficientFunds},
+		{&TxBundle{Txs: types.Transactions{txs[0], transaction(1, 10000000, key)}, BlockNumber: 1}, ErrInsufficientFunds},
+		{&TxBundle{Txs: txs, BlockNumber: 1, MinTimestamp: 10, MaxTimestamp: 5}, ErrBundleTimestamp},
+	}
+	for i, tt := range tests {
+		if err := pool.AddBundle(tt.bundle); err != tt.err {
+			t.Errorf("test %d: error mismatch: have %v, want %v", i, err, tt.err)
+		}
+	}
+	// Add a few valid bundles and check they are returned when valid
+	var (
+		first  = &TxBundle{Txs: txs, BlockNumber: 1}
+		second = &TxBundle{Txs: txs[:1], BlockNumber: 1, MinTimestamp: 10, MaxTimestamp: 20}
+		third  = &TxBundle{Txs: txs[1:], BlockNumber: 2}
+	)
+	for _, bundle := range []*TxBundle{first, second, third} {
+		if err := pool.AddBundle(bundle); err != nil {
+			t.Fatalf("failed to add bundle: %v", err)
+		}
+	}
+	checks := []struct {
+		number, time uint64
+		want         []*TxBundle
+	}{
+		{1, 5, []*TxBundle{first}},
+		{1, 15, []*TxBundle{first, second}},
+		{1, 25, []*TxBundle{first}},
+		{2, 15, []*TxBundle{third}},
+		{3, 15, nil},
+	}
+	for i, tt := range checks {
+		have := pool.Bundles(tt.number, tt.time)
+		if len(have) != len(tt.want) {
+			t.Errorf("check %d: bundle count mismatch: have %d, want %d", i, len(have), len(tt.want))
+			continue
+		}
+		for j := range have {
+			if have[j] != tt.want[j] {
+				t.Errorf("check %d: bundle %d mismatch", i, j)
+			}
+		}
+	}
+	// Ensure the bundles of mined blocks are dropped
+	pool.mu.Lock()
+	pool.pruneBundles(1)
+	pool.mu.Unlock()
+
+	if have := pool.Bundles(1, 15); len(have) != 0 {
+		t.Errorf("stale bundles retained: %d", len(have))
+	}
+	if have := pool.Bundles(2, 15); len(have) != 1 {
+		t.Errorf("pending bundle dropped")
+	}
+}
+
+// Tests that the number of bundles per sender is limited, and that removed
+// bundles don't count against the limit.
+func TestTransactionBundleSenderLimit(t *testing.T) {
+	t.Parallel()
+
+	pool, key := setupTxPool()
+	defer pool.Stop()
+
+	testAddBalance(pool, crypto.PubkeyToAddress(key.PublicKey), big.NewInt(1000000))
+	other, _ := crypto.GenerateKey()
+	testAddBalance(pool, crypto.PubkeyToAddress(other.PublicKey), big.NewInt(1000000))
+
+	var bundles []*TxBundle
+	for i := 0; i < maxBundlesPerSender; i++ {
+		bundle := &TxBundle{Txs: types.Transactions{transaction(0, 100000, key)}, BlockNumber: 1}
+		if err := pool.AddBundle(bundle); err != nil {
+			t.Fatalf("bundle %d: failed to add: %v", i, err)
+		}
+		bundles = append(bundles, bundle)
+	}
+	// Bundles containing any transaction of the sender are rejected
+	bundle := &TxBundle{Txs: types.Transactions{transaction(0, 100000, other), transaction(0, 100000, key)}, BlockNumber: 1}
+	if err := pool.AddBundle(bundle); err != ErrBundleSenderLimit {
+		t.Fatalf("error mismatch: have %v, want %v", err, ErrBundleSenderLimit)
+	}
+	if err := pool.AddBundle(&TxBundle{Txs: types.Transactions{transaction(0, 100000, other)}, BlockNumber: 1}); err != nil {
+		t.Fatalf("failed to add bundle of other sender: %v", err)
+	}
+	pool.RemoveBundle(bundles[0])
+	if err := pool.AddBundle(bundle); err != nil {
+		t.Fatalf("failed to add bundle after removal: %v", err)
+	}
+	if have := len(pool.Bundles(1, 0)); have != maxBundlesPerSender+1 {
+		t.Fatalf("bundle count mismatch: have %d, want %d", have, maxBundlesPerSender+1)
+	}
+}
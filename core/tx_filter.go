@@ -0,0 +1,158 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
+)
+
+// TxFilter is an admission rule evaluated by the transaction pool on top of its
+// own validation, for both local and remote transactions. Filters are invoked
+// with the pool lock held, so they must be fast and must not call back into the
+// pool.
+type TxFilter interface {
+	// FilterTx returns a non-nil error if the transaction must not be admitted
+	// into the pool. Returning a *TxFilterError attributes the rejection to a
+	// specific reason in the metrics.
+	FilterTx(tx *types.Transaction, from common.Address, local bool) error
+}
+
+// TxFilterError is the error returned by the transaction admission filters.
+type TxFilterError struct {
+	Reason string // Short identifier of the rejection reason, used in the metrics
+	Detail string // Human readable description of the rejection
+}
+
+// Error implements error.
+func (e *TxFilterError) Error() string {
+	return "transaction filtered: " + e.Detail
+}
+
+// ContractTip is a minimum tip required for transactions calling a contract.
+type ContractTip struct {
+	Contract common.Address
+	MinTip   *big.Int
+}
+
+// TxFilterRules is the built-in set of admission rules of the transaction pool.
+type TxFilterRules struct {
+	DenySenders    []common.Address `toml:",omitempty"` // Senders whose transactions are rejected
+	DenyRecipients []common.Address `toml:",omitempty"` // Recipients whose transactions are rejected
+	DenySelectors  []hexutil.Bytes  `toml:",omitempty"` // 4 byte method selectors of rejected calls
+	ContractTips   []ContractTip    `toml:",omitempty"` // Per contract minimum tips
+	MaxDataSize    uint64           `toml:",omitempty"` // Maximum calldata size in bytes (0 = unlimited)
+}
+
+// txRuleFilter is a TxFilter enforcing a set of TxFilterRules.
+type txRuleFilter struct {
+	senders    map[common.Address]struct{}
+	recipients map[common.Address]struct{}
+	selectors  map[[4]byte]struct{}
+	tips       map[common.Address]*big.Int
+	maxData    uint64
+}
+
+// newTxRuleFilter creates a filter enforcing the given rules, or nil if there
+// are no rules to enforce. Malformed rules are ignored with a warning.
+func newTxRuleFilter(rules TxFilterRules) *txRuleFilter {
+	f := &txRuleFilter{
+		senders:    make(map[common.Address]struct{}),
+		recipients: make(map[common.Address]struct{}),
+		selectors:  make(map[[4]byte]struct{}),
+		tips:       make(map[common.Address]*big.Int),
+		maxData:    rules.MaxDataSize,
+	}
+	for _, addr := range rules.DenySenders {
+		f.senders[addr] = struct{}{}
+	}
+	for _, addr := range rules.DenyRecipients {
+		f.recipients[addr] = struct{}{}
+	}
+	for _, sel := range rules.DenySelectors {
+		if len(sel) != 4 {
+			log.Warn("Ignoring invalid txpool method selector", "selector", sel)
+			continue
+		}
+		var key [4]byte
+		copy(key[:], sel)
+		f.selectors[key] = struct{}{}
+	}
+	for _, tip := range rules.ContractTips {
+		if tip.MinTip == nil {
+			log.Warn("Ignoring txpool contract tip without value", "contract", tip.Contract)
+			continue
+		}
+		f.tips[tip.Contract] = tip.MinTip
+	}
+	if len(f.senders) == 0 && len(f.recipients) == 0 && len(f.selectors) == 0 && len(f.tips) == 0 && f.maxData == 0 {
+		return nil
+	}
+	return f
+}
+
+// FilterTx implements TxFilter.
+func (f *txRuleFilter) FilterTx(tx *types.Transaction, from common.Address, local bool) error {
+	if _, ok := f.senders[from]; ok {
+		return &TxFilterError{Reason: "sender", Detail: fmt.Sprintf("sender %v denied", from)}
+	}
+	if f.maxData != 0 && uint64(len(tx.Data())) > f.maxData {
+		return &TxFilterError{Reason: "datasize", Detail: fmt.Sprintf("calldata size %d exceeds %d", len(tx.Data()), f.maxData)}
+	}
+	to := tx.To()
+	if to == nil {
+		return nil
+	}
+	if _, ok := f.recipients[*to]; ok {
+		return &TxFilterError{Reason: "recipient", Detail: fmt.Sprintf("recipient %v denied", *to)}
+	}
+	if data := tx.Data(); len(data) >= 4 {
+		var sel [4]byte
+		copy(sel[:], data)
+		if _, ok := f.selectors[sel]; ok {
+			return &TxFilterError{Reason: "selector", Detail: fmt.Sprintf("method %x denied", sel)}
+		}
+	}
+	if tip := f.tips[*to]; tip != nil && tx.GasTipCapIntCmp(tip) < 0 {
+		return &TxFilterError{Reason: "contracttip", Detail: fmt.Sprintf("tip %v below %v required by %v", tx.GasTipCap(), tip, *to)}
+	}
+	return nil
+}
+
+// filterTx runs the admission filters of the pool on a transaction, marking the
+// rejection reason in the metrics.
+func (pool *TxPool) filterTx(tx *types.Transaction, from common.Address, local bool) error {
+	for _, filter := range pool.filters {
+		if err := filter.FilterTx(tx, from, local); err != nil {
+			reason := "other"
+			var ferr *TxFilterError
+			if errors.As(err, &ferr) {
+				reason = ferr.Reason
+			}
+			metrics.GetOrRegisterMeter("txpool/filtered/"+reason, nil).Mark(1)
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,119 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"crypto/ecdsa"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/event"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// txFilterFunc is a TxFilter implemented by a plain function.
+type txFilterFunc func(tx *types.Transaction, from common.Address, local bool) error
+
+func (f txFilterFunc) FilterTx(tx *types.Transaction, from common.Address, local bool) error {
+	return f(tx, from, local)
+}
+
+// Tests that the built-in admission rules and the custom filters are enforced
+// on both local and remote transactions.
+func TestTransactionFilters(t *testing.T) {
+	t.Parallel()
+
+	var (
+		denied, _  = crypto.GenerateKey()
+		allowed, _ = crypto.GenerateKey()
+		blocked    = common.Address{0x01}
+		contract   = common.Address{0x02}
+		custom     = errors.New("custom rejection")
+	)
+	config := testTxPoolConfig
+	config.FilterRules = TxFilterRules{
+		DenySenders:    []common.Address{crypto.PubkeyToAddress(denied.PublicKey)},
+		DenyRecipients: []common.Address{blocked},
+		DenySelectors:  []hexutil.Bytes{{0xa9, 0x05, 0x9c, 0xbb}},
+		ContractTips:   []ContractTip{{Contract: contract, MinTip: big.NewInt(10)}},
+		MaxDataSize:    64,
+	}
+	config.Filters = []TxFilter{txFilterFunc(func(tx *types.Transaction, from common.Address, local bool) error {
+		if tx.Value().Cmp(big.NewInt(1000)) == 0 {
+			return custom
+		}
+		return nil
+	})}
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+	blockchain := &testBlockChain{1000000, statedb, new(event.Feed)}
+
+	pool := NewTxPool(config, params.TestChainConfig, blockchain)
+	defer pool.Stop()
+
+	testAddBalance(pool, crypto.PubkeyToAddress(denied.PublicKey), big.NewInt(1000000000))
+	testAddBalance(pool, crypto.PubkeyToAddress(allowed.PublicKey), big.NewInt(1000000000))
+
+	signer := types.LatestSigner(params.TestChainConfig)
+	tx := func(key *ecdsa.PrivateKey, to common.Address, value int64, price int64, data []byte) *types.Transaction {
+		return types.MustSignNewTx(key, signer, &types.LegacyTx{
+			To:       &to,
+			Value:    big.NewInt(value),
+			Gas:      100000,
+			GasPrice: big.NewInt(price),
+			Data:     data,
+		})
+	}
+	tests := []struct {
+		tx     *types.Transaction
+		reason string
+	}{
+		{tx(denied, common.Address{}, 0, 1, nil), "sender"},
+		{tx(allowed, blocked, 0, 1, nil), "recipient"},
+		{tx(allowed, common.Address{}, 0, 1, make([]byte, 65)), "datasize"},
+		{tx(allowed, common.Address{}, 0, 1, common.FromHex("0xa9059cbb00")), "selector"},
+		{tx(allowed, contract, 0, 9, nil), "contracttip"},
+		{tx(allowed, common.Address{}, 1000, 1, nil), ""},
+	}
+	for i, tt := range tests {
+		for _, local := range []bool{false, true} {
+			var err error
+			if local {
+				err = pool.AddLocal(tt.tx)
+			} else {
+				err = pool.AddRemote(tt.tx)
+			}
+			var ferr *TxFilterError
+			switch {
+			case tt.reason == "" && err != custom:
+				t.Errorf("test %d, local %v: error mismatch: have %v, want %v", i, local, err, custom)
+			case tt.reason != "" && (!errors.As(err, &ferr) || ferr.Reason != tt.reason):
+				t.Errorf("test %d, local %v: error mismatch: have %v, want reason %q", i, local, err, tt.reason)
+			}
+		}
+	}
+	// Ensure transactions passing all the rules are accepted
+	if err := pool.AddRemote(tx(allowed, contract, 0, 10, nil)); err != nil {
+		t.Fatalf("failed to add valid transaction: %v", err)
+	}
+}
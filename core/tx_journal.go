@@ -44,13 +44,15 @@ func (*devNull) Close() error                      { return nil }
 // created transactions to allow non-executed ones to survive node restarts.
 type txJournal struct {
 	path   string         // Filesystem path to store the transactions at
+	kind   string         // Kind of the journaled transactions (local or remote), for logging
 	writer io.WriteCloser // Output stream to write new transactions into
 }
 
 // newTxJournal creates a new transaction journal to
-func newTxJournal(path string) *txJournal {
+func newTxJournal(path string, kind string) *txJournal {
 	return &txJournal{
 		path: path,
+		kind: kind,
 	}
 }
 
@@ -111,7 +113,7 @@ func (journal *txJournal) load(add func([]*types.Transaction) []error) error {
 			batch = batch[:0]
 		}
 	}
-	log.Info("Loaded local transaction journal", "transactions", total, "dropped", dropped)
+	log.Info("Loaded "+journal.kind+" transaction journal", "transactions", total, "dropped", dropped)
 
 	return failure
 }
@@ -163,7 +165,7 @@ func (journal *txJournal) rotate(all map[common.Address]types.Transactions) erro
 		return err
 	}
 	journal.writer = sink
-	log.Info("Regenerated local transaction journal", "transactions", journaled, "accounts", len(all))
+	log.Info("Regenerated "+journal.kind+" transaction journal", "transactions", journaled, "accounts", len(all))
 
 	return nil
 }
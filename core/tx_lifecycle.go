@@ -0,0 +1,117 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// TxLifecycleKind is the kind of state change of a transaction in the pool.
+type TxLifecycleKind string
+
+const (
+	TxAdded    TxLifecycleKind = "added"    // Transaction entered the pool
+	TxPromoted TxLifecycleKind = "promoted" // Transaction moved from the queue to the pending set
+	TxDemoted  TxLifecycleKind = "demoted"  // Transaction moved from the pending set back to the queue
+	TxReplaced TxLifecycleKind = "replaced" // Transaction was replaced by another with the same nonce
+	TxDropped  TxLifecycleKind = "dropped"  // Transaction was removed from the pool without inclusion
+	TxIncluded TxLifecycleKind = "included" // Transaction was removed from the pool as it's mined
+)
+
+// Reasons of the transactions being dropped from the pool.
+const (
+	TxDropNonceTooLow  = "nonce-too-low"       // Nonce already used by another transaction on chain
+	TxDropUnpayable    = "unpayable"           // Sender can't afford the transaction, or it exceeds the gas limit
+	TxDropUnderpriced  = "underpriced"         // Evicted by better paying transactions from a full pool
+	TxDropReplacement  = "replace-underpriced" // Conflicting with a better paying transaction of the same nonce
+	TxDropAccountLimit = "account-limit"       // Exceeding the queue slots of the account
+	TxDropPendingLimit = "pending-limit"       // Exceeding the global pending slots
+	TxDropQueueLimit   = "queue-limit"         // Exceeding the global queue slots
+	TxDropLifetime     = "lifetime"            // Queued for longer than the allowed lifetime
+)
+
+// TxLifecycle is a state change of a transaction in the pool.
+type TxLifecycle struct {
+	Hash       common.Hash     // Hash of the transaction changing state
+	Kind       TxLifecycleKind // Kind of the change
+	Reason     string          // Reason of the removal, for dropped transactions
+	ReplacedBy common.Hash     // Hash of the replacement, for replaced transactions
+}
+
+// maxIncludedDepth is the number of blocks scanned for mined transactions on a
+// pool reset, to tell included transactions apart from the ones dropped due to
+// a nonce reused on chain.
+const maxIncludedDepth = 64
+
+// trackTx records a lifecycle change of a transaction, to be posted when the
+// pool lock is released. The caller must hold pool.mu.
+func (pool *TxPool) trackTx(hash common.Hash, kind TxLifecycleKind, reason string) {
+	pool.changes = append(pool.changes, TxLifecycle{Hash: hash, Kind: kind, Reason: reason})
+}
+
+// trackReplaced records the replacement of a transaction by another one with
+// the same nonce. The caller must hold pool.mu.
+func (pool *TxPool) trackReplaced(old, by common.Hash) {
+	pool.changes = append(pool.changes, TxLifecycle{Hash: old, Kind: TxReplaced, ReplacedBy: by})
+}
+
+// trackStale records the removal of a transaction whose nonce is already used on
+// chain, either by itself or by a conflicting one. The caller must hold pool.mu.
+func (pool *TxPool) trackStale(hash common.Hash) {
+	if _, ok := pool.included[hash]; ok {
+		pool.trackTx(hash, TxIncluded, "")
+	} else {
+		pool.trackTx(hash, TxDropped, TxDropNonceTooLow)
+	}
+}
+
+// takeChanges retrieves and clears the recorded lifecycle changes. The caller
+// must hold pool.mu.
+func (pool *TxPool) takeChanges() []TxLifecycle {
+	changes := pool.changes
+	pool.changes = nil
+	return changes
+}
+
+// postChanges announces the given lifecycle changes to the subscribers. It must
+// be called without holding pool.mu, as the subscribers may be slow.
+func (pool *TxPool) postChanges(changes []TxLifecycle) {
+	if len(changes) > 0 {
+		pool.lifecycleFeed.Send(TxLifecycleEvent{changes})
+	}
+}
+
+// includedTxs collects the hashes of the transactions mined between the old and
+// new head, or only in the new head if the old one is unknown.
+func (pool *TxPool) includedTxs(oldHead, newHead *types.Header) map[common.Hash]struct{} {
+	if newHead == nil {
+		return nil
+	}
+	included := make(map[common.Hash]struct{})
+	block := pool.chain.GetBlock(newHead.Hash(), newHead.Number.Uint64())
+	for depth := 0; block != nil && depth < maxIncludedDepth; depth++ {
+		for _, tx := range block.Transactions() {
+			included[tx.Hash()] = struct{}{}
+		}
+		if oldHead == nil || block.NumberU64() <= oldHead.Number.Uint64()+1 || block.NumberU64() == 0 {
+			break
+		}
+		block = pool.chain.GetBlock(block.ParentHash(), block.NumberU64()-1)
+	}
+	return included
+}
@@ -23,6 +23,8 @@ import (
 	"math/big"
 	"math/rand"
 	"os"
+	"path/filepath"
+	"reflect"
 	"sync/atomic"
 	"testing"
 	"time"
@@ -2346,6 +2348,60 @@ func testTransactionJournaling(t *testing.T, nolocals bool) {
 	pool.Stop()
 }
 
+// Tests that remote transactions are persisted across restarts if the remote
+// journal is enabled, and revalidated against the new head on startup.
+func TestTransactionRemoteJournaling(t *testing.T) {
+	t.Parallel()
+
+	journal := filepath.Join(t.TempDir(), "remotes.rlp")
+
+	// Create the original pool to inject transactions into the journal
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+	blockchain := &testBlockChain{1000000, statedb, new(event.Feed)}
+
+	config := testTxPoolConfig
+	config.RemoteJournal = journal
+
+	pool := NewTxPool(config, params.TestChainConfig, blockchain)
+
+	// Add a local and a few pending and queued remote transactions
+	local, _ := crypto.GenerateKey()
+	remote, _ := crypto.GenerateKey()
+
+	testAddBalance(pool, crypto.PubkeyToAddress(local.PublicKey), big.NewInt(1000000000))
+	testAddBalance(pool, crypto.PubkeyToAddress(remote.PublicKey), big.NewInt(1000000000))
+
+	if err := pool.AddLocal(pricedTransaction(0, 100000, big.NewInt(1), local)); err != nil {
+		t.Fatalf("failed to add local transaction: %v", err)
+	}
+	for _, nonce := range []uint64{0, 1, 3} {
+		if err := pool.addRemoteSync(pricedTransaction(nonce, 100000, big.NewInt(1), remote)); err != nil {
+			t.Fatalf("failed to add remote transaction %d: %v", nonce, err)
+		}
+	}
+	if pending, queued := pool.Stats(); pending != 3 || queued != 1 {
+		t.Fatalf("pool stats mismatch: have %d/%d, want %d/%d", pending, queued, 3, 1)
+	}
+	// Restart the pool with the first remote transaction included in the mean
+	// time, and ensure only the still valid remote ones are restored
+	pool.Stop()
+	statedb.SetNonce(crypto.PubkeyToAddress(remote.PublicKey), 1)
+	blockchain = &testBlockChain{1000000, statedb, new(event.Feed)}
+
+	pool = NewTxPool(config, params.TestChainConfig, blockchain)
+	defer pool.Stop()
+
+	if pending, queued := pool.Stats(); pending != 1 || queued != 1 {
+		t.Fatalf("pool stats mismatch: have %d/%d, want %d/%d", pending, queued, 1, 1)
+	}
+	if pool.locals.contains(crypto.PubkeyToAddress(remote.PublicKey)) {
+		t.Fatalf("restored remote account marked local")
+	}
+	if err := validateTxPoolInternals(pool); err != nil {
+		t.Fatalf("pool internal state corrupted: %v", err)
+	}
+}
+
 // TestTransactionStatusCheck tests that the pool can correctly retrieve the
 // pending status of individual transactions.
 func TestTransactionStatusCheck(t *testing.T) {
@@ -2560,3 +2616,88 @@ func BenchmarkPoolMultiAccountBatchInsert(b *testing.B) {
 		pool.AddRemotesSync([]*types.Transaction{tx})
 	}
 }
+
+// testMinedChain is a testBlockChain returning a fixed block for any lookup, to
+// simulate the inclusion of transactions.
+type testMinedChain struct {
+	*testBlockChain
+	block *types.Block
+}
+
+func (bc *testMinedChain) GetBlock(hash common.Hash, number uint64) *types.Block {
+	return bc.block
+}
+
+// Tests that the lifecycle changes of the transactions are announced, with the
+// reasons of the transactions leaving the pool.
+func TestTransactionLifecycleEvents(t *testing.T) {
+	t.Parallel()
+
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+	blockchain := &testMinedChain{testBlockChain: &testBlockChain{1000000, statedb, new(event.Feed)}}
+
+	pool := NewTxPool(testTxPoolConfig, params.TestChainConfig, blockchain)
+	defer pool.Stop()
+
+	key, _ := crypto.GenerateKey()
+	addr := crypto.PubkeyToAddress(key.PublicKey)
+	testAddBalance(pool, addr, big.NewInt(1000000000))
+
+	events := make(chan TxLifecycleEvent, 16)
+	sub := pool.SubscribeTxLifecycleEvent(events)
+	defer sub.Unsubscribe()
+
+	expect := func(want ...TxLifecycle) {
+		t.Helper()
+
+		var have []TxLifecycle
+		for len(have) < len(want) {
+			select {
+			case ev := <-events:
+				have = append(have, ev.Changes...)
+			case <-time.After(time.Second):
+				t.Fatalf("lifecycle changes missing: have %v, want %v", have, want)
+			}
+		}
+		if !reflect.DeepEqual(have, want) {
+			t.Fatalf("lifecycle changes mismatch: have %v, want %v", have, want)
+		}
+	}
+	// Add a gapped and then an executable transaction
+	tx0, tx1 := pricedTransaction(0, 100000, big.NewInt(1), key), pricedTransaction(1, 100000, big.NewInt(1), key)
+	if err := pool.addRemoteSync(tx1); err != nil {
+		t.Fatalf("failed to add transaction: %v", err)
+	}
+	expect(TxLifecycle{Hash: tx1.Hash(), Kind: TxAdded})
+
+	if err := pool.addRemoteSync(tx0); err != nil {
+		t.Fatalf("failed to add transaction: %v", err)
+	}
+	expect(
+		TxLifecycle{Hash: tx0.Hash(), Kind: TxAdded},
+		TxLifecycle{Hash: tx0.Hash(), Kind: TxPromoted},
+		TxLifecycle{Hash: tx1.Hash(), Kind: TxPromoted},
+	)
+	// Replace the first transaction with a better paying one
+	repl := pricedTransaction(0, 100000, big.NewInt(2), key)
+	if err := pool.addRemoteSync(repl); err != nil {
+		t.Fatalf("failed to add replacement: %v", err)
+	}
+	expect(
+		TxLifecycle{Hash: tx0.Hash(), Kind: TxReplaced, ReplacedBy: repl.Hash()},
+		TxLifecycle{Hash: repl.Hash(), Kind: TxAdded},
+	)
+	// Mine the replacement and ensure it's reported as included
+	header := &types.Header{Number: big.NewInt(1), GasLimit: 1000000, BaseFee: big.NewInt(1)}
+	blockchain.block = types.NewBlock(header, types.Transactions{repl}, nil, nil, trie.NewStackTrie(nil))
+	statedb.SetNonce(addr, 1)
+
+	<-pool.requestReset(nil, header)
+	expect(TxLifecycle{Hash: repl.Hash(), Kind: TxIncluded})
+
+	// Drain the funds of the account and ensure the remaining one is dropped
+	statedb.SetBalance(addr, new(big.Int))
+
+	<-pool.requestReset(nil, header)
+	expect(TxLifecycle{Hash: tx1.Hash(), Kind: TxDropped, Reason: TxDropUnpayable})
+}
@@ -84,6 +84,8 @@ type Ethereum struct {
 
 	bloomRequests     chan chan *bloombits.Retrieval // Channel receiving bloom data retrieval requests
 	bloomIndexer      *core.ChainIndexer             // Bloom indexer operating during block imports
+	logIndexer        *core.ChainIndexer             // Optional exact log indexer operating during block imports
+	statePruner       *pruner.OnlinePruner           // Optional background pruner of stale state
 	closeBloomHandler chan struct{}
 
 	APIBackend *EthAPIBackend
@@ -136,6 +138,23 @@ func New(stack *node.Node, config *ethconfig.Config) (*Ethereum, error) {
 	if err != nil {
 		return nil, err
 	}
+	// Resolve the trie node storage scheme before any state gets written. The
+	// path scheme retains a single persistent state, so it can neither serve
+	// an archive node nor be filled by snap sync, which writes nodes by hash.
+	scheme, err := rawdb.ParseStateScheme(config.StateScheme, chainDb)
+	if err != nil {
+		return nil, err
+	}
+	if scheme == rawdb.PathScheme {
+		if config.NoPruning {
+			return nil, errors.New("path-based state scheme is incompatible with archive mode")
+		}
+		if config.SyncMode == downloader.SnapSync {
+			log.Warn("Switch sync mode from snap sync to full sync", "scheme", scheme)
+			config.SyncMode = downloader.FullSync
+		}
+		rawdb.WriteStateScheme(chainDb, scheme)
+	}
 	chainConfig, genesisHash, genesisErr := core.SetupGenesisBlockWithOverride(chainDb, config.Genesis, config.OverrideArrowGlacier, config.OverrideTerminalTotalDifficulty)
 	if _, ok := genesisErr.(*params.ConfigCompatError); genesisErr != nil && !ok {
 		return nil, genesisErr
@@ -194,6 +213,7 @@ func New(stack *node.Node, config *ethconfig.Config) (*Ethereum, error) {
 			TrieTimeLimit:       config.TrieTimeout,
 			SnapshotLimit:       config.SnapshotCache,
 			Preimages:           config.Preimages,
+			HistoryPruneBefore:  config.HistoryPruneBefore,
 		}
 	)
 	eth.blockchain, err = core.NewBlockChain(chainDb, cacheConfig, chainConfig, eth.engine, vmConfig, eth.shouldPreserve, &config.TxLookupLimit)
@@ -207,10 +227,17 @@ func New(stack *node.Node, config *ethconfig.Config) (*Ethereum, error) {
 		rawdb.WriteChainConfig(chainDb, genesisHash, chainConfig)
 	}
 	eth.bloomIndexer.Start(eth.blockchain)
+	if config.LogIndex {
+		eth.logIndexer = core.NewLogIndexer(chainDb, params.LogIndexBlocks, params.LogIndexConfirms)
+		eth.logIndexer.Start(eth.blockchain)
+	}
 
 	if config.TxPool.Journal != "" {
 		config.TxPool.Journal = stack.ResolvePath(config.TxPool.Journal)
 	}
+	if config.TxPool.RemoteJournal != "" {
+		config.TxPool.RemoteJournal = stack.ResolvePath(config.TxPool.RemoteJournal)
+	}
 	eth.txPool = core.NewTxPool(config.TxPool, chainConfig, eth.blockchain)
 
 	// Permit the downloader to use the trie cache allowance during fast sync
@@ -233,6 +260,16 @@ func New(stack *node.Node, config *ethconfig.Config) (*Ethereum, error) {
 	}); err != nil {
 		return nil, err
 	}
+	if config.StatePrune {
+		eth.statePruner, err = pruner.NewOnlinePruner(chainDb, eth.blockchain, pruner.OnlineConfig{
+			BloomSize: config.StatePruneBloomSize,
+			Interval:  config.StatePruneInterval,
+			RateLimit: config.StatePruneRateLimit,
+		}, eth.Synced)
+		if err != nil {
+			return nil, err
+		}
+	}
 
 	eth.miner = miner.New(eth, &config.Miner, chainConfig, eth.EventMux(), eth.engine, eth.isLocalBlock)
 	eth.miner.SetExtra(makeExtraData(config.Miner.ExtraData))
@@ -297,6 +334,20 @@ func (s *Ethereum) APIs() []rpc.API {
 	// Append any APIs exposed explicitly by the consensus engine
 	apis = append(apis, s.engine.APIs(s.BlockChain())...)
 
+	// Append the raw database writes if explicitly requested
+	if s.config.RPCDBWrite {
+		apis = append(apis, rpc.API{
+			Namespace:     "debug",
+			Version:       "1.0",
+			Service:       ethapi.NewPrivateDBWriteAPI(s.APIBackend),
+			Authenticated: true,
+		})
+	}
+
+	logLimits := filters.LogQueryLimits{
+		MaxBlockRange: s.config.RPCLogQueryMaxBlockRange,
+		MaxResults:    s.config.RPCLogQueryMaxResults,
+	}
 	// Append all the local APIs and return
 	return append(apis, []rpc.API{
 		{
@@ -322,7 +373,7 @@ func (s *Ethereum) APIs() []rpc.API {
 		}, {
 			Namespace: "eth",
 			Version:   "1.0",
-			Service:   filters.NewPublicFilterAPI(s.APIBackend, false, 5*time.Minute),
+			Service:   filters.NewPublicFilterAPI(s.APIBackend, false, 5*time.Minute, logLimits),
 			Public:    true,
 		}, {
 			Namespace: "admin",
@@ -546,6 +597,11 @@ func (s *Ethereum) Start() error {
 	// Regularly update shutdown marker
 	s.shutdownTracker.Start()
 
+	// Start pruning stale state in the background if requested
+	if s.statePruner != nil {
+		s.statePruner.Start()
+	}
+
 	// Figure out a max peers count based on the server limits
 	maxPeers := s.p2pServer.MaxPeers
 	if s.config.LightServ > 0 {
@@ -569,9 +625,15 @@ func (s *Ethereum) Stop() error {
 
 	// Then stop everything else.
 	s.bloomIndexer.Close()
+	if s.logIndexer != nil {
+		s.logIndexer.Close()
+	}
 	close(s.closeBloomHandler)
 	s.txPool.Stop()
 	s.miner.Close()
+	if s.statePruner != nil {
+		s.statePruner.Stop()
+	}
 	s.blockchain.Stop()
 	s.engine.Close()
 
@@ -83,6 +83,9 @@ var Defaults = Config{
 	TrieDirtyCache:          256,
 	TrieTimeout:             60 * time.Minute,
 	SnapshotCache:           102,
+	StatePruneInterval:      12 * time.Hour,
+	StatePruneRateLimit:     20000,
+	StatePruneBloomSize:     2048,
 	Miner: miner.Config{
 		GasCeil:  30000000,
 		GasPrice: big.NewInt(params.GWei),
@@ -133,10 +136,19 @@ type Config struct {
 	EthDiscoveryURLs  []string
 	SnapDiscoveryURLs []string
 
-	NoPruning  bool // Whether to disable pruning and flush everything to disk
-	NoPrefetch bool // Whether to disable prefetching and only load state on demand
+	NoPruning   bool   // Whether to disable pruning and flush everything to disk
+	NoPrefetch  bool   // Whether to disable prefetching and only load state on demand
+	StateScheme string `toml:",omitempty"` // Trie node storage scheme (hash or path), empty to use the existing one
 
-	TxLookupLimit uint64 `toml:",omitempty"` // The maximum number of blocks from head whose tx indices are reserved.
+	TxLookupLimit      uint64 `toml:",omitempty"` // The maximum number of blocks from head whose tx indices are reserved.
+	LogIndex           bool   `toml:",omitempty"` // Whether to maintain an exact address/topic index of the logs
+	HistoryPruneBefore uint64 `toml:",omitempty"` // Block number below which ancient bodies and receipts are pruned (0 = keep all)
+
+	// Online state pruning options
+	StatePrune          bool          `toml:",omitempty"` // Whether to delete stale trie nodes in the background
+	StatePruneInterval  time.Duration `toml:",omitempty"` // Time to wait between two online pruning cycles
+	StatePruneRateLimit int           `toml:",omitempty"` // Maximum number of trie nodes deleted per second (0 = unlimited)
+	StatePruneBloomSize uint64        `toml:",omitempty"` // Megabytes of memory allocated to the live state bloom filter
 
 	// RequiredBlocks is a set of block number -> hash mappings which must be in the
 	// canonical chain of all remote peers. Setting the option makes geth verify the
@@ -199,6 +211,18 @@ type Config struct {
 	// send-transction variants. The unit is ether.
 	RPCTxFeeCap float64
 
+	// RPCLogQueryMaxBlockRange is the maximum number of blocks a single log
+	// query may span (0 = no limit).
+	RPCLogQueryMaxBlockRange uint64
+
+	// RPCLogQueryMaxResults is the maximum number of logs a single log query
+	// may return (0 = no limit).
+	RPCLogQueryMaxResults int
+
+	// RPCDBWrite exposes the raw database write methods on the authenticated
+	// RPC endpoints.
+	RPCDBWrite bool `toml:",omitempty"`
+
 	// Checkpoint is a hardcoded checkpoint which can be nil.
 	Checkpoint *params.TrustedCheckpoint `toml:",omitempty"`
 
@@ -25,7 +25,14 @@ func (c Config) MarshalTOML() (interface{}, error) {
 		SnapDiscoveryURLs               []string
 		NoPruning                       bool
 		NoPrefetch                      bool
+		StateScheme                     string                 `toml:",omitempty"`
 		TxLookupLimit                   uint64                 `toml:",omitempty"`
+		LogIndex                        bool                   `toml:",omitempty"`
+		HistoryPruneBefore              uint64                 `toml:",omitempty"`
+		StatePrune                      bool                   `toml:",omitempty"`
+		StatePruneInterval              time.Duration          `toml:",omitempty"`
+		StatePruneRateLimit             int                    `toml:",omitempty"`
+		StatePruneBloomSize             uint64                 `toml:",omitempty"`
 		RequiredBlocks                  map[uint64]common.Hash `toml:"-"`
 		LightServ                       int                    `toml:",omitempty"`
 		LightIngress                    int                    `toml:",omitempty"`
@@ -57,6 +64,9 @@ func (c Config) MarshalTOML() (interface{}, error) {
 		RPCGasCap                       uint64
 		RPCEVMTimeout                   time.Duration
 		RPCTxFeeCap                     float64
+		RPCLogQueryMaxBlockRange        uint64
+		RPCLogQueryMaxResults           int
+		RPCDBWrite                      bool `toml:",omitempty"`
 		Checkpoint                      *params.TrustedCheckpoint      `toml:",omitempty"`
 		CheckpointOracle                *params.CheckpointOracleConfig `toml:",omitempty"`
 		OverrideArrowGlacier            *big.Int                       `toml:",omitempty"`
@@ -70,7 +80,14 @@ func (c Config) MarshalTOML() (interface{}, error) {
 	enc.SnapDiscoveryURLs = c.SnapDiscoveryURLs
 	enc.NoPruning = c.NoPruning
 	enc.NoPrefetch = c.NoPrefetch
+	enc.StateScheme = c.StateScheme
 	enc.TxLookupLimit = c.TxLookupLimit
+	enc.LogIndex = c.LogIndex
+	enc.HistoryPruneBefore = c.HistoryPruneBefore
+	enc.StatePrune = c.StatePrune
+	enc.StatePruneInterval = c.StatePruneInterval
+	enc.StatePruneRateLimit = c.StatePruneRateLimit
+	enc.StatePruneBloomSize = c.StatePruneBloomSize
 	enc.RequiredBlocks = c.RequiredBlocks
 	enc.LightServ = c.LightServ
 	enc.LightIngress = c.LightIngress
@@ -102,6 +119,9 @@ func (c Config) MarshalTOML() (interface{}, error) {
 	enc.RPCGasCap = c.RPCGasCap
 	enc.RPCEVMTimeout = c.RPCEVMTimeout
 	enc.RPCTxFeeCap = c.RPCTxFeeCap
+	enc.RPCLogQueryMaxBlockRange = c.RPCLogQueryMaxBlockRange
+	enc.RPCLogQueryMaxResults = c.RPCLogQueryMaxResults
+	enc.RPCDBWrite = c.RPCDBWrite
 	enc.Checkpoint = c.Checkpoint
 	enc.CheckpointOracle = c.CheckpointOracle
 	enc.OverrideArrowGlacier = c.OverrideArrowGlacier
@@ -119,7 +139,14 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 		SnapDiscoveryURLs               []string
 		NoPruning                       *bool
 		NoPrefetch                      *bool
+		StateScheme                     *string                `toml:",omitempty"`
 		TxLookupLimit                   *uint64                `toml:",omitempty"`
+		LogIndex                        *bool                  `toml:",omitempty"`
+		HistoryPruneBefore              *uint64                `toml:",omitempty"`
+		StatePrune                      *bool                  `toml:",omitempty"`
+		StatePruneInterval              *time.Duration         `toml:",omitempty"`
+		StatePruneRateLimit             *int                   `toml:",omitempty"`
+		StatePruneBloomSize             *uint64                `toml:",omitempty"`
 		RequiredBlocks                  map[uint64]common.Hash `toml:"-"`
 		LightServ                       *int                   `toml:",omitempty"`
 		LightIngress                    *int                   `toml:",omitempty"`
@@ -151,6 +178,9 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 		RPCGasCap                       *uint64
 		RPCEVMTimeout                   *time.Duration
 		RPCTxFeeCap                     *float64
+		RPCLogQueryMaxBlockRange        *uint64
+		RPCLogQueryMaxResults           *int
+		RPCDBWrite                      *bool `toml:",omitempty"`
 		Checkpoint                      *params.TrustedCheckpoint      `toml:",omitempty"`
 		CheckpointOracle                *params.CheckpointOracleConfig `toml:",omitempty"`
 		OverrideArrowGlacier            *big.Int                       `toml:",omitempty"`
@@ -181,9 +211,30 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 	if dec.NoPrefetch != nil {
 		c.NoPrefetch = *dec.NoPrefetch
 	}
+	if dec.StateScheme != nil {
+		c.StateScheme = *dec.StateScheme
+	}
 	if dec.TxLookupLimit != nil {
 		c.TxLookupLimit = *dec.TxLookupLimit
 	}
+	if dec.LogIndex != nil {
+		c.LogIndex = *dec.LogIndex
+	}
+	if dec.HistoryPruneBefore != nil {
+		c.HistoryPruneBefore = *dec.HistoryPruneBefore
+	}
+	if dec.StatePrune != nil {
+		c.StatePrune = *dec.StatePrune
+	}
+	if dec.StatePruneInterval != nil {
+		c.StatePruneInterval = *dec.StatePruneInterval
+	}
+	if dec.StatePruneRateLimit != nil {
+		c.StatePruneRateLimit = *dec.StatePruneRateLimit
+	}
+	if dec.StatePruneBloomSize != nil {
+		c.StatePruneBloomSize = *dec.StatePruneBloomSize
+	}
 	if dec.RequiredBlocks != nil {
 		c.RequiredBlocks = dec.RequiredBlocks
 	}
@@ -277,6 +328,15 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 	if dec.RPCTxFeeCap != nil {
 		c.RPCTxFeeCap = *dec.RPCTxFeeCap
 	}
+	if dec.RPCLogQueryMaxBlockRange != nil {
+		c.RPCLogQueryMaxBlockRange = *dec.RPCLogQueryMaxBlockRange
+	}
+	if dec.RPCLogQueryMaxResults != nil {
+		c.RPCLogQueryMaxResults = *dec.RPCLogQueryMaxResults
+	}
+	if dec.RPCDBWrite != nil {
+		c.RPCDBWrite = *dec.RPCDBWrite
+	}
 	if dec.Checkpoint != nil {
 		c.Checkpoint = dec.Checkpoint
 	}
@@ -28,6 +28,7 @@ import (
 	"github.com/ethereum/go-ethereum"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/rpc"
 )
@@ -43,6 +44,12 @@ type filter struct {
 	s        *Subscription // associated subscription in event system
 }
 
+// LogQueryLimits bounds the work done by a single log query.
+type LogQueryLimits struct {
+	MaxBlockRange uint64 // maximum number of blocks a query may span, 0 means no limit
+	MaxResults    int    // maximum number of logs a query may return, 0 means no limit
+}
+
 // PublicFilterAPI offers support to create and manage filters. This will allow external clients to retrieve various
 // information related to the Ethereum protocol such als blocks, transactions and logs.
 type PublicFilterAPI struct {
@@ -51,15 +58,17 @@ type PublicFilterAPI struct {
 	filtersMu sync.Mutex
 	filters   map[rpc.ID]*filter
 	timeout   time.Duration
+	limits    LogQueryLimits
 }
 
 // NewPublicFilterAPI returns a new PublicFilterAPI instance.
-func NewPublicFilterAPI(backend Backend, lightMode bool, timeout time.Duration) *PublicFilterAPI {
+func NewPublicFilterAPI(backend Backend, lightMode bool, timeout time.Duration, limits LogQueryLimits) *PublicFilterAPI {
 	api := &PublicFilterAPI{
 		backend: backend,
 		events:  NewEventSystem(backend, lightMode),
 		filters: make(map[rpc.ID]*filter),
 		timeout: timeout,
+		limits:  limits,
 	}
 	go api.timeoutLoop(timeout)
 
@@ -169,6 +178,54 @@ func (api *PublicFilterAPI) NewPendingTransactions(ctx context.Context) (*rpc.Su
 	return rpcSub, nil
 }
 
+// txPoolEvent is the JSON representation of a lifecycle change of a transaction
+// in the transaction pool.
+type txPoolEvent struct {
+	Hash       common.Hash          `json:"hash"`
+	Kind       core.TxLifecycleKind `json:"kind"`
+	Reason     string               `json:"reason,omitempty"`
+	ReplacedBy *common.Hash         `json:"replacedBy,omitempty"`
+}
+
+// TxpoolEvents creates a subscription that is triggered each time a transaction
+// changes state in the transaction pool: it's added, promoted to the pending set,
+// demoted back to the queue, replaced, dropped or included in a block.
+func (api *PublicFilterAPI) TxpoolEvents(ctx context.Context) (*rpc.Subscription, error) {
+	notifier, supported := rpc.NotifierFromContext(ctx)
+	if !supported {
+		return &rpc.Subscription{}, rpc.ErrNotificationsUnsupported
+	}
+
+	rpcSub := notifier.CreateSubscription()
+
+	go func() {
+		changes := make(chan []core.TxLifecycle, 128)
+		changesSub := api.events.SubscribeTxPoolEvents(changes)
+
+		for {
+			select {
+			case batch := <-changes:
+				for _, change := range batch {
+					ev := &txPoolEvent{Hash: change.Hash, Kind: change.Kind, Reason: change.Reason}
+					if change.Kind == core.TxReplaced {
+						by := change.ReplacedBy
+						ev.ReplacedBy = &by
+					}
+					notifier.Notify(rpcSub.ID, ev)
+				}
+			case <-rpcSub.Err():
+				changesSub.Unsubscribe()
+				return
+			case <-notifier.Closed():
+				changesSub.Unsubscribe()
+				return
+			}
+		}
+	}()
+
+	return rpcSub, nil
+}
+
 // NewBlockFilter creates a filter that fetches blocks that are imported into the chain.
 // It is part of the filter package since polling goes with eth_getFilterChanges.
 //
@@ -325,6 +382,109 @@ func (api *PublicFilterAPI) NewFilter(crit FilterCriteria) (rpc.ID, error) {
 //
 // https://eth.wiki/json-rpc/API#eth_getlogs
 func (api *PublicFilterAPI) GetLogs(ctx context.Context, crit FilterCriteria) ([]*types.Log, error) {
+	return api.queryLogs(ctx, crit)
+}
+
+// LogCursor marks how far a paginated log query has progressed. All matching logs
+// up to and including log LogIndex of block BlockNumber have been returned. If
+// LogIndex is nil, the block has been processed completely.
+type LogCursor struct {
+	BlockNumber hexutil.Uint64 `json:"blockNumber"`
+	BlockHash   common.Hash    `json:"blockHash"`
+	LogIndex    *hexutil.Uint  `json:"logIndex"`
+}
+
+// LogsPage is a single page of the results of a paginated log query.
+type LogsPage struct {
+	Logs   []*types.Log `json:"logs"`
+	Cursor *LogCursor   `json:"cursor"` // nil if the query is complete
+}
+
+// GetLogsPage returns a page of the logs matching the given criteria. A page spans
+// at most the configured maximum block range and holds at most the configured
+// maximum number of logs, or limit logs if that is lower.
+//
+// If the query is not complete, the page contains a cursor. Passing that cursor
+// along with the same criteria returns the next page. The cursor pins the hash of
+// its block, so the query fails if the chain is reorganized while paging.
+func (api *PublicFilterAPI) GetLogsPage(ctx context.Context, crit FilterCriteria, cursor *LogCursor, limit *hexutil.Uint) (*LogsPage, error) {
+	if crit.BlockHash != nil {
+		return nil, errors.New("block hash queries can't be paginated")
+	}
+	begin, end, err := api.resolveRange(ctx, crit)
+	if err != nil {
+		return nil, err
+	}
+	if cursor != nil {
+		number := int64(cursor.BlockNumber)
+		if number < begin || number > end {
+			return nil, fmt.Errorf("cursor block %d outside of query range [%d, %d]", number, begin, end)
+		}
+		header, err := api.backend.HeaderByNumber(ctx, rpc.BlockNumber(number))
+		if err != nil {
+			return nil, err
+		}
+		if header == nil || header.Hash() != cursor.BlockHash {
+			return nil, fmt.Errorf("cursor block %d (%x) no longer canonical", number, cursor.BlockHash)
+		}
+		begin = number
+		if cursor.LogIndex == nil {
+			begin++
+		}
+	}
+	page := &LogsPage{Logs: []*types.Log{}}
+	if begin > end {
+		return page, nil
+	}
+	last := end
+	if max := api.limits.MaxBlockRange; max > 0 && uint64(end-begin) >= max {
+		last = begin + int64(max) - 1
+	}
+	maxResults := api.limits.MaxResults
+	if limit != nil && *limit > 0 && (maxResults == 0 || int(*limit) < maxResults) {
+		maxResults = int(*limit)
+	}
+	// Stop the search as soon as there are more logs than fit on the page, which
+	// also need to include the ones of the cursor block skipped below.
+	filter := NewRangeFilter(api.backend, begin, last, crit.Addresses, crit.Topics)
+	if maxResults > 0 {
+		filter.limit = maxResults + 1
+		if cursor != nil && cursor.LogIndex != nil {
+			filter.limit += int(*cursor.LogIndex) + 1
+		}
+	}
+	logs, err := filter.Logs(ctx)
+	if err != nil {
+		return nil, err
+	}
+	// Skip the logs of the cursor block that were returned on the previous page.
+	if cursor != nil && cursor.LogIndex != nil {
+		for len(logs) > 0 && logs[0].BlockNumber == uint64(cursor.BlockNumber) && logs[0].Index <= uint(*cursor.LogIndex) {
+			logs = logs[1:]
+		}
+	}
+	switch {
+	case maxResults > 0 && len(logs) > maxResults:
+		logs = logs[:maxResults]
+		tail := logs[len(logs)-1]
+		index := hexutil.Uint(tail.Index)
+		page.Cursor = &LogCursor{BlockNumber: hexutil.Uint64(tail.BlockNumber), BlockHash: tail.BlockHash, LogIndex: &index}
+	case last < end:
+		header, err := api.backend.HeaderByNumber(ctx, rpc.BlockNumber(last))
+		if err != nil {
+			return nil, err
+		}
+		if header == nil {
+			return nil, fmt.Errorf("block #%d not found", last)
+		}
+		page.Cursor = &LogCursor{BlockNumber: hexutil.Uint64(last), BlockHash: header.Hash()}
+	}
+	page.Logs = returnLogs(logs)
+	return page, nil
+}
+
+// queryLogs runs a log query, enforcing the configured limits.
+func (api *PublicFilterAPI) queryLogs(ctx context.Context, crit FilterCriteria) ([]*types.Log, error) {
 	var filter *Filter
 	if crit.BlockHash != nil {
 		// Block filter requested, construct a single-shot filter
@@ -339,15 +499,67 @@ func (api *PublicFilterAPI) GetLogs(ctx context.Context, crit FilterCriteria) ([
 		if crit.ToBlock != nil {
 			end = crit.ToBlock.Int64()
 		}
+		if max := api.limits.MaxBlockRange; max > 0 {
+			var err error
+			if begin, end, err = api.resolveRange(ctx, crit); err != nil {
+				return nil, err
+			}
+			if begin <= end && uint64(end-begin) >= max {
+				return nil, fmt.Errorf("query exceeds maximum block range of %d blocks, use eth_getLogsPage", max)
+			}
+		}
 		// Construct the range filter
 		filter = NewRangeFilter(api.backend, begin, end, crit.Addresses, crit.Topics)
 	}
-	// Run the filter and return all the logs
+	// Run the filter and return all the logs, there is no need to look any
+	// further than one log past the limit.
+	if max := api.limits.MaxResults; max > 0 {
+		filter.limit = max + 1
+	}
 	logs, err := filter.Logs(ctx)
 	if err != nil {
 		return nil, err
 	}
-	return returnLogs(logs), err
+	if max := api.limits.MaxResults; max > 0 && len(logs) > max {
+		return nil, fmt.Errorf("query returned more than %d results, use eth_getLogsPage", max)
+	}
+	return returnLogs(logs), nil
+}
+
+// resolveRange converts the block range of the criteria into block numbers.
+// Unset bounds default to the latest block.
+func (api *PublicFilterAPI) resolveRange(ctx context.Context, crit FilterCriteria) (int64, int64, error) {
+	begin, end := rpc.LatestBlockNumber, rpc.LatestBlockNumber
+	if crit.FromBlock != nil {
+		begin = rpc.BlockNumber(crit.FromBlock.Int64())
+	}
+	if crit.ToBlock != nil {
+		end = rpc.BlockNumber(crit.ToBlock.Int64())
+	}
+	from, err := api.resolveBlockNumber(ctx, begin)
+	if err != nil {
+		return 0, 0, err
+	}
+	to, err := api.resolveBlockNumber(ctx, end)
+	if err != nil {
+		return 0, 0, err
+	}
+	return from, to, nil
+}
+
+// resolveBlockNumber converts a block number or tag into a block number.
+func (api *PublicFilterAPI) resolveBlockNumber(ctx context.Context, number rpc.BlockNumber) (int64, error) {
+	if number >= 0 {
+		return number.Int64(), nil
+	}
+	header, err := api.backend.HeaderByNumber(ctx, number)
+	if err != nil {
+		return 0, err
+	}
+	if header == nil {
+		return 0, errors.New("unknown block")
+	}
+	return header.Number.Int64(), nil
 }
 
 // UninstallFilter removes the filter with the given filter id.
@@ -380,29 +592,7 @@ func (api *PublicFilterAPI) GetFilterLogs(ctx context.Context, id rpc.ID) ([]*ty
 		return nil, fmt.Errorf("filter not found")
 	}
 
-	var filter *Filter
-	if f.crit.BlockHash != nil {
-		// Block filter requested, construct a single-shot filter
-		filter = NewBlockFilter(api.backend, *f.crit.BlockHash, f.crit.Addresses, f.crit.Topics)
-	} else {
-		// Convert the RPC block numbers into internal representations
-		begin := rpc.LatestBlockNumber.Int64()
-		if f.crit.FromBlock != nil {
-			begin = f.crit.FromBlock.Int64()
-		}
-		end := rpc.LatestBlockNumber.Int64()
-		if f.crit.ToBlock != nil {
-			end = f.crit.ToBlock.Int64()
-		}
-		// Construct the range filter
-		filter = NewRangeFilter(api.backend, begin, end, f.crit.Addresses, f.crit.Topics)
-	}
-	// Run the filter and return all the logs
-	logs, err := filter.Logs(ctx)
-	if err != nil {
-		return nil, err
-	}
-	return returnLogs(logs), nil
+	return api.queryLogs(ctx, f.crit)
 }
 
 // GetFilterChanges returns the logs for the filter with the given id since
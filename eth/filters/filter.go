@@ -20,10 +20,12 @@ import (
 	"context"
 	"errors"
 	"math/big"
+	"sort"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/bloombits"
+	"github.com/ethereum/go-ethereum/core/rawdb"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/event"
@@ -38,12 +40,14 @@ type Backend interface {
 	GetLogs(ctx context.Context, blockHash common.Hash) ([][]*types.Log, error)
 
 	SubscribeNewTxsEvent(chan<- core.NewTxsEvent) event.Subscription
+	SubscribeTxLifecycleEvent(chan<- core.TxLifecycleEvent) event.Subscription
 	SubscribeChainEvent(ch chan<- core.ChainEvent) event.Subscription
 	SubscribeRemovedLogsEvent(ch chan<- core.RemovedLogsEvent) event.Subscription
 	SubscribeLogsEvent(ch chan<- []*types.Log) event.Subscription
 	SubscribePendingLogsEvent(ch chan<- []*types.Log) event.Subscription
 
 	BloomStatus() (uint64, uint64)
+	LogIndexStatus() (uint64, uint64)
 	ServiceFilter(ctx context.Context, session *bloombits.MatcherSession)
 }
 
@@ -57,6 +61,7 @@ type Filter struct {
 
 	block      common.Hash // Block hash if filtering a single block
 	begin, end int64       // Range interval if filtering multiple blocks
+	limit      int         // Number of logs after which to stop searching (0 = no limit)
 
 	matcher *bloombits.Matcher
 }
@@ -142,30 +147,58 @@ func (f *Filter) Logs(ctx context.Context) ([]*types.Log, error) {
 	if f.end == -1 {
 		end = head
 	}
+	// Refuse ranges reaching into pruned history (the genesis is always kept)
+	if tail, err := f.db.Tail(); err == nil {
+		first := uint64(f.begin)
+		if first == 0 {
+			first = 1
+		}
+		if first < tail && first <= end {
+			return nil, core.ErrHistoryPruned
+		}
+	}
 	// Gather all indexed logs, and finish with non indexed ones
 	var (
 		logs []*types.Log
 		err  error
 	)
+	if f.constrained() {
+		size, sections := f.backend.LogIndexStatus()
+		if indexed := sections * size; indexed > uint64(f.begin) {
+			if indexed > end {
+				logs, err = f.logIndexedLogs(ctx, end, logs)
+			} else {
+				logs, err = f.logIndexedLogs(ctx, indexed-1, logs)
+			}
+			if err != nil || f.full(logs) {
+				return logs, err
+			}
+		}
+	}
 	size, sections := f.backend.BloomStatus()
 	if indexed := sections * size; indexed > uint64(f.begin) {
 		if indexed > end {
-			logs, err = f.indexedLogs(ctx, end)
+			logs, err = f.indexedLogs(ctx, end, logs)
 		} else {
-			logs, err = f.indexedLogs(ctx, indexed-1)
+			logs, err = f.indexedLogs(ctx, indexed-1, logs)
 		}
-		if err != nil {
+		if err != nil || f.full(logs) {
 			return logs, err
 		}
 	}
-	rest, err := f.unindexedLogs(ctx, end)
-	logs = append(logs, rest...)
-	return logs, err
+	return f.unindexedLogs(ctx, end, logs)
+}
+
+// full reports whether the given logs reach the limit of the filter, after
+// which the search is stopped. Logs are collected a block at a time, so the
+// limit might be overshot by the logs of the last block.
+func (f *Filter) full(logs []*types.Log) bool {
+	return f.limit > 0 && len(logs) >= f.limit
 }
 
-// indexedLogs returns the logs matching the filter criteria based on the bloom
+// indexedLogs appends the logs matching the filter criteria based on the bloom
 // bits indexed available locally or via the network.
-func (f *Filter) indexedLogs(ctx context.Context, end uint64) ([]*types.Log, error) {
+func (f *Filter) indexedLogs(ctx context.Context, end uint64, logs []*types.Log) ([]*types.Log, error) {
 	// Create a matcher session and request servicing from the backend
 	matches := make(chan uint64, 64)
 
@@ -178,8 +211,6 @@ func (f *Filter) indexedLogs(ctx context.Context, end uint64) ([]*types.Log, err
 	f.backend.ServiceFilter(ctx, session)
 
 	// Iterate over the matches until exhausted or context closed
-	var logs []*types.Log
-
 	for {
 		select {
 		case number, ok := <-matches:
@@ -202,7 +233,9 @@ func (f *Filter) indexedLogs(ctx context.Context, end uint64) ([]*types.Log, err
 			if err != nil {
 				return logs, err
 			}
-			logs = append(logs, found...)
+			if logs = append(logs, found...); f.full(logs) {
+				return logs, nil
+			}
 
 		case <-ctx.Done():
 			return logs, ctx.Err()
@@ -210,11 +243,168 @@ func (f *Filter) indexedLogs(ctx context.Context, end uint64) ([]*types.Log, err
 	}
 }
 
-// unindexedLogs returns the logs matching the filter criteria based on raw block
-// iteration and bloom matching.
-func (f *Filter) unindexedLogs(ctx context.Context, end uint64) ([]*types.Log, error) {
-	var logs []*types.Log
+// constrained reports whether the filter restricts the address or any topic of
+// the logs, which is required for a log index lookup.
+func (f *Filter) constrained() bool {
+	if len(f.addresses) > 0 {
+		return true
+	}
+	for _, topics := range f.topics {
+		if len(topics) > 0 {
+			return true
+		}
+	}
+	return false
+}
 
+// logIndexedLogs appends the logs matching the filter criteria based on the exact
+// log index. Lookups stop at the first section which is not consistent with the
+// canonical chain, leaving the rest of the range to the other retrieval methods.
+func (f *Filter) logIndexedLogs(ctx context.Context, end uint64, logs []*types.Log) ([]*types.Log, error) {
+	size, _ := f.backend.LogIndexStatus()
+
+	for section := uint64(f.begin) / size; f.begin <= int64(end); section++ {
+		if err := ctx.Err(); err != nil {
+			return logs, err
+		}
+		head := rawdb.ReadLogIndexHead(f.db, section)
+		if head == (common.Hash{}) || head != rawdb.ReadCanonicalHash(f.db, (section+1)*size-1) {
+			return logs, nil
+		}
+		last := (section+1)*size - 1
+		if last > end {
+			last = end
+		}
+		// Positions matching every constraint group are the candidates
+		var (
+			candidates []rawdb.LogPosition
+			first      = true
+		)
+		if len(f.addresses) > 0 {
+			var groups [][]rawdb.LogPosition
+			for _, address := range f.addresses {
+				groups = append(groups, rawdb.ReadLogIndexAddress(f.db, section, address))
+			}
+			candidates, first = unionPositions(groups), false
+		}
+		for i, topics := range f.topics {
+			if len(topics) == 0 {
+				continue
+			}
+			var groups [][]rawdb.LogPosition
+			for _, topic := range topics {
+				groups = append(groups, rawdb.ReadLogIndexTopic(f.db, section, i, topic))
+			}
+			positions := unionPositions(groups)
+			if first {
+				candidates, first = positions, false
+			} else {
+				candidates = intersectPositions(candidates, positions)
+			}
+			if len(candidates) == 0 {
+				break
+			}
+		}
+		// Resolve the candidates within the queried range block by block
+		for len(candidates) > 0 {
+			number := candidates[0].Block
+			n := 1
+			for n < len(candidates) && candidates[n].Block == number {
+				n++
+			}
+			block := candidates[:n]
+			candidates = candidates[n:]
+
+			if number < uint64(f.begin) {
+				continue
+			}
+			if number > last {
+				break
+			}
+			found, err := f.positionLogs(ctx, number, block)
+			if err != nil {
+				return logs, err
+			}
+			if logs = append(logs, found...); f.full(logs) {
+				f.begin = int64(number) + 1
+				return logs, nil
+			}
+		}
+		f.begin = int64(last) + 1
+	}
+	return logs, nil
+}
+
+// positionLogs retrieves the logs at the given positions of a canonical block,
+// dropping any which don't match the filter criteria.
+func (f *Filter) positionLogs(ctx context.Context, number uint64, positions []rawdb.LogPosition) ([]*types.Log, error) {
+	header, err := f.backend.HeaderByNumber(ctx, rpc.BlockNumber(number))
+	if header == nil || err != nil {
+		return nil, err
+	}
+	logsList, err := f.backend.GetLogs(ctx, header.Hash())
+	if err != nil {
+		return nil, err
+	}
+	var unfiltered []*types.Log
+	for _, logs := range logsList {
+		unfiltered = append(unfiltered, logs...)
+	}
+	var selected []*types.Log
+	for _, pos := range positions {
+		if pos.Index < uint(len(unfiltered)) {
+			selected = append(selected, unfiltered[pos.Index])
+		}
+	}
+	return filterLogs(selected, nil, nil, f.addresses, f.topics), nil
+}
+
+// unionPositions merges the given position lists into a single sorted list
+// without duplicates.
+func unionPositions(groups [][]rawdb.LogPosition) []rawdb.LogPosition {
+	var merged []rawdb.LogPosition
+	for _, group := range groups {
+		merged = append(merged, group...)
+	}
+	sort.Slice(merged, func(i, j int) bool { return positionLess(merged[i], merged[j]) })
+
+	out := merged[:0]
+	for i, pos := range merged {
+		if i == 0 || pos != merged[i-1] {
+			out = append(out, pos)
+		}
+	}
+	return out
+}
+
+// intersectPositions returns the positions contained in both sorted lists.
+func intersectPositions(a, b []rawdb.LogPosition) []rawdb.LogPosition {
+	var out []rawdb.LogPosition
+	for i, j := 0, 0; i < len(a) && j < len(b); {
+		switch {
+		case a[i] == b[j]:
+			out = append(out, a[i])
+			i++
+			j++
+		case positionLess(a[i], b[j]):
+			i++
+		default:
+			j++
+		}
+	}
+	return out
+}
+
+func positionLess(a, b rawdb.LogPosition) bool {
+	if a.Block != b.Block {
+		return a.Block < b.Block
+	}
+	return a.Index < b.Index
+}
+
+// unindexedLogs appends the logs matching the filter criteria based on raw block
+// iteration and bloom matching.
+func (f *Filter) unindexedLogs(ctx context.Context, end uint64, logs []*types.Log) ([]*types.Log, error) {
 	for ; f.begin <= int64(end); f.begin++ {
 		header, err := f.backend.HeaderByNumber(ctx, rpc.BlockNumber(f.begin))
 		if header == nil || err != nil {
@@ -224,7 +414,10 @@ func (f *Filter) unindexedLogs(ctx context.Context, end uint64) ([]*types.Log, e
 		if err != nil {
 			return logs, err
 		}
-		logs = append(logs, found...)
+		if logs = append(logs, found...); f.full(logs) {
+			f.begin++
+			return logs, nil
+		}
 	}
 	return logs, nil
 }
@@ -19,15 +19,20 @@ package filters
 import (
 	"context"
 	"math/big"
+	"reflect"
 	"testing"
+	"time"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/consensus/ethash"
 	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/rawdb"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/event"
 	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rpc"
 )
 
 func makeReceipt(addr common.Address) *types.Receipt {
@@ -217,3 +222,233 @@ func TestFilters(t *testing.T) {
 		t.Error("expected 0 log, got", len(logs))
 	}
 }
+
+// newLogTestChain creates a chain of the given length in which every block but the
+// genesis contains two logs emitted by addr.
+func newLogTestChain(t *testing.T, length int) (*testBackend, common.Address) {
+	var (
+		db      = rawdb.NewMemoryDatabase()
+		backend = &testBackend{db: db}
+		key, _  = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		addr    = crypto.PubkeyToAddress(key.PublicKey)
+	)
+	genesis := core.GenesisBlockForTesting(db, addr, big.NewInt(1000000))
+	chain, receipts := core.GenerateChain(params.TestChainConfig, genesis, ethash.NewFaker(), db, length, func(i int, gen *core.BlockGen) {
+		receipt := types.NewReceipt(nil, false, 0)
+		receipt.Logs = []*types.Log{{Address: addr}, {Address: addr}}
+		gen.AddUncheckedReceipt(receipt)
+		gen.AddUncheckedTx(types.NewTransaction(uint64(i), common.HexToAddress("0x1"), big.NewInt(1), 1, gen.BaseFee(), nil))
+	})
+	for i, block := range chain {
+		rawdb.WriteBlock(db, block)
+		rawdb.WriteCanonicalHash(db, block.Hash(), block.NumberU64())
+		rawdb.WriteHeadBlockHash(db, block.Hash())
+		rawdb.WriteReceipts(db, block.Hash(), block.NumberU64(), receipts[i])
+	}
+	return backend, addr
+}
+
+func TestGetLogsLimits(t *testing.T) {
+	backend, addr := newLogTestChain(t, 20)
+
+	tests := []struct {
+		limits   LogQueryLimits
+		from, to int64
+		want     int
+		fail     bool
+	}{
+		{limits: LogQueryLimits{}, from: 1, to: 20, want: 40},
+		{limits: LogQueryLimits{MaxBlockRange: 5}, from: 1, to: 20, fail: true},
+		{limits: LogQueryLimits{MaxBlockRange: 5}, from: 2, to: 6, want: 10},
+		{limits: LogQueryLimits{MaxBlockRange: 5}, from: 16, to: rpc.LatestBlockNumber.Int64(), want: 10},
+		{limits: LogQueryLimits{MaxBlockRange: 5}, from: 15, to: rpc.LatestBlockNumber.Int64(), fail: true},
+		{limits: LogQueryLimits{MaxResults: 4}, from: 1, to: 2, want: 4},
+		{limits: LogQueryLimits{MaxResults: 4}, from: 1, to: 3, fail: true},
+	}
+	for i, test := range tests {
+		api := NewPublicFilterAPI(backend, false, deadline, test.limits)
+		crit := FilterCriteria{FromBlock: big.NewInt(test.from), ToBlock: big.NewInt(test.to), Addresses: []common.Address{addr}}
+		logs, err := api.GetLogs(context.Background(), crit)
+		if test.fail {
+			if err == nil {
+				t.Errorf("test %d: expected error, got %d logs", i, len(logs))
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("test %d: unexpected error: %v", i, err)
+		} else if len(logs) != test.want {
+			t.Errorf("test %d: wrong number of logs: have %d, want %d", i, len(logs), test.want)
+		}
+	}
+}
+
+func TestFilterLimit(t *testing.T) {
+	backend, addr := newLogTestChain(t, 20)
+
+	// Logs are collected a block at a time, so the limit is rounded up to the
+	// logs of whole blocks.
+	for limit, want := range map[int]int{0: 40, 1: 2, 3: 4, 4: 4, 39: 40, 50: 40} {
+		filter := NewRangeFilter(backend, 1, 20, []common.Address{addr}, nil)
+		filter.limit = limit
+
+		logs, err := filter.Logs(context.Background())
+		if err != nil {
+			t.Fatalf("limit %d: filter failed: %v", limit, err)
+		}
+		if len(logs) != want {
+			t.Errorf("limit %d: wrong number of logs: have %d, want %d", limit, len(logs), want)
+		}
+	}
+}
+
+func TestGetLogsPage(t *testing.T) {
+	backend, addr := newLogTestChain(t, 20)
+
+	crit := FilterCriteria{FromBlock: big.NewInt(1), Addresses: []common.Address{addr}}
+	want, err := NewPublicFilterAPI(backend, false, deadline, LogQueryLimits{}).GetLogs(context.Background(), crit)
+	if err != nil {
+		t.Fatal("unpaginated query failed:", err)
+	}
+	tests := []struct {
+		limits   LogQueryLimits
+		pageSize uint
+		pages    int
+	}{
+		{limits: LogQueryLimits{}, pages: 1},
+		{limits: LogQueryLimits{MaxBlockRange: 4}, pages: 5},
+		{limits: LogQueryLimits{MaxResults: 3}, pages: 14},
+		{limits: LogQueryLimits{MaxBlockRange: 3, MaxResults: 4}, pages: 10},
+		{limits: LogQueryLimits{MaxResults: 10}, pageSize: 1, pages: 40},
+	}
+	for i, test := range tests {
+		var (
+			api    = NewPublicFilterAPI(backend, false, deadline, test.limits)
+			logs   []*types.Log
+			cursor *LogCursor
+			pages  int
+			limit  *hexutil.Uint
+		)
+		if test.pageSize > 0 {
+			limit = (*hexutil.Uint)(&test.pageSize)
+		}
+		for {
+			page, err := api.GetLogsPage(context.Background(), crit, cursor, limit)
+			if err != nil {
+				t.Fatalf("test %d: page %d failed: %v", i, pages, err)
+			}
+			pages++
+			logs = append(logs, page.Logs...)
+			if page.Cursor == nil {
+				break
+			}
+			cursor = page.Cursor
+		}
+		if pages != test.pages {
+			t.Errorf("test %d: wrong number of pages: have %d, want %d", i, pages, test.pages)
+		}
+		if !reflect.DeepEqual(logs, want) {
+			t.Errorf("test %d: paginated logs differ from unpaginated query: have %d, want %d logs", i, len(logs), len(want))
+		}
+	}
+
+	// Check that a cursor pointing at a non-canonical block is rejected.
+	api := NewPublicFilterAPI(backend, false, deadline, LogQueryLimits{MaxBlockRange: 4})
+	page, err := api.GetLogsPage(context.Background(), crit, nil, nil)
+	if err != nil || page.Cursor == nil {
+		t.Fatalf("first page failed: %v", err)
+	}
+	page.Cursor.BlockHash = common.Hash{0x01}
+	if _, err := api.GetLogsPage(context.Background(), crit, page.Cursor, nil); err == nil {
+		t.Error("expected error for non-canonical cursor")
+	}
+}
+
+// staticChain is a core.ChainIndexerChain with a fixed head.
+type staticChain struct {
+	head *types.Header
+	feed event.Feed
+}
+
+func (c *staticChain) CurrentHeader() *types.Header { return c.head }
+
+func (c *staticChain) SubscribeChainHeadEvent(ch chan<- core.ChainHeadEvent) event.Subscription {
+	return c.feed.Subscribe(ch)
+}
+
+func TestLogIndexFilter(t *testing.T) {
+	var (
+		db      = rawdb.NewMemoryDatabase()
+		key, _  = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		addr    = crypto.PubkeyToAddress(key.PublicKey)
+		addrs   = []common.Address{common.HexToAddress("0x1"), common.HexToAddress("0x2"), common.HexToAddress("0x3")}
+		topics  = []common.Hash{common.HexToHash("0x11"), common.HexToHash("0x22"), common.HexToHash("0x33")}
+		size    = uint64(8)
+		indexed = uint64(5)
+	)
+	genesis := core.GenesisBlockForTesting(db, addr, big.NewInt(1000000))
+	chain, receipts := core.GenerateChain(params.TestChainConfig, genesis, ethash.NewFaker(), db, 44, func(i int, gen *core.BlockGen) {
+		receipt := types.NewReceipt(nil, false, 0)
+		for j := 0; j < i%4; j++ {
+			receipt.Logs = append(receipt.Logs, &types.Log{
+				Address: addrs[(i+j)%len(addrs)],
+				Topics:  []common.Hash{topics[i%len(topics)], topics[(i+j)%len(topics)]},
+			})
+		}
+		gen.AddUncheckedReceipt(receipt)
+		gen.AddUncheckedTx(types.NewTransaction(uint64(i), common.HexToAddress("0x1"), big.NewInt(1), 1, gen.BaseFee(), nil))
+	})
+	for i, block := range chain {
+		rawdb.WriteBlock(db, block)
+		rawdb.WriteCanonicalHash(db, block.Hash(), block.NumberU64())
+		rawdb.WriteHeadBlockHash(db, block.Hash())
+		rawdb.WriteReceipts(db, block.Hash(), block.NumberU64(), receipts[i])
+	}
+	indexer := core.NewLogIndexer(db, size, 0)
+	defer indexer.Close()
+	indexer.Start(&staticChain{head: chain[indexed*size-1].Header()})
+
+	for start := time.Now(); ; time.Sleep(10 * time.Millisecond) {
+		if sections, _, _ := indexer.Sections(); sections == indexed {
+			break
+		}
+		if time.Since(start) > 5*time.Second {
+			t.Fatal("log index not built in time")
+		}
+	}
+	// Mark the second to last indexed section stale, lookups must fall back to
+	// the bloom filters from there on.
+	rawdb.WriteLogIndexHead(db, indexed-2, common.Hash{0x01})
+
+	var (
+		plain = &testBackend{db: db}
+		index = &testBackend{db: db, logSize: size, logSections: indexed}
+	)
+	tests := []struct {
+		begin, end int64
+		addresses  []common.Address
+		topics     [][]common.Hash
+	}{
+		{0, -1, []common.Address{addrs[0]}, nil},
+		{3, 30, []common.Address{addrs[1], addrs[2]}, nil},
+		{0, -1, nil, [][]common.Hash{{topics[1]}}},
+		{5, 17, nil, [][]common.Hash{nil, {topics[0], topics[2]}}},
+		{0, -1, []common.Address{addrs[2]}, [][]common.Hash{{topics[0]}, {topics[1]}}},
+		{10, 20, []common.Address{addrs[0]}, [][]common.Hash{{topics[2]}}},
+		{0, 1, []common.Address{common.HexToAddress("0x4")}, nil},
+		{0, -1, nil, [][]common.Hash{nil, nil}},
+	}
+	for i, test := range tests {
+		want, err := NewRangeFilter(plain, test.begin, test.end, test.addresses, test.topics).Logs(context.Background())
+		if err != nil {
+			t.Fatalf("test %d: bloom lookup failed: %v", i, err)
+		}
+		have, err := NewRangeFilter(index, test.begin, test.end, test.addresses, test.topics).Logs(context.Background())
+		if err != nil {
+			t.Fatalf("test %d: index lookup failed: %v", i, err)
+		}
+		if !reflect.DeepEqual(have, want) {
+			t.Errorf("test %d: log mismatch: have %d logs, want %d", i, len(have), len(want))
+		}
+	}
+}
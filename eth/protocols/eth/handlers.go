@@ -256,7 +256,8 @@ func ServiceGetNodeDataQuery(chain *core.BlockChain, query GetNodeDataPacket) []
 			lookups >= 2*maxNodeDataServe {
 			break
 		}
-		// Retrieve the requested state entry
+		// Retrieve the requested state entry. Trie nodes can't be looked up by
+		// hash under the path scheme, only contract codes are served then.
 		entry, err := chain.TrieNode(hash)
 		if len(entry) == 0 || err != nil {
 			// Read the contract code with prefix only to save unnecessary lookups.
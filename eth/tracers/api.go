@@ -20,6 +20,7 @@ import (
 	"bufio"
 	"bytes"
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"os"
@@ -60,6 +61,10 @@ const (
 	// For non-archive nodes, this limit _will_ be overblown, as disk-backed tries
 	// will only be found every ~15K blocks or so.
 	defaultTracechainMemLimit = common.StorageSize(500 * 1024 * 1024)
+
+	// maxTraceCallMany is the maximum number of calls that can be traced in a
+	// single traceCallMany request.
+	maxTraceCallMany = 256
 )
 
 // Backend interface provides the common API services (that are provided by
@@ -169,6 +174,9 @@ type TraceConfig struct {
 	Tracer  *string
 	Timeout *string
 	Reexec  *uint64
+	// Config specific to given tracer. Note struct logger
+	// config are historically embedded in main object.
+	TracerConfig json.RawMessage
 }
 
 // TraceCallConfig is the config for traceCall API. It holds one more
@@ -178,7 +186,25 @@ type TraceCallConfig struct {
 	Tracer         *string
 	Timeout        *string
 	Reexec         *uint64
+	TracerConfig   json.RawMessage
 	StateOverrides *ethapi.StateOverride
+	// Index of the transaction within the block to execute the call(s) on
+	// top of. If unset, the call(s) are executed after the whole block.
+	TxIndex *hexutil.Uint
+}
+
+// traceConfig returns the trace function config of the call config.
+func (config *TraceCallConfig) traceConfig() *TraceConfig {
+	if config == nil {
+		return nil
+	}
+	return &TraceConfig{
+		Config:       config.Config,
+		Tracer:       config.Tracer,
+		Timeout:      config.Timeout,
+		Reexec:       config.Reexec,
+		TracerConfig: config.TracerConfig,
+	}
 }
 
 // StdTraceConfig holds extra parameters to standard-json trace functions.
@@ -808,6 +834,76 @@ func (api *API) TraceTransaction(ctx context.Context, hash common.Hash, config *
 // top of the provided block and returns them as a JSON object.
 // You can provide -2 as a block number to trace on top of the pending block.
 func (api *API) TraceCall(ctx context.Context, args ethapi.TransactionArgs, blockNrOrHash rpc.BlockNumberOrHash, config *TraceCallConfig) (interface{}, error) {
+	block, vmctx, statedb, err := api.callState(ctx, blockNrOrHash, config)
+	if err != nil {
+		return nil, err
+	}
+	// Execute the trace
+	msg, err := args.ToMessage(api.backend.RPCGasCap(), block.BaseFee())
+	if err != nil {
+		return nil, err
+	}
+	return api.traceTx(ctx, msg, new(Context), vmctx, statedb, config.traceConfig())
+}
+
+// TraceCallMany lets you trace a sequence of eth_calls, each of them executed
+// on top of the state changes made by the previous ones. Like TraceCall, the
+// calls are applied on top of the provided block, or right before the
+// transaction at the configured index within it. One trace result is returned
+// per call, a call failing to execute does not abort the remaining ones.
+//
+// The RPC gas cap, or the gas limit of the block if no cap is configured,
+// applies to the sequence as a whole, not to the individual calls: once the
+// calls have used up all of it, the request is aborted.
+func (api *API) TraceCallMany(ctx context.Context, args []ethapi.TransactionArgs, blockNrOrHash rpc.BlockNumberOrHash, config *TraceCallConfig) ([]*txTraceResult, error) {
+	if len(args) > maxTraceCallMany {
+		return nil, fmt.Errorf("too many calls: %d > %d", len(args), maxTraceCallMany)
+	}
+	block, vmctx, statedb, err := api.callState(ctx, blockNrOrHash, config)
+	if err != nil {
+		return nil, err
+	}
+	var (
+		traceConfig = config.traceConfig()
+		deleteEmpty = api.backend.ChainConfig().IsEIP158(block.Number())
+		results     = make([]*txTraceResult, len(args))
+		gasCap      = api.backend.RPCGasCap()
+	)
+	if gasCap == 0 {
+		gasCap = block.GasLimit()
+	}
+	gp := new(core.GasPool).AddGas(gasCap)
+	for i, call := range args {
+		// Cap the call by whatever is left of the gas allowance. The pool
+		// is charged for the gas used by each call, so it tracks the gas
+		// left for the remainder of the sequence.
+		if gp.Gas() == 0 {
+			return nil, fmt.Errorf("call %d: gas allowance of %d exhausted", i, gasCap)
+		}
+		msg, err := call.ToMessage(gp.Gas(), block.BaseFee())
+		if err != nil {
+			results[i] = &txTraceResult{Error: err.Error()}
+			continue
+		}
+		res, err := api.traceTxWithGasPool(ctx, msg, new(Context), vmctx, statedb, traceConfig, gp)
+		if err != nil {
+			results[i] = &txTraceResult{Error: err.Error()}
+			continue
+		}
+		results[i] = &txTraceResult{Result: res}
+
+		// Make the state changes visible to the next call
+		statedb.Finalise(deleteEmpty)
+	}
+	return results, nil
+}
+
+// callState retrieves the block specified for a call trace, along with the
+// block context and state the call should be executed in. If a transaction
+// index is configured, the state right before that transaction is returned,
+// otherwise the state after the whole block. Any configured state overrides
+// are applied on top.
+func (api *API) callState(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash, config *TraceCallConfig) (*types.Block, vm.BlockContext, *state.StateDB, error) {
 	// Try to retrieve the specified block
 	var (
 		err   error
@@ -818,49 +914,48 @@ func (api *API) TraceCall(ctx context.Context, args ethapi.TransactionArgs, bloc
 	} else if number, ok := blockNrOrHash.Number(); ok {
 		block, err = api.blockByNumber(ctx, number)
 	} else {
-		return nil, errors.New("invalid arguments; neither block nor hash specified")
+		return nil, vm.BlockContext{}, nil, errors.New("invalid arguments; neither block nor hash specified")
 	}
 	if err != nil {
-		return nil, err
+		return nil, vm.BlockContext{}, nil, err
 	}
 	// try to recompute the state
 	reexec := defaultTraceReexec
 	if config != nil && config.Reexec != nil {
 		reexec = *config.Reexec
 	}
-	statedb, err := api.backend.StateAtBlock(ctx, block, reexec, nil, true, false)
-	if err != nil {
-		return nil, err
-	}
-	// Apply the customized state rules if required.
-	if config != nil {
-		if err := config.StateOverrides.Apply(statedb); err != nil {
-			return nil, err
+	var statedb *state.StateDB
+	if config != nil && config.TxIndex != nil {
+		if block.NumberU64() == 0 {
+			return nil, vm.BlockContext{}, nil, errors.New("genesis is not traceable")
 		}
+		_, _, statedb, err = api.backend.StateAtTransaction(ctx, block, int(*config.TxIndex), reexec)
+	} else {
+		statedb, err = api.backend.StateAtBlock(ctx, block, reexec, nil, true, false)
 	}
-	// Execute the trace
-	msg, err := args.ToMessage(api.backend.RPCGasCap(), block.BaseFee())
 	if err != nil {
-		return nil, err
+		return nil, vm.BlockContext{}, nil, err
 	}
 	vmctx := core.NewEVMBlockContext(block.Header(), api.chainContext(ctx), nil)
-
-	var traceConfig *TraceConfig
+	// Apply the customized state rules if required.
 	if config != nil {
-		traceConfig = &TraceConfig{
-			Config:  config.Config,
-			Tracer:  config.Tracer,
-			Timeout: config.Timeout,
-			Reexec:  config.Reexec,
+		if err := config.StateOverrides.Apply(statedb); err != nil {
+			return nil, vm.BlockContext{}, nil, err
 		}
 	}
-	return api.traceTx(ctx, msg, new(Context), vmctx, statedb, traceConfig)
+	return block, vmctx, statedb, nil
 }
 
 // traceTx configures a new tracer according to the provided configuration, and
 // executes the given message in the provided environment. The return value will
 // be tracer dependent.
 func (api *API) traceTx(ctx context.Context, message core.Message, txctx *Context, vmctx vm.BlockContext, statedb *state.StateDB, config *TraceConfig) (interface{}, error) {
+	return api.traceTxWithGasPool(ctx, message, txctx, vmctx, statedb, config, new(core.GasPool).AddGas(message.Gas()))
+}
+
+// traceTxWithGasPool is like traceTx, but charges the gas used by the message
+// to the given gas pool.
+func (api *API) traceTxWithGasPool(ctx context.Context, message core.Message, txctx *Context, vmctx vm.BlockContext, statedb *state.StateDB, config *TraceConfig, gp *core.GasPool) (interface{}, error) {
 	var (
 		tracer    Tracer
 		err       error
@@ -873,7 +968,7 @@ func (api *API) traceTx(ctx context.Context, message core.Message, txctx *Contex
 	// Default tracer is the struct logger
 	tracer = logger.NewStructLogger(config.Config)
 	if config.Tracer != nil {
-		tracer, err = New(*config.Tracer, txctx)
+		tracer, err = New(*config.Tracer, txctx, config.TracerConfig)
 		if err != nil {
 			return nil, err
 		}
@@ -897,7 +992,7 @@ func (api *API) traceTx(ctx context.Context, message core.Message, txctx *Contex
 	vmenv := vm.NewEVM(vmctx, txContext, statedb, api.backend.ChainConfig(), vm.Config{Debug: true, Tracer: tracer, NoBaseFee: true})
 	// Call Prepare to clear out the statedb access list
 	statedb.Prepare(txctx.TxHash, txctx.TxIndex)
-	if _, err = core.ApplyMessage(vmenv, message, new(core.GasPool).AddGas(message.Gas())); err != nil {
+	if _, err = core.ApplyMessage(vmenv, message, gp); err != nil {
 		return nil, fmt.Errorf("tracing failed: %w", err)
 	}
 	return tracer.GetResult()
@@ -913,5 +1008,11 @@ func APIs(backend Backend) []rpc.API {
 			Service:   NewAPI(backend),
 			Public:    false,
 		},
+		{
+			Namespace: "trace",
+			Version:   "1.0",
+			Service:   NewTraceAPI(backend),
+			Public:    false,
+		},
 	}
 }
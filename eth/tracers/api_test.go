@@ -26,6 +26,7 @@ import (
 	"math/big"
 	"reflect"
 	"sort"
+	"strings"
 	"testing"
 	"time"
 
@@ -57,6 +58,7 @@ type testBackend struct {
 	engine      consensus.Engine
 	chaindb     ethdb.Database
 	chain       *core.BlockChain
+	gasCap      uint64
 }
 
 func newTestBackend(t *testing.T, n int, gspec *core.Genesis, generator func(i int, b *core.BlockGen)) *testBackend {
@@ -64,6 +66,7 @@ func newTestBackend(t *testing.T, n int, gspec *core.Genesis, generator func(i i
 		chainConfig: params.TestChainConfig,
 		engine:      ethash.NewFaker(),
 		chaindb:     rawdb.NewMemoryDatabase(),
+		gasCap:      25000000,
 	}
 	// Generate blocks for testing
 	gspec.Config = backend.chainConfig
@@ -124,7 +127,7 @@ func (b *testBackend) GetTransaction(ctx context.Context, txHash common.Hash) (*
 }
 
 func (b *testBackend) RPCGasCap() uint64 {
-	return 25000000
+	return b.gasCap
 }
 
 func (b *testBackend) ChainConfig() *params.ChainConfig {
@@ -311,6 +314,174 @@ func TestTraceCall(t *testing.T) {
 	}
 }
 
+func TestTraceCallMany(t *testing.T) {
+	t.Parallel()
+
+	// Initialize test accounts, the last one is left unfunded
+	accounts := newAccounts(3)
+	genesis := &core.Genesis{Alloc: core.GenesisAlloc{
+		accounts[0].addr: {Balance: big.NewInt(params.Ether)},
+		accounts[1].addr: {Balance: big.NewInt(params.Ether)},
+	}}
+	genBlocks := 2
+	signer := types.HomesteadSigner{}
+	api := NewAPI(newTestBackend(t, genBlocks, genesis, func(i int, b *core.BlockGen) {
+		// Transfer from account[0] to account[1]
+		//    value: 1000 wei
+		//    fee:   0 wei
+		tx, _ := types.SignTx(types.NewTransaction(uint64(i), accounts[1].addr, big.NewInt(1000), params.TxGas, b.BaseFee(), nil), signer, accounts[0].key)
+		b.AddTx(tx)
+	}))
+	var (
+		fund = ethapi.TransactionArgs{
+			From:  &accounts[0].addr,
+			To:    &accounts[2].addr,
+			Value: (*hexutil.Big)(big.NewInt(1000)),
+		}
+		spend = ethapi.TransactionArgs{
+			From:  &accounts[2].addr,
+			To:    &accounts[1].addr,
+			Value: (*hexutil.Big)(big.NewInt(1000)),
+		}
+		drain = ethapi.TransactionArgs{
+			From:  &accounts[0].addr,
+			To:    &accounts[1].addr,
+			Value: (*hexutil.Big)(big.NewInt(params.Ether)),
+		}
+		first = hexutil.Uint(0)
+		last  = hexutil.Uint(1)
+	)
+	var testSuite = []struct {
+		blockNumber rpc.BlockNumber
+		calls       []ethapi.TransactionArgs
+		config      *TraceCallConfig
+		expectErr   error
+		expectFail  []bool
+	}{
+		// Each call sees the state changes of the previous ones
+		{
+			blockNumber: rpc.LatestBlockNumber,
+			calls:       []ethapi.TransactionArgs{fund, spend},
+			expectFail:  []bool{false, false},
+		},
+		// A failing call doesn't abort the remaining ones
+		{
+			blockNumber: rpc.LatestBlockNumber,
+			calls:       []ethapi.TransactionArgs{spend, fund, spend},
+			expectFail:  []bool{true, false, false},
+		},
+		// Calls on top of the whole block see the block's transfers
+		{
+			blockNumber: rpc.BlockNumber(1),
+			calls:       []ethapi.TransactionArgs{drain},
+			expectFail:  []bool{true},
+		},
+		// Calls on top of a transaction index only see the preceding transfers
+		{
+			blockNumber: rpc.BlockNumber(1),
+			calls:       []ethapi.TransactionArgs{drain},
+			config:      &TraceCallConfig{TxIndex: &first},
+			expectFail:  []bool{false},
+		},
+		// Transaction index out of range, error expects
+		{
+			blockNumber: rpc.BlockNumber(1),
+			calls:       []ethapi.TransactionArgs{drain},
+			config:      &TraceCallConfig{TxIndex: &last},
+			expectErr:   errors.New("transaction index 1 out of range for block"),
+		},
+	}
+	for i, testspec := range testSuite {
+		results, err := api.TraceCallMany(context.Background(), testspec.calls, rpc.BlockNumberOrHash{BlockNumber: &testspec.blockNumber}, testspec.config)
+		if testspec.expectErr != nil {
+			if err == nil {
+				t.Errorf("test %d: expect error %v, get nothing", i, testspec.expectErr)
+			} else if !strings.HasPrefix(err.Error(), testspec.expectErr.Error()) {
+				t.Errorf("test %d: error mismatch, want %v, get %v", i, testspec.expectErr, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("test %d: expect no error, get %v", i, err)
+			continue
+		}
+		if len(results) != len(testspec.calls) {
+			t.Errorf("test %d: result count mismatch, want %d, get %d", i, len(testspec.calls), len(results))
+			continue
+		}
+		for j, res := range results {
+			if failed := res.Error != ""; failed != testspec.expectFail[j] {
+				t.Errorf("test %d, call %d: failure mismatch, want %v, get %v (%s)", i, j, testspec.expectFail[j], failed, res.Error)
+				continue
+			}
+			if res.Error != "" {
+				continue
+			}
+			var have *logger.ExecutionResult
+			if err := json.Unmarshal(res.Result.(json.RawMessage), &have); err != nil {
+				t.Errorf("test %d, call %d: failed to unmarshal result %v", i, j, err)
+			}
+			if have.Failed || have.Gas != params.TxGas {
+				t.Errorf("test %d, call %d: unexpected result %v", i, j, have)
+			}
+		}
+	}
+}
+
+func TestTraceCallManyLimits(t *testing.T) {
+	t.Parallel()
+
+	accounts := newAccounts(2)
+	genesis := &core.Genesis{Alloc: core.GenesisAlloc{
+		accounts[0].addr: {Balance: big.NewInt(params.Ether)},
+	}}
+	backend := newTestBackend(t, 1, genesis, func(i int, b *core.BlockGen) {})
+	backend.gasCap = 2 * params.TxGas
+	api := NewAPI(backend)
+
+	var (
+		latest   = rpc.BlockNumberOrHashWithNumber(rpc.LatestBlockNumber)
+		transfer = ethapi.TransactionArgs{
+			From:  &accounts[0].addr,
+			To:    &accounts[1].addr,
+			Value: (*hexutil.Big)(big.NewInt(1000)),
+		}
+	)
+	// Calls fitting into the gas cap together must all succeed
+	results, err := api.TraceCallMany(context.Background(), []ethapi.TransactionArgs{transfer, transfer}, latest, nil)
+	if err != nil {
+		t.Fatalf("failed to trace calls within the gas cap: %v", err)
+	}
+	for i, res := range results {
+		if res.Error != "" {
+			t.Errorf("call %d: unexpected failure: %s", i, res.Error)
+		}
+	}
+	// The gas cap must be enforced across the whole sequence
+	_, err = api.TraceCallMany(context.Background(), []ethapi.TransactionArgs{transfer, transfer, transfer}, latest, nil)
+	if err == nil || !strings.Contains(err.Error(), "call 2: gas allowance") {
+		t.Errorf("exceeded gas cap: have error %v", err)
+	}
+	// The number of calls must be capped
+	calls := make([]ethapi.TransactionArgs, maxTraceCallMany+1)
+	if _, err := api.TraceCallMany(context.Background(), calls, latest, nil); err == nil || !strings.HasPrefix(err.Error(), "too many calls") {
+		t.Errorf("too many calls: have error %v", err)
+	}
+	// Without a gas cap, the block gas limit must be enforced instead
+	genesis.GasLimit = 2 * params.TxGas
+	backend = newTestBackend(t, 1, genesis, func(i int, b *core.BlockGen) {})
+	backend.gasCap = 0
+	api = NewAPI(backend)
+
+	if _, err := api.TraceCallMany(context.Background(), []ethapi.TransactionArgs{transfer, transfer}, latest, nil); err != nil {
+		t.Fatalf("failed to trace calls within the block gas limit: %v", err)
+	}
+	_, err = api.TraceCallMany(context.Background(), []ethapi.TransactionArgs{transfer, transfer, transfer}, latest, nil)
+	if err == nil || !strings.Contains(err.Error(), "call 2: gas allowance") {
+		t.Errorf("exceeded block gas limit: have error %v", err)
+	}
+}
+
 func TestTraceTransaction(t *testing.T) {
 	t.Parallel()
 
@@ -546,6 +717,110 @@ func TestTracingWithOverrides(t *testing.T) {
 	}
 }
 
+func TestParityStateDiff(t *testing.T) {
+	t.Parallel()
+
+	// Pre and post states as reported by the prestate tracer in diff mode:
+	// 0x01 is modified, 0x02 is destructed and 0x03 is created.
+	var prestate struct {
+		Pre  map[common.Address]*prestateDiffAccount `json:"pre"`
+		Post map[common.Address]*prestateDiffAccount `json:"post"`
+	}
+	input := `{
+		"pre": {
+			"0x0000000000000000000000000000000000000001": {"balance": "0x10", "nonce": 1, "code": "0x", "storage": {"0x0000000000000000000000000000000000000000000000000000000000000001": "0x0000000000000000000000000000000000000000000000000000000000000001"}},
+			"0x0000000000000000000000000000000000000002": {"balance": "0x5", "nonce": 0, "code": "0x60"}
+		},
+		"post": {
+			"0x0000000000000000000000000000000000000001": {"balance": "0x8", "storage": {"0x0000000000000000000000000000000000000000000000000000000000000002": "0x0000000000000000000000000000000000000000000000000000000000000002"}},
+			"0x0000000000000000000000000000000000000003": {"balance": "0x3", "nonce": 1, "code": "0x6001"}
+		}
+	}`
+	if err := json.Unmarshal([]byte(input), &prestate); err != nil {
+		t.Fatalf("failed to unmarshal prestate: %v", err)
+	}
+	want := `{
+		"0x0000000000000000000000000000000000000001": {
+			"balance": {"*": {"from": "0x10", "to": "0x8"}},
+			"code": "=",
+			"nonce": "=",
+			"storage": {
+				"0x0000000000000000000000000000000000000000000000000000000000000001": {"*": {"from": "0x0000000000000000000000000000000000000000000000000000000000000001", "to": "0x0000000000000000000000000000000000000000000000000000000000000000"}},
+				"0x0000000000000000000000000000000000000000000000000000000000000002": {"*": {"from": "0x0000000000000000000000000000000000000000000000000000000000000000", "to": "0x0000000000000000000000000000000000000000000000000000000000000002"}}
+			}
+		},
+		"0x0000000000000000000000000000000000000002": {
+			"balance": {"-": "0x5"},
+			"code": {"-": "0x60"},
+			"nonce": {"-": "0x0"},
+			"storage": {}
+		},
+		"0x0000000000000000000000000000000000000003": {
+			"balance": {"+": "0x3"},
+			"code": {"+": "0x6001"},
+			"nonce": {"+": "0x1"},
+			"storage": {}
+		}
+	}`
+	have, err := json.Marshal(parityStateDiff(prestate.Pre, prestate.Post))
+	if err != nil {
+		t.Fatalf("failed to marshal state diff: %v", err)
+	}
+	if !jsonEqual(t, have, []byte(want)) {
+		t.Errorf("state diff mismatch:\nhave %s\nwant %s", have, want)
+	}
+}
+
+func TestFilterFlatTrace(t *testing.T) {
+	t.Parallel()
+
+	var (
+		a = common.HexToAddress("0x01")
+		b = common.HexToAddress("0x02")
+		c = common.HexToAddress("0x03")
+
+		call    = json.RawMessage(`{"action":{"callType":"call","from":"0x0000000000000000000000000000000000000001","to":"0x0000000000000000000000000000000000000002"},"type":"call"}`)
+		create  = json.RawMessage(`{"action":{"from":"0x0000000000000000000000000000000000000001"},"result":{"address":"0x0000000000000000000000000000000000000003"},"type":"create"}`)
+		suicide = json.RawMessage(`{"action":{"address":"0x0000000000000000000000000000000000000002","refundAddress":"0x0000000000000000000000000000000000000003"},"type":"suicide"}`)
+	)
+	var tests = []struct {
+		trace    json.RawMessage
+		from, to []common.Address
+		want     bool
+	}{
+		{call, nil, nil, true},
+		{call, []common.Address{a}, nil, true},
+		{call, []common.Address{b}, nil, false},
+		{call, nil, []common.Address{b, c}, true},
+		{call, []common.Address{a}, []common.Address{c}, false},
+		{create, nil, []common.Address{c}, true},
+		{create, []common.Address{c}, nil, false},
+		{suicide, []common.Address{b}, []common.Address{c}, true},
+		{suicide, []common.Address{a}, nil, false},
+	}
+	for i, tt := range tests {
+		have, err := filterFlatTrace(tt.trace, addressSet(tt.from), addressSet(tt.to))
+		if err != nil {
+			t.Fatalf("test %d: failed to filter trace: %v", i, err)
+		}
+		if have != tt.want {
+			t.Errorf("test %d: match mismatch: have %v, want %v", i, have, tt.want)
+		}
+	}
+}
+
+// jsonEqual reports whether the two json blobs are semantically equal.
+func jsonEqual(t *testing.T, x, y []byte) bool {
+	var xv, yv interface{}
+	if err := json.Unmarshal(x, &xv); err != nil {
+		t.Fatalf("failed to unmarshal json: %v", err)
+	}
+	if err := json.Unmarshal(y, &yv); err != nil {
+		t.Fatalf("failed to unmarshal json: %v", err)
+	}
+	return reflect.DeepEqual(xv, yv)
+}
+
 type Account struct {
 	key  *ecdsa.PrivateKey
 	addr common.Address
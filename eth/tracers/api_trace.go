@@ -0,0 +1,515 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package tracers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+const (
+	// maxTraceFilterBlockRange is the maximum number of blocks trace_filter is
+	// willing to re-execute in a single request.
+	maxTraceFilterBlockRange = 1000
+
+	// Names of the native tracers backing the trace namespace.
+	flatCallTracerName = "flatCallTracer"
+	prestateTracerName = "prestateTracer"
+	muxTracerName      = "muxTracer"
+
+	// Parity trace types accepted by the replay methods.
+	traceTypeTrace     = "trace"
+	traceTypeStateDiff = "stateDiff"
+	traceTypeVmTrace   = "vmTrace"
+)
+
+// flatCallTracerConfig is the tracer config used for all trace namespace
+// methods, reporting errors the way Parity/OpenEthereum did.
+var flatCallTracerConfig = json.RawMessage(`{"convertParityErrors":true}`)
+
+// TraceAPI is the collection of Parity/OpenEthereum compatible tracing APIs
+// exposed over the trace namespace. All methods are built on top of the
+// native flatCallTracer.
+type TraceAPI struct {
+	api *API
+}
+
+// NewTraceAPI creates a new API definition for the trace namespace.
+func NewTraceAPI(backend Backend) *TraceAPI {
+	return &TraceAPI{api: NewAPI(backend)}
+}
+
+// TraceFilterArgs represents the arguments for the trace_filter method.
+type TraceFilterArgs struct {
+	FromBlock   *rpc.BlockNumber `json:"fromBlock"`
+	ToBlock     *rpc.BlockNumber `json:"toBlock"`
+	FromAddress []common.Address `json:"fromAddress"`
+	ToAddress   []common.Address `json:"toAddress"`
+	After       *uint64          `json:"after"`
+	Count       *uint64          `json:"count"`
+}
+
+// TraceReplayResult is the result of replaying a single transaction with the
+// requested trace types. Fields for trace types that were not requested are
+// reported as null.
+type TraceReplayResult struct {
+	Output          hexutil.Bytes                        `json:"output"`
+	StateDiff       map[common.Address]*StateDiffAccount `json:"stateDiff"`
+	Trace           []json.RawMessage                    `json:"trace"`
+	VmTrace         interface{}                          `json:"vmTrace"`
+	TransactionHash *common.Hash                         `json:"transactionHash,omitempty"`
+}
+
+// StateDiffAccount is the Parity-style state difference of a single account.
+// Each field is either the string "=" if unchanged, or an object with one of
+// the keys "+" (born), "-" (died) or "*" (changed, holding "from" and "to").
+type StateDiffAccount struct {
+	Balance interface{}                 `json:"balance"`
+	Code    interface{}                 `json:"code"`
+	Nonce   interface{}                 `json:"nonce"`
+	Storage map[common.Hash]interface{} `json:"storage"`
+}
+
+// Block returns the flat call traces of all transactions in the given block.
+func (api *TraceAPI) Block(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash) ([]json.RawMessage, error) {
+	block, err := api.blockByNumberOrHash(ctx, blockNrOrHash)
+	if err != nil {
+		return nil, err
+	}
+	return api.traceBlock(ctx, block)
+}
+
+// Transaction returns the flat call traces of the given transaction.
+func (api *TraceAPI) Transaction(ctx context.Context, hash common.Hash) (interface{}, error) {
+	tracer := flatCallTracerName
+	return api.api.TraceTransaction(ctx, hash, &TraceConfig{Tracer: &tracer, TracerConfig: flatCallTracerConfig})
+}
+
+// Filter returns the flat call traces within the given block range matching
+// the sender and recipient filters. An empty address filter matches all traces,
+// if both are given a trace has to satisfy both of them.
+func (api *TraceAPI) Filter(ctx context.Context, args TraceFilterArgs) ([]json.RawMessage, error) {
+	from, err := api.resolveBlockNumber(ctx, args.FromBlock)
+	if err != nil {
+		return nil, err
+	}
+	to, err := api.resolveBlockNumber(ctx, args.ToBlock)
+	if err != nil {
+		return nil, err
+	}
+	if from > to {
+		return nil, fmt.Errorf("invalid block range: from %d is after to %d", from, to)
+	}
+	if to-from >= maxTraceFilterBlockRange {
+		return nil, fmt.Errorf("block range too large: %d, max %d", to-from+1, maxTraceFilterBlockRange)
+	}
+	var (
+		fromAddrs = addressSet(args.FromAddress)
+		toAddrs   = addressSet(args.ToAddress)
+		skip      uint64
+		results   = []json.RawMessage{}
+	)
+	if args.After != nil {
+		skip = *args.After
+	}
+	for number := from; number <= to; number++ {
+		// Don't bother tracing more blocks if the results are already full
+		if args.Count != nil && uint64(len(results)) >= *args.Count {
+			break
+		}
+		// The genesis block has no transactions to trace
+		if number == 0 {
+			continue
+		}
+		block, err := api.api.blockByNumber(ctx, rpc.BlockNumber(number))
+		if err != nil {
+			return nil, err
+		}
+		if len(block.Transactions()) == 0 {
+			continue
+		}
+		traces, err := api.traceBlock(ctx, block)
+		if err != nil {
+			return nil, err
+		}
+		for _, trace := range traces {
+			match, err := filterFlatTrace(trace, fromAddrs, toAddrs)
+			if err != nil {
+				return nil, err
+			}
+			if !match {
+				continue
+			}
+			if skip > 0 {
+				skip--
+				continue
+			}
+			if args.Count != nil && uint64(len(results)) >= *args.Count {
+				return results, nil
+			}
+			results = append(results, trace)
+		}
+	}
+	return results, nil
+}
+
+// ReplayBlockTransactions replays all transactions of the given block and
+// returns the requested trace types for each of them. Supported trace types
+// are "trace" and "stateDiff", "vmTrace" is accepted but always null.
+func (api *TraceAPI) ReplayBlockTransactions(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash, traceTypes []string) ([]*TraceReplayResult, error) {
+	config, err := replayTraceConfig(traceTypes)
+	if err != nil {
+		return nil, err
+	}
+	block, err := api.blockByNumberOrHash(ctx, blockNrOrHash)
+	if err != nil {
+		return nil, err
+	}
+	if block.NumberU64() == 0 {
+		return []*TraceReplayResult{}, nil
+	}
+	results, err := api.api.traceBlock(ctx, block, config)
+	if err != nil {
+		return nil, err
+	}
+	var (
+		txs     = block.Transactions()
+		replays = make([]*TraceReplayResult, len(results))
+	)
+	for i, res := range results {
+		if res.Error != "" {
+			return nil, fmt.Errorf("tracing transaction %d of block %d failed: %s", i, block.NumberU64(), res.Error)
+		}
+		if replays[i], err = newTraceReplayResult(res.Result, traceTypes); err != nil {
+			return nil, err
+		}
+		hash := txs[i].Hash()
+		replays[i].TransactionHash = &hash
+	}
+	return replays, nil
+}
+
+// ReplayTransaction replays the given transaction and returns the requested
+// trace types.
+func (api *TraceAPI) ReplayTransaction(ctx context.Context, hash common.Hash, traceTypes []string) (*TraceReplayResult, error) {
+	config, err := replayTraceConfig(traceTypes)
+	if err != nil {
+		return nil, err
+	}
+	res, err := api.api.TraceTransaction(ctx, hash, config)
+	if err != nil {
+		return nil, err
+	}
+	return newTraceReplayResult(res, traceTypes)
+}
+
+// blockByNumberOrHash retrieves the block specified either by number or hash.
+func (api *TraceAPI) blockByNumberOrHash(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash) (*types.Block, error) {
+	if hash, ok := blockNrOrHash.Hash(); ok {
+		return api.api.blockByHash(ctx, hash)
+	}
+	if number, ok := blockNrOrHash.Number(); ok {
+		return api.api.blockByNumber(ctx, number)
+	}
+	return nil, errors.New("invalid arguments; neither block nor hash specified")
+}
+
+// resolveBlockNumber converts the given block number or tag into an absolute
+// block number, defaulting to the latest block.
+func (api *TraceAPI) resolveBlockNumber(ctx context.Context, number *rpc.BlockNumber) (uint64, error) {
+	if number != nil && *number >= 0 {
+		return uint64(*number), nil
+	}
+	tag := rpc.LatestBlockNumber
+	if number != nil {
+		tag = *number
+	}
+	header, err := api.api.backend.HeaderByNumber(ctx, tag)
+	if err != nil {
+		return 0, err
+	}
+	if header == nil {
+		return 0, fmt.Errorf("block #%d not found", tag)
+	}
+	return header.Number.Uint64(), nil
+}
+
+// traceBlock runs the flat call tracer on all transactions of the block and
+// concatenates the produced traces.
+func (api *TraceAPI) traceBlock(ctx context.Context, block *types.Block) ([]json.RawMessage, error) {
+	traces := []json.RawMessage{}
+	if block.NumberU64() == 0 {
+		return traces, nil
+	}
+	tracer := flatCallTracerName
+	results, err := api.api.traceBlock(ctx, block, &TraceConfig{Tracer: &tracer, TracerConfig: flatCallTracerConfig})
+	if err != nil {
+		return nil, err
+	}
+	for i, res := range results {
+		if res.Error != "" {
+			return nil, fmt.Errorf("tracing transaction %d of block %d failed: %s", i, block.NumberU64(), res.Error)
+		}
+		var frames []json.RawMessage
+		if err := unmarshalTraceResult(res.Result, &frames); err != nil {
+			return nil, err
+		}
+		traces = append(traces, frames...)
+	}
+	return traces, nil
+}
+
+// replayTraceConfig validates the requested trace types and assembles the
+// mux tracer config producing them.
+func replayTraceConfig(traceTypes []string) (*TraceConfig, error) {
+	mux := make(map[string]json.RawMessage)
+	for _, typ := range traceTypes {
+		switch typ {
+		case traceTypeTrace:
+			mux[flatCallTracerName] = flatCallTracerConfig
+		case traceTypeStateDiff:
+			mux[prestateTracerName] = json.RawMessage(`{"diffMode":true}`)
+		case traceTypeVmTrace:
+			// Not supported, reported as null
+		default:
+			return nil, fmt.Errorf("unknown trace type: %q", typ)
+		}
+	}
+	// The output of the call is always reported, so run the call tracer even
+	// if no call traces were requested.
+	if _, ok := mux[flatCallTracerName]; !ok {
+		mux[flatCallTracerName] = flatCallTracerConfig
+	}
+	cfg, err := json.Marshal(mux)
+	if err != nil {
+		return nil, err
+	}
+	tracer := muxTracerName
+	return &TraceConfig{Tracer: &tracer, TracerConfig: cfg}, nil
+}
+
+// newTraceReplayResult assembles a replay result from the output of the mux
+// tracer, filling in the requested trace types only.
+func newTraceReplayResult(result interface{}, traceTypes []string) (*TraceReplayResult, error) {
+	var mux struct {
+		Calls    []json.RawMessage `json:"flatCallTracer"`
+		Prestate *struct {
+			Pre  map[common.Address]*prestateDiffAccount `json:"pre"`
+			Post map[common.Address]*prestateDiffAccount `json:"post"`
+		} `json:"prestateTracer"`
+	}
+	if err := unmarshalTraceResult(result, &mux); err != nil {
+		return nil, err
+	}
+	replay := &TraceReplayResult{Output: hexutil.Bytes{}}
+	if len(mux.Calls) > 0 {
+		var top struct {
+			Result *struct {
+				Output hexutil.Bytes `json:"output"`
+			} `json:"result"`
+		}
+		if err := json.Unmarshal(mux.Calls[0], &top); err != nil {
+			return nil, err
+		}
+		if top.Result != nil && top.Result.Output != nil {
+			replay.Output = top.Result.Output
+		}
+	}
+	for _, typ := range traceTypes {
+		switch typ {
+		case traceTypeTrace:
+			replay.Trace = mux.Calls
+		case traceTypeStateDiff:
+			if mux.Prestate != nil {
+				replay.StateDiff = parityStateDiff(mux.Prestate.Pre, mux.Prestate.Post)
+			}
+		}
+	}
+	return replay, nil
+}
+
+// unmarshalTraceResult decodes the raw output of a tracer into v.
+func unmarshalTraceResult(result interface{}, v interface{}) error {
+	raw, ok := result.(json.RawMessage)
+	if !ok {
+		return fmt.Errorf("unexpected trace result type %T", result)
+	}
+	return json.Unmarshal(raw, v)
+}
+
+// flatTraceAddresses holds the fields of a flat call trace that the
+// trace_filter address filters are matched against.
+type flatTraceAddresses struct {
+	Action struct {
+		From          *common.Address `json:"from"`
+		To            *common.Address `json:"to"`
+		Address       *common.Address `json:"address"`
+		RefundAddress *common.Address `json:"refundAddress"`
+	} `json:"action"`
+	Result *struct {
+		Address *common.Address `json:"address"`
+	} `json:"result"`
+}
+
+// filterFlatTrace reports whether the given flat call trace matches the sender
+// and recipient filters. The sender of a suicide is the destructed contract,
+// and its recipient the refund address. The recipient of a contract creation
+// is the created contract.
+func filterFlatTrace(trace json.RawMessage, fromAddrs, toAddrs map[common.Address]struct{}) (bool, error) {
+	if len(fromAddrs) == 0 && len(toAddrs) == 0 {
+		return true, nil
+	}
+	var frame flatTraceAddresses
+	if err := json.Unmarshal(trace, &frame); err != nil {
+		return false, err
+	}
+	from := frame.Action.From
+	if from == nil {
+		from = frame.Action.Address
+	}
+	to := frame.Action.To
+	if to == nil {
+		to = frame.Action.RefundAddress
+	}
+	if to == nil && frame.Result != nil {
+		to = frame.Result.Address
+	}
+	return matchAddress(from, fromAddrs) && matchAddress(to, toAddrs), nil
+}
+
+func matchAddress(addr *common.Address, set map[common.Address]struct{}) bool {
+	if len(set) == 0 {
+		return true
+	}
+	if addr == nil {
+		return false
+	}
+	_, ok := set[*addr]
+	return ok
+}
+
+func addressSet(addrs []common.Address) map[common.Address]struct{} {
+	set := make(map[common.Address]struct{}, len(addrs))
+	for _, addr := range addrs {
+		set[addr] = struct{}{}
+	}
+	return set
+}
+
+// prestateDiffAccount is an account entry as reported by the prestate tracer
+// in diff mode. Absent fields are unchanged.
+type prestateDiffAccount struct {
+	Balance *hexutil.Big                `json:"balance"`
+	Nonce   *uint64                     `json:"nonce"`
+	Code    *hexutil.Bytes              `json:"code"`
+	Storage map[common.Hash]common.Hash `json:"storage"`
+}
+
+// diffChange is the "*" entry of a Parity-style state diff.
+type diffChange struct {
+	From interface{} `json:"from"`
+	To   interface{} `json:"to"`
+}
+
+// parityStateDiff converts the pre and post states reported by the prestate
+// tracer in diff mode into a Parity-style state diff. Accounts only present
+// in the post state were created, accounts only present in the pre state were
+// destructed.
+func parityStateDiff(pre, post map[common.Address]*prestateDiffAccount) map[common.Address]*StateDiffAccount {
+	diff := make(map[common.Address]*StateDiffAccount)
+	for addr, prev := range pre {
+		next, ok := post[addr]
+		if !ok {
+			diff[addr] = accountDiff(prev, "-")
+			continue
+		}
+		account := &StateDiffAccount{
+			Balance: "=",
+			Code:    "=",
+			Nonce:   "=",
+			Storage: make(map[common.Hash]interface{}),
+		}
+		if next.Balance != nil {
+			account.Balance = map[string]interface{}{"*": diffChange{From: orZeroBig(prev.Balance), To: next.Balance}}
+		}
+		if next.Nonce != nil {
+			account.Nonce = map[string]interface{}{"*": diffChange{From: orZeroUint64(prev.Nonce), To: hexutil.Uint64(*next.Nonce)}}
+		}
+		if next.Code != nil {
+			account.Code = map[string]interface{}{"*": diffChange{From: orEmptyBytes(prev.Code), To: next.Code}}
+		}
+		// Slots cleared by the transaction are absent from the post state,
+		// slots populated by it are absent from the pre state.
+		for key, val := range prev.Storage {
+			account.Storage[key] = map[string]interface{}{"*": diffChange{From: val, To: next.Storage[key]}}
+		}
+		for key, val := range next.Storage {
+			if _, ok := prev.Storage[key]; !ok {
+				account.Storage[key] = map[string]interface{}{"*": diffChange{From: common.Hash{}, To: val}}
+			}
+		}
+		diff[addr] = account
+	}
+	for addr, next := range post {
+		if _, ok := pre[addr]; !ok {
+			diff[addr] = accountDiff(next, "+")
+		}
+	}
+	return diff
+}
+
+// accountDiff reports all fields of the account as born ("+") or died ("-").
+func accountDiff(account *prestateDiffAccount, op string) *StateDiffAccount {
+	diff := &StateDiffAccount{
+		Balance: map[string]interface{}{op: orZeroBig(account.Balance)},
+		Code:    map[string]interface{}{op: orEmptyBytes(account.Code)},
+		Nonce:   map[string]interface{}{op: orZeroUint64(account.Nonce)},
+		Storage: make(map[common.Hash]interface{}),
+	}
+	for key, val := range account.Storage {
+		diff.Storage[key] = map[string]interface{}{op: val}
+	}
+	return diff
+}
+
+func orZeroBig(n *hexutil.Big) *hexutil.Big {
+	if n == nil {
+		return new(hexutil.Big)
+	}
+	return n
+}
+
+func orZeroUint64(n *uint64) hexutil.Uint64 {
+	if n == nil {
+		return 0
+	}
+	return hexutil.Uint64(*n)
+}
+
+func orEmptyBytes(b *hexutil.Bytes) *hexutil.Bytes {
+	if b == nil {
+		return &hexutil.Bytes{}
+	}
+	return b
+}
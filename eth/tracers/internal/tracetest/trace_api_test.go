@@ -0,0 +1,165 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package tracetest
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/eth"
+	"github.com/ethereum/go-ethereum/eth/ethconfig"
+	"github.com/ethereum/go-ethereum/eth/tracers"
+	"github.com/ethereum/go-ethereum/node"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// flatTrace is the subset of a trace namespace call frame checked by the tests.
+type flatTrace struct {
+	Action struct {
+		From  common.Address `json:"from"`
+		To    common.Address `json:"to"`
+		Value *hexutil.Big   `json:"value"`
+	} `json:"action"`
+	BlockNumber     uint64      `json:"blockNumber"`
+	TransactionHash common.Hash `json:"transactionHash"`
+	Type            string      `json:"type"`
+}
+
+// Tests that the trace namespace produces the expected flat call traces when
+// served by a full node over RPC.
+func TestTraceNamespace(t *testing.T) {
+	var (
+		key, _  = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		addr    = crypto.PubkeyToAddress(key.PublicKey)
+		alice   = common.HexToAddress("0xa11ce")
+		bob     = common.HexToAddress("0xb0b")
+		genesis = &core.Genesis{
+			Config: params.AllEthashProtocolChanges,
+			Alloc:  core.GenesisAlloc{addr: {Balance: big.NewInt(params.Ether)}},
+		}
+		signer = types.LatestSigner(genesis.Config)
+		txs    []*types.Transaction
+	)
+	// Generate a chain with two transfers in block #1, one in block #2 and
+	// an empty block #3.
+	db := rawdb.NewMemoryDatabase()
+	blocks, _ := core.GenerateChain(genesis.Config, genesis.MustCommit(db), ethash.NewFaker(), db, 3, func(i int, b *core.BlockGen) {
+		var recipients []common.Address
+		switch i {
+		case 0:
+			recipients = []common.Address{alice, bob}
+		case 1:
+			recipients = []common.Address{alice}
+		}
+		for _, to := range recipients {
+			tx := types.MustSignNewTx(key, signer, &types.LegacyTx{
+				Nonce:    b.TxNonce(addr),
+				To:       &to,
+				Value:    big.NewInt(int64(len(txs) + 1)),
+				Gas:      params.TxGas,
+				GasPrice: b.BaseFee(),
+			})
+			b.AddTx(tx)
+			txs = append(txs, tx)
+		}
+	})
+	// Serve the chain from a full node with the trace namespace enabled.
+	stack, err := node.New(&node.Config{})
+	if err != nil {
+		t.Fatalf("failed to create node: %v", err)
+	}
+	defer stack.Close()
+
+	config := &ethconfig.Config{Genesis: genesis}
+	config.Ethash.PowMode = ethash.ModeFake
+	backend, err := eth.New(stack, config)
+	if err != nil {
+		t.Fatalf("failed to create ethereum service: %v", err)
+	}
+	stack.RegisterAPIs(tracers.APIs(backend.APIBackend))
+	if err := stack.Start(); err != nil {
+		t.Fatalf("failed to start node: %v", err)
+	}
+	if _, err := backend.BlockChain().InsertChain(blocks); err != nil {
+		t.Fatalf("failed to import chain: %v", err)
+	}
+	client, err := stack.Attach()
+	if err != nil {
+		t.Fatalf("failed to attach to node: %v", err)
+	}
+	defer client.Close()
+
+	// check verifies that the given traces are the top level calls of the
+	// given transactions, in order.
+	check := func(name string, have []flatTrace, want []*types.Transaction) {
+		t.Helper()
+		if len(have) != len(want) {
+			t.Fatalf("%s: trace count mismatch: have %d, want %d", name, len(have), len(want))
+		}
+		for i, tx := range want {
+			trace := have[i]
+			if trace.TransactionHash != tx.Hash() {
+				t.Errorf("%s: trace %d: transaction mismatch: have %x, want %x", name, i, trace.TransactionHash, tx.Hash())
+			}
+			if trace.Type != "call" || trace.Action.From != addr || trace.Action.To != *tx.To() {
+				t.Errorf("%s: trace %d: action mismatch: have %s %x->%x, want call %x->%x", name, i, trace.Type, trace.Action.From, trace.Action.To, addr, *tx.To())
+			}
+			if trace.Action.Value == nil || trace.Action.Value.ToInt().Cmp(tx.Value()) != 0 {
+				t.Errorf("%s: trace %d: value mismatch: have %v, want %v", name, i, trace.Action.Value, tx.Value())
+			}
+		}
+	}
+	// Block traces must contain all transactions of the block.
+	for number, want := range map[uint64][]*types.Transaction{1: txs[:2], 2: txs[2:], 3: nil} {
+		var traces []flatTrace
+		if err := client.Call(&traces, "trace_block", hexutil.Uint64(number)); err != nil {
+			t.Fatalf("trace_block %d failed: %v", number, err)
+		}
+		check("trace_block", traces, want)
+	}
+	// Filtered traces must honour the address filters and the pagination.
+	var (
+		from     = rpc.BlockNumber(1)
+		latest   = rpc.LatestBlockNumber
+		zero     = uint64(0)
+		one      = uint64(1)
+		filterBy = func(args tracers.TraceFilterArgs) []flatTrace {
+			t.Helper()
+			args.FromBlock, args.ToBlock = &from, &latest
+
+			var traces []flatTrace
+			if err := client.Call(&traces, "trace_filter", args); err != nil {
+				t.Fatalf("trace_filter failed: %v", err)
+			}
+			return traces
+		}
+	)
+	check("all", filterBy(tracers.TraceFilterArgs{}), txs)
+	check("to alice", filterBy(tracers.TraceFilterArgs{ToAddress: []common.Address{alice}}), []*types.Transaction{txs[0], txs[2]})
+	check("from alice", filterBy(tracers.TraceFilterArgs{FromAddress: []common.Address{alice}}), nil)
+	check("after 1", filterBy(tracers.TraceFilterArgs{After: &one}), txs[1:])
+	check("after 1, count 1", filterBy(tracers.TraceFilterArgs{After: &one, Count: &one}), txs[1:2])
+	check("count 0", filterBy(tracers.TraceFilterArgs{Count: &zero}), nil)
+}
@@ -0,0 +1,409 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package native
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/eth/tracers"
+)
+
+func init() {
+	register("flatCallTracer", newFlatCallTracer)
+}
+
+var parityErrorMapping = map[string]string{
+	"contract creation code storage out of gas": "Out of gas",
+	"out of gas":                      "Out of gas",
+	"gas uint64 overflow":             "Out of gas",
+	"max code size exceeded":          "Out of gas",
+	"invalid jump destination":        "Bad jump destination",
+	"execution reverted":              "Reverted",
+	"return data out of bounds":       "Out of bounds",
+	"stack limit reached 1024 (1023)": "Out of stack",
+	"precompiled failed":              "Built-in failed",
+	"invalid input length":            "Built-in failed",
+}
+
+var parityErrorMappingStartingWith = map[string]string{
+	"invalid opcode:": "Bad instruction",
+	"stack underflow": "Stack underflow",
+}
+
+// flatCallFrame is a standalone callframe in the Parity/OpenEthereum trace format.
+type flatCallFrame struct {
+	Action              flatCallAction  `json:"action"`
+	BlockHash           *common.Hash    `json:"blockHash"`
+	BlockNumber         uint64          `json:"blockNumber"`
+	Error               string          `json:"error,omitempty"`
+	Result              *flatCallResult `json:"result,omitempty"`
+	Subtraces           int             `json:"subtraces"`
+	TraceAddress        []int           `json:"traceAddress"`
+	TransactionHash     *common.Hash    `json:"transactionHash"`
+	TransactionPosition int             `json:"transactionPosition"`
+	Type                string          `json:"type"`
+}
+
+type flatCallAction struct {
+	SelfDestructed *common.Address `json:"address,omitempty"`
+	Balance        *hexutil.Big    `json:"balance,omitempty"`
+	CallType       string          `json:"callType,omitempty"`
+	CreationMethod string          `json:"creationMethod,omitempty"`
+	From           *common.Address `json:"from,omitempty"`
+	Gas            *hexutil.Uint64 `json:"gas,omitempty"`
+	Init           *hexutil.Bytes  `json:"init,omitempty"`
+	Input          *hexutil.Bytes  `json:"input,omitempty"`
+	RefundAddress  *common.Address `json:"refundAddress,omitempty"`
+	To             *common.Address `json:"to,omitempty"`
+	Value          *hexutil.Big    `json:"value,omitempty"`
+}
+
+type flatCallResult struct {
+	Address *common.Address `json:"address,omitempty"`
+	Code    *hexutil.Bytes  `json:"code,omitempty"`
+	GasUsed *hexutil.Uint64 `json:"gasUsed,omitempty"`
+	Output  *hexutil.Bytes  `json:"output,omitempty"`
+}
+
+// flatCallTracer reports call frames of a tx in a flat format, i.e.
+// as opposed to the nested format of `callTracer`.
+type flatCallTracer struct {
+	tracer            *callTracer
+	config            flatCallTracerConfig
+	ctx               *tracers.Context // Holds tracer context data
+	blockNumber       uint64           // Number of the block the tx is executed in
+	activePrecompiles []common.Address // Updated on CaptureStart based on given rules
+}
+
+type flatCallTracerConfig struct {
+	ConvertParityErrors bool `json:"convertParityErrors"` // If true, call tracer converts errors to parity format
+	IncludePrecompiles  bool `json:"includePrecompiles"`  // If true, call tracer includes calls to precompiled contracts
+}
+
+// newFlatCallTracer returns a new flatCallTracer.
+func newFlatCallTracer(ctx *tracers.Context, cfg json.RawMessage) (tracers.Tracer, error) {
+	var config flatCallTracerConfig
+	if cfg != nil {
+		if err := json.Unmarshal(cfg, &config); err != nil {
+			return nil, err
+		}
+	}
+	// The flat tracer needs the full call tree, so the inner
+	// call tracer always runs with its default config.
+	tracer, err := newCallTracer(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &flatCallTracer{tracer: tracer.(*callTracer), ctx: ctx, config: config}, nil
+}
+
+// CaptureStart implements the EVMLogger interface to initialize the tracing operation.
+func (t *flatCallTracer) CaptureStart(env *vm.EVM, from common.Address, to common.Address, create bool, input []byte, gas uint64, value *big.Int) {
+	t.tracer.CaptureStart(env, from, to, create, input, gas, value)
+
+	t.blockNumber = env.Context.BlockNumber.Uint64()
+
+	// Update list of precompiles based on current block
+	rules := env.ChainConfig().Rules(env.Context.BlockNumber, env.Context.Random != nil)
+	t.activePrecompiles = vm.ActivePrecompiles(rules)
+}
+
+// CaptureEnd is called after the call finishes to finalize the tracing.
+func (t *flatCallTracer) CaptureEnd(output []byte, gasUsed uint64, d time.Duration, err error) {
+	t.tracer.CaptureEnd(output, gasUsed, d, err)
+}
+
+// CaptureState implements the EVMLogger interface to trace a single step of VM execution.
+func (t *flatCallTracer) CaptureState(pc uint64, op vm.OpCode, gas, cost uint64, scope *vm.ScopeContext, rData []byte, depth int, err error) {
+	t.tracer.CaptureState(pc, op, gas, cost, scope, rData, depth, err)
+}
+
+// CaptureFault implements the EVMLogger interface to trace an execution fault.
+func (t *flatCallTracer) CaptureFault(pc uint64, op vm.OpCode, gas, cost uint64, scope *vm.ScopeContext, depth int, err error) {
+	t.tracer.CaptureFault(pc, op, gas, cost, scope, depth, err)
+}
+
+// CaptureEnter is called when EVM enters a new scope (via call, create or selfdestruct).
+func (t *flatCallTracer) CaptureEnter(typ vm.OpCode, from common.Address, to common.Address, input []byte, gas uint64, value *big.Int) {
+	t.tracer.CaptureEnter(typ, from, to, input, gas, value)
+}
+
+// CaptureExit is called when EVM exits a scope, even if the scope didn't
+// execute any code.
+func (t *flatCallTracer) CaptureExit(output []byte, gasUsed uint64, err error) {
+	t.tracer.CaptureExit(output, gasUsed, err)
+
+	if t.config.IncludePrecompiles {
+		return
+	}
+	// Drop the frame just added to the parent if it was a call into a precompile
+	size := len(t.tracer.callstack)
+	if size == 0 {
+		return
+	}
+	parent := &t.tracer.callstack[size-1]
+	if len(parent.Calls) == 0 {
+		return
+	}
+	last := parent.Calls[len(parent.Calls)-1]
+	if last.Type != vm.CALL.String() && last.Type != vm.STATICCALL.String() {
+		return
+	}
+	if t.isPrecompiled(common.HexToAddress(last.To)) {
+		parent.Calls = parent.Calls[:len(parent.Calls)-1]
+	}
+}
+
+func (t *flatCallTracer) CaptureTxStart(gasLimit uint64) {
+	t.tracer.CaptureTxStart(gasLimit)
+}
+
+func (t *flatCallTracer) CaptureTxEnd(restGas uint64) {
+	t.tracer.CaptureTxEnd(restGas)
+}
+
+// GetResult returns the json-encoded list of flat call traces, and any
+// error arising from the encoding or forceful termination (via `Stop`).
+func (t *flatCallTracer) GetResult() (json.RawMessage, error) {
+	if len(t.tracer.callstack) != 1 {
+		return nil, errors.New("incorrect number of top-level calls")
+	}
+	flat, err := t.flatFromNested(&t.tracer.callstack[0], []int{})
+	if err != nil {
+		return nil, err
+	}
+	res, err := json.Marshal(flat)
+	if err != nil {
+		return nil, err
+	}
+	return res, t.tracer.reason
+}
+
+// Stop terminates execution of the tracer at the first opportune moment.
+func (t *flatCallTracer) Stop(err error) {
+	t.tracer.Stop(err)
+}
+
+// isPrecompiled returns whether the addr is a precompile.
+func (t *flatCallTracer) isPrecompiled(addr common.Address) bool {
+	for _, p := range t.activePrecompiles {
+		if p == addr {
+			return true
+		}
+	}
+	return false
+}
+
+// flatFromNested converts the given nested call frame and all its children
+// into a depth-first ordered list of flat frames.
+func (t *flatCallTracer) flatFromNested(input *callFrame, traceAddress []int) ([]flatCallFrame, error) {
+	var (
+		frame *flatCallFrame
+		err   error
+	)
+	switch input.Type {
+	case vm.CREATE.String(), vm.CREATE2.String():
+		frame, err = newFlatCreate(input)
+	case vm.SELFDESTRUCT.String():
+		frame, err = newFlatSuicide(input)
+	case vm.CALL.String(), vm.STATICCALL.String(), vm.CALLCODE.String(), vm.DELEGATECALL.String():
+		frame, err = newFlatCall(input)
+	default:
+		return nil, fmt.Errorf("unrecognized call frame type: %s", input.Type)
+	}
+	if err != nil {
+		return nil, err
+	}
+	frame.Error = input.Error
+	if t.config.ConvertParityErrors {
+		frame.Error = convertErrorToParity(frame.Error)
+	}
+	frame.Subtraces = len(input.Calls)
+	frame.TraceAddress = traceAddress
+	frame.BlockNumber = t.blockNumber
+	if t.ctx != nil {
+		if t.ctx.BlockHash != (common.Hash{}) {
+			hash := t.ctx.BlockHash
+			frame.BlockHash = &hash
+		}
+		if t.ctx.TxHash != (common.Hash{}) {
+			hash := t.ctx.TxHash
+			frame.TransactionHash = &hash
+		}
+		frame.TransactionPosition = t.ctx.TxIndex
+	}
+	// Revert output contains useful information (revert reason).
+	// Otherwise discard result.
+	if input.Error != "" && input.Error != vm.ErrExecutionReverted.Error() {
+		frame.Result = nil
+	}
+	output := []flatCallFrame{*frame}
+	for i := range input.Calls {
+		childAddr := make([]int, len(traceAddress), len(traceAddress)+1)
+		copy(childAddr, traceAddress)
+		childAddr = append(childAddr, i)
+
+		flat, err := t.flatFromNested(&input.Calls[i], childAddr)
+		if err != nil {
+			return nil, err
+		}
+		output = append(output, flat...)
+	}
+	return output, nil
+}
+
+func newFlatCreate(input *callFrame) (*flatCallFrame, error) {
+	from, gas, value, err := decodeFrameCommon(input)
+	if err != nil {
+		return nil, err
+	}
+	gasUsed, init, code, err := decodeFrameResult(input)
+	if err != nil {
+		return nil, err
+	}
+	frame := &flatCallFrame{
+		Type: "create",
+		Action: flatCallAction{
+			CreationMethod: strings.ToLower(input.Type),
+			From:           &from,
+			Gas:            &gas,
+			Value:          value,
+			Init:           &init,
+		},
+		Result: &flatCallResult{
+			GasUsed: &gasUsed,
+			Code:    &code,
+		},
+	}
+	if input.To != "" {
+		addr := common.HexToAddress(input.To)
+		frame.Result.Address = &addr
+	}
+	return frame, nil
+}
+
+func newFlatCall(input *callFrame) (*flatCallFrame, error) {
+	from, gas, value, err := decodeFrameCommon(input)
+	if err != nil {
+		return nil, err
+	}
+	gasUsed, data, output, err := decodeFrameResult(input)
+	if err != nil {
+		return nil, err
+	}
+	to := common.HexToAddress(input.To)
+	return &flatCallFrame{
+		Type: "call",
+		Action: flatCallAction{
+			CallType: strings.ToLower(input.Type),
+			From:     &from,
+			To:       &to,
+			Gas:      &gas,
+			Value:    value,
+			Input:    &data,
+		},
+		Result: &flatCallResult{
+			GasUsed: &gasUsed,
+			Output:  &output,
+		},
+	}, nil
+}
+
+func newFlatSuicide(input *callFrame) (*flatCallFrame, error) {
+	var (
+		addr    = common.HexToAddress(input.From)
+		refund  = common.HexToAddress(input.To)
+		balance = new(hexutil.Big)
+	)
+	if input.Value != "" {
+		if err := balance.UnmarshalText([]byte(input.Value)); err != nil {
+			return nil, err
+		}
+	}
+	return &flatCallFrame{
+		Type: "suicide",
+		Action: flatCallAction{
+			SelfDestructed: &addr,
+			Balance:        balance,
+			RefundAddress:  &refund,
+		},
+	}, nil
+}
+
+// decodeFrameCommon decodes the sender, gas allowance and value of a frame.
+func decodeFrameCommon(input *callFrame) (common.Address, hexutil.Uint64, *hexutil.Big, error) {
+	gas, err := hexutil.DecodeUint64(input.Gas)
+	if err != nil {
+		return common.Address{}, 0, nil, err
+	}
+	value := new(hexutil.Big)
+	if input.Value != "" {
+		if err := value.UnmarshalText([]byte(input.Value)); err != nil {
+			return common.Address{}, 0, nil, err
+		}
+	}
+	return common.HexToAddress(input.From), hexutil.Uint64(gas), value, nil
+}
+
+// decodeFrameResult decodes the gas used, input and output data of a frame.
+func decodeFrameResult(input *callFrame) (hexutil.Uint64, hexutil.Bytes, hexutil.Bytes, error) {
+	var (
+		gasUsed uint64
+		data    hexutil.Bytes
+		output  = hexutil.Bytes{}
+		err     error
+	)
+	if input.GasUsed != "" {
+		if gasUsed, err = hexutil.DecodeUint64(input.GasUsed); err != nil {
+			return 0, nil, nil, err
+		}
+	}
+	if data, err = hexutil.Decode(input.Input); err != nil {
+		return 0, nil, nil, err
+	}
+	if input.Output != "" {
+		if output, err = hexutil.Decode(input.Output); err != nil {
+			return 0, nil, nil, err
+		}
+	}
+	return hexutil.Uint64(gasUsed), data, output, nil
+}
+
+// convertErrorToParity maps a geth execution error message to its parity
+// counterpart, leaving unknown errors as is.
+func convertErrorToParity(err string) string {
+	if err == "" {
+		return err
+	}
+	if mapped, ok := parityErrorMapping[err]; ok {
+		return mapped
+	}
+	for prefix, mapped := range parityErrorMappingStartingWith {
+		if strings.HasPrefix(err, prefix) {
+			return mapped
+		}
+	}
+	return err
+}
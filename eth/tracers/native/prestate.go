@@ -17,6 +17,7 @@
 package native
 
 import (
+	"bytes"
 	"encoding/json"
 	"math/big"
 	"sync/atomic"
@@ -41,20 +42,47 @@ type account struct {
 	Storage map[common.Hash]common.Hash `json:"storage"`
 }
 
+// accountDiff is the post-transaction state of an account in diff mode. Only
+// the fields which were modified by the transaction are populated.
+type accountDiff struct {
+	Balance string                      `json:"balance,omitempty"`
+	Nonce   uint64                      `json:"nonce,omitempty"`
+	Code    string                      `json:"code,omitempty"`
+	Storage map[common.Hash]common.Hash `json:"storage,omitempty"`
+}
+
 type prestateTracer struct {
 	env       *vm.EVM
 	prestate  prestate
+	post      map[common.Address]*accountDiff
 	create    bool
 	to        common.Address
 	gasLimit  uint64 // Amount of gas bought for the whole tx
+	config    prestateTracerConfig
+	created   map[common.Address]bool
+	deleted   map[common.Address]bool
 	interrupt uint32 // Atomic flag to signal execution interruption
 	reason    error  // Textual reason for the interruption
 }
 
-func newPrestateTracer(ctx *tracers.Context) tracers.Tracer {
-	// First callframe contains tx context info
-	// and is populated on start and end.
-	return &prestateTracer{prestate: prestate{}}
+type prestateTracerConfig struct {
+	DiffMode bool `json:"diffMode"` // If true, this tracer will return state modifications
+}
+
+func newPrestateTracer(ctx *tracers.Context, cfg json.RawMessage) (tracers.Tracer, error) {
+	var config prestateTracerConfig
+	if cfg != nil {
+		if err := json.Unmarshal(cfg, &config); err != nil {
+			return nil, err
+		}
+	}
+	return &prestateTracer{
+		prestate: prestate{},
+		post:     make(map[common.Address]*accountDiff),
+		config:   config,
+		created:  make(map[common.Address]bool),
+		deleted:  make(map[common.Address]bool),
+	}, nil
 }
 
 // CaptureStart implements the EVMLogger interface to initialize the tracing operation.
@@ -65,6 +93,10 @@ func (t *prestateTracer) CaptureStart(env *vm.EVM, from common.Address, to commo
 
 	t.lookupAccount(from)
 	t.lookupAccount(to)
+	if t.config.DiffMode {
+		// The miner fee is credited after execution, track it in diff mode.
+		t.lookupAccount(env.Context.Coinbase)
+	}
 
 	// The recipient balance includes the value transferred.
 	toBal := hexutil.MustDecodeBig(t.prestate[to].Balance)
@@ -83,6 +115,9 @@ func (t *prestateTracer) CaptureStart(env *vm.EVM, from common.Address, to commo
 
 // CaptureEnd is called after the call finishes to finalize the tracing.
 func (t *prestateTracer) CaptureEnd(output []byte, gasUsed uint64, _ time.Duration, err error) {
+	if t.config.DiffMode {
+		return
+	}
 	if t.create {
 		// Exclude created contract.
 		delete(t.prestate, t.to)
@@ -101,20 +136,27 @@ func (t *prestateTracer) CaptureState(pc uint64, op vm.OpCode, gas, cost uint64,
 	case stackLen >= 1 && (op == vm.EXTCODECOPY || op == vm.EXTCODEHASH || op == vm.EXTCODESIZE || op == vm.BALANCE || op == vm.SELFDESTRUCT):
 		addr := common.Address(stackData[stackLen-1].Bytes20())
 		t.lookupAccount(addr)
+		if op == vm.SELFDESTRUCT {
+			t.deleted[scope.Contract.Address()] = true
+		}
 	case stackLen >= 5 && (op == vm.DELEGATECALL || op == vm.CALL || op == vm.STATICCALL || op == vm.CALLCODE):
 		addr := common.Address(stackData[stackLen-2].Bytes20())
 		t.lookupAccount(addr)
 	case op == vm.CREATE:
 		addr := scope.Contract.Address()
 		nonce := t.env.StateDB.GetNonce(addr)
-		t.lookupAccount(crypto.CreateAddress(addr, nonce))
+		created := crypto.CreateAddress(addr, nonce)
+		t.lookupAccount(created)
+		t.created[created] = true
 	case stackLen >= 4 && op == vm.CREATE2:
 		offset := stackData[stackLen-2]
 		size := stackData[stackLen-3]
 		init := scope.Memory.GetCopy(int64(offset.Uint64()), int64(size.Uint64()))
 		inithash := crypto.Keccak256(init)
 		salt := stackData[stackLen-4]
-		t.lookupAccount(crypto.CreateAddress2(scope.Contract.Address(), salt.Bytes32(), inithash))
+		created := crypto.CreateAddress2(scope.Contract.Address(), salt.Bytes32(), inithash)
+		t.lookupAccount(created)
+		t.created[created] = true
 	}
 }
 
@@ -135,12 +177,82 @@ func (t *prestateTracer) CaptureTxStart(gasLimit uint64) {
 	t.gasLimit = gasLimit
 }
 
-func (t *prestateTracer) CaptureTxEnd(restGas uint64) {}
+func (t *prestateTracer) CaptureTxEnd(restGas uint64) {
+	if !t.config.DiffMode {
+		return
+	}
+	if t.create {
+		t.created[t.to] = true
+	}
+	for addr, state := range t.prestate {
+		// The deleted account's state is pruned from `post` but kept in `pre`
+		if t.deleted[addr] {
+			continue
+		}
+		var (
+			modified    = false
+			postAccount = &accountDiff{Storage: make(map[common.Hash]common.Hash)}
+			newBalance  = bigToHex(t.env.StateDB.GetBalance(addr))
+			newNonce    = t.env.StateDB.GetNonce(addr)
+			newCode     = t.env.StateDB.GetCode(addr)
+		)
+		if newBalance != state.Balance {
+			modified = true
+			postAccount.Balance = newBalance
+		}
+		if newNonce != state.Nonce {
+			modified = true
+			postAccount.Nonce = newNonce
+		}
+		if !bytes.Equal(newCode, common.FromHex(state.Code)) {
+			modified = true
+			postAccount.Code = bytesToHex(newCode)
+		}
+		for key, val := range state.Storage {
+			newVal := t.env.StateDB.GetState(addr, key)
+			if val == newVal {
+				// Omit unchanged slots
+				delete(state.Storage, key)
+				continue
+			}
+			modified = true
+			if newVal != (common.Hash{}) {
+				postAccount.Storage[key] = newVal
+			}
+			// Don't include the empty slot in the pre state
+			if val == (common.Hash{}) {
+				delete(state.Storage, key)
+			}
+		}
+		if modified {
+			t.post[addr] = postAccount
+		} else {
+			// If state is not modified, then no need to include into the pre state
+			delete(t.prestate, addr)
+		}
+	}
+	// The new created contracts' prestate were empty, so delete them
+	for addr := range t.created {
+		// The created contract maybe exists in statedb before the creating tx
+		if s := t.prestate[addr]; s != nil && !s.exists() {
+			delete(t.prestate, addr)
+		}
+	}
+}
 
 // GetResult returns the json-encoded nested list of call traces, and any
 // error arising from the encoding or forceful termination (via `Stop`).
 func (t *prestateTracer) GetResult() (json.RawMessage, error) {
-	res, err := json.Marshal(t.prestate)
+	var res []byte
+	var err error
+	if t.config.DiffMode {
+		res, err = json.Marshal(struct {
+			Post map[common.Address]*accountDiff `json:"post"`
+			Pre  prestate                        `json:"pre"`
+		}{t.post, t.prestate})
+	} else {
+		res, err = json.Marshal(t.prestate)
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -153,6 +265,12 @@ func (t *prestateTracer) Stop(err error) {
 	atomic.StoreUint32(&t.interrupt, 1)
 }
 
+// exists returns whether the account existed before the traced transaction,
+// i.e. it wasn't empty.
+func (a *account) exists() bool {
+	return a.Nonce > 0 || a.Code != "0x" || len(a.Storage) > 0 || a.Balance != "0x0"
+}
+
 // lookupAccount fetches details of an account and adds it to the prestate
 // if it doesn't exist there.
 func (t *prestateTracer) lookupAccount(addr common.Address) {
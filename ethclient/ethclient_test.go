@@ -23,11 +23,13 @@ import (
 	"fmt"
 	"math/big"
 	"reflect"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/ethereum/go-ethereum"
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/consensus/ethash"
 	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/rawdb"
@@ -212,6 +214,10 @@ var testTx2 = types.MustSignNewTx(testKey, types.LatestSigner(genesis.Config), &
 })
 
 func newTestBackend(t *testing.T) (*node.Node, []*types.Block) {
+	return newTestBackendWithConfig(t, &ethconfig.Config{Genesis: genesis})
+}
+
+func newTestBackendWithConfig(t *testing.T, config *ethconfig.Config) (*node.Node, []*types.Block) {
 	// Generate test chain.
 	blocks := generateTestChain()
 
@@ -221,7 +227,6 @@ func newTestBackend(t *testing.T) (*node.Node, []*types.Block) {
 		t.Fatalf("can't create new node: %v", err)
 	}
 	// Create Ethereum Service
-	config := &ethconfig.Config{Genesis: genesis}
 	config.Ethash.PowMode = ethash.ModeFake
 	ethservice, err := eth.New(n, config)
 	if err != nil {
@@ -294,6 +299,9 @@ func TestEthClient(t *testing.T) {
 		"TransactionSender": {
 			func(t *testing.T) { testTransactionSender(t, client) },
 		},
+		"BlockReceipts": {
+			func(t *testing.T) { testBlockReceipts(t, chain, client) },
+		},
 	}
 
 	t.Parallel()
@@ -669,6 +677,45 @@ func testTransactionSender(t *testing.T, client *rpc.Client) {
 	}
 }
 
+func testBlockReceipts(t *testing.T, chain []*types.Block, client *rpc.Client) {
+	ec := NewClient(client)
+	ctx := context.Background()
+
+	// Block #2 contains both test transactions, query it by number and by hash.
+	queries := []rpc.BlockNumberOrHash{
+		rpc.BlockNumberOrHashWithNumber(2),
+		rpc.BlockNumberOrHashWithHash(chain[2].Hash(), false),
+	}
+	for _, query := range queries {
+		receipts, err := ec.BlockReceipts(ctx, query)
+		if err != nil {
+			t.Fatalf("BlockReceipts(%v) error: %v", query.String(), err)
+		}
+		if len(receipts) != 2 {
+			t.Fatalf("BlockReceipts(%v) returned %d receipts, want 2", query.String(), len(receipts))
+		}
+		for i, tx := range []*types.Transaction{testTx1, testTx2} {
+			if receipts[i].TxHash != tx.Hash() {
+				t.Fatalf("receipt %d: wrong tx hash %v, want %v", i, receipts[i].TxHash, tx.Hash())
+			}
+			if receipts[i].BlockHash != chain[2].Hash() {
+				t.Fatalf("receipt %d: wrong block hash %v, want %v", i, receipts[i].BlockHash, chain[2].Hash())
+			}
+			want, err := ec.TransactionReceipt(ctx, tx.Hash())
+			if err != nil {
+				t.Fatalf("TransactionReceipt(%v) error: %v", tx.Hash(), err)
+			}
+			if !reflect.DeepEqual(receipts[i], want) {
+				t.Fatalf("receipt %d mismatch:\nhave %+v\nwant %+v", i, receipts[i], want)
+			}
+		}
+	}
+	// Querying a non-existent block should yield NotFound.
+	if _, err := ec.BlockReceipts(ctx, rpc.BlockNumberOrHashWithNumber(1000000000)); !errors.Is(err, ethereum.NotFound) {
+		t.Fatalf("BlockReceipts(future) error = %v, want %v", err, ethereum.NotFound)
+	}
+}
+
 func sendTransaction(ec *Client) error {
 	chainID, err := ec.ChainID(context.Background())
 	if err != nil {
@@ -692,3 +739,102 @@ func sendTransaction(ec *Client) error {
 	}
 	return ec.SendTransaction(context.Background(), tx)
 }
+
+// simulateResult is the subset of an eth_simulateV1 block result checked by
+// the tests.
+type simulateResult struct {
+	Number    hexutil.Uint64 `json:"number"`
+	Timestamp hexutil.Uint64 `json:"timestamp"`
+	GasUsed   hexutil.Uint64 `json:"gasUsed"`
+	Calls     []struct {
+		ReturnData hexutil.Bytes  `json:"returnData"`
+		GasUsed    hexutil.Uint64 `json:"gasUsed"`
+		Status     hexutil.Uint64 `json:"status"`
+	} `json:"calls"`
+}
+
+func TestSimulateV1(t *testing.T) {
+	backend, chain := newTestBackendWithConfig(t, &ethconfig.Config{Genesis: genesis, RPCGasCap: 2 * params.TxGas})
+	client, _ := backend.Attach()
+	defer backend.Close()
+	defer client.Close()
+
+	var (
+		head     = chain[len(chain)-1].Header()
+		contract = common.HexToAddress("0xc0de")
+		// PUSH1 0 SLOAD PUSH1 0 MSTORE PUSH1 32 PUSH1 0 RETURN
+		code     = hexutil.Bytes(common.FromHex("0x60005460005260206000f3"))
+		slot     = common.Hash{}
+		value    = common.BigToHash(big.NewInt(42))
+		transfer = map[string]interface{}{"from": testAddr, "to": common.Address{2}, "value": "0x1"}
+		readSlot = map[string]interface{}{"from": testAddr, "to": contract, "gas": "0x10000"}
+		simulate = func(blocks ...map[string]interface{}) ([]simulateResult, error) {
+			var res []simulateResult
+			err := client.Call(&res, "eth_simulateV1", map[string]interface{}{"blockStateCalls": blocks}, "latest")
+			return res, err
+		}
+	)
+	// State overrides of a block must be visible to all the blocks after it.
+	res, err := simulate(
+		map[string]interface{}{
+			"stateOverrides": map[common.Address]interface{}{
+				contract: map[string]interface{}{
+					"code":      code,
+					"stateDiff": map[common.Hash]common.Hash{slot: value},
+				},
+			},
+		},
+		map[string]interface{}{
+			"calls": []interface{}{readSlot},
+		},
+	)
+	if err != nil {
+		t.Fatalf("failed to simulate state overrides: %v", err)
+	}
+	if len(res) != 2 {
+		t.Fatalf("block count mismatch: have %d, want 2", len(res))
+	}
+	if len(res[1].Calls) != 1 || !bytes.Equal(res[1].Calls[0].ReturnData, value[:]) {
+		t.Fatalf("overridden storage not visible in the next block: %+v", res[1].Calls)
+	}
+	// Simulated blocks must follow the base block unless overridden.
+	for i, block := range res {
+		if want := head.Number.Uint64() + uint64(i) + 1; uint64(block.Number) != want {
+			t.Errorf("block %d: number mismatch: have %d, want %d", i, block.Number, want)
+		}
+		if want := head.Time + uint64(i+1)*12; uint64(block.Timestamp) != want {
+			t.Errorf("block %d: timestamp mismatch: have %d, want %d", i, block.Timestamp, want)
+		}
+	}
+	// Block numbers and timestamps must be strictly increasing.
+	number := hexutil.Uint64(head.Number.Uint64())
+	if _, err := simulate(map[string]interface{}{"blockOverrides": map[string]interface{}{"number": number}}); err == nil || !strings.Contains(err.Error(), "block number not increasing") {
+		t.Errorf("non-increasing number: have error %v", err)
+	}
+	if _, err := simulate(map[string]interface{}{"blockOverrides": map[string]interface{}{"time": hexutil.Uint64(head.Time)}}); err == nil || !strings.Contains(err.Error(), "block timestamp not increasing") {
+		t.Errorf("non-increasing timestamp: have error %v", err)
+	}
+	// The gas cap must be enforced across all the blocks of the simulation.
+	res, err = simulate(map[string]interface{}{"calls": []interface{}{transfer}}, map[string]interface{}{"calls": []interface{}{transfer}})
+	if err != nil {
+		t.Fatalf("failed to simulate within the gas cap: %v", err)
+	}
+	if res[0].GasUsed != hexutil.Uint64(params.TxGas) || res[1].GasUsed != hexutil.Uint64(params.TxGas) {
+		t.Fatalf("gas used mismatch: have %d and %d, want %d", res[0].GasUsed, res[1].GasUsed, params.TxGas)
+	}
+	_, err = simulate(map[string]interface{}{"calls": []interface{}{transfer}}, map[string]interface{}{"calls": []interface{}{transfer, transfer}})
+	if err == nil || !strings.Contains(err.Error(), "block 1: call 1: gas allowance") {
+		t.Errorf("exceeded gas cap: have error %v", err)
+	}
+	// A call exceeding the gas left in its block must abort the simulation.
+	_, err = simulate(map[string]interface{}{
+		"blockOverrides": map[string]interface{}{"gasLimit": hexutil.Uint64(params.TxGas + 1000)},
+		"calls": []interface{}{
+			transfer,
+			map[string]interface{}{"from": testAddr, "to": common.Address{2}, "gas": hexutil.Uint64(params.TxGas)},
+		},
+	})
+	if err == nil || !strings.Contains(err.Error(), core.ErrGasLimitReached.Error()) {
+		t.Errorf("exceeded block gas limit: have error %v, want %v", err, core.ErrGasLimitReached)
+	}
+}
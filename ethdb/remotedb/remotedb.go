@@ -15,21 +15,33 @@
 // along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
 
 // Package remotedb implements the key-value database layer based on a remote geth
-// node. Under the hood, it utilises the `debug_dbGet` method to implement a
-// read-only database.
+// node. Under the hood, it utilises the `debug_db*` methods to implement a
+// database with paged iteration and ancient store reads. Writes are supported
+// if the remote node exposes them (--rpc.dbwrite), which requires connecting
+// to its authenticated endpoint.
 // There really are no guarantees in this database, since the local geth does not
 // exclusive access, but it can be used for basic diagnostics of a remote node.
 package remotedb
 
 import (
 	"errors"
+	"net/http"
 	"strings"
+	"time"
 
+	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/golang-jwt/jwt/v4"
 )
 
+// errNotSupported is returned for operations which can't be done remotely.
+var errNotSupported = errors.New("not supported by remote database")
+
+// iteratorPageSize is the number of entries requested per debug_dbIterate call.
+const iteratorPageSize = 1024
+
 // Database is a key-value lookup for a remote database via debug_dbGet.
 type Database struct {
 	remote *rpc.Client
@@ -68,7 +80,15 @@ func (db *Database) Ancient(kind string, number uint64) ([]byte, error) {
 }
 
 func (db *Database) AncientRange(kind string, start, count, maxBytes uint64) ([][]byte, error) {
-	panic("not supported")
+	var resp []hexutil.Bytes
+	if err := db.remote.Call(&resp, "debug_dbAncientRange", kind, start, count, maxBytes); err != nil {
+		return nil, err
+	}
+	items := make([][]byte, len(resp))
+	for i, item := range resp {
+		items[i] = item
+	}
+	return items, nil
 }
 
 func (db *Database) Ancients() (uint64, error) {
@@ -78,11 +98,15 @@ func (db *Database) Ancients() (uint64, error) {
 }
 
 func (db *Database) Tail() (uint64, error) {
-	panic("not supported")
+	var resp uint64
+	err := db.remote.Call(&resp, "debug_dbTail")
+	return resp, err
 }
 
 func (db *Database) AncientSize(kind string) (uint64, error) {
-	panic("not supported")
+	var resp uint64
+	err := db.remote.Call(&resp, "debug_dbAncientSize", kind)
+	return resp, err
 }
 
 func (db *Database) ReadAncients(fn func(op ethdb.AncientReaderOp) error) (err error) {
@@ -90,23 +114,23 @@ func (db *Database) ReadAncients(fn func(op ethdb.AncientReaderOp) error) (err e
 }
 
 func (db *Database) Put(key []byte, value []byte) error {
-	panic("not supported")
+	return db.remote.Call(nil, "debug_dbPut", hexutil.Bytes(key), hexutil.Bytes(value))
 }
 
 func (db *Database) Delete(key []byte) error {
-	panic("not supported")
+	return db.remote.Call(nil, "debug_dbDelete", hexutil.Bytes(key))
 }
 
 func (db *Database) ModifyAncients(f func(ethdb.AncientWriteOp) error) (int64, error) {
-	panic("not supported")
+	return 0, errNotSupported
 }
 
 func (db *Database) TruncateHead(n uint64) error {
-	panic("not supported")
+	return errNotSupported
 }
 
 func (db *Database) TruncateTail(n uint64) error {
-	panic("not supported")
+	return errNotSupported
 }
 
 func (db *Database) Sync() error {
@@ -114,27 +138,34 @@ func (db *Database) Sync() error {
 }
 
 func (db *Database) MigrateTable(s string, f func([]byte) ([]byte, error)) error {
-	panic("not supported")
+	return errNotSupported
 }
 
 func (db *Database) NewBatch() ethdb.Batch {
-	panic("not supported")
+	return &batch{db: db}
 }
 
 func (db *Database) NewBatchWithSize(size int) ethdb.Batch {
-	panic("not supported")
+	return &batch{db: db}
 }
 
 func (db *Database) NewIterator(prefix []byte, start []byte) ethdb.Iterator {
-	panic("not supported")
+	return &iterator{
+		db:     db,
+		prefix: common.CopyBytes(prefix),
+		next:   common.CopyBytes(start),
+		pos:    -1,
+	}
 }
 
 func (db *Database) Stat(property string) (string, error) {
-	panic("not supported")
+	var resp string
+	err := db.remote.Call(&resp, "debug_chaindbProperty", property)
+	return resp, err
 }
 
 func (db *Database) AncientDatadir() (string, error) {
-	panic("not supported")
+	return "", errNotSupported
 }
 
 func (db *Database) Compact(start []byte, limit []byte) error {
@@ -142,7 +173,7 @@ func (db *Database) Compact(start []byte, limit []byte) error {
 }
 
 func (db *Database) NewSnapshot() (ethdb.Snapshot, error) {
-	panic("not supported")
+	return nil, errNotSupported
 }
 
 func (db *Database) Close() error {
@@ -171,3 +202,186 @@ func New(endpoint string) (ethdb.Database, error) {
 		remote: client,
 	}, nil
 }
+
+// NewAuthenticated connects to the authenticated HTTP endpoint of a remote node
+// using the given JWT secret. This is needed for writing to the database.
+func NewAuthenticated(endpoint string, secret []byte) (ethdb.Database, error) {
+	if len(secret) != 32 {
+		return nil, errors.New("invalid JWT secret")
+	}
+	if !strings.HasPrefix(endpoint, "http://") && !strings.HasPrefix(endpoint, "https://") {
+		return nil, errors.New("authenticated remote database requires an HTTP endpoint")
+	}
+	client, err := rpc.DialHTTPWithClient(endpoint, &http.Client{
+		Transport: &jwtTransport{secret: secret, next: http.DefaultTransport},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &Database{
+		remote: client,
+	}, nil
+}
+
+// jwtTransport is an HTTP transport attaching a freshly issued JWT token to
+// every request, as the tokens are only accepted shortly after being issued.
+type jwtTransport struct {
+	secret []byte
+	next   http.RoundTripper
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *jwtTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.RegisteredClaims{
+		IssuedAt: jwt.NewNumericDate(time.Now()),
+	}).SignedString(t.secret)
+	if err != nil {
+		return nil, err
+	}
+	req = req.Clone(req.Context())
+	req.Header.Set("Authorization", "Bearer "+token)
+	return t.next.RoundTrip(req)
+}
+
+// iterator is a database iterator fetching the entries from the remote node in
+// pages via debug_dbIterate.
+type iterator struct {
+	db     *Database
+	prefix []byte
+	next   []byte // Start of the next page relative to the prefix
+	done   bool   // Whether the last page was fetched
+	keys   [][]byte
+	values [][]byte
+	pos    int
+	err    error
+}
+
+// dbIterateResult is a page of entries returned by debug_dbIterate.
+type dbIterateResult struct {
+	Keys   []hexutil.Bytes `json:"keys"`
+	Values []hexutil.Bytes `json:"values"`
+	Next   *hexutil.Bytes  `json:"next"` // Null if the iteration is exhausted
+}
+
+// Next moves the iterator to the next key/value pair, fetching the next page
+// from the remote node if needed.
+func (it *iterator) Next() bool {
+	if it.err != nil {
+		return false
+	}
+	it.pos++
+	for it.pos >= len(it.keys) {
+		if it.done {
+			return false
+		}
+		var resp dbIterateResult
+		if it.err = it.db.remote.Call(&resp, "debug_dbIterate", hexutil.Bytes(it.prefix), hexutil.Bytes(it.next), iteratorPageSize); it.err != nil {
+			return false
+		}
+		if len(resp.Keys) != len(resp.Values) {
+			it.err = errors.New("invalid iteration response")
+			return false
+		}
+		it.keys, it.values, it.pos = it.keys[:0], it.values[:0], 0
+		for i := range resp.Keys {
+			it.keys = append(it.keys, resp.Keys[i])
+			it.values = append(it.values, resp.Values[i])
+		}
+		if resp.Next == nil {
+			it.done = true
+		} else {
+			it.next = *resp.Next
+		}
+	}
+	return true
+}
+
+// Error returns any accumulated error.
+func (it *iterator) Error() error {
+	return it.err
+}
+
+// Key returns the key of the current key/value pair, or nil if done.
+func (it *iterator) Key() []byte {
+	if it.pos < 0 || it.pos >= len(it.keys) {
+		return nil
+	}
+	return it.keys[it.pos]
+}
+
+// Value returns the value of the current key/value pair, or nil if done.
+func (it *iterator) Value() []byte {
+	if it.pos < 0 || it.pos >= len(it.values) {
+		return nil
+	}
+	return it.values[it.pos]
+}
+
+// Release releases associated resources.
+func (it *iterator) Release() {
+	it.keys, it.values, it.done = nil, nil, true
+}
+
+// dbWriteOp is a single operation of a debug_dbWrite batch.
+type dbWriteOp struct {
+	Key    hexutil.Bytes `json:"key"`
+	Value  hexutil.Bytes `json:"value,omitempty"`
+	Delete bool          `json:"delete,omitempty"`
+}
+
+// batch is a write-only database batch, which is applied atomically on the
+// remote node via debug_dbWrite.
+type batch struct {
+	db   *Database
+	ops  []dbWriteOp
+	size int
+}
+
+// Put inserts the given value into the batch.
+func (b *batch) Put(key, value []byte) error {
+	b.ops = append(b.ops, dbWriteOp{Key: common.CopyBytes(key), Value: common.CopyBytes(value)})
+	b.size += len(key) + len(value)
+	return nil
+}
+
+// Delete inserts the key removal into the batch.
+func (b *batch) Delete(key []byte) error {
+	b.ops = append(b.ops, dbWriteOp{Key: common.CopyBytes(key), Delete: true})
+	b.size += len(key)
+	return nil
+}
+
+// ValueSize retrieves the amount of data queued up for writing.
+func (b *batch) ValueSize() int {
+	return b.size
+}
+
+// Write sends the batch to the remote node.
+func (b *batch) Write() error {
+	if len(b.ops) == 0 {
+		return nil
+	}
+	return b.db.remote.Call(nil, "debug_dbWrite", b.ops)
+}
+
+// Reset resets the batch for reuse.
+func (b *batch) Reset() {
+	b.ops = b.ops[:0]
+	b.size = 0
+}
+
+// Replay replays the batch contents.
+func (b *batch) Replay(w ethdb.KeyValueWriter) error {
+	for _, op := range b.ops {
+		var err error
+		if op.Delete {
+			err = w.Delete(op.Key)
+		} else {
+			err = w.Put(op.Key, op.Value)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,129 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package remotedb
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/internal/ethapi"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// testBackend is an API backend only serving the chain database.
+type testBackend struct {
+	ethapi.Backend
+	db ethdb.Database
+}
+
+func (b *testBackend) ChainDb() ethdb.Database { return b.db }
+
+// newTestDatabase creates a remote database connected in-process to the debug
+// APIs serving the given database.
+func newTestDatabase(t *testing.T, db ethdb.Database) *Database {
+	backend := &testBackend{db: db}
+
+	server := rpc.NewServer()
+	t.Cleanup(server.Stop)
+	if err := server.RegisterName("debug", ethapi.NewPrivateDebugAPI(backend)); err != nil {
+		t.Fatalf("failed to register debug API: %v", err)
+	}
+	if err := server.RegisterName("debug", ethapi.NewPrivateDBWriteAPI(backend)); err != nil {
+		t.Fatalf("failed to register database write API: %v", err)
+	}
+	return &Database{remote: rpc.DialInProc(server)}
+}
+
+// Tests that iterating the remote database returns the same entries as the
+// local one, across multiple pages.
+func TestIterator(t *testing.T) {
+	local := rawdb.NewMemoryDatabase()
+	for i := 0; i < 2*iteratorPageSize+10; i++ {
+		key := make([]byte, 5)
+		key[0] = 'p'
+		binary.BigEndian.PutUint32(key[1:], uint32(i))
+		local.Put(key, []byte{byte(i)})
+	}
+	local.Put([]byte("a"), []byte{0x01})
+	local.Put([]byte("q"), []byte{0x02})
+
+	remote := newTestDatabase(t, local)
+	defer remote.Close()
+
+	tests := []struct {
+		prefix, start []byte
+	}{
+		{nil, nil},
+		{[]byte("p"), nil},
+		{[]byte("p"), []byte{0x00, 0x00, 0x04, 0x00}},
+		{[]byte("p"), []byte{0xff}},
+		{[]byte("x"), nil},
+	}
+	for i, tt := range tests {
+		var (
+			want  = local.NewIterator(tt.prefix, tt.start)
+			have  = remote.NewIterator(tt.prefix, tt.start)
+			count int
+		)
+		for want.Next() {
+			if !have.Next() {
+				t.Fatalf("test %d: remote iterator exhausted after %d entries: %v", i, count, have.Error())
+			}
+			if !bytes.Equal(have.Key(), want.Key()) || !bytes.Equal(have.Value(), want.Value()) {
+				t.Fatalf("test %d: entry %d mismatch: have %x=%x, want %x=%x", i, count, have.Key(), have.Value(), want.Key(), want.Value())
+			}
+			count++
+		}
+		if have.Next() {
+			t.Fatalf("test %d: remote iterator not exhausted after %d entries, at %x", i, count, have.Key())
+		}
+		if err := have.Error(); err != nil {
+			t.Fatalf("test %d: iteration failed: %v", i, err)
+		}
+		want.Release()
+		have.Release()
+	}
+}
+
+// Tests that writes are applied to the remote database.
+func TestWrite(t *testing.T) {
+	local := rawdb.NewMemoryDatabase()
+	remote := newTestDatabase(t, local)
+	defer remote.Close()
+
+	if err := remote.Put([]byte("a"), []byte{0x01}); err != nil {
+		t.Fatalf("failed to put: %v", err)
+	}
+	batch := remote.NewBatch()
+	batch.Put([]byte("b"), []byte{0x02})
+	batch.Put([]byte("c"), []byte{0x03})
+	batch.Delete([]byte("a"))
+	if err := batch.Write(); err != nil {
+		t.Fatalf("failed to write batch: %v", err)
+	}
+	if ok, _ := local.Has([]byte("a")); ok {
+		t.Fatalf("deleted entry still present")
+	}
+	for key, want := range map[string]byte{"b": 0x02, "c": 0x03} {
+		if have, err := remote.Get([]byte(key)); err != nil || !bytes.Equal(have, []byte{want}) {
+			t.Fatalf("entry %s mismatch: have %x, want %x, err %v", key, have, want, err)
+		}
+	}
+}
@@ -246,6 +246,10 @@ const schema string = `
         rawHeader: Bytes!
         # Raw is the RLP encoding of the block.
         raw: Bytes!
+        # RawReceipts is the list of canonical encodings of all the receipts in
+        # this block, in transaction order. For post EIP-2718 typed transactions
+        # each entry is equivalent to TxType || ReceiptEncoding.
+        rawReceipts: [Bytes!]!
     }
 
     # CallData represents the data associated with a local contract call.
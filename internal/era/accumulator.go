@@ -0,0 +1,105 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package era
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// accumulatorDepth is the depth of the merkle tree the header records are
+// hashed into, fitting exactly MaxEra1Size leaves.
+const accumulatorDepth = 13
+
+// zeroHashes are the roots of empty subtrees of increasing depth.
+var zeroHashes = func() [accumulatorDepth + 1][32]byte {
+	var hashes [accumulatorDepth + 1][32]byte
+	for i := 1; i <= accumulatorDepth; i++ {
+		hashes[i] = sha256.Sum256(append(hashes[i-1][:], hashes[i-1][:]...))
+	}
+	return hashes
+}()
+
+// ComputeAccumulator calculates the SSZ hash tree root of the Era1 accumulator
+// of header records, where every record is the tuple of a block hash and the
+// total difficulty at that block:
+//
+//	HeaderRecord = Container(block_hash: Bytes32, total_difficulty: uint256)
+//	Accumulator  = List[HeaderRecord, 8192]
+func ComputeAccumulator(hashes []common.Hash, tds []*big.Int) (common.Hash, error) {
+	if len(hashes) != len(tds) {
+		return common.Hash{}, fmt.Errorf("hash and total difficulty count mismatch: %d != %d", len(hashes), len(tds))
+	}
+	if len(hashes) > MaxEra1Size {
+		return common.Hash{}, fmt.Errorf("too many records: have %d, max %d", len(hashes), MaxEra1Size)
+	}
+	layer := make([][32]byte, len(hashes))
+	for i := range hashes {
+		if tds[i].Sign() < 0 || tds[i].BitLen() > 256 {
+			return common.Hash{}, fmt.Errorf("invalid total difficulty %v", tds[i])
+		}
+		var record [64]byte
+		copy(record[:32], hashes[i][:])
+		copy(record[32:], bigToUint256LE(tds[i]))
+		layer[i] = sha256.Sum256(record[:])
+	}
+	// Merkleize the records, padding each layer with the empty subtree roots
+	for depth := 0; depth < accumulatorDepth; depth++ {
+		next := make([][32]byte, (len(layer)+1)/2)
+		for i := range next {
+			right := zeroHashes[depth]
+			if 2*i+1 < len(layer) {
+				right = layer[2*i+1]
+			}
+			next[i] = sha256.Sum256(append(layer[2*i][:], right[:]...))
+		}
+		layer = next
+	}
+	root := zeroHashes[accumulatorDepth]
+	if len(layer) > 0 {
+		root = layer[0]
+	}
+	// Mix in the length of the list
+	var length [32]byte
+	binary.LittleEndian.PutUint64(length[:], uint64(len(hashes)))
+	return sha256.Sum256(append(root[:], length[:]...)), nil
+}
+
+// bigToUint256LE encodes the given integer as a 32 byte little endian value.
+func bigToUint256LE(n *big.Int) []byte {
+	var (
+		out = make([]byte, 32)
+		be  = n.Bytes()
+	)
+	for i, b := range be {
+		out[len(be)-1-i] = b
+	}
+	return out
+}
+
+// uint256LEToBig decodes a 32 byte little endian value into an integer.
+func uint256LEToBig(b []byte) *big.Int {
+	be := make([]byte, len(b))
+	for i, c := range b {
+		be[len(b)-1-i] = c
+	}
+	return new(big.Int).SetBytes(be)
+}
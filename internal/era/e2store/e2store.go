@@ -0,0 +1,195 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package e2store implements the e2store container format, a simple flat
+// sequence of type-length-value records.
+package e2store
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+const (
+	// headerSize is the size of a record header: a 2 byte type, a 4 byte
+	// length and 2 reserved bytes which must be zero.
+	headerSize = 8
+
+	// valueSizeLimit is the maximum size of a single record value.
+	valueSizeLimit = 1024 * 1024 * 50
+)
+
+var (
+	errReservedNotZero = errors.New("reserved header bytes must be zero")
+	errTooMuchData     = errors.New("entry value exceeds size limit")
+)
+
+// Entry is a variable-length-data record in an e2store.
+type Entry struct {
+	Type  uint16
+	Value []byte
+}
+
+// Writer writes entries using the e2store encoding.
+type Writer struct {
+	w io.Writer
+}
+
+// NewWriter returns a new Writer that writes to w.
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{w: w}
+}
+
+// Write writes a single e2store entry to the underlying writer, returning the
+// number of bytes written.
+//
+// The entry is encoded as a type-length-value record:
+//
+//	entry := header | value
+//	header := type | length | reserved
+//	type := Data[0:2]
+//	length := Data[2:6]
+//	reserved := Data[6:8]
+//	value := Data[8:8+length]
+func (w *Writer) Write(typ uint16, value []byte) (int, error) {
+	if len(value) > valueSizeLimit {
+		return 0, errTooMuchData
+	}
+	var header [headerSize]byte
+	binary.LittleEndian.PutUint16(header[:], typ)
+	binary.LittleEndian.PutUint32(header[2:], uint32(len(value)))
+
+	n, err := w.w.Write(header[:])
+	if err != nil {
+		return n, err
+	}
+	m, err := w.w.Write(value)
+	return n + m, err
+}
+
+// Reader reads entries from an e2store encoded source.
+type Reader struct {
+	r      io.ReaderAt
+	offset int64
+}
+
+// NewReader returns a new Reader that reads from r.
+func NewReader(r io.ReaderAt) *Reader {
+	return &Reader{r: r}
+}
+
+// Read reads the next entry from the source, advancing the read position.
+// Returns io.EOF once the source is exhausted.
+func (r *Reader) Read() (*Entry, error) {
+	e, err := r.ReadAt(r.offset)
+	if err != nil {
+		return nil, err
+	}
+	r.offset += int64(headerSize + len(e.Value))
+	return e, nil
+}
+
+// ReadAt reads the entry starting at the given offset, without changing the
+// read position of sequential reads.
+func (r *Reader) ReadAt(off int64) (*Entry, error) {
+	typ, length, err := r.ReadMetadataAt(off)
+	if err != nil {
+		return nil, err
+	}
+	entry := &Entry{Type: typ}
+	if length == 0 {
+		return entry, nil
+	}
+	entry.Value = make([]byte, length)
+	if n, err := r.r.ReadAt(entry.Value, off+headerSize); err != nil && n != len(entry.Value) {
+		if err == io.EOF {
+			return nil, io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	return entry, nil
+}
+
+// ReaderAt returns a reader for the value of the entry starting at the given
+// offset, along with the total length of the entry including its header.
+func (r *Reader) ReaderAt(expectedType uint16, off int64) (io.Reader, int, error) {
+	typ, length, err := r.ReadMetadataAt(off)
+	if err != nil {
+		return nil, 0, err
+	}
+	if typ != expectedType {
+		return nil, 0, fmt.Errorf("wrong type, want %d have %d", expectedType, typ)
+	}
+	return io.NewSectionReader(r.r, off+headerSize, int64(length)), headerSize + int(length), nil
+}
+
+// ReadMetadataAt reads the type and the value length of the entry starting at
+// the given offset.
+func (r *Reader) ReadMetadataAt(off int64) (uint16, uint32, error) {
+	var header [headerSize]byte
+	if n, err := r.r.ReadAt(header[:], off); err != nil && n != headerSize {
+		if err == io.EOF && n > 0 {
+			return 0, 0, io.ErrUnexpectedEOF
+		}
+		return 0, 0, err
+	}
+	if header[6] != 0 || header[7] != 0 {
+		return 0, 0, errReservedNotZero
+	}
+	length := binary.LittleEndian.Uint32(header[2:6])
+	if length > valueSizeLimit {
+		return 0, 0, errTooMuchData
+	}
+	return binary.LittleEndian.Uint16(header[:2]), length, nil
+}
+
+// Find returns the first entry with the matching type, starting the search at
+// the beginning of the source. Returns io.EOF if no entry matched.
+func (r *Reader) Find(want uint16) (*Entry, error) {
+	var off int64
+	for {
+		e, err := r.ReadAt(off)
+		if err != nil {
+			return nil, err
+		}
+		if e.Type == want {
+			return e, nil
+		}
+		off += int64(headerSize + len(e.Value))
+	}
+}
+
+// FindAll returns all entries with the matching type.
+func (r *Reader) FindAll(want uint16) ([]*Entry, error) {
+	var (
+		off     int64
+		entries []*Entry
+	)
+	for {
+		e, err := r.ReadAt(off)
+		if err == io.EOF {
+			return entries, nil
+		} else if err != nil {
+			return entries, err
+		}
+		if e.Type == want {
+			entries = append(entries, e)
+		}
+		off += int64(headerSize + len(e.Value))
+	}
+}
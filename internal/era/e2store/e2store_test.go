@@ -0,0 +1,149 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package e2store
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+func TestEncode(t *testing.T) {
+	for _, test := range []struct {
+		entries []Entry
+		want    string
+		name    string
+	}{
+		{
+			name:    "emptyEntry",
+			entries: []Entry{{0xffff, nil}},
+			want:    "ffff000000000000",
+		},
+		{
+			name:    "beef",
+			entries: []Entry{{42, common.Hex2Bytes("beef")}},
+			want:    "2a00020000000000beef",
+		},
+		{
+			name: "twoEntries",
+			entries: []Entry{
+				{42, common.Hex2Bytes("beef")},
+				{9, common.Hex2Bytes("abcdabcd")},
+			},
+			want: "2a00020000000000beef0900040000000000abcdabcd",
+		},
+	} {
+		tt := test
+		t.Run(tt.name, func(t *testing.T) {
+			var (
+				b = bytes.NewBuffer(nil)
+				w = NewWriter(b)
+			)
+			for _, e := range tt.entries {
+				if _, err := w.Write(e.Type, e.Value); err != nil {
+					t.Fatalf("encoding error: %v", err)
+				}
+			}
+			if want, have := common.FromHex(tt.want), b.Bytes(); !bytes.Equal(want, have) {
+				t.Fatalf("encoding mismatch (want %x, have %x", want, have)
+			}
+			r := NewReader(bytes.NewReader(b.Bytes()))
+			for _, want := range tt.entries {
+				have, err := r.Read()
+				if err != nil {
+					t.Fatalf("decoding error: %v", err)
+				}
+				if want.Type != have.Type {
+					t.Fatalf("type mismatch (want %d, have %d", want.Type, have.Type)
+				}
+				if !bytes.Equal(want.Value, have.Value) {
+					t.Fatalf("value mismatch (want %x, have %x", want.Value, have.Value)
+				}
+			}
+			if _, err := r.Read(); err != io.EOF {
+				t.Fatalf("expected EOF, have %v", err)
+			}
+		})
+	}
+}
+
+func TestDecode(t *testing.T) {
+	for i, tt := range []struct {
+		have string
+		err  error
+	}{
+		{ // basic valid decoding
+			have: "ffff000000000000",
+		},
+		{ // basic invalid decoding
+			have: "ffff000000000001",
+			err:  errReservedNotZero,
+		},
+		{ // no more entries to read, returns EOF
+			have: "",
+			err:  io.EOF,
+		},
+		{ // malformed type
+			have: "bad",
+			err:  io.ErrUnexpectedEOF,
+		},
+		{ // malformed length
+			have: "badbeef",
+			err:  io.ErrUnexpectedEOF,
+		},
+		{ // specified length longer than actual value
+			have: "beef010000000000",
+			err:  io.ErrUnexpectedEOF,
+		},
+	} {
+		r := NewReader(bytes.NewReader(common.FromHex(tt.have)))
+		if _, err := r.Read(); err != tt.err {
+			t.Fatalf("test %d, mismatch err: want %v, have %v", i, tt.err, err)
+		}
+	}
+}
+
+func TestFind(t *testing.T) {
+	var (
+		b = bytes.NewBuffer(nil)
+		w = NewWriter(b)
+	)
+	w.Write(1, []byte("one"))
+	w.Write(2, []byte("two"))
+	w.Write(1, []byte("three"))
+
+	r := NewReader(bytes.NewReader(b.Bytes()))
+	e, err := r.Find(2)
+	if err != nil {
+		t.Fatalf("failed to find entry: %v", err)
+	}
+	if string(e.Value) != "two" {
+		t.Fatalf("wrong entry found: %s", e.Value)
+	}
+	if _, err := r.Find(3); err != io.EOF {
+		t.Fatalf("expected EOF for missing type, have %v", err)
+	}
+	all, err := r.FindAll(1)
+	if err != nil {
+		t.Fatalf("failed to find entries: %v", err)
+	}
+	if len(all) != 2 || string(all[0].Value) != "one" || string(all[1].Value) != "three" {
+		t.Fatalf("wrong entries found: %v", all)
+	}
+}
@@ -0,0 +1,464 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package era implements the Era1 archive format for execution layer history:
+// fixed size epochs of blocks, receipts and total difficulties, indexed for
+// random access and committed to by an accumulator root.
+package era
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/internal/era/e2store"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/trie"
+	"github.com/golang/snappy"
+)
+
+// Era1 entry types.
+const (
+	TypeVersion            uint16 = 0x3265
+	TypeCompressedHeader   uint16 = 0x03
+	TypeCompressedBody     uint16 = 0x04
+	TypeCompressedReceipts uint16 = 0x05
+	TypeTotalDifficulty    uint16 = 0x06
+	TypeAccumulator        uint16 = 0x07
+	TypeBlockIndex         uint16 = 0x3266
+)
+
+// MaxEra1Size is the maximum number of blocks in a single Era1 file, bounded
+// by the size of the accumulator.
+const MaxEra1Size = 8192
+
+// Filename returns a recognizable Era1-formatted file name for the specified
+// epoch and network.
+func Filename(network string, epoch int, root common.Hash) string {
+	return fmt.Sprintf("%s-%05d-%s.era1", network, epoch, root.Hex()[2:10])
+}
+
+// ReadDir reads all the era1 files in a directory for a given network.
+// Format: <network>-<epoch>-<hexroot>.era1
+func ReadDir(dir, network string) ([]string, error) {
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("error reading directory %s: %w", dir, err)
+	}
+	var (
+		next = uint64(0)
+		eras []string
+	)
+	for _, entry := range entries {
+		if path.Ext(entry.Name()) != ".era1" {
+			continue
+		}
+		parts := strings.Split(entry.Name(), "-")
+		if len(parts) != 3 || parts[0] != network {
+			// Invalid era1 filename, skip.
+			continue
+		}
+		if epoch, err := strconv.ParseUint(parts[1], 10, 64); err != nil {
+			return nil, fmt.Errorf("malformed era1 filename: %s", entry.Name())
+		} else if len(eras) != 0 && epoch != next {
+			return nil, fmt.Errorf("missing epoch %d", next)
+		} else {
+			next = epoch + 1
+		}
+		eras = append(eras, entry.Name())
+	}
+	return eras, nil
+}
+
+// ReadAtSeekCloser is the file handle an Era1 archive is read through.
+type ReadAtSeekCloser interface {
+	io.ReaderAt
+	io.Seeker
+	io.Closer
+}
+
+// Era reads an Era1 file.
+type Era struct {
+	f   ReadAtSeekCloser // backing era1 file
+	s   *e2store.Reader  // e2store reader over f
+	m   metadata         // start, count, length info
+	mu  *sync.Mutex      // lock for buf
+	buf [8]byte          // buffer reading entry offsets
+}
+
+// metadata wraps the metadata in the block index.
+type metadata struct {
+	start  uint64
+	count  uint64
+	length int64
+}
+
+// Open returns an Era backed by the given filename.
+func Open(filename string) (*Era, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	e, err := From(f)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return e, nil
+}
+
+// From returns an Era backed by f.
+func From(f ReadAtSeekCloser) (*Era, error) {
+	m, err := readMetadata(f)
+	if err != nil {
+		return nil, err
+	}
+	e := &Era{
+		f:  f,
+		s:  e2store.NewReader(f),
+		m:  m,
+		mu: new(sync.Mutex),
+	}
+	version, err := e.s.ReadAt(0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read version entry: %w", err)
+	}
+	if version.Type != TypeVersion {
+		return nil, fmt.Errorf("invalid version entry type %d", version.Type)
+	}
+	return e, nil
+}
+
+// Close closes the Era file safely.
+func (e *Era) Close() error {
+	if e.f == nil {
+		return nil
+	}
+	err := e.f.Close()
+	e.f = nil
+	return err
+}
+
+// Start returns the listed start block.
+func (e *Era) Start() uint64 {
+	return e.m.start
+}
+
+// Count returns the total number of blocks in the Era1.
+func (e *Era) Count() uint64 {
+	return e.m.count
+}
+
+// GetBlockByNumber returns the block for the given block number.
+func (e *Era) GetBlockByNumber(num uint64) (*types.Block, error) {
+	off, err := e.readOffset(num)
+	if err != nil {
+		return nil, err
+	}
+	r, n, err := newSnappyReader(e.s, TypeCompressedHeader, off)
+	if err != nil {
+		return nil, err
+	}
+	var header types.Header
+	if err := rlp.Decode(r, &header); err != nil {
+		return nil, err
+	}
+	off += n
+	r, _, err = newSnappyReader(e.s, TypeCompressedBody, off)
+	if err != nil {
+		return nil, err
+	}
+	var body types.Body
+	if err := rlp.Decode(r, &body); err != nil {
+		return nil, err
+	}
+	return types.NewBlockWithHeader(&header).WithBody(body.Transactions, body.Uncles), nil
+}
+
+// GetRawBodyByNumber returns the RLP-encoded body for the given block number.
+func (e *Era) GetRawBodyByNumber(num uint64) ([]byte, error) {
+	off, err := e.readOffset(num)
+	if err != nil {
+		return nil, err
+	}
+	off, err = e.skipEntries(off, 1)
+	if err != nil {
+		return nil, err
+	}
+	r, _, err := newSnappyReader(e.s, TypeCompressedBody, off)
+	if err != nil {
+		return nil, err
+	}
+	return ioutil.ReadAll(r)
+}
+
+// GetRawReceiptsByNumber returns the RLP-encoded receipts for the given block
+// number.
+func (e *Era) GetRawReceiptsByNumber(num uint64) ([]byte, error) {
+	off, err := e.readOffset(num)
+	if err != nil {
+		return nil, err
+	}
+	off, err = e.skipEntries(off, 2)
+	if err != nil {
+		return nil, err
+	}
+	r, _, err := newSnappyReader(e.s, TypeCompressedReceipts, off)
+	if err != nil {
+		return nil, err
+	}
+	return ioutil.ReadAll(r)
+}
+
+// GetReceiptsByNumber returns the receipts for the given block number. Only
+// the consensus fields of the receipts are populated.
+func (e *Era) GetReceiptsByNumber(num uint64) (types.Receipts, error) {
+	blob, err := e.GetRawReceiptsByNumber(num)
+	if err != nil {
+		return nil, err
+	}
+	return decodeReceipts(blob)
+}
+
+// GetTotalDifficulty returns the total difficulty of the chain up to and
+// including the given block number.
+func (e *Era) GetTotalDifficulty(num uint64) (*big.Int, error) {
+	off, err := e.readOffset(num)
+	if err != nil {
+		return nil, err
+	}
+	off, err = e.skipEntries(off, 3)
+	if err != nil {
+		return nil, err
+	}
+	return e.readTotalDifficulty(off)
+}
+
+// Accumulator reads the accumulator entry in the Era1 file.
+func (e *Era) Accumulator() (common.Hash, error) {
+	// The accumulator is the entry right before the block index
+	entry, err := e.s.ReadAt(e.indexOffset() - 8 - common.HashLength)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	if entry.Type != TypeAccumulator || len(entry.Value) != common.HashLength {
+		return common.Hash{}, fmt.Errorf("invalid accumulator entry, type %d length %d", entry.Type, len(entry.Value))
+	}
+	return common.BytesToHash(entry.Value), nil
+}
+
+// InitialTD returns initial total difficulty before the difficulty of the
+// first block of the Era1 is applied.
+func (e *Era) InitialTD() (*big.Int, error) {
+	off, err := e.readOffset(e.m.start)
+	if err != nil {
+		return nil, err
+	}
+	r, n, err := newSnappyReader(e.s, TypeCompressedHeader, off)
+	if err != nil {
+		return nil, err
+	}
+	var header types.Header
+	if err := rlp.Decode(r, &header); err != nil {
+		return nil, err
+	}
+	off += n
+	off, err = e.skipEntries(off, 2)
+	if err != nil {
+		return nil, err
+	}
+	td, err := e.readTotalDifficulty(off)
+	if err != nil {
+		return nil, err
+	}
+	return td.Sub(td, header.Difficulty), nil
+}
+
+// readTotalDifficulty reads the total difficulty entry at the given offset.
+func (e *Era) readTotalDifficulty(off int64) (*big.Int, error) {
+	entry, err := e.s.ReadAt(off)
+	if err != nil {
+		return nil, err
+	}
+	if entry.Type != TypeTotalDifficulty {
+		return nil, fmt.Errorf("wrong type, want %d have %d", TypeTotalDifficulty, entry.Type)
+	}
+	if len(entry.Value) != 32 {
+		return nil, fmt.Errorf("invalid total difficulty length %d", len(entry.Value))
+	}
+	return uint256LEToBig(entry.Value), nil
+}
+
+// skipEntries skips the given number of entries starting at the offset and
+// returns the offset of the entry following them.
+func (e *Era) skipEntries(off int64, n int) (int64, error) {
+	for i := 0; i < n; i++ {
+		_, length, err := e.s.ReadMetadataAt(off)
+		if err != nil {
+			return 0, err
+		}
+		off += 8 + int64(length)
+	}
+	return off, nil
+}
+
+// readOffset reads a specific block's offset from the block index. The value n
+// is the absolute block number desired.
+func (e *Era) readOffset(n uint64) (int64, error) {
+	if n < e.m.start || n >= e.m.start+e.m.count {
+		return 0, fmt.Errorf("out-of-bounds: %d not in [%d, %d)", n, e.m.start, e.m.start+e.m.count)
+	}
+	var (
+		blockIndexRecordOffset = e.indexOffset()
+		firstIndex             = blockIndexRecordOffset + 16 // first index after header / start-num
+		indexOffset            = int64(n-e.m.start) * 8      // desired index * size of indexes
+		offOffset              = firstIndex + indexOffset    // offset of block offset
+	)
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if _, err := e.f.ReadAt(e.buf[:], offOffset); err != nil {
+		return 0, err
+	}
+	// Since the block offset is relative from the start of the block index record
+	// we need to add the record offset to it's offset to get the block's absolute
+	// offset.
+	return blockIndexRecordOffset + int64(binary.LittleEndian.Uint64(e.buf[:])), nil
+}
+
+// indexOffset returns the offset of the block index record, skipping its
+// header, the start number, the offsets and the count.
+func (e *Era) indexOffset() int64 {
+	return e.m.length - 24 - int64(e.m.count)*8
+}
+
+// newSnappyReader returns a snappy.Reader for the e2store entry value at off.
+func newSnappyReader(e *e2store.Reader, expectedType uint16, off int64) (io.Reader, int64, error) {
+	r, n, err := e.ReaderAt(expectedType, off)
+	if err != nil {
+		return nil, 0, err
+	}
+	return snappy.NewReader(r), int64(n), err
+}
+
+// decodeReceipts decodes the RLP encoded storage receipts of a block.
+func decodeReceipts(blob []byte) (types.Receipts, error) {
+	var storage []*types.ReceiptForStorage
+	if err := rlp.DecodeBytes(blob, &storage); err != nil {
+		return nil, err
+	}
+	receipts := make(types.Receipts, len(storage))
+	for i, receipt := range storage {
+		receipts[i] = (*types.Receipt)(receipt)
+	}
+	return receipts, nil
+}
+
+// readMetadata reads the metadata stored in an Era1 file's block index.
+func readMetadata(f ReadAtSeekCloser) (m metadata, err error) {
+	// Determine length of reader.
+	if m.length, err = f.Seek(0, io.SeekEnd); err != nil {
+		return
+	}
+	b := make([]byte, 16)
+	// Read count. It's the last 8 bytes of the file.
+	if _, err = f.ReadAt(b[:8], m.length-8); err != nil {
+		return
+	}
+	m.count = binary.LittleEndian.Uint64(b)
+	if m.count == 0 || m.count > uint64(MaxEra1Size) || int64(m.count)*8+32 > m.length {
+		return m, fmt.Errorf("invalid block count %d", m.count)
+	}
+	// Read start. It's at the offset -sizeof(m.count) -
+	// count*sizeof(indexEntry) - sizeof(m.start)
+	if _, err = f.ReadAt(b[8:], m.length-16-int64(m.count*8)); err != nil {
+		return
+	}
+	m.start = binary.LittleEndian.Uint64(b[8:])
+	return
+}
+
+// Verify checks the internal consistency of the Era1 archive: every block body
+// and receipt list must match the roots in its header, the blocks must form a
+// contiguous hash chain with consistent total difficulties, and the stored
+// accumulator must match the one computed from the contents. The verified
+// accumulator root is returned.
+func (e *Era) Verify() (common.Hash, error) {
+	it, err := NewIterator(e)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	var (
+		hashes = make([]common.Hash, 0, e.Count())
+		tds    = make([]*big.Int, 0, e.Count())
+		prev   *types.Block
+		prevTd *big.Int
+	)
+	for it.Next() {
+		var (
+			block    = it.Block()
+			receipts = it.Receipts()
+			td       = it.TotalDifficulty()
+			header   = block.Header()
+		)
+		if block.NumberU64() != it.Number() {
+			return common.Hash{}, fmt.Errorf("block %d: number mismatch, have %d", it.Number(), block.NumberU64())
+		}
+		if hash := types.DeriveSha(block.Transactions(), trie.NewStackTrie(nil)); hash != header.TxHash {
+			return common.Hash{}, fmt.Errorf("block %d: transaction root mismatch: have %x, want %x", it.Number(), hash, header.TxHash)
+		}
+		if hash := types.CalcUncleHash(block.Uncles()); hash != header.UncleHash {
+			return common.Hash{}, fmt.Errorf("block %d: uncle root mismatch: have %x, want %x", it.Number(), hash, header.UncleHash)
+		}
+		if hash := types.DeriveSha(receipts, trie.NewStackTrie(nil)); hash != header.ReceiptHash {
+			return common.Hash{}, fmt.Errorf("block %d: receipt root mismatch: have %x, want %x", it.Number(), hash, header.ReceiptHash)
+		}
+		if prev != nil {
+			if block.ParentHash() != prev.Hash() {
+				return common.Hash{}, fmt.Errorf("block %d: parent hash mismatch: have %x, want %x", it.Number(), block.ParentHash(), prev.Hash())
+			}
+			if want := new(big.Int).Add(prevTd, block.Difficulty()); td.Cmp(want) != 0 {
+				return common.Hash{}, fmt.Errorf("block %d: total difficulty mismatch: have %v, want %v", it.Number(), td, want)
+			}
+		}
+		hashes = append(hashes, block.Hash())
+		tds = append(tds, td)
+		prev, prevTd = block, td
+	}
+	if err := it.Error(); err != nil {
+		return common.Hash{}, err
+	}
+	want, err := ComputeAccumulator(hashes, tds)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	have, err := e.Accumulator()
+	if err != nil {
+		return common.Hash{}, err
+	}
+	if have != want {
+		return common.Hash{}, fmt.Errorf("accumulator mismatch: have %x, want %x", have, want)
+	}
+	return want, nil
+}
@@ -0,0 +1,265 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package era
+
+import (
+	"bytes"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/trie"
+)
+
+// makeTestChain creates a chain of blocks with a transaction and a matching
+// receipt each, along with the total difficulties of the blocks.
+func makeTestChain(start uint64, count int) ([]*types.Block, []types.Receipts, []*big.Int) {
+	var (
+		blocks   []*types.Block
+		receipts []types.Receipts
+		tds      []*big.Int
+		parent   = common.Hash{0x01}
+		td       = big.NewInt(int64(start) * 100)
+	)
+	for i := 0; i < count; i++ {
+		number := start + uint64(i)
+		header := &types.Header{
+			ParentHash: parent,
+			Number:     new(big.Int).SetUint64(number),
+			Difficulty: big.NewInt(int64(100 + i)),
+			GasLimit:   8_000_000,
+			Extra:      []byte("era test"),
+		}
+		tx := types.NewTx(&types.AccessListTx{
+			ChainID: big.NewInt(1),
+			Nonce:   uint64(i),
+			Gas:     21000,
+			Value:   big.NewInt(int64(i)),
+		})
+		receipt := &types.Receipt{
+			Type:              types.AccessListTxType,
+			Status:            types.ReceiptStatusSuccessful,
+			CumulativeGasUsed: 21000,
+			Logs: []*types.Log{{
+				Address: common.Address{byte(i)},
+				Topics:  []common.Hash{{byte(i)}},
+				Data:    []byte{byte(i)},
+			}},
+		}
+		receipt.Bloom = types.CreateBloom(types.Receipts{receipt})
+		block := types.NewBlock(header, []*types.Transaction{tx}, nil, []*types.Receipt{receipt}, trie.NewStackTrie(nil))
+
+		td = new(big.Int).Add(td, header.Difficulty)
+		blocks = append(blocks, block)
+		receipts = append(receipts, types.Receipts{receipt})
+		tds = append(tds, td)
+		parent = block.Hash()
+	}
+	return blocks, receipts, tds
+}
+
+func TestEra1Builder(t *testing.T) {
+	f, err := ioutil.TempFile("", "era1-test")
+	if err != nil {
+		t.Fatalf("error creating temp file: %v", err)
+	}
+	defer os.Remove(f.Name())
+
+	var (
+		builder               = NewBuilder(f)
+		blocks, receipts, tds = makeTestChain(8192, 128)
+	)
+	for i, block := range blocks {
+		if err := builder.Add(block, receipts[i], tds[i]); err != nil {
+			t.Fatalf("error adding block %d: %v", i, err)
+		}
+	}
+	root, err := builder.Finalize()
+	if err != nil {
+		t.Fatalf("error finalizing era1: %v", err)
+	}
+	f.Close()
+
+	// Verify Era1 contents.
+	e, err := Open(f.Name())
+	if err != nil {
+		t.Fatalf("failed to open era: %v", err)
+	}
+	defer e.Close()
+
+	if e.Start() != 8192 || e.Count() != 128 {
+		t.Fatalf("metadata mismatch: have start %d count %d", e.Start(), e.Count())
+	}
+	if have, err := e.Accumulator(); err != nil || have != root {
+		t.Fatalf("accumulator mismatch: have %x (%v), want %x", have, err, root)
+	}
+	if have, err := e.Verify(); err != nil || have != root {
+		t.Fatalf("verification failed: have %x (%v), want %x", have, err, root)
+	}
+	if td, err := e.InitialTD(); err != nil || td.Cmp(big.NewInt(8192*100)) != 0 {
+		t.Fatalf("initial total difficulty mismatch: have %v (%v)", td, err)
+	}
+	// Check random access in reverse order.
+	for i := len(blocks) - 1; i >= 0; i-- {
+		num := blocks[i].NumberU64()
+		block, err := e.GetBlockByNumber(num)
+		if err != nil {
+			t.Fatalf("error reading block %d: %v", num, err)
+		}
+		if block.Hash() != blocks[i].Hash() {
+			t.Fatalf("block %d hash mismatch", num)
+		}
+		body, err := e.GetRawBodyByNumber(num)
+		if err != nil {
+			t.Fatalf("error reading body %d: %v", num, err)
+		}
+		want, _ := rlp.EncodeToBytes(blocks[i].Body())
+		if !bytes.Equal(body, want) {
+			t.Fatalf("body %d mismatch", num)
+		}
+		have, err := e.GetReceiptsByNumber(num)
+		if err != nil {
+			t.Fatalf("error reading receipts %d: %v", num, err)
+		}
+		if len(have) != 1 || have[0].CumulativeGasUsed != 21000 || len(have[0].Logs) != 1 || have[0].Logs[0].Address != (common.Address{byte(i)}) {
+			t.Fatalf("receipts %d mismatch", num)
+		}
+		td, err := e.GetTotalDifficulty(num)
+		if err != nil {
+			t.Fatalf("error reading total difficulty %d: %v", num, err)
+		}
+		if td.Cmp(tds[i]) != 0 {
+			t.Fatalf("total difficulty %d mismatch: have %v, want %v", num, td, tds[i])
+		}
+	}
+	// Out of range lookups must fail.
+	if _, err := e.GetBlockByNumber(8191); err == nil {
+		t.Fatalf("expected error for block before start")
+	}
+	if _, err := e.GetBlockByNumber(8192 + 128); err == nil {
+		t.Fatalf("expected error for block after end")
+	}
+}
+
+func TestEra1BuilderContiguous(t *testing.T) {
+	var (
+		builder               = NewBuilder(new(bytes.Buffer))
+		blocks, receipts, tds = makeTestChain(0, 3)
+	)
+	if err := builder.Add(blocks[0], receipts[0], tds[0]); err != nil {
+		t.Fatalf("error adding block: %v", err)
+	}
+	if err := builder.Add(blocks[2], receipts[2], tds[2]); err == nil {
+		t.Fatalf("expected error for gapped block")
+	}
+	fork, forkReceipts, forkTds := makeTestChain(1, 1)
+	if err := builder.Add(fork[0], forkReceipts[0], forkTds[0]); err == nil {
+		t.Fatalf("expected error for unlinked block")
+	}
+}
+
+func TestEra1Tampered(t *testing.T) {
+	var (
+		buf                   = new(bytes.Buffer)
+		builder               = NewBuilder(buf)
+		blocks, receipts, tds = makeTestChain(0, 16)
+	)
+	for i, block := range blocks {
+		// Report a wrong total difficulty for one of the blocks
+		td := tds[i]
+		if i == 7 {
+			td = new(big.Int).Add(td, common.Big1)
+		}
+		if err := builder.Add(block, receipts[i], td); err != nil {
+			t.Fatalf("error adding block %d: %v", i, err)
+		}
+	}
+	if _, err := builder.Finalize(); err != nil {
+		t.Fatalf("error finalizing era1: %v", err)
+	}
+	e, err := From(nopCloser{bytes.NewReader(buf.Bytes())})
+	if err != nil {
+		t.Fatalf("failed to open era: %v", err)
+	}
+	if _, err := e.Verify(); err == nil {
+		t.Fatalf("tampered era verified")
+	}
+}
+
+func TestEra1Filenames(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{
+		Filename("mainnet", 0, common.Hash{0xaa}),
+		Filename("mainnet", 1, common.Hash{0xbb}),
+		Filename("sepolia", 0, common.Hash{0xcc}),
+		"readme.txt",
+	} {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), nil, 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	entries, err := ReadDir(dir, "mainnet")
+	if err != nil {
+		t.Fatalf("failed to read dir: %v", err)
+	}
+	if len(entries) != 2 || entries[0] != "mainnet-00000-aa000000.era1" || entries[1] != "mainnet-00001-bb000000.era1" {
+		t.Fatalf("unexpected entries: %v", entries)
+	}
+	// Gaps in the epochs are rejected
+	os.Remove(filepath.Join(dir, entries[0]))
+	if err := ioutil.WriteFile(filepath.Join(dir, Filename("mainnet", 3, common.Hash{})), nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ReadDir(dir, "mainnet"); err == nil {
+		t.Fatalf("expected error for missing epoch")
+	}
+}
+
+func TestAccumulator(t *testing.T) {
+	// The root of an empty list is the empty subtree root mixed with a zero length
+	empty, err := ComputeAccumulator(nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := common.HexToHash("0x4a8c3a07c8d23adc5bac61157555c3c784d53d9bc110c1370809bd23cd93777d"); empty != want {
+		t.Fatalf("empty accumulator mismatch: have %x, want %x", empty, want)
+	}
+	single, err := ComputeAccumulator([]common.Hash{{0x01}}, []*big.Int{big.NewInt(1000)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := common.HexToHash("0xf4facb750f3a5634024013f40fb3497966e994c94bc1e29d7dda2757f10ef07c"); single != want {
+		t.Fatalf("single record accumulator mismatch: have %x, want %x", single, want)
+	}
+	if _, err := ComputeAccumulator(make([]common.Hash, MaxEra1Size+1), make([]*big.Int, MaxEra1Size+1)); err == nil {
+		t.Fatalf("expected error for oversized accumulator")
+	}
+	if _, err := ComputeAccumulator([]common.Hash{{}}, nil); err == nil {
+		t.Fatalf("expected error for mismatching lengths")
+	}
+}
+
+type nopCloser struct {
+	*bytes.Reader
+}
+
+func (nopCloser) Close() error { return nil }
@@ -0,0 +1,101 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package era
+
+import (
+	"errors"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// Iterator wraps an Era1 archive and allows in-order iteration over its
+// blocks, receipts and total difficulties.
+type Iterator struct {
+	e    *Era
+	next uint64
+	err  error
+
+	block    *types.Block
+	receipts types.Receipts
+	td       *big.Int
+}
+
+// NewIterator returns a new Iterator instance. Next must be immediately
+// called on new iterators to load the first item.
+func NewIterator(e *Era) (*Iterator, error) {
+	if e.Count() == 0 {
+		return nil, errors.New("empty era file")
+	}
+	return &Iterator{e: e, next: e.Start()}, nil
+}
+
+// Next moves the iterator to the next block entry. It returns false when all
+// items have been read or an error has halted its progress. Error() should be
+// called after to determine if an error occurred.
+func (it *Iterator) Next() bool {
+	if it.err != nil || it.next >= it.e.Start()+it.e.Count() {
+		return false
+	}
+	num := it.next
+	if it.block, it.err = it.e.GetBlockByNumber(num); it.err != nil {
+		return false
+	}
+	if it.receipts, it.err = it.e.GetReceiptsByNumber(num); it.err != nil {
+		return false
+	}
+	// The transaction types are not part of the stored receipts, fill them
+	// in from the block so the receipts can be hashed.
+	if txs := it.block.Transactions(); len(txs) == len(it.receipts) {
+		for i, receipt := range it.receipts {
+			receipt.Type = txs[i].Type()
+		}
+	}
+	if it.td, it.err = it.e.GetTotalDifficulty(num); it.err != nil {
+		return false
+	}
+	it.next++
+	return true
+}
+
+// Number returns the current number of the block the iterator is at.
+func (it *Iterator) Number() uint64 {
+	return it.next - 1
+}
+
+// Error returns the error status of the iterator. It should be called before
+// reading from any of the iterator's values.
+func (it *Iterator) Error() error {
+	return it.err
+}
+
+// Block returns the block for the iterator's current position.
+func (it *Iterator) Block() *types.Block {
+	return it.block
+}
+
+// Receipts returns the receipts for the iterator's current position. Only the
+// consensus fields of the receipts are populated.
+func (it *Iterator) Receipts() types.Receipts {
+	return it.receipts
+}
+
+// TotalDifficulty returns the total difficulty for the iterator's current
+// position.
+func (it *Iterator) TotalDifficulty() *big.Int {
+	return it.td
+}
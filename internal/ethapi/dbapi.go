@@ -19,6 +19,7 @@ package ethapi
 import (
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/rawdb"
 )
 
 // DbGet returns the raw value of a key stored in the database.
@@ -41,3 +42,133 @@ func (api *PrivateDebugAPI) DbAncient(kind string, number uint64) (hexutil.Bytes
 func (api *PrivateDebugAPI) DbAncients() (uint64, error) {
 	return api.b.ChainDb().Ancients()
 }
+
+// DbStats returns the number of entries and bytes written into and deleted from
+// the key-value store per category of data since startup, along with the net
+// change of the stored bytes, and the sizes of the ancient tables.
+func (api *PrivateDebugAPI) DbStats() []*rawdb.CategoryStats {
+	return rawdb.ReadDatabaseStats(api.b.ChainDb())
+}
+
+// dbIterateLimit is the maximum number of entries returned by a DbIterate call.
+const dbIterateLimit = 1024
+
+// dbIterateSizeLimit is the soft limit of the size of the entries returned by
+// a DbIterate call.
+const dbIterateSizeLimit = 2 * 1024 * 1024
+
+// DbIterateResult is a page of database entries returned by DbIterate.
+type DbIterateResult struct {
+	Keys   []hexutil.Bytes `json:"keys"`
+	Values []hexutil.Bytes `json:"values"`
+	Next   *hexutil.Bytes  `json:"next"` // Start of the next page relative to the prefix, nil if exhausted
+}
+
+// DbIterate returns a page of at most limit database entries with the given key
+// prefix, starting at the given key (relative to the prefix). The iteration can
+// be continued by passing the returned next key as the start of the next call.
+func (api *PrivateDebugAPI) DbIterate(prefix hexutil.Bytes, start hexutil.Bytes, limit int) (*DbIterateResult, error) {
+	if limit <= 0 || limit > dbIterateLimit {
+		limit = dbIterateLimit
+	}
+	it := api.b.ChainDb().NewIterator(prefix, start)
+	defer it.Release()
+
+	var (
+		result = &DbIterateResult{Keys: []hexutil.Bytes{}, Values: []hexutil.Bytes{}}
+		size   int
+	)
+	for it.Next() {
+		if len(result.Keys) >= limit || size >= dbIterateSizeLimit {
+			next := hexutil.Bytes(common.CopyBytes(it.Key()[len(prefix):]))
+			result.Next = &next
+			break
+		}
+		result.Keys = append(result.Keys, common.CopyBytes(it.Key()))
+		result.Values = append(result.Values, common.CopyBytes(it.Value()))
+		size += len(it.Key()) + len(it.Value())
+	}
+	return result, it.Error()
+}
+
+// DbAncientRange retrieves multiple items in sequence from an ancient table,
+// starting at the given index. It returns at most count items, and at most
+// maxBytes of data unless the first item alone is larger.
+// It is a mapping to the `AncientReaderOp.AncientRange` method
+func (api *PrivateDebugAPI) DbAncientRange(kind string, start, count, maxBytes uint64) ([]hexutil.Bytes, error) {
+	items, err := api.b.ChainDb().AncientRange(kind, start, count, maxBytes)
+	if err != nil {
+		return nil, err
+	}
+	blobs := make([]hexutil.Bytes, len(items))
+	for i, item := range items {
+		blobs[i] = item
+	}
+	return blobs, nil
+}
+
+// DbTail returns the number of the first stored item in the ancient store.
+// It is a mapping to the `AncientReaderOp.Tail` method
+func (api *PrivateDebugAPI) DbTail() (uint64, error) {
+	return api.b.ChainDb().Tail()
+}
+
+// DbAncientSize returns the size of the given ancient table.
+// It is a mapping to the `AncientReaderOp.AncientSize` method
+func (api *PrivateDebugAPI) DbAncientSize(kind string) (uint64, error) {
+	return api.b.ChainDb().AncientSize(kind)
+}
+
+// PrivateDBWriteAPI provides write access to the raw chain database. It can
+// corrupt the node's database, so it's only served on the authenticated RPC
+// endpoints and has to be explicitly enabled.
+type PrivateDBWriteAPI struct {
+	b Backend
+}
+
+// NewPrivateDBWriteAPI creates a new API for writing into the chain database.
+func NewPrivateDBWriteAPI(b Backend) *PrivateDBWriteAPI {
+	return &PrivateDBWriteAPI{b: b}
+}
+
+// DbPut stores a raw value under the given key in the database.
+func (api *PrivateDBWriteAPI) DbPut(key string, value hexutil.Bytes) error {
+	blob, err := common.ParseHexOrString(key)
+	if err != nil {
+		return err
+	}
+	return api.b.ChainDb().Put(blob, value)
+}
+
+// DbDelete removes the given key from the database.
+func (api *PrivateDBWriteAPI) DbDelete(key string) error {
+	blob, err := common.ParseHexOrString(key)
+	if err != nil {
+		return err
+	}
+	return api.b.ChainDb().Delete(blob)
+}
+
+// DbWriteOp is a single operation of a database write batch.
+type DbWriteOp struct {
+	Key    hexutil.Bytes `json:"key"`
+	Value  hexutil.Bytes `json:"value,omitempty"`
+	Delete bool          `json:"delete,omitempty"`
+}
+
+// DbWrite atomically applies a batch of puts and deletes to the database.
+func (api *PrivateDBWriteAPI) DbWrite(ops []DbWriteOp) error {
+	batch := api.b.ChainDb().NewBatch()
+	for _, op := range ops {
+		var err error
+		if op.Delete {
+			err = batch.Delete(op.Key)
+		} else {
+			err = batch.Put(op.Key, op.Value)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return batch.Write()
+}
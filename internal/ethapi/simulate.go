@@ -0,0 +1,400 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package ethapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/consensus/misc"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+const (
+	// maxSimulateBlocks is the maximum number of blocks that can be simulated
+	// in a single request.
+	maxSimulateBlocks = 256
+
+	// simulateBlockTime is the timestamp increment used for simulated blocks
+	// that don't override their timestamp.
+	simulateBlockTime = 12
+)
+
+// BlockOverrides is a set of header fields to override when simulating a block.
+type BlockOverrides struct {
+	Number     *hexutil.Big    `json:"number"`
+	Difficulty *hexutil.Big    `json:"difficulty"`
+	Time       *hexutil.Uint64 `json:"time"`
+	GasLimit   *hexutil.Uint64 `json:"gasLimit"`
+	Coinbase   *common.Address `json:"coinbase"`
+	Random     *common.Hash    `json:"random"`
+	BaseFee    *hexutil.Big    `json:"baseFee"`
+}
+
+// apply overrides the fields of the given header with the requested values.
+func (diff *BlockOverrides) apply(header *types.Header) {
+	if diff == nil {
+		return
+	}
+	if diff.Number != nil {
+		header.Number = new(big.Int).Set(diff.Number.ToInt())
+	}
+	if diff.Difficulty != nil {
+		header.Difficulty = new(big.Int).Set(diff.Difficulty.ToInt())
+	}
+	if diff.Time != nil {
+		header.Time = uint64(*diff.Time)
+	}
+	if diff.GasLimit != nil {
+		header.GasLimit = uint64(*diff.GasLimit)
+	}
+	if diff.Coinbase != nil {
+		header.Coinbase = *diff.Coinbase
+	}
+	if diff.Random != nil {
+		header.MixDigest = *diff.Random
+	}
+	if diff.BaseFee != nil {
+		header.BaseFee = new(big.Int).Set(diff.BaseFee.ToInt())
+	}
+}
+
+// SimBlock is a batch of calls to be simulated sequentially on top of the
+// same block, along with the block and state modifications to apply first.
+type SimBlock struct {
+	BlockOverrides *BlockOverrides   `json:"blockOverrides"`
+	StateOverrides *StateOverride    `json:"stateOverrides"`
+	Calls          []TransactionArgs `json:"calls"`
+}
+
+// SimOpts are the inputs to eth_simulateV1.
+type SimOpts struct {
+	BlockStateCalls []SimBlock `json:"blockStateCalls"`
+}
+
+// simCallResult is the result of a single simulated call.
+type simCallResult struct {
+	ReturnValue hexutil.Bytes  `json:"returnData"`
+	Logs        []*types.Log   `json:"logs"`
+	GasUsed     hexutil.Uint64 `json:"gasUsed"`
+	Status      hexutil.Uint64 `json:"status"`
+	Error       *callError     `json:"error,omitempty"`
+}
+
+// callError is the error of a failed simulated call. Unlike top level errors,
+// these don't abort the simulation but are reported alongside the call.
+type callError struct {
+	Message string `json:"message"`
+	Code    int    `json:"code"`
+	Data    string `json:"data,omitempty"`
+}
+
+// errCodeVMError is the error code of simulated calls failing for any reason
+// other than an explicit revert (e.g. out of gas).
+const errCodeVMError = -32015
+
+// simulator runs a sequence of simulated blocks on top of a base block,
+// carrying the state forward between calls and blocks.
+type simulator struct {
+	b       Backend
+	state   *state.StateDB
+	base    *types.Header
+	gasCap  uint64 // gas allowance for the entire simulation, 0 = unlimited
+	gasUsed uint64 // gas used by all the calls simulated so far
+	headers []*types.Header
+
+	hashCache map[uint64]common.Hash // cache of canonical ancestor hashes for BLOCKHASH
+}
+
+// execute simulates all the given blocks in order and returns the simulated
+// headers along with the results of every call.
+func (sim *simulator) execute(ctx context.Context, blocks []SimBlock) ([]map[string]interface{}, error) {
+	if len(blocks) > maxSimulateBlocks {
+		return nil, fmt.Errorf("too many blocks: %d > %d", len(blocks), maxSimulateBlocks)
+	}
+	var (
+		results = make([]map[string]interface{}, 0, len(blocks))
+		parent  = sim.base
+	)
+	for bi, block := range blocks {
+		header, err := sim.makeHeader(parent, block.BlockOverrides)
+		if err != nil {
+			return nil, fmt.Errorf("block %d: %w", bi, err)
+		}
+		if err := block.StateOverrides.Apply(sim.state); err != nil {
+			return nil, fmt.Errorf("block %d: %w", bi, err)
+		}
+		calls, err := sim.processBlock(ctx, header, block.Calls)
+		if err != nil {
+			return nil, fmt.Errorf("block %d: %w", bi, err)
+		}
+		sim.headers = append(sim.headers, header)
+
+		fields := RPCMarshalHeader(header)
+		fields["calls"] = calls
+		results = append(results, fields)
+
+		parent = header
+	}
+	return results, nil
+}
+
+// makeHeader assembles the header of the next simulated block on top of the
+// given parent, applying the requested overrides.
+func (sim *simulator) makeHeader(parent *types.Header, overrides *BlockOverrides) (*types.Header, error) {
+	header := &types.Header{
+		ParentHash: parent.Hash(),
+		UncleHash:  types.EmptyUncleHash,
+		Coinbase:   parent.Coinbase,
+		Difficulty: new(big.Int).Set(parent.Difficulty),
+		Number:     new(big.Int).Add(parent.Number, common.Big1),
+		GasLimit:   parent.GasLimit,
+		Time:       parent.Time + simulateBlockTime,
+		MixDigest:  parent.MixDigest,
+	}
+	config := sim.b.ChainConfig()
+	if config.IsLondon(header.Number) {
+		header.BaseFee = misc.CalcBaseFee(config, parent)
+	}
+	overrides.apply(header)
+
+	if header.Number.Cmp(parent.Number) <= 0 {
+		return nil, fmt.Errorf("block number not increasing: %v <= %v", header.Number, parent.Number)
+	}
+	if header.Time <= parent.Time {
+		return nil, fmt.Errorf("block timestamp not increasing: %d <= %d", header.Time, parent.Time)
+	}
+	// The block number might have been overridden into London without also
+	// specifying a base fee.
+	if header.BaseFee == nil && config.IsLondon(header.Number) {
+		header.BaseFee = new(big.Int)
+	}
+	return header, nil
+}
+
+// processBlock executes the calls of a single simulated block and finalizes
+// the header with the resulting gas usage, bloom and state root.
+func (sim *simulator) processBlock(ctx context.Context, header *types.Header, calls []TransactionArgs) ([]simCallResult, error) {
+	var (
+		config  = sim.b.ChainConfig()
+		gp      = new(core.GasPool).AddGas(header.GasLimit)
+		results = make([]simCallResult, len(calls))
+		allLogs []*types.Log
+	)
+	for i, args := range calls {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		// Cap the call by whatever is left of the global gas allowance.
+		var gasCap uint64
+		if sim.gasCap != 0 {
+			if sim.gasUsed >= sim.gasCap {
+				return nil, fmt.Errorf("call %d: gas allowance of %d exhausted", i, sim.gasCap)
+			}
+			gasCap = sim.gasCap - sim.gasUsed
+		}
+		// Default the gas limit of the call to whatever is left in the block,
+		// so that calls without explicit limits don't exceed it.
+		if args.Gas == nil {
+			remaining := hexutil.Uint64(gp.Gas())
+			args.Gas = &remaining
+		}
+		msg, err := args.ToMessage(gasCap, header.BaseFee)
+		if err != nil {
+			return nil, fmt.Errorf("call %d: %w", i, err)
+		}
+		// Derive a deterministic hash for the call so that its logs can be
+		// told apart from those of the other calls.
+		txHash := types.NewTx(&types.LegacyTx{
+			Nonce:    sim.state.GetNonce(msg.From()),
+			GasPrice: msg.GasPrice(),
+			Gas:      msg.Gas(),
+			To:       msg.To(),
+			Value:    msg.Value(),
+			Data:     msg.Data(),
+		}).Hash()
+		sim.state.Prepare(txHash, i)
+
+		result, err := sim.applyMessage(ctx, msg, header, gp)
+		if err != nil {
+			return nil, fmt.Errorf("call %d: %w", i, err)
+		}
+		sim.gasUsed += result.UsedGas
+		header.GasUsed += result.UsedGas
+
+		// Collect the logs and the outcome of the call.
+		logs := sim.state.GetLogs(txHash, common.Hash{})
+		for _, l := range logs {
+			l.BlockNumber = header.Number.Uint64()
+			l.Index = uint(len(allLogs))
+			allLogs = append(allLogs, l)
+		}
+		if logs == nil {
+			logs = []*types.Log{}
+		}
+		res := simCallResult{
+			ReturnValue: result.Return(),
+			Logs:        logs,
+			GasUsed:     hexutil.Uint64(result.UsedGas),
+			Status:      hexutil.Uint64(types.ReceiptStatusSuccessful),
+		}
+		if result.Failed() {
+			res.Status = hexutil.Uint64(types.ReceiptStatusFailed)
+			if errors.Is(result.Err, vm.ErrExecutionReverted) {
+				revert := newRevertError(result)
+				res.Error = &callError{Message: revert.Error(), Code: revert.ErrorCode(), Data: revert.reason}
+			} else {
+				res.Error = &callError{Message: result.Err.Error(), Code: errCodeVMError}
+			}
+		}
+		results[i] = res
+
+		// Finalise the state so that the next call observes the changes of
+		// this one, the same way consecutive transactions would.
+		sim.state.Finalise(config.IsEIP158(header.Number))
+	}
+	header.Bloom = types.BytesToBloom(types.LogsBloom(allLogs))
+	header.Root = sim.state.IntermediateRoot(config.IsEIP158(header.Number))
+
+	// Now that the header is final, fill in the block hash of the logs.
+	hash := header.Hash()
+	for _, l := range allLogs {
+		l.BlockHash = hash
+	}
+	return results, nil
+}
+
+// applyMessage executes a single message on top of the current simulation
+// state, using the given header as the block context.
+func (sim *simulator) applyMessage(ctx context.Context, msg types.Message, header *types.Header, gp *core.GasPool) (*core.ExecutionResult, error) {
+	evm, vmError, err := sim.b.GetEVM(ctx, msg, sim.state, header, &vm.Config{NoBaseFee: true})
+	if err != nil {
+		return nil, err
+	}
+	// The backend derives the block context from the canonical chain, which
+	// doesn't know about the simulated blocks. Patch in the fields it can't
+	// resolve on its own.
+	evm.Context.Coinbase = header.Coinbase
+	evm.Context.GetHash = sim.getHash
+
+	// Wait for the context to be done and cancel the evm. Even if the
+	// EVM has finished, cancelling may be done (repeatedly)
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			evm.Cancel()
+		case <-done:
+		}
+	}()
+	result, err := core.ApplyMessage(evm, msg, gp)
+	if err := vmError(); err != nil {
+		return nil, err
+	}
+	if evm.Cancelled() {
+		return nil, fmt.Errorf("execution aborted: %w", ctx.Err())
+	}
+	if err != nil {
+		return nil, fmt.Errorf("err: %w (supplied gas %d)", err, msg.Gas())
+	}
+	return result, nil
+}
+
+// getHash returns the hash of the block with the given number, resolving both
+// the simulated blocks and the ancestors of the base block.
+func (sim *simulator) getHash(number uint64) common.Hash {
+	for i := len(sim.headers) - 1; i >= 0; i-- {
+		if sim.headers[i].Number.Uint64() == number {
+			return sim.headers[i].Hash()
+		}
+	}
+	if number > sim.base.Number.Uint64() {
+		return common.Hash{} // gap between simulated blocks
+	}
+	if number == sim.base.Number.Uint64() {
+		return sim.base.Hash()
+	}
+	if hash, ok := sim.hashCache[number]; ok {
+		return hash
+	}
+	// Walk back from the base block, caching every hash along the way.
+	var (
+		ctx    = context.Background()
+		header = sim.base
+	)
+	for header.Number.Uint64() > number {
+		parent, err := sim.b.HeaderByHash(ctx, header.ParentHash)
+		if parent == nil || err != nil {
+			return common.Hash{}
+		}
+		sim.hashCache[parent.Number.Uint64()] = parent.Hash()
+		header = parent
+	}
+	return header.Hash()
+}
+
+// SimulateV1 executes a series of simulated blocks on top of the given block,
+// each of them with its own block and state overrides and a list of calls.
+// The state changes of every call are visible to all subsequent calls, both
+// within the same block and in the blocks after it.
+//
+// Note, this function doesn't make any changes in the state/blockchain and is
+// useful to preview the outcome of bundles of transactions.
+func (s *PublicBlockChainAPI) SimulateV1(ctx context.Context, opts SimOpts, blockNrOrHash *rpc.BlockNumberOrHash) ([]map[string]interface{}, error) {
+	defer func(start time.Time) { log.Debug("Executing EVM simulation finished", "runtime", time.Since(start)) }(time.Now())
+
+	if len(opts.BlockStateCalls) == 0 {
+		return nil, errors.New("empty input")
+	}
+	bNrOrHash := rpc.BlockNumberOrHashWithNumber(rpc.LatestBlockNumber)
+	if blockNrOrHash != nil {
+		bNrOrHash = *blockNrOrHash
+	}
+	state, header, err := s.b.StateAndHeaderByNumberOrHash(ctx, bNrOrHash)
+	if state == nil || err != nil {
+		return nil, err
+	}
+	// Setup context so it may be cancelled the simulation has completed
+	// or, in case of unmetered gas, setup a context with a timeout.
+	var cancel context.CancelFunc
+	if timeout := s.b.RPCEVMTimeout(); timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+	} else {
+		ctx, cancel = context.WithCancel(ctx)
+	}
+	defer cancel()
+
+	sim := &simulator{
+		b:         s.b,
+		state:     state,
+		base:      header,
+		gasCap:    s.b.RPCGasCap(),
+		hashCache: make(map[uint64]common.Hash),
+	}
+	return sim.execute(ctx, opts.BlockStateCalls)
+}
@@ -284,6 +284,10 @@ func (s *LightDummyAPI) Mining() bool {
 func (s *LightEthereum) APIs() []rpc.API {
 	apis := ethapi.GetAPIs(s.ApiBackend)
 	apis = append(apis, s.engine.APIs(s.BlockChain().HeaderChain())...)
+	logLimits := filters.LogQueryLimits{
+		MaxBlockRange: s.config.RPCLogQueryMaxBlockRange,
+		MaxResults:    s.config.RPCLogQueryMaxResults,
+	}
 	return append(apis, []rpc.API{
 		{
 			Namespace: "eth",
@@ -298,7 +302,7 @@ func (s *LightEthereum) APIs() []rpc.API {
 		}, {
 			Namespace: "eth",
 			Version:   "1.0",
-			Service:   filters.NewPublicFilterAPI(s.ApiBackend, true, 5*time.Minute),
+			Service:   filters.NewPublicFilterAPI(s.ApiBackend, true, 5*time.Minute, logLimits),
 			Public:    true,
 		}, {
 			Namespace: "net",
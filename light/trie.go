@@ -54,7 +54,7 @@ func (db *odrDatabase) OpenTrie(root common.Hash) (state.Trie, error) {
 	return &odrTrie{db: db, id: db.id}, nil
 }
 
-func (db *odrDatabase) OpenStorageTrie(addrHash, root common.Hash) (state.Trie, error) {
+func (db *odrDatabase) OpenStorageTrie(stateRoot, addrHash, root common.Hash) (state.Trie, error) {
 	return &odrTrie{db: db, id: StorageTrieID(db.id, addrHash, root)}, nil
 }
 
@@ -144,6 +144,11 @@ func (t *odrTrie) Commit(onleaf trie.LeafCallback) (common.Hash, int, error) {
 	return t.trie.Commit(onleaf)
 }
 
+func (t *odrTrie) CommitNodes(onleaf trie.LeafCallback) (common.Hash, int, *trie.NodeSet, error) {
+	root, committed, err := t.Commit(onleaf)
+	return root, committed, nil, err
+}
+
 func (t *odrTrie) Hash() common.Hash {
 	if t.trie == nil {
 		return t.id.Root
@@ -0,0 +1,211 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package miner
+
+import (
+	"bytes"
+	"container/heap"
+	"fmt"
+	"math/big"
+	"sort"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+const (
+	// TipOrdering orders the transactions by effective miner tip, the default.
+	TipOrdering = "tip"
+
+	// FIFOOrdering orders the transactions by the time they were first seen.
+	FIFOOrdering = "fifo"
+)
+
+// TransactionSet is a set of pending transactions, which are consumed in order
+// while filling a block. Transactions of the same account must be returned in
+// nonce order.
+type TransactionSet interface {
+	// Peek returns the next transaction to include, or nil if the set is empty.
+	Peek() *types.Transaction
+
+	// Shift replaces the current transaction with the next one from the same
+	// account.
+	Shift()
+
+	// Pop removes the current transaction, *not* replacing it with the next
+	// one from the same account, because it can't be executed.
+	Pop()
+}
+
+// OrderingPolicy decides the order in which the pending transactions are
+// included into the blocks built by the miner.
+type OrderingPolicy interface {
+	// Order creates the set of the given per account nonce-sorted transactions,
+	// which the block is filled from. The map is owned by the set.
+	Order(signer types.Signer, txs map[common.Address]types.Transactions, baseFee *big.Int) TransactionSet
+}
+
+var (
+	orderingLock     sync.RWMutex
+	orderingPolicies = map[string]OrderingPolicy{
+		TipOrdering:  tipOrdering{},
+		FIFOOrdering: fifoOrdering{},
+	}
+)
+
+// RegisterOrderingPolicy makes a custom ordering policy selectable by name in
+// the miner configuration. It's meant to be called during initialization.
+func RegisterOrderingPolicy(name string, policy OrderingPolicy) {
+	orderingLock.Lock()
+	defer orderingLock.Unlock()
+
+	orderingPolicies[name] = policy
+}
+
+// LookupOrderingPolicy returns the ordering policy of the given name. The tip
+// ordering is returned for the empty name.
+func LookupOrderingPolicy(name string) (OrderingPolicy, error) {
+	if name == "" {
+		name = TipOrdering
+	}
+	orderingLock.RLock()
+	defer orderingLock.RUnlock()
+
+	policy, ok := orderingPolicies[name]
+	if !ok {
+		names := make([]string, 0, len(orderingPolicies))
+		for name := range orderingPolicies {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return nil, fmt.Errorf("unknown ordering policy %q, available: %v", name, names)
+	}
+	return policy, nil
+}
+
+// tipOrdering orders the transactions by effective miner tip, breaking ties by
+// the time they were first seen.
+type tipOrdering struct{}
+
+// Order implements OrderingPolicy.
+func (tipOrdering) Order(signer types.Signer, txs map[common.Address]types.Transactions, baseFee *big.Int) TransactionSet {
+	return types.NewTransactionsByPriceAndNonce(signer, txs, baseFee)
+}
+
+// fifoOrdering orders the transactions by the time they were first seen,
+// regardless of the tip they pay.
+type fifoOrdering struct{}
+
+// Order implements OrderingPolicy.
+func (fifoOrdering) Order(signer types.Signer, txs map[common.Address]types.Transactions, baseFee *big.Int) TransactionSet {
+	return newTransactionsByTimeAndNonce(signer, txs, baseFee)
+}
+
+// txByTime is a heap of the next transaction of each account, ordered by the
+// time they were first seen.
+type txByTime []*types.Transaction
+
+func (s txByTime) Len() int { return len(s) }
+func (s txByTime) Less(i, j int) bool {
+	if s[i].Time().Equal(s[j].Time()) {
+		// Break ties deterministically
+		hi, hj := s[i].Hash(), s[j].Hash()
+		return bytes.Compare(hi[:], hj[:]) < 0
+	}
+	return s[i].Time().Before(s[j].Time())
+}
+func (s txByTime) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
+
+func (s *txByTime) Push(x interface{}) {
+	*s = append(*s, x.(*types.Transaction))
+}
+
+func (s *txByTime) Pop() interface{} {
+	old := *s
+	n := len(old)
+	x := old[n-1]
+	old[n-1] = nil
+	*s = old[0 : n-1]
+	return x
+}
+
+// transactionsByTimeAndNonce is a transaction set returning the transactions
+// in the order they were first seen, while honouring the nonce order within
+// each account.
+type transactionsByTimeAndNonce struct {
+	txs     map[common.Address]types.Transactions // Per account nonce-sorted list of transactions
+	heads   txByTime                              // Next transaction for each unique account
+	signer  types.Signer                          // Signer for the set of transactions
+	baseFee *big.Int                              // Current base fee
+}
+
+// newTransactionsByTimeAndNonce creates a transaction set that retrieves the
+// transactions in arrival order, dropping the ones not paying the base fee.
+func newTransactionsByTimeAndNonce(signer types.Signer, txs map[common.Address]types.Transactions, baseFee *big.Int) *transactionsByTimeAndNonce {
+	heads := make(txByTime, 0, len(txs))
+	for from, accTxs := range txs {
+		acc, _ := types.Sender(signer, accTxs[0])
+		// Remove transaction if sender doesn't match from, or if it's underpriced
+		if acc != from || !paysBaseFee(accTxs[0], baseFee) {
+			delete(txs, from)
+			continue
+		}
+		heads = append(heads, accTxs[0])
+		txs[from] = accTxs[1:]
+	}
+	heap.Init(&heads)
+
+	return &transactionsByTimeAndNonce{
+		txs:     txs,
+		heads:   heads,
+		signer:  signer,
+		baseFee: baseFee,
+	}
+}
+
+// Peek returns the earliest seen transaction.
+func (t *transactionsByTimeAndNonce) Peek() *types.Transaction {
+	if len(t.heads) == 0 {
+		return nil
+	}
+	return t.heads[0]
+}
+
+// Shift replaces the current head with the next one from the same account.
+func (t *transactionsByTimeAndNonce) Shift() {
+	acc, _ := types.Sender(t.signer, t.heads[0])
+	if txs, ok := t.txs[acc]; ok && len(txs) > 0 && paysBaseFee(txs[0], t.baseFee) {
+		t.heads[0], t.txs[acc] = txs[0], txs[1:]
+		heap.Fix(&t.heads, 0)
+		return
+	}
+	heap.Pop(&t.heads)
+}
+
+// Pop removes the current head, *not* replacing it with the next one from the
+// same account.
+func (t *transactionsByTimeAndNonce) Pop() {
+	heap.Pop(&t.heads)
+}
+
+// paysBaseFee reports whether the transaction can be included into a block with
+// the given base fee.
+func paysBaseFee(tx *types.Transaction, baseFee *big.Int) bool {
+	_, err := tx.EffectiveGasTip(baseFee)
+	return err == nil
+}
@@ -0,0 +1,104 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package miner
+
+import (
+	"crypto/ecdsa"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// Tests that the FIFO ordering returns the transactions in the order they were
+// first seen, honouring the nonces and ignoring the tips.
+func TestFIFOOrdering(t *testing.T) {
+	var (
+		signer  = types.LatestSignerForChainID(big.NewInt(1))
+		baseFee = big.NewInt(10)
+		keys    = make([]*ecdsa.PrivateKey, 3)
+		nonces  = make([]uint64, len(keys))
+		pending = make(map[common.Address]types.Transactions)
+		want    []common.Hash
+	)
+	for i := range keys {
+		keys[i], _ = crypto.GenerateKey()
+	}
+	// Create the transactions round robin, with increasing tips, and a final
+	// one not paying the base fee
+	sign := func(key int, feeCap int64) *types.Transaction {
+		tx := types.MustSignNewTx(keys[key], signer, &types.DynamicFeeTx{
+			ChainID:   big.NewInt(1),
+			Nonce:     nonces[key],
+			GasTipCap: big.NewInt(feeCap),
+			GasFeeCap: big.NewInt(feeCap),
+			Gas:       21000,
+		})
+		// Ensure the arrival times are strictly increasing
+		time.Sleep(time.Millisecond)
+		nonces[key]++
+		from := crypto.PubkeyToAddress(keys[key].PublicKey)
+		pending[from] = append(pending[from], tx)
+		return tx
+	}
+	for i := 0; i < 9; i++ {
+		want = append(want, sign(i%len(keys), int64(100+i)).Hash())
+	}
+	sign(0, 1)
+
+	policy, err := LookupOrderingPolicy(FIFOOrdering)
+	if err != nil {
+		t.Fatal(err)
+	}
+	txset := policy.Order(signer, pending, baseFee)
+
+	var have []common.Hash
+	for tx := txset.Peek(); tx != nil; tx = txset.Peek() {
+		have = append(have, tx.Hash())
+		txset.Shift()
+	}
+	if len(have) != len(want) {
+		t.Fatalf("transaction count mismatch: have %d, want %d", len(have), len(want))
+	}
+	for i := range want {
+		if have[i] != want[i] {
+			t.Errorf("transaction %d mismatch: have %x, want %x", i, have[i], want[i])
+		}
+	}
+}
+
+func TestLookupOrderingPolicy(t *testing.T) {
+	if policy, err := LookupOrderingPolicy(""); err != nil || policy != (tipOrdering{}) {
+		t.Errorf("default policy mismatch: %v, %v", policy, err)
+	}
+	if _, err := LookupOrderingPolicy("custom"); err == nil {
+		t.Error("unknown policy resolved")
+	}
+	RegisterOrderingPolicy("custom", fifoOrdering{})
+	t.Cleanup(func() {
+		orderingLock.Lock()
+		defer orderingLock.Unlock()
+
+		delete(orderingPolicies, "custom")
+	})
+	if _, err := LookupOrderingPolicy("custom"); err != nil {
+		t.Errorf("registered policy not resolved: %v", err)
+	}
+}
@@ -188,6 +188,7 @@ type worker struct {
 	engine      consensus.Engine
 	eth         Backend
 	chain       *core.BlockChain
+	ordering    OrderingPolicy // Policy deciding the order of the included transactions
 
 	// Feeds
 	pendingLogsFeed event.Feed
@@ -282,6 +283,14 @@ func newWorker(config *Config, chainConfig *params.ChainConfig, engine consensus
 	worker.chainHeadSub = eth.BlockChain().SubscribeChainHeadEvent(worker.chainHeadCh)
 	worker.chainSideSub = eth.BlockChain().SubscribeChainSideEvent(worker.chainSideCh)
 
+	// Resolve the transaction ordering policy, falling back to the tip ordering
+	policy, err := LookupOrderingPolicy(config.Ordering)
+	if err != nil {
+		log.Warn("Falling back to tip transaction ordering", "err", err)
+		policy = tipOrdering{}
+	}
+	worker.ordering = policy
+
 	// Sanitize recommit interval if the user-specified one is too short.
 	recommit := worker.config.Recommit
 	if recommit < minRecommitInterval {
@@ -595,7 +604,7 @@ func (w *worker) mainLoop() {
 					acc, _ := types.Sender(w.current.signer, tx)
 					txs[acc] = append(txs[acc], tx)
 				}
-				txset := types.NewTransactionsByPriceAndNonce(w.current.signer, txs, w.current.header.BaseFee)
+				txset := w.ordering.Order(w.current.signer, txs, w.current.header.BaseFee)
 				tcount := w.current.tcount
 				w.commitTransactions(w.current, txset, nil)
 
@@ -846,7 +855,7 @@ func (w *worker) commitTransaction(env *environment, tx *types.Transaction) ([]*
 	return receipt.Logs, nil
 }
 
-func (w *worker) commitTransactions(env *environment, txs *types.TransactionsByPriceAndNonce, interrupt *int32) error {
+func (w *worker) commitTransactions(env *environment, txs TransactionSet, interrupt *int32) error {
 	gasLimit := env.header.GasLimit
 	if env.gasPool == nil {
 		env.gasPool = new(core.GasPool).AddGas(gasLimit)
@@ -1055,9 +1064,11 @@ func (w *worker) prepareWork(genParams *generateParams) (*environment, error) {
 }
 
 // fillTransactions retrieves the pending transactions from the txpool and fills them
-// into the given sealing block. The transaction selection and ordering strategy can
-// be customized with the plugin in the future.
+// into the given sealing block, in the order of the configured ordering policy.
 func (w *worker) fillTransactions(interrupt *int32, env *environment) error {
+	// Place the bundles targeting this block at the top of it
+	w.commitBundles(env)
+
 	// Split the pending transactions into locals and remotes
 	// Fill the block with all available pending transactions.
 	pending := w.eth.TxPool().Pending(true)
@@ -1069,13 +1080,13 @@ func (w *worker) fillTransactions(interrupt *int32, env *environment) error {
 		}
 	}
 	if len(localTxs) > 0 {
-		txs := types.NewTransactionsByPriceAndNonce(env.signer, localTxs, env.header.BaseFee)
+		txs := w.ordering.Order(env.signer, localTxs, env.header.BaseFee)
 		if err := w.commitTransactions(env, txs, interrupt); err != nil {
 			return err
 		}
 	}
 	if len(remoteTxs) > 0 {
-		txs := types.NewTransactionsByPriceAndNonce(env.signer, remoteTxs, env.header.BaseFee)
+		txs := w.ordering.Order(env.signer, remoteTxs, env.header.BaseFee)
 		if err := w.commitTransactions(env, txs, interrupt); err != nil {
 			return err
 		}
@@ -1083,6 +1094,55 @@ func (w *worker) fillTransactions(interrupt *int32, env *environment) error {
 	return nil
 }
 
+// commitBundles includes the transaction bundles targeting the block being built,
+// in submission order. Each bundle is executed on a copy of the environment,
+// which is adopted if all of its transactions succeed. Bundles with failing
+// transactions, or reverting ones without being allowed to, are dropped from
+// the pool.
+func (w *worker) commitBundles(env *environment) {
+	bundles := w.eth.TxPool().Bundles(env.header.Number.Uint64(), env.header.Time)
+	if len(bundles) == 0 {
+		return
+	}
+	if env.gasPool == nil {
+		env.gasPool = new(core.GasPool).AddGas(env.header.GasLimit)
+	}
+	for _, bundle := range bundles {
+		// Execute the bundle on a copy, the state can't be reverted across the
+		// transactions once they are finalised
+		cpy := env.copy()
+		if err := w.commitBundle(cpy, bundle); err != nil {
+			log.Debug("Discarded transaction bundle", "number", bundle.BlockNumber, "txs", len(bundle.Txs), "err", err)
+			w.eth.TxPool().RemoveBundle(bundle)
+			continue
+		}
+		// The copied state only holds an inactive copy of the prefetcher, stop
+		// the running one and restart it on the adopted state
+		env.state.StopPrefetcher()
+		env.state, env.txs, env.receipts = cpy.state, cpy.txs, cpy.receipts
+		env.gasPool, env.tcount = cpy.gasPool, cpy.tcount
+		env.header.GasUsed = cpy.header.GasUsed
+		env.state.StartPrefetcher("miner")
+	}
+}
+
+// commitBundle applies the transactions of a bundle on top of the environment,
+// aborting at the first one failing or reverting without being allowed to.
+func (w *worker) commitBundle(env *environment, bundle *core.TxBundle) error {
+	for _, tx := range bundle.Txs {
+		env.state.Prepare(tx.Hash(), env.tcount)
+		if _, err := w.commitTransaction(env, tx); err != nil {
+			return fmt.Errorf("transaction %x failed: %v", tx.Hash(), err)
+		}
+		env.tcount++
+
+		if receipt := env.receipts[len(env.receipts)-1]; receipt.Status == types.ReceiptStatusFailed && !bundle.AllowsRevert(tx.Hash()) {
+			return fmt.Errorf("transaction %x reverted", tx.Hash())
+		}
+	}
+	return nil
+}
+
 // generateWork generates a sealing block based on the given parameters.
 func (w *worker) generateWork(params *generateParams) (*types.Block, error) {
 	work, err := w.prepareWork(params)
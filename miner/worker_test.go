@@ -17,6 +17,7 @@
 package miner
 
 import (
+	"crypto/ecdsa"
 	"errors"
 	"math/big"
 	"math/rand"
@@ -671,3 +672,80 @@ func testGetSealingWork(t *testing.T, chainConfig *params.ChainConfig, engine co
 		}
 	}
 }
+
+// Tests that transaction bundles are placed at the top of the block they target,
+// and discarded as a whole if any of their transactions fail, or revert without
+// being allowed to.
+func TestCommitBundles(t *testing.T) {
+	engine := ethash.NewFaker()
+	defer engine.Close()
+
+	w, b := newTestWorker(t, ethashChainConfig, engine, rawdb.NewMemoryDatabase(), 0)
+	defer w.close()
+
+	var (
+		signer   = types.LatestSigner(ethashChainConfig)
+		gasPrice = big.NewInt(10 * params.InitialBaseFee)
+	)
+	transfer := func(key *ecdsa.PrivateKey, nonce uint64, value int64) *types.Transaction {
+		return types.MustSignNewTx(key, signer, &types.LegacyTx{
+			Nonce:    nonce,
+			To:       &testUserAddress,
+			Value:    big.NewInt(value),
+			Gas:      params.TxGas,
+			GasPrice: gasPrice,
+		})
+	}
+	// Contract creation whose init code reverts right away
+	revert := types.MustSignNewTx(testBankKey, signer, &types.LegacyTx{
+		Nonce:    1,
+		Value:    big.NewInt(0),
+		Gas:      100000,
+		GasPrice: gasPrice,
+		Data:     common.FromHex("0x60006000fd"),
+	})
+	var (
+		reverting = &core.TxBundle{Txs: types.Transactions{transfer(testBankKey, 0, 1), revert}, BlockNumber: 1}
+		allowed   = &core.TxBundle{Txs: types.Transactions{transfer(testBankKey, 0, 2), revert}, BlockNumber: 1, RevertingTxHashes: []common.Hash{revert.Hash()}}
+		failing   = &core.TxBundle{Txs: types.Transactions{transfer(testBankKey, 5, 3)}, BlockNumber: 1} // nonce too high
+		future    = &core.TxBundle{Txs: types.Transactions{transfer(testBankKey, 0, 4)}, BlockNumber: 2}
+	)
+	for _, bundle := range []*core.TxBundle{reverting, allowed, failing, future} {
+		if err := b.txPool.AddBundle(bundle); err != nil {
+			t.Fatalf("failed to add bundle: %v", err)
+		}
+	}
+	// The pending pool transactions of the test worker use the same nonces as
+	// the bundle transactions of the bank account
+	for _, tx := range pendingTxs {
+		if b.txPool.Get(tx.Hash()) == nil {
+			t.Fatalf("pending transaction %x missing from the pool", tx.Hash())
+		}
+	}
+	resCh, errCh, err := w.getSealingBlock(b.chain.CurrentBlock().Hash(), uint64(time.Now().Unix()), testBankAddress, common.Hash{}, false)
+	if err != nil {
+		t.Fatalf("failed to request block: %v", err)
+	}
+	block := <-resCh
+	if err := <-errCh; err != nil {
+		t.Fatalf("failed to generate block: %v", err)
+	}
+	// Only the allowed bundle must be included, the pending pool transactions
+	// conflicting with it are skipped
+	txs := block.Transactions()
+	if len(txs) != len(allowed.Txs) {
+		t.Fatalf("transaction count mismatch: have %d, want %d", len(txs), len(allowed.Txs))
+	}
+	for i, tx := range allowed.Txs {
+		if txs[i].Hash() != tx.Hash() {
+			t.Errorf("transaction %d mismatch: have %x, want %x", i, txs[i].Hash(), tx.Hash())
+		}
+	}
+	// The bundles which couldn't be included must be dropped from the pool
+	if bundles := b.txPool.Bundles(1, block.Time()); len(bundles) != 1 || bundles[0] != allowed {
+		t.Errorf("failed bundles retained: have %d bundles, want the allowed one", len(bundles))
+	}
+	if bundles := b.txPool.Bundles(2, block.Time()); len(bundles) != 1 || bundles[0] != future {
+		t.Errorf("future bundle dropped")
+	}
+}
@@ -65,6 +65,11 @@ type Config struct {
 	// in memory.
 	DataDir string
 
+	// DBEngine is the key-value store backing the databases, "leveldb" or "pebble".
+	// If unset, existing databases are opened with the engine they were created
+	// with and new ones use LevelDB.
+	DBEngine string `toml:",omitempty"`
+
 	// Configuration of peer-to-peer networking.
 	P2P p2p.Config
 
@@ -177,6 +182,15 @@ type Config struct {
 	// private APIs to untrusted users is a major security risk.
 	WSExposeAll bool `toml:",omitempty"`
 
+	// BatchRequestLimit is the maximum number of requests in a batch served by the
+	// HTTP, WebSocket and IPC RPC endpoints. Zero means unlimited.
+	BatchRequestLimit int `toml:",omitempty"`
+
+	// BatchResponseMaxSize is the maximum number of response bytes across all requests
+	// in a batch served by the HTTP, WebSocket and IPC RPC endpoints. Zero means
+	// unlimited.
+	BatchResponseMaxSize int `toml:",omitempty"`
+
 	// GraphQLCors is the Cross-Origin Resource Sharing header to send to requesting
 	// clients. Please be aware that CORS is a browser enforced security, it's fully
 	// useless for custom HTTP clients.
@@ -22,15 +22,20 @@ import (
 	"io"
 	"net"
 	"net/http"
+	"os"
+	"path/filepath"
 	"reflect"
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/p2p"
 	"github.com/ethereum/go-ethereum/rpc"
 
+	"github.com/golang-jwt/jwt/v4"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -479,6 +484,72 @@ func TestWebsocketHTTPOnSeparatePort_WSRequest(t *testing.T) {
 	}
 }
 
+// authTestAPI is an API only meant to be served on the authenticated endpoint.
+type authTestAPI struct{}
+
+func (authTestAPI) Secret() string { return "secret" }
+
+// Tests that authenticated APIs are only served on the authenticated endpoint,
+// even if their namespace is enabled on the unauthenticated HTTP and WS ones.
+func TestAuthenticatedAPIs(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal("can't listen:", err)
+	}
+	port := listener.Addr().(*net.TCPAddr).Port
+	listener.Close()
+
+	secret := make([]byte, 32)
+	secretFile := filepath.Join(t.TempDir(), "jwtsecret")
+	if err := os.WriteFile(secretFile, []byte(hexutil.Encode(secret)), 0600); err != nil {
+		t.Fatal(err)
+	}
+	node, err := New(&Config{
+		HTTPHost:    "127.0.0.1",
+		HTTPModules: []string{"test"},
+		WSHost:      "127.0.0.1",
+		WSPort:      port,
+		WSModules:   []string{"test"},
+		AuthAddr:    "127.0.0.1",
+		JWTSecret:   secretFile,
+	})
+	if err != nil {
+		t.Fatalf("could not create a new node: %v", err)
+	}
+	node.RegisterAPIs([]rpc.API{{Namespace: "test", Service: authTestAPI{}, Authenticated: true}})
+	if err := node.Start(); err != nil {
+		t.Fatalf("could not start node: %v", err)
+	}
+	defer node.Close()
+
+	call := func(url string, token string) error {
+		client, err := rpc.Dial(url)
+		if err != nil {
+			return err
+		}
+		defer client.Close()
+
+		if token != "" {
+			client.SetHeader("Authorization", "Bearer "+token)
+		}
+		var result string
+		return client.Call(&result, "test_secret")
+	}
+	if err := call(node.HTTPEndpoint(), ""); err == nil {
+		t.Errorf("authenticated API served over HTTP")
+	}
+	if err := call(node.WSEndpoint(), ""); err == nil {
+		t.Errorf("authenticated API served over WS")
+	}
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"iat": time.Now().Unix()}).SignedString(secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := call("http://"+node.httpAuth.listenAddr(), token); err != nil {
+		t.Errorf("authenticated API not served on the authenticated endpoint: %v", err)
+	}
+}
+
 type rpcPrefixTest struct {
 	httpPrefix, wsPrefix string
 	// These lists paths on which JSON-RPC should be served / not served.
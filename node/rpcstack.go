@@ -40,6 +40,7 @@ type httpConfig struct {
 	Vhosts             []string
 	prefix             string // path prefix on which to mount http handler
 	jwtSecret          []byte // optional JWT secret
+	rpcEndpointConfig
 }
 
 // wsConfig is the JSON-RPC/Websocket configuration
@@ -48,6 +49,13 @@ type wsConfig struct {
 	Modules   []string
 	prefix    string // path prefix on which to mount ws handler
 	jwtSecret []byte // optional JWT secret
+	rpcEndpointConfig
+}
+
+// rpcEndpointConfig holds the limits shared by all JSON-RPC endpoints.
+type rpcEndpointConfig struct {
+	batchItemLimit         int // maximum number of requests in a batch
+	batchResponseSizeLimit int // maximum number of response bytes in a batch
 }
 
 type rpcHandler struct {
@@ -281,6 +289,7 @@ func (h *httpServer) enableRPC(apis []rpc.API, config httpConfig) error {
 
 	// Create RPC server and handler.
 	srv := rpc.NewServer()
+	srv.SetBatchLimits(config.batchItemLimit, config.batchResponseSizeLimit)
 	if err := RegisterApis(apis, config.Modules, srv, false); err != nil {
 		return err
 	}
@@ -312,6 +321,7 @@ func (h *httpServer) enableWS(apis []rpc.API, config wsConfig) error {
 	}
 	// Create RPC server and handler.
 	srv := rpc.NewServer()
+	srv.SetBatchLimits(config.batchItemLimit, config.batchResponseSizeLimit)
 	if err := RegisterApis(apis, config.Modules, srv, false); err != nil {
 		return err
 	}
@@ -495,18 +505,27 @@ func newIPCServer(log log.Logger, endpoint string) *ipcServer {
 }
 
 // Start starts the httpServer's http.Server
-func (is *ipcServer) start(apis []rpc.API) error {
+func (is *ipcServer) start(apis []rpc.API, config rpcEndpointConfig) error {
 	is.mu.Lock()
 	defer is.mu.Unlock()
 
 	if is.listener != nil {
 		return nil // already running
 	}
-	listener, srv, err := rpc.StartIPCEndpoint(is.endpoint, apis)
+	// Create RPC server exposing all APIs.
+	srv := rpc.NewServer()
+	srv.SetBatchLimits(config.batchItemLimit, config.batchResponseSizeLimit)
+	if err := RegisterApis(apis, nil, srv, true); err != nil {
+		return err
+	}
+	listener, err := rpc.ListenIPC(is.endpoint)
 	if err != nil {
 		is.log.Warn("IPC opening failed", "url", is.endpoint, "error", err)
+		srv.Stop()
 		return err
 	}
+	go srv.ServeListener(listener)
+
 	is.log.Info("IPC endpoint opened", "url", is.endpoint)
 	is.listener, is.srv = listener, srv
 	return nil
@@ -18,6 +18,7 @@ package node
 
 import (
 	"bytes"
+	"encoding/json"
 	"fmt"
 	"net/http"
 	"net/url"
@@ -374,3 +375,36 @@ func TestJWT(t *testing.T) {
 	}
 	srv.stop()
 }
+
+// TestHTTPBatchLimits checks that the configured batch limits are applied to the
+// HTTP endpoint.
+func TestHTTPBatchLimits(t *testing.T) {
+	srv := createAndStartServer(t, &httpConfig{rpcEndpointConfig: rpcEndpointConfig{batchItemLimit: 1}}, false, &wsConfig{})
+	defer srv.stop()
+
+	body := bytes.NewReader([]byte(`[{"jsonrpc":"2.0","id":1,"method":"rpc_modules"},{"jsonrpc":"2.0","id":2,"method":"rpc_modules"}]`))
+	resp, err := http.Post("http://"+srv.listenAddr(), "application/json", body)
+	if err != nil {
+		t.Fatal("request failed:", err)
+	}
+	defer resp.Body.Close()
+
+	var results []struct {
+		ID    int `json:"id"`
+		Error *struct {
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&results); err != nil {
+		t.Fatal("invalid response:", err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("wrong number of responses: %d", len(results))
+	}
+	if results[0].Error != nil {
+		t.Errorf("unexpected error for first item: %v", results[0].Error)
+	}
+	if results[1].Error == nil || results[1].Error.Message != "batch too large" {
+		t.Errorf("wrong error for second item: %v", results[1].Error)
+	}
+}
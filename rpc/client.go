@@ -24,6 +24,7 @@ import (
 	"net/url"
 	"reflect"
 	"strconv"
+	"sync"
 	"sync/atomic"
 	"time"
 
@@ -79,9 +80,17 @@ type Client struct {
 
 	idCounter uint32
 
+	// batch limits applied to batches served on the connection
+	batchItemLimit     int
+	batchResponseLimit int
+
 	// This function, if non-nil, is called when the connection is lost.
 	reconnectFunc reconnectFunc
 
+	// Configuration of subscription reconnects, nil unless enabled.
+	reconnectMu     sync.Mutex
+	reconnectConfig *ReconnectConfig
+
 	// writeConn is used for writing to the connection on the caller's goroutine. It should
 	// only be accessed outside of dispatch, with the write lock held. The write lock is
 	// taken by sending on reqInit and released by sending on reqSent.
@@ -112,7 +121,7 @@ func (c *Client) newClientConn(conn ServerCodec) *clientConn {
 	ctx := context.Background()
 	ctx = context.WithValue(ctx, clientContextKey{}, c)
 	ctx = context.WithValue(ctx, peerInfoContextKey{}, conn.peerInfo())
-	handler := newHandler(ctx, conn, c.idgen, c.services)
+	handler := newHandler(ctx, conn, c.idgen, c.services, c.batchItemLimit, c.batchResponseLimit)
 	return &clientConn{conn, handler}
 }
 
@@ -127,10 +136,11 @@ type readOp struct {
 }
 
 type requestOp struct {
-	ids  []json.RawMessage
-	err  error
-	resp chan *jsonrpcMessage // receives up to len(ids) responses
-	sub  *ClientSubscription  // only set for EthSubscribe requests
+	ids         []json.RawMessage
+	err         error
+	resp        chan *jsonrpcMessage // receives up to len(ids) responses
+	sub         *ClientSubscription  // only set for EthSubscribe requests
+	resubscribe bool                 // set when sub is re-established after reconnect
 }
 
 func (op *requestOp) wait(ctx context.Context, c *Client) (*jsonrpcMessage, error) {
@@ -198,27 +208,29 @@ func newClient(initctx context.Context, connect reconnectFunc) (*Client, error)
 	if err != nil {
 		return nil, err
 	}
-	c := initClient(conn, randomIDGenerator(), new(serviceRegistry))
+	c := initClient(conn, randomIDGenerator(), new(serviceRegistry), 0, 0)
 	c.reconnectFunc = connect
 	return c, nil
 }
 
-func initClient(conn ServerCodec, idgen func() ID, services *serviceRegistry) *Client {
+func initClient(conn ServerCodec, idgen func() ID, services *serviceRegistry, batchItemLimit, batchResponseLimit int) *Client {
 	_, isHTTP := conn.(*httpConn)
 	c := &Client{
-		isHTTP:      isHTTP,
-		idgen:       idgen,
-		services:    services,
-		writeConn:   conn,
-		close:       make(chan struct{}),
-		closing:     make(chan struct{}),
-		didClose:    make(chan struct{}),
-		reconnected: make(chan ServerCodec),
-		readOp:      make(chan readOp),
-		readErr:     make(chan error),
-		reqInit:     make(chan *requestOp),
-		reqSent:     make(chan error, 1),
-		reqTimeout:  make(chan *requestOp),
+		isHTTP:             isHTTP,
+		idgen:              idgen,
+		services:           services,
+		batchItemLimit:     batchItemLimit,
+		batchResponseLimit: batchResponseLimit,
+		writeConn:          conn,
+		close:              make(chan struct{}),
+		closing:            make(chan struct{}),
+		didClose:           make(chan struct{}),
+		reconnected:        make(chan ServerCodec),
+		readOp:             make(chan readOp),
+		readErr:            make(chan error),
+		reqInit:            make(chan *requestOp),
+		reqSent:            make(chan error, 1),
+		reqTimeout:         make(chan *requestOp),
 	}
 	if !isHTTP {
 		go c.dispatch(conn)
@@ -430,6 +442,9 @@ func (c *Client) ShhSubscribe(ctx context.Context, channel interface{}, args ...
 // before considering the subscriber dead. The subscription Err channel will receive
 // ErrSubscriptionQueueOverflow. Use a sufficiently large buffer on the channel or ensure
 // that the channel usually has at least one reader to prevent this issue.
+//
+// The subscription ends when the connection is lost, unless reconnecting has been
+// enabled through EnableReconnect.
 func (c *Client) Subscribe(ctx context.Context, namespace string, channel interface{}, args ...interface{}) (*ClientSubscription, error) {
 	// Check type of channel first.
 	chanVal := reflect.ValueOf(channel)
@@ -452,6 +467,7 @@ func (c *Client) Subscribe(ctx context.Context, namespace string, channel interf
 		resp: make(chan *jsonrpcMessage),
 		sub:  newClientSubscription(c, namespace, chanVal),
 	}
+	op.sub.params = msg.Params
 
 	// Send the subscription request.
 	// The arrival and validity of the response is signaled on sub.quit.
@@ -571,6 +587,7 @@ func (c *Client) dispatch(codec ServerCodec) {
 
 		case err := <-c.readErr:
 			conn.handler.log.Debug("RPC connection read error", "err", err)
+			c.handOverSubscriptions(conn)
 			conn.close(err, lastOp)
 			reading = false
 
@@ -583,6 +600,7 @@ func (c *Client) dispatch(codec ServerCodec) {
 				// In those cases the caller will notice first and reconnect. Closing the
 				// handler terminates all waiting requests (closing op.resp) except for
 				// lastOp, which will be transferred to the new handler.
+				c.handOverSubscriptions(conn)
 				conn.close(errClientReconnected, lastOp)
 				c.drainRead()
 			}
@@ -19,6 +19,7 @@ package rpc
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"math/rand"
 	"net"
@@ -29,6 +30,7 @@ import (
 	"runtime"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -666,6 +668,119 @@ func TestClientReconnect(t *testing.T) {
 	}
 }
 
+// severablePipeClient creates a client which is connected to srv through a pipe.
+// The returned function breaks the current connection. Calls to the dialer fail
+// once failDial is set.
+func severablePipeClient(srv *Server, failDial *int32) (*Client, func()) {
+	var (
+		mu   sync.Mutex
+		conn net.Conn
+	)
+	client, _ := newClient(context.Background(), func(context.Context) (ServerCodec, error) {
+		if atomic.LoadInt32(failDial) != 0 {
+			return nil, errors.New("dial failed")
+		}
+		p1, p2 := net.Pipe()
+		go srv.ServeCodec(NewCodec(p1), 0)
+		mu.Lock()
+		conn = p2
+		mu.Unlock()
+		return NewCodec(p2), nil
+	})
+	sever := func() {
+		mu.Lock()
+		defer mu.Unlock()
+		conn.Close()
+	}
+	return client, sever
+}
+
+func TestClientResubscribe(t *testing.T) {
+	server := newTestServer()
+	defer server.Stop()
+	client, sever := severablePipeClient(server, new(int32))
+	defer client.Close()
+
+	if err := client.EnableReconnect(ReconnectConfig{MinBackoff: 10 * time.Millisecond}); err != nil {
+		t.Fatal("can't enable reconnect:", err)
+	}
+	nc := make(chan int)
+	count := 3
+	sub, err := client.Subscribe(context.Background(), "nftest", nc, "someSubscription", count, 0)
+	if err != nil {
+		t.Fatal("can't subscribe:", err)
+	}
+	defer sub.Unsubscribe()
+
+	for round := 0; round < 3; round++ {
+		if round > 0 {
+			sever()
+			select {
+			case <-sub.Resubscribed():
+			case err := <-sub.Err():
+				t.Fatalf("round %d: subscription failed: %v", round, err)
+			case <-time.After(5 * time.Second):
+				t.Fatalf("round %d: subscription not re-established", round)
+			}
+		}
+		// The server re-sends its notifications for every new subscription.
+		for i := 0; i < count; i++ {
+			select {
+			case val := <-nc:
+				if val != i {
+					t.Fatalf("round %d: value mismatch: got %d, want %d", round, val, i)
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatalf("round %d: timed out waiting for notification %d", round, i)
+			}
+		}
+	}
+}
+
+func TestClientResubscribeGiveUp(t *testing.T) {
+	server := newTestServer()
+	defer server.Stop()
+	failDial := new(int32)
+	client, sever := severablePipeClient(server, failDial)
+	defer client.Close()
+
+	config := ReconnectConfig{MinBackoff: 10 * time.Millisecond, MaxAttempts: 3}
+	if err := client.EnableReconnect(config); err != nil {
+		t.Fatal("can't enable reconnect:", err)
+	}
+	nc := make(chan int)
+	sub, err := client.Subscribe(context.Background(), "nftest", nc, "someSubscription", 1, 0)
+	if err != nil {
+		t.Fatal("can't subscribe:", err)
+	}
+	<-nc
+
+	atomic.StoreInt32(failDial, 1)
+	sever()
+	select {
+	case err := <-sub.Err():
+		if err == nil || err.Error() != "dial failed" {
+			t.Fatalf("wrong subscription error: %v", err)
+		}
+	case <-sub.Resubscribed():
+		t.Fatal("subscription re-established without server")
+	case <-time.After(5 * time.Second):
+		t.Fatal("subscription not closed after failed reconnect attempts")
+	}
+}
+
+func TestClientReconnectUnsupported(t *testing.T) {
+	server := newTestServer()
+	defer server.Stop()
+	client, hs := httpTestClient(server, "http", nil)
+	defer hs.Close()
+	defer client.Close()
+
+	if err := client.EnableReconnect(DefaultReconnectConfig); err != errReconnectUnsupported {
+		t.Fatalf("wrong error for HTTP client: %v", err)
+	}
+}
+
 func httpTestClient(srv *Server, transport string, fl *flakeyListener) (*Client, *httptest.Server) {
 	// Create the HTTP server.
 	var hs *httptest.Server
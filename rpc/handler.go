@@ -62,6 +62,9 @@ type handler struct {
 	log            log.Logger
 	allowSubscribe bool
 
+	batchItemLimit     int // maximum number of calls in a batch, zero means unlimited
+	batchResponseLimit int // maximum number of response bytes per batch, zero means unlimited
+
 	subLock    sync.Mutex
 	serverSubs map[ID]*Subscription
 }
@@ -71,19 +74,21 @@ type callProc struct {
 	notifiers []*Notifier
 }
 
-func newHandler(connCtx context.Context, conn jsonWriter, idgen func() ID, reg *serviceRegistry) *handler {
+func newHandler(connCtx context.Context, conn jsonWriter, idgen func() ID, reg *serviceRegistry, batchItemLimit, batchResponseLimit int) *handler {
 	rootCtx, cancelRoot := context.WithCancel(connCtx)
 	h := &handler{
-		reg:            reg,
-		idgen:          idgen,
-		conn:           conn,
-		respWait:       make(map[string]*requestOp),
-		clientSubs:     make(map[string]*ClientSubscription),
-		rootCtx:        rootCtx,
-		cancelRoot:     cancelRoot,
-		allowSubscribe: true,
-		serverSubs:     make(map[ID]*Subscription),
-		log:            log.Root(),
+		reg:                reg,
+		idgen:              idgen,
+		conn:               conn,
+		respWait:           make(map[string]*requestOp),
+		clientSubs:         make(map[string]*ClientSubscription),
+		rootCtx:            rootCtx,
+		cancelRoot:         cancelRoot,
+		allowSubscribe:     true,
+		serverSubs:         make(map[ID]*Subscription),
+		log:                log.Root(),
+		batchItemLimit:     batchItemLimit,
+		batchResponseLimit: batchResponseLimit,
 	}
 	if conn.remoteAddr() != "" {
 		h.log = h.log.New("conn", conn.remoteAddr())
@@ -114,9 +119,35 @@ func (h *handler) handleBatch(msgs []*jsonrpcMessage) {
 	}
 	// Process calls on a goroutine because they may block indefinitely:
 	h.startCallProc(func(cp *callProc) {
-		answers := make([]*jsonrpcMessage, 0, len(msgs))
-		for _, msg := range calls {
-			if answer := h.handleCallMsg(cp, msg); answer != nil {
+		var (
+			answers  = make([]*jsonrpcMessage, 0, len(msgs))
+			respSize int
+			tooLarge bool
+		)
+		for i, msg := range calls {
+			var answer *jsonrpcMessage
+			switch {
+			case h.batchItemLimit > 0 && i >= h.batchItemLimit:
+				// Calls beyond the item limit are not executed
+				if !msg.isNotification() {
+					answer = msg.errorResponse(&invalidRequestError{errMsgBatchTooLarge})
+				}
+			case tooLarge:
+				// The response size limit was hit, skip all remaining calls
+				if !msg.isNotification() {
+					answer = msg.errorResponse(&responseTooLargeError{})
+				}
+			default:
+				answer = h.handleCallMsg(cp, msg)
+				if answer != nil && h.batchResponseLimit > 0 {
+					respSize += len(answer.Result)
+					if respSize > h.batchResponseLimit {
+						tooLarge = true
+						answer = msg.errorResponse(&responseTooLargeError{})
+					}
+				}
+			}
+			if answer != nil {
 				answers = append(answers, answer)
 			}
 		}
@@ -280,10 +311,26 @@ func (h *handler) handleResponse(msg *jsonrpcMessage) {
 		op.err = msg.Error
 		return
 	}
-	if op.err = json.Unmarshal(msg.Result, &op.sub.subid); op.err == nil {
-		go op.sub.run()
-		h.clientSubs[op.sub.subid] = op.sub
+	var subid string
+	if op.err = json.Unmarshal(msg.Result, &subid); op.err == nil {
+		op.sub.setID(subid)
+		// The forwarding loop of re-established subscriptions is still running.
+		if !op.resubscribe {
+			go op.sub.run()
+		}
+		h.clientSubs[subid] = op.sub
+	}
+}
+
+// takeClientSubscriptions removes all active client subscriptions from the handler
+// and returns them.
+func (h *handler) takeClientSubscriptions() []*ClientSubscription {
+	subs := make([]*ClientSubscription, 0, len(h.clientSubs))
+	for id, sub := range h.clientSubs {
+		delete(h.clientSubs, id)
+		subs = append(subs, sub)
 	}
+	return subs
 }
 
 // handleCallMsg executes a call message and returns the answer.
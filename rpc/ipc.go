@@ -39,6 +39,13 @@ func (s *Server) ServeListener(l net.Listener) error {
 	}
 }
 
+// ListenIPC creates an IPC listener on the given endpoint, which can be served
+// using ServeListener. On Unix the endpoint is the path to a unix socket, on
+// Windows it is the name of a named pipe.
+func ListenIPC(endpoint string) (net.Listener, error) {
+	return ipcListen(endpoint)
+}
+
 // DialIPC create a new IPC client that connects to the given endpoint. On Unix it assumes
 // the endpoint is the full path to a unix socket, and Windows the endpoint is an
 // identifier for a named pipe.
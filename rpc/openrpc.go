@@ -0,0 +1,340 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"path"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+// openRPCVersion is the version of the OpenRPC specification implemented by the
+// discovery document.
+const openRPCVersion = "1.2.6"
+
+// OpenRPCDocument is the service description returned by rpc_discover. It follows
+// the OpenRPC specification (https://spec.open-rpc.org).
+type OpenRPCDocument struct {
+	OpenRPC    string             `json:"openrpc"`
+	Info       OpenRPCInfo        `json:"info"`
+	Methods    []*OpenRPCMethod   `json:"methods"`
+	Components *OpenRPCComponents `json:"components,omitempty"`
+}
+
+// OpenRPCInfo contains metadata about the API.
+type OpenRPCInfo struct {
+	Title   string `json:"title"`
+	Version string `json:"version"`
+}
+
+// OpenRPCMethod describes a single RPC method.
+type OpenRPCMethod struct {
+	Name   string                      `json:"name"`
+	Params []*OpenRPCContentDescriptor `json:"params"`
+	Result *OpenRPCContentDescriptor   `json:"result,omitempty"`
+}
+
+// OpenRPCContentDescriptor describes a method parameter or result.
+type OpenRPCContentDescriptor struct {
+	Name     string         `json:"name"`
+	Required bool           `json:"required,omitempty"`
+	Schema   *OpenRPCSchema `json:"schema"`
+}
+
+// OpenRPCComponents holds the schemas of named types, which are referenced by
+// method parameters and results.
+type OpenRPCComponents struct {
+	Schemas map[string]*OpenRPCSchema `json:"schemas,omitempty"`
+}
+
+// OpenRPCSchema is the subset of JSON schema used to describe RPC values.
+type OpenRPCSchema struct {
+	Ref                  string                    `json:"$ref,omitempty"`
+	Title                string                    `json:"title,omitempty"`
+	Type                 string                    `json:"type,omitempty"`
+	Pattern              string                    `json:"pattern,omitempty"`
+	Enum                 []string                  `json:"enum,omitempty"`
+	Items                *OpenRPCSchema            `json:"items,omitempty"`
+	Properties           map[string]*OpenRPCSchema `json:"properties,omitempty"`
+	AdditionalProperties *OpenRPCSchema            `json:"additionalProperties,omitempty"`
+	OneOf                []*OpenRPCSchema          `json:"oneOf,omitempty"`
+}
+
+// Schemas of commonly used types with custom JSON encodings.
+var (
+	quantitySchema = &OpenRPCSchema{Title: "hex encoded unsigned integer", Type: "string", Pattern: "^0x(0|[1-9a-f][0-9a-f]*)$"}
+	bytesSchema    = &OpenRPCSchema{Title: "hex encoded bytes", Type: "string", Pattern: "^0x[0-9a-f]*$"}
+	addressSchema  = &OpenRPCSchema{Title: "hex encoded address", Type: "string", Pattern: "^0x[0-9a-fA-F]{40}$"}
+	hashSchema     = &OpenRPCSchema{Title: "hex encoded hash", Type: "string", Pattern: "^0x[0-9a-f]{64}$"}
+
+	blockNumberSchema = &OpenRPCSchema{
+		Title: "block number or tag",
+		OneOf: []*OpenRPCSchema{
+			quantitySchema,
+			{Title: "block tag", Type: "string", Enum: []string{"earliest", "latest", "pending", "finalized"}},
+		},
+	}
+	blockNumberOrHashSchema = &OpenRPCSchema{
+		Title: "block number, tag or hash",
+		OneOf: []*OpenRPCSchema{
+			blockNumberSchema,
+			hashSchema,
+			{
+				Type: "object",
+				Properties: map[string]*OpenRPCSchema{
+					"blockNumber":      blockNumberSchema,
+					"blockHash":        hashSchema,
+					"requireCanonical": {Type: "boolean"},
+				},
+			},
+		},
+	}
+
+	knownSchemas = map[reflect.Type]*OpenRPCSchema{
+		reflect.TypeOf(hexutil.Big{}):                  quantitySchema,
+		reflect.TypeOf(hexutil.Uint64(0)):              quantitySchema,
+		reflect.TypeOf(hexutil.Uint(0)):                quantitySchema,
+		reflect.TypeOf(hexutil.Bytes{}):                bytesSchema,
+		reflect.TypeOf(common.Address{}):               addressSchema,
+		reflect.TypeOf(common.Hash{}):                  hashSchema,
+		reflect.TypeOf(big.Int{}):                      {Type: "integer"},
+		reflect.TypeOf(BlockNumber(0)):                 blockNumberSchema,
+		reflect.TypeOf(BlockNumberOrHash{}):            blockNumberOrHashSchema,
+		reflect.TypeOf(ID("")):                         {Title: "subscription id", Type: "string"},
+		reflect.TypeOf((*json.RawMessage)(nil)).Elem(): {},
+	}
+
+	jsonMarshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+// discoverDocument creates the OpenRPC document for all methods in the registry.
+func (r *serviceRegistry) discoverDocument() *OpenRPCDocument {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	gen := &schemaGenerator{schemas: make(map[string]*OpenRPCSchema), names: make(map[reflect.Type]string)}
+	doc := &OpenRPCDocument{
+		OpenRPC: openRPCVersion,
+		Info:    OpenRPCInfo{Title: "Ethereum JSON-RPC API", Version: "1.0"},
+		Methods: []*OpenRPCMethod{},
+	}
+	for svcname, svc := range r.services {
+		for name, cb := range svc.callbacks {
+			doc.Methods = append(doc.Methods, gen.method(svcname+serviceMethodSeparator+name, cb))
+		}
+		if len(svc.subscriptions) > 0 {
+			doc.Methods = append(doc.Methods, gen.subscribeMethods(svcname, svc.subscriptions)...)
+		}
+	}
+	sort.Slice(doc.Methods, func(i, j int) bool {
+		return doc.Methods[i].Name < doc.Methods[j].Name
+	})
+	if len(gen.schemas) > 0 {
+		doc.Components = &OpenRPCComponents{Schemas: gen.schemas}
+	}
+	return doc
+}
+
+// schemaGenerator derives JSON schemas from Go types. Named struct types are
+// stored as components and referenced, which also handles recursive types.
+type schemaGenerator struct {
+	schemas map[string]*OpenRPCSchema // component schemas by name
+	names   map[reflect.Type]string   // component names by type
+}
+
+// method creates the description of a method callback.
+func (g *schemaGenerator) method(name string, cb *callback) *OpenRPCMethod {
+	m := &OpenRPCMethod{Name: name, Params: g.params(cb.argTypes)}
+	if out := cb.fn.Type(); out.NumOut() > 0 && cb.errPos != 0 {
+		m.Result = &OpenRPCContentDescriptor{Name: "result", Schema: g.schema(out.Out(0))}
+	} else {
+		m.Result = &OpenRPCContentDescriptor{Name: "result", Schema: &OpenRPCSchema{Type: "null"}}
+	}
+	return m
+}
+
+// subscribeMethods creates the descriptions of the subscribe and unsubscribe
+// methods of a service.
+func (g *schemaGenerator) subscribeMethods(service string, subs map[string]*callback) []*OpenRPCMethod {
+	names := make([]string, 0, len(subs))
+	for name := range subs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	idSchema := knownSchemas[reflect.TypeOf(ID(""))]
+	subscribe := &OpenRPCMethod{
+		Name: service + subscribeMethodSuffix,
+		Params: []*OpenRPCContentDescriptor{
+			{Name: "subscription", Required: true, Schema: &OpenRPCSchema{Type: "string", Enum: names}},
+			{Name: "params", Schema: &OpenRPCSchema{}},
+		},
+		Result: &OpenRPCContentDescriptor{Name: "id", Schema: idSchema},
+	}
+	unsubscribe := &OpenRPCMethod{
+		Name:   service + unsubscribeMethodSuffix,
+		Params: []*OpenRPCContentDescriptor{{Name: "id", Required: true, Schema: idSchema}},
+		Result: &OpenRPCContentDescriptor{Name: "result", Schema: &OpenRPCSchema{Type: "boolean"}},
+	}
+	return []*OpenRPCMethod{subscribe, unsubscribe}
+}
+
+// params creates the parameter descriptions. Trailing pointer arguments are
+// optional, matching the argument parsing of the server.
+func (g *schemaGenerator) params(types []reflect.Type) []*OpenRPCContentDescriptor {
+	params := make([]*OpenRPCContentDescriptor, len(types))
+	optional := true
+	for i := len(types) - 1; i >= 0; i-- {
+		if types[i].Kind() != reflect.Ptr {
+			optional = false
+		}
+		params[i] = &OpenRPCContentDescriptor{
+			Name:     fmt.Sprintf("arg%d", i),
+			Required: !optional,
+			Schema:   g.schema(types[i]),
+		}
+	}
+	return params
+}
+
+// schema returns the JSON schema of values of type t.
+func (g *schemaGenerator) schema(t reflect.Type) *OpenRPCSchema {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if s, ok := knownSchemas[t]; ok {
+		return s
+	}
+	ptr := reflect.PtrTo(t)
+	switch {
+	case t.Implements(jsonMarshalerType) || ptr.Implements(jsonMarshalerType):
+		// The encoding is defined by custom code, don't guess.
+		return &OpenRPCSchema{Title: t.String()}
+	case t.Implements(textMarshalerType) || ptr.Implements(textMarshalerType):
+		return &OpenRPCSchema{Title: t.String(), Type: "string"}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return &OpenRPCSchema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return &OpenRPCSchema{Type: "integer"}
+	case reflect.Float32, reflect.Float64:
+		return &OpenRPCSchema{Type: "number"}
+	case reflect.String:
+		return &OpenRPCSchema{Type: "string"}
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &OpenRPCSchema{Title: "base64 encoded bytes", Type: "string"}
+		}
+		return &OpenRPCSchema{Type: "array", Items: g.schema(t.Elem())}
+	case reflect.Array:
+		return &OpenRPCSchema{Type: "array", Items: g.schema(t.Elem())}
+	case reflect.Map:
+		return &OpenRPCSchema{Type: "object", AdditionalProperties: g.schema(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return g.structSchema(t)
+		}
+		return g.componentRef(t)
+	default:
+		// Interfaces can hold any value. Channels and functions can't be
+		// encoded at all, but are accepted by the server.
+		return &OpenRPCSchema{}
+	}
+}
+
+// componentRef stores the schema of the named struct type t as a component and
+// returns a reference to it.
+func (g *schemaGenerator) componentRef(t reflect.Type) *OpenRPCSchema {
+	name, ok := g.names[t]
+	if !ok {
+		name = path.Base(t.PkgPath()) + "." + t.Name()
+		for i := 2; g.schemas[name] != nil; i++ {
+			name = fmt.Sprintf("%s.%s%d", path.Base(t.PkgPath()), t.Name(), i)
+		}
+		g.names[t] = name
+		g.schemas[name] = &OpenRPCSchema{} // placeholder for recursive references
+		*g.schemas[name] = *g.structSchema(t)
+	}
+	return &OpenRPCSchema{Ref: "#/components/schemas/" + name}
+}
+
+// structSchema creates the object schema of struct type t, following the field
+// naming rules of encoding/json.
+func (g *schemaGenerator) structSchema(t reflect.Type) *OpenRPCSchema {
+	s := &OpenRPCSchema{Type: "object", Properties: make(map[string]*OpenRPCSchema)}
+	g.addFields(s, t)
+	return s
+}
+
+func (g *schemaGenerator) addFields(s *OpenRPCSchema, t reflect.Type) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, opts := tag, ""
+		if idx := strings.Index(tag, ","); idx >= 0 {
+			name, opts = tag[:idx], tag[idx+1:]
+		}
+		ft := field.Type
+		for ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+		// Fields of untagged embedded structs are promoted into the parent object.
+		if field.Anonymous && name == "" && ft.Kind() == reflect.Struct {
+			g.addFields(s, ft)
+			continue
+		}
+		if field.PkgPath != "" {
+			continue // unexported
+		}
+		if name == "" {
+			name = field.Name
+		}
+		if _, ok := s.Properties[name]; ok {
+			continue
+		}
+		if hasOption(opts, "string") {
+			s.Properties[name] = &OpenRPCSchema{Type: "string"}
+		} else {
+			s.Properties[name] = g.schema(field.Type)
+		}
+	}
+}
+
+// hasOption reports whether the comma-separated struct tag options contain opt.
+func hasOption(opts, opt string) bool {
+	for _, o := range strings.Split(opts, ",") {
+		if o == opt {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,126 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+func TestDiscover(t *testing.T) {
+	server := newTestServer()
+	defer server.Stop()
+	client := DialInProc(server)
+	defer client.Close()
+
+	var doc OpenRPCDocument
+	if err := client.Call(&doc, "rpc_discover"); err != nil {
+		t.Fatal("discover failed:", err)
+	}
+	if doc.OpenRPC != openRPCVersion {
+		t.Errorf("wrong openrpc version %q", doc.OpenRPC)
+	}
+	methods := make(map[string]*OpenRPCMethod)
+	for _, m := range doc.Methods {
+		methods[m.Name] = m
+	}
+	for _, name := range []string{"rpc_discover", "rpc_modules", "test_echo", "nftest_subscribe", "nftest_unsubscribe"} {
+		if methods[name] == nil {
+			t.Errorf("method %s missing from document", name)
+		}
+	}
+
+	// Check the description of test_echo.
+	echo := methods["test_echo"]
+	if echo == nil {
+		t.Fatal("test_echo missing")
+	}
+	wantParams := []*OpenRPCContentDescriptor{
+		{Name: "arg0", Required: true, Schema: &OpenRPCSchema{Type: "string"}},
+		{Name: "arg1", Required: true, Schema: &OpenRPCSchema{Type: "integer"}},
+		{Name: "arg2", Schema: &OpenRPCSchema{Ref: "#/components/schemas/rpc.echoArgs"}},
+	}
+	if !reflect.DeepEqual(echo.Params, wantParams) {
+		have, _ := json.Marshal(echo.Params)
+		want, _ := json.Marshal(wantParams)
+		t.Errorf("wrong test_echo params:\nhave %s\nwant %s", have, want)
+	}
+	if echo.Result == nil || echo.Result.Schema.Ref != "#/components/schemas/rpc.echoResult" {
+		t.Errorf("wrong test_echo result: %+v", echo.Result)
+	}
+	if doc.Components == nil || doc.Components.Schemas["rpc.echoResult"] == nil {
+		t.Fatal("echoResult schema missing from components")
+	}
+	props := doc.Components.Schemas["rpc.echoResult"].Properties
+	if len(props) != 3 || props["String"] == nil || props["Int"] == nil || props["Args"] == nil {
+		t.Errorf("wrong echoResult properties: %v", props)
+	}
+
+	// Check the subscription names.
+	sub := methods["nftest_subscribe"]
+	if sub == nil || len(sub.Params) == 0 {
+		t.Fatal("nftest_subscribe has no params")
+	}
+	if enum := sub.Params[0].Schema.Enum; !reflect.DeepEqual(enum, []string{"hangSubscription", "someSubscription"}) {
+		t.Errorf("wrong subscription names %v", enum)
+	}
+}
+
+type schemaTestEmbedded struct {
+	Embedded string `json:"embedded"`
+}
+
+type schemaTestStruct struct {
+	schemaTestEmbedded
+	Balance  *hexutil.Big            `json:"balance"`
+	Accounts []common.Address        `json:"accounts,omitempty"`
+	Nonces   map[string]hexutil.Uint `json:"nonces"`
+	Limit    uint64                  `json:"limit,string"`
+	Data     []byte                  `json:"data"`
+	Next     *schemaTestStruct       `json:"next"`
+	Ignored  int                     `json:"-"`
+	internal int
+}
+
+func TestDiscoverSchema(t *testing.T) {
+	gen := &schemaGenerator{schemas: make(map[string]*OpenRPCSchema), names: make(map[reflect.Type]string)}
+	ref := gen.schema(reflect.TypeOf(new(schemaTestStruct)))
+	if ref.Ref != "#/components/schemas/rpc.schemaTestStruct" {
+		t.Fatalf("wrong reference %q", ref.Ref)
+	}
+	want := &OpenRPCSchema{
+		Type: "object",
+		Properties: map[string]*OpenRPCSchema{
+			"embedded": {Type: "string"},
+			"balance":  quantitySchema,
+			"accounts": {Type: "array", Items: addressSchema},
+			"nonces":   {Type: "object", AdditionalProperties: quantitySchema},
+			"limit":    {Type: "string"},
+			"data":     {Title: "base64 encoded bytes", Type: "string"},
+			"next":     ref,
+		},
+	}
+	if have := gen.schemas["rpc.schemaTestStruct"]; !reflect.DeepEqual(have, want) {
+		haveJSON, _ := json.Marshal(have)
+		wantJSON, _ := json.Marshal(want)
+		t.Errorf("wrong schema:\nhave %s\nwant %s", haveJSON, wantJSON)
+	}
+}
@@ -0,0 +1,218 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/ethereum/go-ethereum/log"
+)
+
+var errReconnectUnsupported = errors.New("client does not support reconnecting")
+
+// ReconnectConfig configures how the client re-establishes its subscriptions after
+// the connection to the server is lost.
+type ReconnectConfig struct {
+	MinBackoff  time.Duration // delay after the first failed redial attempt
+	MaxBackoff  time.Duration // upper bound of the exponentially growing redial delay
+	MaxAttempts int           // number of redial attempts before giving up, zero means no limit
+}
+
+// DefaultReconnectConfig contains the default reconnect settings.
+var DefaultReconnectConfig = ReconnectConfig{
+	MinBackoff: 100 * time.Millisecond,
+	MaxBackoff: 30 * time.Second,
+}
+
+// EnableReconnect turns on the reconnecting mode of the client. When the connection
+// breaks, the client redials the server with exponential backoff and re-issues the
+// subscribe calls of all live subscriptions on the new connection. Subscriptions
+// only end with an error if the server can't be reached within the configured
+// number of attempts or if it rejects the subscription.
+//
+// Notifications sent while the client was disconnected are lost. Every
+// re-established subscription signals this on its Resubscribed channel.
+//
+// Reconnecting is not supported for HTTP clients and for clients created with
+// NewClient, which have no way to redial.
+func (c *Client) EnableReconnect(config ReconnectConfig) error {
+	if c.isHTTP || c.reconnectFunc == nil {
+		return errReconnectUnsupported
+	}
+	if config.MinBackoff <= 0 {
+		config.MinBackoff = DefaultReconnectConfig.MinBackoff
+	}
+	if config.MaxBackoff < config.MinBackoff {
+		config.MaxBackoff = config.MinBackoff
+	}
+	c.reconnectMu.Lock()
+	defer c.reconnectMu.Unlock()
+	c.reconnectConfig = &config
+	return nil
+}
+
+// getReconnectConfig returns the reconnect settings, or nil if reconnecting
+// is not enabled.
+func (c *Client) getReconnectConfig() *ReconnectConfig {
+	c.reconnectMu.Lock()
+	defer c.reconnectMu.Unlock()
+	return c.reconnectConfig
+}
+
+// handOverSubscriptions is called by dispatch when conn has failed. If reconnecting
+// is enabled, it detaches the active subscriptions from the connection's handler so
+// they aren't closed along with it, and starts re-establishing them.
+func (c *Client) handOverSubscriptions(conn *clientConn) {
+	config := c.getReconnectConfig()
+	if config == nil {
+		return
+	}
+	subs := conn.handler.takeClientSubscriptions()
+	if len(subs) == 0 {
+		return
+	}
+	log.Debug("RPC client lost connection, resubscribing", "subs", len(subs))
+	go c.resubscribeLoop(conn.codec, subs, *config)
+}
+
+// resubscribeLoop redials the server and re-issues the given subscriptions until all
+// of them are either established or terminated.
+func (c *Client) resubscribeLoop(dead jsonWriter, subs []*ClientSubscription, config ReconnectConfig) {
+	for len(subs) > 0 {
+		conn, err := c.redial(dead, config)
+		if err != nil {
+			for _, sub := range subs {
+				sub.close(err)
+			}
+			return
+		}
+		var failed []*ClientSubscription
+		for _, sub := range subs {
+			err := c.resubscribe(sub)
+			switch {
+			case err == nil:
+				sub.resubscribed()
+			case isConnectionError(err):
+				// The new connection broke too, try again with the next one.
+				failed = append(failed, sub)
+			default:
+				sub.close(err)
+			}
+		}
+		subs, dead = failed, conn
+	}
+}
+
+// redial replaces the dead connection, backing off between failed attempts. If the
+// write path has already established a new connection, that connection is used.
+func (c *Client) redial(dead jsonWriter, config ReconnectConfig) (jsonWriter, error) {
+	backoff := config.MinBackoff
+	for attempt := 1; ; attempt++ {
+		conn, err := c.tryRedial(dead)
+		if err == nil || err == ErrClientQuit {
+			return conn, err
+		}
+		if config.MaxAttempts > 0 && attempt >= config.MaxAttempts {
+			log.Debug("RPC client giving up reconnecting", "attempts", attempt, "err", err)
+			return nil, err
+		}
+		log.Debug("RPC client reconnect failed", "attempt", attempt, "backoff", backoff, "err", err)
+
+		timer := time.NewTimer(backoff)
+		select {
+		case <-timer.C:
+		case <-c.closing:
+			timer.Stop()
+			return nil, ErrClientQuit
+		}
+		if backoff *= 2; backoff > config.MaxBackoff {
+			backoff = config.MaxBackoff
+		}
+	}
+}
+
+// tryRedial takes the write lock and dials a new connection unless the current one
+// is still usable.
+func (c *Client) tryRedial(dead jsonWriter) (jsonWriter, error) {
+	op := new(requestOp)
+	select {
+	case c.reqInit <- op:
+	case <-c.closing:
+		return nil, ErrClientQuit
+	}
+	var err error
+	if c.writeConn == nil || c.writeConn == dead {
+		c.writeConn = nil
+		err = c.reconnect(context.Background())
+	}
+	conn := c.writeConn
+	c.reqSent <- err
+	return conn, err
+}
+
+// resubscribe re-issues the subscribe call of sub on the current connection.
+func (c *Client) resubscribe(sub *ClientSubscription) error {
+	ctx, cancel := context.WithTimeout(context.Background(), subscribeTimeout)
+	defer cancel()
+
+	msg := &jsonrpcMessage{
+		Version: vsn,
+		ID:      c.nextID(),
+		Method:  sub.namespace + subscribeMethodSuffix,
+		Params:  sub.params,
+	}
+	op := &requestOp{
+		ids:         []json.RawMessage{msg.ID},
+		resp:        make(chan *jsonrpcMessage),
+		sub:         sub,
+		resubscribe: true,
+	}
+	if err := c.send(ctx, op, msg); err != nil {
+		return err
+	}
+	if _, err := op.wait(ctx, c); err != nil {
+		return err
+	}
+	// The subscription may have been ended by the consumer while it was
+	// being re-established. Remove it from the server in that case.
+	select {
+	case <-sub.forwardDone:
+		sub.requestUnsubscribe()
+	default:
+	}
+	return nil
+}
+
+// isConnectionError reports whether err is caused by a connection failure rather
+// than by the server or the client shutting down.
+func isConnectionError(err error) bool {
+	var rpcErr Error
+	if errors.As(err, &rpcErr) {
+		return false
+	}
+	var (
+		syntaxErr *json.SyntaxError
+		typeErr   *json.UnmarshalTypeError
+	)
+	if errors.As(err, &syntaxErr) || errors.As(err, &typeErr) {
+		return false
+	}
+	return err != ErrClientQuit
+}
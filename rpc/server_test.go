@@ -23,6 +23,7 @@ import (
 	"net"
 	"os"
 	"path/filepath"
+	"reflect"
 	"strings"
 	"testing"
 	"time"
@@ -152,3 +153,49 @@ func TestServerShortLivedConn(t *testing.T) {
 		}
 	}
 }
+
+func TestServerBatchLimits(t *testing.T) {
+	tests := []struct {
+		itemLimit, responseLimit int
+		wantErrors               []*jsonError
+	}{
+		// No limits, all calls are executed.
+		{0, 0, []*jsonError{nil, nil, nil, nil}},
+		// Calls beyond the item limit are rejected.
+		{2, 0, []*jsonError{nil, nil, {Code: -32600, Message: "batch too large"}, {Code: -32600, Message: "batch too large"}}},
+		// Calls reaching the response size limit and after are rejected. Each
+		// echo result is 47 bytes long.
+		{0, 100, []*jsonError{nil, nil, {Code: -32003, Message: "response too large"}, {Code: -32003, Message: "response too large"}}},
+	}
+	for i, tt := range tests {
+		server := newTestServer()
+		server.SetBatchLimits(tt.itemLimit, tt.responseLimit)
+		client := DialInProc(server)
+
+		batch := make([]BatchElem, len(tt.wantErrors))
+		for j := range batch {
+			batch[j] = BatchElem{
+				Method: "test_echo",
+				Args:   []interface{}{"hello", j, &echoArgs{"world"}},
+				Result: new(echoResult),
+			}
+		}
+		if err := client.BatchCall(batch); err != nil {
+			t.Fatalf("test %d: batch call failed: %v", i, err)
+		}
+		for j, elem := range batch {
+			want := tt.wantErrors[j]
+			if want == nil {
+				if elem.Error != nil {
+					t.Errorf("test %d, call %d: unexpected error: %v", i, j, elem.Error)
+				}
+				continue
+			}
+			if !reflect.DeepEqual(elem.Error, want) {
+				t.Errorf("test %d, call %d: wrong error: have %v, want %v", i, j, elem.Error, want)
+			}
+		}
+		client.Close()
+		server.Stop()
+	}
+}
@@ -207,7 +207,10 @@ type ClientSubscription struct {
 	etype     reflect.Type
 	channel   reflect.Value
 	namespace string
-	subid     string
+	params    json.RawMessage // parameters of the subscribe call, kept for resubscribing
+
+	mu    sync.Mutex // protects subid, which changes when resubscribing
+	subid string
 
 	// The in channel receives notification values from client dispatcher.
 	in chan json.RawMessage
@@ -223,6 +226,10 @@ type ClientSubscription struct {
 	quit        chan error
 	forwardDone chan struct{}
 	unsubDone   chan struct{}
+
+	// The resub channel is signaled when the subscription has been re-established
+	// after the client lost its connection.
+	resub chan struct{}
 }
 
 // This is the sentinel value sent on sub.quit when Unsubscribe is called.
@@ -239,6 +246,7 @@ func newClientSubscription(c *Client, namespace string, channel reflect.Value) *
 		forwardDone: make(chan struct{}),
 		unsubDone:   make(chan struct{}),
 		err:         make(chan error, 1),
+		resub:       make(chan struct{}, 1),
 	}
 	return sub
 }
@@ -255,6 +263,17 @@ func (sub *ClientSubscription) Err() <-chan error {
 	return sub.err
 }
 
+// Resubscribed returns a channel that receives a value whenever the subscription has
+// been re-established after the client lost its connection to the server. This can
+// only happen if reconnecting is enabled on the client, see Client.EnableReconnect.
+//
+// Notifications sent by the server while the connection was down are lost, so every
+// value received on this channel signals a possible gap in the notification stream.
+// Consumers that need to see all events should use it as a cue to backfill.
+func (sub *ClientSubscription) Resubscribed() <-chan struct{} {
+	return sub.resub
+}
+
 // Unsubscribe unsubscribes the notification and closes the error channel.
 // It can safely be called more than once.
 func (sub *ClientSubscription) Unsubscribe() {
@@ -278,6 +297,15 @@ func (sub *ClientSubscription) deliver(result json.RawMessage) (ok bool) {
 	}
 }
 
+// resubscribed is called by the client after the subscription was re-established
+// on a new connection.
+func (sub *ClientSubscription) resubscribed() {
+	select {
+	case sub.resub <- struct{}{}:
+	default:
+	}
+}
+
 // close is called by the client's message dispatcher when the connection is closed.
 func (sub *ClientSubscription) close(err error) {
 	select {
@@ -369,7 +397,19 @@ func (sub *ClientSubscription) unmarshal(result json.RawMessage) (interface{}, e
 	return val.Elem().Interface(), err
 }
 
+func (sub *ClientSubscription) setID(id string) {
+	sub.mu.Lock()
+	defer sub.mu.Unlock()
+	sub.subid = id
+}
+
+func (sub *ClientSubscription) id() string {
+	sub.mu.Lock()
+	defer sub.mu.Unlock()
+	return sub.subid
+}
+
 func (sub *ClientSubscription) requestUnsubscribe() error {
 	var result interface{}
-	return sub.client.Call(&result, sub.namespace+unsubscribeMethodSuffix, sub.subid)
+	return sub.client.Call(&result, sub.namespace+unsubscribeMethodSuffix, sub.id())
 }
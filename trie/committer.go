@@ -33,6 +33,7 @@ type leaf struct {
 	size int         // size of the rlp data (estimate)
 	hash common.Hash // hash of rlp data
 	node node        // the node to commit
+	path []byte      // the path of the node in the trie
 }
 
 // committer is a type used for the trie Commit operation. A committer has some
@@ -44,6 +45,9 @@ type leaf struct {
 type committer struct {
 	onleaf LeafCallback
 	leafCh chan *leaf
+	owner  common.Hash
+	nodes  *NodeSet   // Committed nodes of the path scheme, nil for the hash scheme
+	lock   sync.Mutex // Protects the node set, written by the leaf loop too
 }
 
 // committers live in a global sync.Pool
@@ -53,14 +57,21 @@ var committerPool = sync.Pool{
 	},
 }
 
-// newCommitter creates a new committer or picks one from the pool.
-func newCommitter() *committer {
-	return committerPool.Get().(*committer)
+// newCommitter creates a new committer for the trie of the given owner or picks
+// one from the pool. With the path scheme, the committed nodes are collected
+// into the given node set instead of the database.
+func newCommitter(owner common.Hash, nodes *NodeSet) *committer {
+	c := committerPool.Get().(*committer)
+	c.owner = owner
+	c.nodes = nodes
+	return c
 }
 
 func returnCommitterToPool(h *committer) {
 	h.onleaf = nil
 	h.leafCh = nil
+	h.owner = common.Hash{}
+	h.nodes = nil
 	committerPool.Put(h)
 }
 
@@ -69,7 +80,7 @@ func (c *committer) Commit(n node, db *Database) (hashNode, int, error) {
 	if db == nil {
 		return nil, 0, errors.New("no db provided")
 	}
-	h, committed, err := c.commit(n, db)
+	h, committed, err := c.commit(nil, n, db)
 	if err != nil {
 		return nil, 0, err
 	}
@@ -77,7 +88,7 @@ func (c *committer) Commit(n node, db *Database) (hashNode, int, error) {
 }
 
 // commit collapses a node down into a hash node and inserts it into the database
-func (c *committer) commit(n node, db *Database) (node, int, error) {
+func (c *committer) commit(path []byte, n node, db *Database) (node, int, error) {
 	// if this path is clean, use available cached data
 	hash, dirty := n.cache()
 	if hash != nil && !dirty {
@@ -93,7 +104,7 @@ func (c *committer) commit(n node, db *Database) (node, int, error) {
 		// otherwise it can only be hashNode or valueNode.
 		var childCommitted int
 		if _, ok := cn.Val.(*fullNode); ok {
-			childV, committed, err := c.commit(cn.Val, db)
+			childV, committed, err := c.commit(concat(path, cn.Key...), cn.Val, db)
 			if err != nil {
 				return nil, 0, err
 			}
@@ -101,20 +112,20 @@ func (c *committer) commit(n node, db *Database) (node, int, error) {
 		}
 		// The key needs to be copied, since we're delivering it to database
 		collapsed.Key = hexToCompact(cn.Key)
-		hashedNode := c.store(collapsed, db)
+		hashedNode := c.store(path, collapsed, db)
 		if hn, ok := hashedNode.(hashNode); ok {
 			return hn, childCommitted + 1, nil
 		}
 		return collapsed, childCommitted, nil
 	case *fullNode:
-		hashedKids, childCommitted, err := c.commitChildren(cn, db)
+		hashedKids, childCommitted, err := c.commitChildren(path, cn, db)
 		if err != nil {
 			return nil, 0, err
 		}
 		collapsed := cn.copy()
 		collapsed.Children = hashedKids
 
-		hashedNode := c.store(collapsed, db)
+		hashedNode := c.store(path, collapsed, db)
 		if hn, ok := hashedNode.(hashNode); ok {
 			return hn, childCommitted + 1, nil
 		}
@@ -128,7 +139,7 @@ func (c *committer) commit(n node, db *Database) (node, int, error) {
 }
 
 // commitChildren commits the children of the given fullnode
-func (c *committer) commitChildren(n *fullNode, db *Database) ([17]node, int, error) {
+func (c *committer) commitChildren(path []byte, n *fullNode, db *Database) ([17]node, int, error) {
 	var (
 		committed int
 		children  [17]node
@@ -148,7 +159,7 @@ func (c *committer) commitChildren(n *fullNode, db *Database) ([17]node, int, er
 		// Commit the child recursively and store the "hashed" value.
 		// Note the returned node can be some embedded nodes, so it's
 		// possible the type is not hashNode.
-		hashed, childCommitted, err := c.commit(child, db)
+		hashed, childCommitted, err := c.commit(concat(path, byte(i)), child, db)
 		if err != nil {
 			return children, 0, err
 		}
@@ -165,17 +176,25 @@ func (c *committer) commitChildren(n *fullNode, db *Database) ([17]node, int, er
 // store hashes the node n and if we have a storage layer specified, it writes
 // the key/value pair to it and tracks any node->child references as well as any
 // node->external trie references.
-func (c *committer) store(n node, db *Database) node {
+func (c *committer) store(path []byte, n node, db *Database) node {
 	// Larger nodes are replaced by their hash and stored in the database.
 	var (
-		hash, _ = n.cache()
-		size    int
+		hash, dirty = n.cache()
+		size        int
 	)
 	if hash == nil {
 		// This was not generated - must be a small node stored in the parent.
 		// In theory, we should apply the leafCall here if it's not nil(embedded
 		// node usually contains value). But small value(less than 32bytes) is
 		// not our target.
+		//
+		// With the path scheme, a standalone node previously stored at the
+		// same path is stale now that the node is embedded, delete it.
+		if dirty && c.nodes != nil {
+			c.lock.Lock()
+			c.nodes.insert(c.owner, path, common.Hash{}, nil)
+			c.lock.Unlock()
+		}
 		return n
 	} else {
 		// We have the hash already, estimate the RLP encoding-size of the node.
@@ -189,17 +208,32 @@ func (c *committer) store(n node, db *Database) node {
 			size: size,
 			hash: common.BytesToHash(hash),
 			node: n,
+			path: path,
 		}
 	} else if db != nil {
 		// No leaf-callback used, but there's still a database. Do serial
 		// insertion
 		db.lock.Lock()
-		db.insert(common.BytesToHash(hash), size, n)
+		c.insert(db, path, common.BytesToHash(hash), size, n)
 		db.lock.Unlock()
 	}
 	return hash
 }
 
+// insert writes the collapsed node into the database keyed by hash, or into the
+// node set keyed by path, depending on the storage scheme.
+//
+// Note, this method assumes that the database's lock is held!
+func (c *committer) insert(db *Database, path []byte, hash common.Hash, size int, n node) {
+	if c.nodes != nil {
+		c.lock.Lock()
+		c.nodes.insert(c.owner, path, hash, nodeToBytes(n))
+		c.lock.Unlock()
+		return
+	}
+	db.insert(hash, size, n)
+}
+
 // commitLoop does the actual insert + leaf callback for nodes.
 func (c *committer) commitLoop(db *Database) {
 	for item := range c.leafCh {
@@ -210,7 +244,7 @@ func (c *committer) commitLoop(db *Database) {
 		)
 		// We are pooling the trie nodes into an intermediate memory cache
 		db.lock.Lock()
-		db.insert(hash, size, n)
+		c.insert(db, item.path, hash, size, n)
 		db.lock.Unlock()
 
 		if c.onleaf != nil {
@@ -88,6 +88,10 @@ type Database struct {
 	childrenSize  common.StorageSize // Storage size of the external children tracking
 	preimagesSize common.StorageSize // Storage size of the preimages cache
 
+	path *pathDB // Path-based scheme state, nil if running with the hash scheme
+
+	onFlush func(hash common.Hash) // Hook invoked before a trie node is flushed to disk
+
 	lock sync.RWMutex
 }
 
@@ -263,9 +267,11 @@ func expandNode(hash hashNode, n node) node {
 
 // Config defines all necessary options for database.
 type Config struct {
-	Cache     int    // Memory allowance (MB) to use for caching trie nodes in memory
-	Journal   string // Journal of clean cache to survive node restarts
-	Preimages bool   // Flag whether the preimage of trie key is recorded
+	Cache        int    // Memory allowance (MB) to use for caching trie nodes in memory
+	Journal      string // Journal of clean cache to survive node restarts
+	Preimages    bool   // Flag whether the preimage of trie key is recorded
+	Scheme       string // Trie node storage scheme, empty for the hash scheme
+	ReverseDiffs uint64 // Number of reverse diffs retained by the path scheme, 0 for the default
 }
 
 // NewDatabase creates a new trie database to store ephemeral trie content before
@@ -297,9 +303,26 @@ func NewDatabaseWithConfig(diskdb ethdb.KeyValueStore, config *Config) *Database
 	if config == nil || config.Preimages { // TODO(karalabe): Flip to default off in the future
 		db.preimages = make(map[common.Hash][]byte)
 	}
+	if config != nil && config.Scheme == rawdb.PathScheme {
+		histories := uint64(defaultReverseDiffs)
+		if config != nil && config.ReverseDiffs > 0 {
+			histories = config.ReverseDiffs
+		}
+		db.path = newPathDB(diskdb, histories)
+	}
 	return db
 }
 
+// SetFlushHook installs a callback which is invoked with the hash of every trie
+// node flushed from the dirty cache, before the write reaches the disk database.
+// It's only used by the hash scheme, path-based nodes are overwritten in place.
+func (db *Database) SetFlushHook(hook func(hash common.Hash)) {
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	db.onFlush = hook
+}
+
 // DiskDB retrieves the persistent storage backing the trie database.
 func (db *Database) DiskDB() ethdb.KeyValueStore {
 	return db.diskdb
@@ -359,6 +382,10 @@ func (db *Database) insertPreimage(hash common.Hash, preimage []byte) {
 // node retrieves a cached trie node from memory, or returns nil if none can be
 // found in the memory cache.
 func (db *Database) node(hash common.Hash) node {
+	// The path scheme keys nodes by path, they can't be resolved by hash alone
+	if db.path != nil {
+		return nil
+	}
 	// Retrieve the node from the clean cache if available
 	if db.cleans != nil {
 		if enc := db.cleans.Get(nil, hash[:]); enc != nil {
@@ -399,6 +426,10 @@ func (db *Database) Node(hash common.Hash) ([]byte, error) {
 	if hash == (common.Hash{}) {
 		return nil, errors.New("not found")
 	}
+	// The path scheme keys nodes by path, they can't be resolved by hash alone
+	if db.path != nil {
+		return nil, ErrPathSchemeUnsupported
+	}
 	// Retrieve the node from the clean cache if available
 	if db.cleans != nil {
 		if enc := db.cleans.Get(nil, hash[:]); enc != nil {
@@ -609,10 +640,17 @@ func (db *Database) Cap(limit common.StorageSize) error {
 		}
 	}
 	// Keep committing nodes from the flush-list until we're below allowance
+	db.lock.RLock()
+	onFlush := db.onFlush
+	db.lock.RUnlock()
+
 	oldest := db.oldest
 	for size > limit && oldest != (common.Hash{}) {
 		// Fetch the oldest referenced node and push into the batch
 		node := db.dirties[oldest]
+		if onFlush != nil {
+			onFlush(oldest)
+		}
 		rawdb.WriteTrieNode(batch, oldest, node.rlp())
 
 		// If we exceeded the ideal batch size, commit and reset
@@ -677,11 +715,16 @@ func (db *Database) Cap(limit common.StorageSize) error {
 
 // Commit iterates over all the children of a particular node, writes them out
 // to disk, forcefully tearing down all references in both directions. As a side
-// effect, all pre-images accumulated up to this point are also written.
+// effect, all pre-images accumulated up to this point are also written. With
+// the path scheme, all diff layers leading up to the given root are flattened
+// into the persistent state instead.
 //
 // Note, this method is a non-synchronized mutator. It is unsafe to call this
 // concurrently with other mutators.
 func (db *Database) Commit(node common.Hash, report bool, callback func(common.Hash)) error {
+	if db.path != nil {
+		return db.commitPath(node)
+	}
 	// Create a database batch to flush persistent data out. It is important that
 	// outside code doesn't see an inconsistent state (referenced data removed from
 	// memory cache during commit but not yet in persistent storage). This is ensured
@@ -702,6 +745,20 @@ func (db *Database) Commit(node common.Hash, report bool, callback func(common.H
 	// Move the trie itself into the batch, flushing if enough data is accumulated
 	nodes, storage := len(db.dirties), db.dirtiesSize
 
+	db.lock.RLock()
+	if onFlush := db.onFlush; onFlush != nil {
+		if callback == nil {
+			callback = onFlush
+		} else {
+			inner := callback
+			callback = func(hash common.Hash) {
+				onFlush(hash)
+				inner(hash)
+			}
+		}
+	}
+	db.lock.RUnlock()
+
 	uncacher := &cleaner{db}
 	if err := db.commit(node, batch, uncacher, callback); err != nil {
 		log.Error("Failed to commit trie from trie database", "err", err)
@@ -832,6 +889,9 @@ func (db *Database) Size() (common.StorageSize, common.StorageSize) {
 	// db.dirtiesSize only contains the useful data in the cache, but when reporting
 	// the total memory consumption, the maintenance metadata is also needed to be
 	// counted.
+	if db.path != nil {
+		return db.pathSize(), db.preimagesSize
+	}
 	var metadataSize = common.StorageSize((len(db.dirties) - 1) * cachedNodeSize)
 	var metarootRefs = common.StorageSize(len(db.dirties[common.Hash{}].children) * (common.HashLength + 2))
 	return db.dirtiesSize + db.childrenSize + metadataSize - metarootRefs, db.preimagesSize
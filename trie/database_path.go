@@ -0,0 +1,582 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package trie
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+var (
+	pathLayerHitMeter  = metrics.NewRegisteredMeter("trie/path/layer/hit", nil)
+	pathLayerMissMeter = metrics.NewRegisteredMeter("trie/path/layer/miss", nil)
+	pathDiskHitMeter   = metrics.NewRegisteredMeter("trie/path/disk/hit", nil)
+	pathDiskMissMeter  = metrics.NewRegisteredMeter("trie/path/disk/miss", nil)
+
+	pathFlattenTimeTimer  = metrics.NewRegisteredResettingTimer("trie/path/flatten/time", nil)
+	pathFlattenNodesMeter = metrics.NewRegisteredMeter("trie/path/flatten/nodes", nil)
+	pathFlattenSizeMeter  = metrics.NewRegisteredMeter("trie/path/flatten/size", nil)
+)
+
+// defaultReverseDiffs is the number of reverse diffs retained on disk if not
+// configured otherwise, which is the maximum depth of a reorg below the
+// persistent state.
+const defaultReverseDiffs = 90000
+
+var (
+	// errStateUnrecoverable is returned if the requested state can't be
+	// reconstructed from the reverse diffs on disk.
+	errStateUnrecoverable = errors.New("state is unrecoverable")
+
+	// errLayerNotConnected is returned if a diff layer about to be flattened
+	// is not built on top of the persistent state.
+	errLayerNotConnected = errors.New("diff layer is not connected to the persistent state")
+
+	// ErrPathSchemeUnsupported is returned when retrieving a trie node by hash
+	// alone from a database running with the path scheme, which can only look
+	// up nodes by their owner and path.
+	ErrPathSchemeUnsupported = errors.New("trie node lookup by hash is unsupported under the path scheme")
+
+	// errPathCommit is returned if a trie of the path scheme is committed without
+	// retrieving the committed nodes, which would be lost.
+	errPathCommit = errors.New("path scheme tries must be committed with CommitNodes")
+)
+
+// pathNode is a trie node tracked by the path-based storage scheme. A nil blob
+// marks a node which has been deleted from its path.
+type pathNode struct {
+	hash common.Hash
+	blob []byte
+}
+
+// pathNodeSize is the approximate memory overhead of a tracked path node.
+const pathNodeSize = common.HashLength + 24
+
+// nodeSet is a collection of path nodes grouped by their owning trie. The
+// account trie is owned by the empty hash, storage tries by the hash of the
+// account address.
+type nodeSet map[common.Hash]map[string]*pathNode
+
+// get returns the node tracked at the given owner and path, if any.
+func (set nodeSet) get(owner common.Hash, path []byte) *pathNode {
+	if subset, ok := set[owner]; ok {
+		return subset[string(path)]
+	}
+	return nil
+}
+
+// NodeSet is the collection of trie nodes changed by committing one or more
+// tries of a state transition, along with the storage tries deleted by it. It
+// is only produced by the path-based scheme, where it's sealed into a diff
+// layer by Database.Update.
+type NodeSet struct {
+	nodes     nodeSet
+	destructs map[common.Hash]struct{}
+	size      common.StorageSize
+}
+
+// NewNodeSet creates an empty node set.
+func NewNodeSet() *NodeSet {
+	return &NodeSet{
+		nodes:     make(nodeSet),
+		destructs: make(map[common.Hash]struct{}),
+	}
+}
+
+// insert tracks a committed trie node at the given owner and path. A nil blob
+// marks a deletion.
+func (set *NodeSet) insert(owner common.Hash, path []byte, hash common.Hash, blob []byte) {
+	subset, ok := set.nodes[owner]
+	if !ok {
+		subset = make(map[string]*pathNode)
+		set.nodes[owner] = subset
+	}
+	if prev, ok := subset[string(path)]; ok {
+		set.size -= common.StorageSize(len(path) + len(prev.blob) + pathNodeSize)
+	}
+	subset[string(path)] = &pathNode{hash: hash, blob: blob}
+	set.size += common.StorageSize(len(path) + len(blob) + pathNodeSize)
+}
+
+// DeleteStorage marks the storage trie of the given account as deleted by the
+// state transition. All of its nodes are wiped from disk when the transition
+// is flattened into the persistent state, before any nodes of a storage trie
+// recreated by the same transition are written.
+func (set *NodeSet) DeleteStorage(owner common.Hash) {
+	set.destructs[owner] = struct{}{}
+}
+
+// Merge adds the nodes and deleted storage tries of the given set, which may
+// be nil, overriding the nodes tracked at the same paths.
+func (set *NodeSet) Merge(other *NodeSet) {
+	if other == nil {
+		return
+	}
+	for owner, subset := range other.nodes {
+		for path, n := range subset {
+			set.insert(owner, []byte(path), n.hash, n.blob)
+		}
+	}
+	for owner := range other.destructs {
+		set.destructs[owner] = struct{}{}
+	}
+}
+
+// diffLayer is an in-memory collection of the trie nodes changed by a single
+// state transition, not yet flushed into the persistent state.
+type diffLayer struct {
+	root      common.Hash              // Root hash of the state after the transition
+	parent    common.Hash              // Root hash of the state the transition is based on
+	nodes     nodeSet                  // Trie nodes changed by the transition
+	destructs map[common.Hash]struct{} // Storage tries deleted by the transition
+	size      common.StorageSize       // Approximate memory used by the layer
+}
+
+// reverseDiff is the persisted record of the trie nodes overwritten when a diff
+// layer is flattened into the persistent state. Applying it reverts the disk
+// state from Root back to Parent.
+type reverseDiff struct {
+	Parent common.Hash
+	Root   common.Hash
+	Nodes  []reverseDiffNode
+}
+
+// reverseDiffNode is the previous value of a single trie node. An empty blob
+// means the node didn't exist before.
+type reverseDiffNode struct {
+	Owner common.Hash
+	Path  []byte
+	Blob  []byte
+}
+
+// pathDB is the state of a trie database running with the path-based storage
+// scheme. Trie nodes are persisted keyed by their owner and path, the most
+// recent state transitions are kept as diff layers in memory and the flushed
+// ones are recorded as reverse diffs on disk to allow deep reorgs.
+type pathDB struct {
+	layers    map[common.Hash]*diffLayer // In-memory diff layers keyed by state root
+	diskRoot  common.Hash                // Root hash of the persistent state
+	histories uint64                     // Number of reverse diffs to retain, 0 for all
+}
+
+// newPathDB creates the path scheme state for the given disk database.
+func newPathDB(diskdb ethdb.KeyValueReader, histories uint64) *pathDB {
+	return &pathDB{
+		layers:    make(map[common.Hash]*diffLayer),
+		diskRoot:  readDiskRoot(diskdb),
+		histories: histories,
+	}
+}
+
+// readDiskRoot resolves the root hash of the persistent state from the account
+// trie root node stored at the empty path.
+func readDiskRoot(diskdb ethdb.KeyValueReader) common.Hash {
+	blob := rawdb.ReadAccountTrieNode(diskdb, nil)
+	if len(blob) == 0 {
+		return emptyRoot
+	}
+	return crypto.Keccak256Hash(blob)
+}
+
+// readPathNode retrieves the trie node persisted at the given owner and path.
+func readPathNode(db ethdb.KeyValueReader, owner common.Hash, path []byte) []byte {
+	if owner == (common.Hash{}) {
+		return rawdb.ReadAccountTrieNode(db, path)
+	}
+	return rawdb.ReadStorageTrieNode(db, owner, path)
+}
+
+// writePathNode persists the trie node at the given owner and path, or deletes
+// it if the blob is empty.
+func writePathNode(db ethdb.KeyValueWriter, owner common.Hash, path []byte, blob []byte) {
+	switch {
+	case owner == (common.Hash{}) && len(blob) == 0:
+		rawdb.DeleteAccountTrieNode(db, path)
+	case owner == (common.Hash{}):
+		rawdb.WriteAccountTrieNode(db, path, blob)
+	case len(blob) == 0:
+		rawdb.DeleteStorageTrieNode(db, owner, path)
+	default:
+		rawdb.WriteStorageTrieNode(db, owner, path, blob)
+	}
+}
+
+// Scheme returns the trie node storage scheme used by the database.
+func (db *Database) Scheme() string {
+	if db.path != nil {
+		return rawdb.PathScheme
+	}
+	return rawdb.HashScheme
+}
+
+// pathNodeBlob retrieves the encoded trie node with the given hash from its
+// owner and path, as part of the given state. The diff layers are walked from
+// the state down to the persistent state, the first one tracking the path
+// holds the node valid in the state. Nodes of states no longer tracked in
+// memory are only served from the persistent state if unchanged.
+func (db *Database) pathNodeBlob(state common.Hash, owner common.Hash, path []byte, hash common.Hash) []byte {
+	if db.cleans != nil {
+		if enc := db.cleans.Get(nil, hash[:]); enc != nil {
+			memcacheCleanHitMeter.Mark(1)
+			memcacheCleanReadMeter.Mark(int64(len(enc)))
+			return enc
+		}
+	}
+	db.lock.RLock()
+	for layer := db.path.layers[state]; layer != nil; layer = db.path.layers[layer.parent] {
+		if n := layer.nodes.get(owner, path); n != nil {
+			db.lock.RUnlock()
+			if n.hash != hash {
+				pathLayerMissMeter.Mark(1)
+				return nil
+			}
+			pathLayerHitMeter.Mark(1)
+			return n.blob
+		}
+		if _, ok := layer.destructs[owner]; ok {
+			// The storage trie was wiped by the transition and the path
+			// is not recreated, nothing below is valid.
+			db.lock.RUnlock()
+			pathLayerMissMeter.Mark(1)
+			return nil
+		}
+	}
+	db.lock.RUnlock()
+	pathLayerMissMeter.Mark(1)
+
+	// Content unavailable in memory, the node stored on disk at the same path
+	// might be a different version, so make sure it's the requested one.
+	enc := readPathNode(db.diskdb, owner, path)
+	if len(enc) == 0 || crypto.Keccak256Hash(enc) != hash {
+		pathDiskMissMeter.Mark(1)
+		return nil
+	}
+	pathDiskHitMeter.Mark(1)
+	if db.cleans != nil {
+		db.cleans.Set(hash[:], enc)
+		memcacheCleanMissMeter.Mark(1)
+		memcacheCleanWriteMeter.Mark(int64(len(enc)))
+	}
+	return enc
+}
+
+// hasStateRoot reports whether the state of the given root is tracked, either
+// as the persistent state or as a diff layer.
+func (db *Database) hasStateRoot(root common.Hash) bool {
+	db.lock.RLock()
+	defer db.lock.RUnlock()
+
+	if root == db.path.diskRoot {
+		return true
+	}
+	_, ok := db.path.layers[root]
+	return ok
+}
+
+// Update seals the trie nodes committed by a state transition into a diff layer
+// representing the transition from the parent state to the given root. It's a
+// noop for the hash-based scheme, where nodes are reference counted instead.
+func (db *Database) Update(root common.Hash, parent common.Hash, nodes *NodeSet) error {
+	if db.path == nil {
+		return nil
+	}
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	if nodes == nil {
+		nodes = NewNodeSet()
+	}
+	if parent == (common.Hash{}) {
+		parent = emptyRoot
+	}
+
+	// Skip the layer creation if the state didn't change or the transition
+	// is already known, the committed nodes are the same in that case.
+	if root == parent || root == db.path.diskRoot {
+		return nil
+	}
+	if _, ok := db.path.layers[root]; ok {
+		return nil
+	}
+	if parent != db.path.diskRoot {
+		if _, ok := db.path.layers[parent]; !ok {
+			return fmt.Errorf("parent state %x is not available", parent)
+		}
+	}
+	db.path.layers[root] = &diffLayer{
+		root:      root,
+		parent:    parent,
+		nodes:     nodes.nodes,
+		destructs: nodes.destructs,
+		size:      nodes.size,
+	}
+	return nil
+}
+
+// CapLayers flattens the oldest diff layers along the ancestry of the given
+// root into the persistent state, keeping at most the requested number of
+// layers in memory. Diff layers no longer connected to the persistent state
+// are discarded. It's a noop for the hash-based scheme.
+func (db *Database) CapLayers(root common.Hash, layers int) error {
+	if db.path == nil {
+		return nil
+	}
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	return db.capLayers(root, layers)
+}
+
+// capLayers is the private locked version of CapLayers.
+func (db *Database) capLayers(root common.Hash, layers int) error {
+	var chain []*diffLayer
+	for layer := db.path.layers[root]; layer != nil; layer = db.path.layers[layer.parent] {
+		chain = append(chain, layer)
+		if len(chain) > len(db.path.layers) {
+			return fmt.Errorf("diff layer cycle at %x", layer.root)
+		}
+	}
+	for len(chain) > layers {
+		bottom := chain[len(chain)-1]
+		if err := db.flatten(bottom); err != nil {
+			return err
+		}
+		delete(db.path.layers, bottom.root)
+		chain = chain[:len(chain)-1]
+	}
+	// Drop all the layers which were forked off below the new persistent
+	// state, they can never become canonical anymore.
+	for {
+		var dropped bool
+		for hash, layer := range db.path.layers {
+			if _, ok := db.path.layers[layer.parent]; !ok && layer.parent != db.path.diskRoot {
+				delete(db.path.layers, hash)
+				dropped = true
+			}
+		}
+		if !dropped {
+			break
+		}
+	}
+	return nil
+}
+
+// flatten writes the given diff layer into the persistent state, recording the
+// overwritten nodes as a reverse diff.
+//
+// Note, this method assumes that the database's lock is held!
+func (db *Database) flatten(layer *diffLayer) error {
+	if layer.parent != db.path.diskRoot {
+		return fmt.Errorf("%w: layer %x, parent %x, disk %x", errLayerNotConnected, layer.root, layer.parent, db.path.diskRoot)
+	}
+	var (
+		start = time.Now()
+		batch = db.diskdb.NewBatch()
+		diff  = reverseDiff{Parent: layer.parent, Root: layer.root}
+		nodes int
+	)
+	// Move all of the accumulated preimages into the write batch
+	if db.preimages != nil && len(db.preimages) > 0 {
+		rawdb.WritePreimages(batch, db.preimages)
+		db.preimages, db.preimagesSize = make(map[common.Hash][]byte), 0
+	}
+	// Wipe the storage tries deleted by the transition first, the nodes of any
+	// storage trie recreated afterwards are written on top.
+	wiped := make(map[common.Hash]map[string]struct{})
+	for owner := range layer.destructs {
+		paths := make(map[string]struct{})
+		it := rawdb.IterateStorageTrieNodes(db.diskdb, owner)
+		for it.Next() {
+			path := common.CopyBytes(it.Key()[len(rawdb.TrieNodeStoragePrefix)+common.HashLength:])
+			diff.Nodes = append(diff.Nodes, reverseDiffNode{
+				Owner: owner,
+				Path:  path,
+				Blob:  common.CopyBytes(it.Value()),
+			})
+			rawdb.DeleteStorageTrieNode(batch, owner, path)
+			paths[string(path)] = struct{}{}
+			nodes++
+		}
+		it.Release()
+		if err := it.Error(); err != nil {
+			return err
+		}
+		wiped[owner] = paths
+	}
+	for owner, subset := range layer.nodes {
+		for path, n := range subset {
+			// The previous value of wiped nodes is already recorded
+			if _, ok := wiped[owner][path]; !ok {
+				diff.Nodes = append(diff.Nodes, reverseDiffNode{
+					Owner: owner,
+					Path:  []byte(path),
+					Blob:  readPathNode(db.diskdb, owner, []byte(path)),
+				})
+			}
+			writePathNode(batch, owner, []byte(path), n.blob)
+			if n.blob != nil && db.cleans != nil {
+				db.cleans.Set(n.hash[:], n.blob)
+			}
+			nodes++
+		}
+	}
+	blob, err := rlp.EncodeToBytes(&diff)
+	if err != nil {
+		return err
+	}
+	id := rawdb.ReadPersistentStateID(db.diskdb) + 1
+	if id == 1 {
+		rawdb.WriteStateID(batch, layer.parent, 0)
+	}
+	rawdb.WriteReverseDiff(batch, id, blob)
+	rawdb.WriteStateID(batch, layer.root, id)
+	rawdb.WritePersistentStateID(batch, id)
+
+	// Prune the reverse diffs beyond the retention limit, along with the
+	// lookups of the states they could revert to.
+	if db.path.histories > 0 && id > db.path.histories {
+		for tail := id - db.path.histories; tail > 0; tail-- {
+			enc := rawdb.ReadReverseDiff(db.diskdb, tail)
+			if len(enc) == 0 {
+				break
+			}
+			var old reverseDiff
+			if err := rlp.DecodeBytes(enc, &old); err != nil {
+				return err
+			}
+			if stored := rawdb.ReadStateID(db.diskdb, old.Parent); stored != nil && *stored == tail-1 {
+				rawdb.DeleteStateID(batch, old.Parent)
+			}
+			rawdb.DeleteReverseDiff(batch, tail)
+		}
+	}
+	if err := batch.Write(); err != nil {
+		return err
+	}
+	db.path.diskRoot = layer.root
+
+	pathFlattenTimeTimer.Update(time.Since(start))
+	pathFlattenNodesMeter.Mark(int64(nodes))
+	pathFlattenSizeMeter.Mark(int64(layer.size))
+
+	log.Debug("Flattened state diff layer", "root", layer.root, "id", id, "nodes", nodes, "size", layer.size, "elapsed", common.PrettyDuration(time.Since(start)))
+	return nil
+}
+
+// commitPath flattens all the diff layers along the ancestry of the given root
+// into the persistent state.
+func (db *Database) commitPath(root common.Hash) error {
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	if _, ok := db.path.layers[root]; !ok {
+		// Nothing to flush, but still persist the preimages as the hash
+		// based scheme does.
+		if db.preimages != nil && len(db.preimages) > 0 {
+			batch := db.diskdb.NewBatch()
+			rawdb.WritePreimages(batch, db.preimages)
+			if err := batch.Write(); err != nil {
+				return err
+			}
+			db.preimages, db.preimagesSize = make(map[common.Hash][]byte), 0
+		}
+		return nil
+	}
+	return db.capLayers(root, 0)
+}
+
+// Recoverable reports whether the persistent state can be reverted to the given
+// root using the reverse diffs on disk.
+func (db *Database) Recoverable(root common.Hash) bool {
+	if db.path == nil {
+		return false
+	}
+	id := rawdb.ReadStateID(db.diskdb, root)
+	if id == nil {
+		return false
+	}
+	return *id <= rawdb.ReadPersistentStateID(db.diskdb)
+}
+
+// Recover reverts the persistent state to the given root by applying reverse
+// diffs, dropping all in-memory diff layers. It's only supported by the path
+// based scheme.
+func (db *Database) Recover(root common.Hash) error {
+	if db.path == nil {
+		return errors.New("state recovery is not supported by the hash scheme")
+	}
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	target := rawdb.ReadStateID(db.diskdb, root)
+	if target == nil {
+		return errStateUnrecoverable
+	}
+	current := rawdb.ReadPersistentStateID(db.diskdb)
+	if *target > current {
+		return errStateUnrecoverable
+	}
+	start := time.Now()
+	for ; current > *target; current-- {
+		enc := rawdb.ReadReverseDiff(db.diskdb, current)
+		if len(enc) == 0 {
+			return fmt.Errorf("%w: missing reverse diff %d", errStateUnrecoverable, current)
+		}
+		var diff reverseDiff
+		if err := rlp.DecodeBytes(enc, &diff); err != nil {
+			return err
+		}
+		if diff.Root != db.path.diskRoot {
+			return fmt.Errorf("reverse diff %d mismatch: have %x, want %x", current, diff.Root, db.path.diskRoot)
+		}
+		batch := db.diskdb.NewBatch()
+		for _, n := range diff.Nodes {
+			writePathNode(batch, n.Owner, n.Path, n.Blob)
+		}
+		rawdb.DeleteReverseDiff(batch, current)
+		rawdb.DeleteStateID(batch, diff.Root)
+		rawdb.WritePersistentStateID(batch, current-1)
+		if err := batch.Write(); err != nil {
+			return err
+		}
+		db.path.diskRoot = diff.Parent
+	}
+	db.path.layers = make(map[common.Hash]*diffLayer)
+
+	log.Info("Recovered persistent state", "root", root, "id", *target, "elapsed", common.PrettyDuration(time.Since(start)))
+	return nil
+}
+
+// pathSize returns the memory used by the diff layers.
+//
+// Note, this method assumes that the database's lock is held!
+func (db *Database) pathSize() common.StorageSize {
+	var size common.StorageSize
+	for _, layer := range db.path.layers {
+		size += layer.size
+	}
+	return size
+}
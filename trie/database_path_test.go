@@ -0,0 +1,437 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package trie
+
+import (
+	"bytes"
+	"math/rand"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
+)
+
+// pathTestState is a flat representation of the expected trie content.
+type pathTestState map[string][]byte
+
+// copy returns a deep copy of the state.
+func (s pathTestState) copy() pathTestState {
+	cpy := make(pathTestState)
+	for k, v := range s {
+		cpy[k] = v
+	}
+	return cpy
+}
+
+// newPathDatabase creates a path scheme trie database on top of the disk db.
+func newPathDatabase(diskdb ethdb.KeyValueStore) *Database {
+	return NewDatabaseWithConfig(diskdb, &Config{Scheme: rawdb.PathScheme})
+}
+
+// mutatePathTrie applies a batch of random updates and deletions onto the trie
+// of the given root, seals the result as a diff layer and returns the new root.
+// The trie root doubles as the state root.
+func mutatePathTrie(t *testing.T, db *Database, owner common.Hash, root common.Hash, state pathTestState, rng *rand.Rand) common.Hash {
+	t.Helper()
+
+	hash, nodes := commitPathTrie(t, db, owner, root, state, rng)
+	if err := db.Update(hash, root, nodes); err != nil {
+		t.Fatalf("failed to update database: %v", err)
+	}
+	return hash
+}
+
+// commitPathTrie applies a batch of random updates and deletions onto the trie
+// of the given root and commits it, returning the new root and the committed
+// nodes without sealing them.
+func commitPathTrie(t *testing.T, db *Database, owner common.Hash, root common.Hash, state pathTestState, rng *rand.Rand) (common.Hash, *NodeSet) {
+	t.Helper()
+
+	tr, err := NewWithOwner(root, owner, root, db)
+	if err != nil {
+		t.Fatalf("failed to open trie %x: %v", root, err)
+	}
+	for i := 0; i < 100; i++ {
+		if len(state) > 0 && rng.Intn(3) == 0 {
+			for k := range state {
+				if err := tr.TryDelete([]byte(k)); err != nil {
+					t.Fatalf("failed to delete: %v", err)
+				}
+				delete(state, k)
+				break
+			}
+			continue
+		}
+		key := make([]byte, 1+rng.Intn(8))
+		rng.Read(key)
+		val := make([]byte, 1+rng.Intn(64))
+		rng.Read(val)
+
+		if err := tr.TryUpdate(key, val); err != nil {
+			t.Fatalf("failed to update: %v", err)
+		}
+		state[string(key)] = val
+	}
+	hash, _, nodes, err := tr.CommitNodes(nil)
+	if err != nil {
+		t.Fatalf("failed to commit trie: %v", err)
+	}
+	return hash, nodes
+}
+
+// checkPathTrie verifies that the trie of the given root holds exactly the
+// expected content.
+func checkPathTrie(t *testing.T, db *Database, owner common.Hash, root common.Hash, state pathTestState) {
+	t.Helper()
+
+	tr, err := NewWithOwner(root, owner, root, db)
+	if err != nil {
+		t.Fatalf("failed to open trie %x: %v", root, err)
+	}
+	for k, v := range state {
+		have, err := tr.TryGet([]byte(k))
+		if err != nil {
+			t.Fatalf("failed to retrieve %x: %v", k, err)
+		}
+		if !bytes.Equal(have, v) {
+			t.Fatalf("value mismatch for %x: have %x, want %x", k, have, v)
+		}
+	}
+	var leaves int
+	it := NewIterator(tr.NodeIterator(nil))
+	for it.Next() {
+		leaves++
+	}
+	if it.Err != nil {
+		t.Fatalf("failed to iterate trie: %v", it.Err)
+	}
+	if leaves != len(state) {
+		t.Fatalf("leaf count mismatch: have %d, want %d", leaves, len(state))
+	}
+}
+
+// Tests that committed nodes are readable from the diff layers and from disk
+// after flattening, and that stale nodes are overwritten or deleted in place.
+func TestPathSchemeFlatten(t *testing.T) {
+	var (
+		rng    = rand.New(rand.NewSource(1))
+		diskdb = rawdb.NewMemoryDatabase()
+		db     = newPathDatabase(diskdb)
+		state  = make(pathTestState)
+		root   = emptyRoot
+	)
+	for i := 0; i < 20; i++ {
+		root = mutatePathTrie(t, db, common.Hash{}, root, state, rng)
+		checkPathTrie(t, db, common.Hash{}, root, state)
+
+		if err := db.Commit(root, false, nil); err != nil {
+			t.Fatalf("failed to flatten state: %v", err)
+		}
+		if size, _ := db.Size(); size != 0 {
+			t.Fatalf("dangling diff layers after flattening: %v", size)
+		}
+		// Reopen the database to make sure the disk content is complete
+		reopened := newPathDatabase(diskdb)
+		checkPathTrie(t, reopened, common.Hash{}, root, state)
+
+		// Every stored node must be part of the current trie
+		tr, _ := New(root, reopened)
+		var nodes int
+		it := tr.NodeIterator(nil)
+		for it.Next(true) {
+			if it.Hash() != (common.Hash{}) {
+				nodes++
+			}
+		}
+		var stored int
+		dbit := diskdb.NewIterator(rawdb.TrieNodeAccountPrefix, nil)
+		for dbit.Next() {
+			stored++
+		}
+		dbit.Release()
+		if stored != nodes {
+			t.Fatalf("iteration %d: stored node count mismatch: have %d, want %d", i, stored, nodes)
+		}
+	}
+}
+
+// Tests that only the requested number of diff layers are kept in memory and
+// that the states of all retained layers remain accessible.
+func TestPathSchemeCapLayers(t *testing.T) {
+	var (
+		rng    = rand.New(rand.NewSource(2))
+		db     = newPathDatabase(rawdb.NewMemoryDatabase())
+		owner  = common.HexToHash("0xdeadbeef")
+		root   = emptyRoot
+		roots  []common.Hash
+		states []pathTestState
+		state  = make(pathTestState)
+	)
+	for i := 0; i < 10; i++ {
+		root = mutatePathTrie(t, db, owner, root, state, rng)
+		roots = append(roots, root)
+		states = append(states, state.copy())
+	}
+	if err := db.CapLayers(root, 4); err != nil {
+		t.Fatalf("failed to cap layers: %v", err)
+	}
+	if have := len(db.path.layers); have != 4 {
+		t.Fatalf("diff layer count mismatch: have %d, want 4", have)
+	}
+	if db.path.diskRoot != roots[5] {
+		t.Fatalf("disk root mismatch: have %x, want %x", db.path.diskRoot, roots[5])
+	}
+	for i := 5; i < 10; i++ {
+		checkPathTrie(t, db, owner, roots[i], states[i])
+	}
+	// States below the persistent one are gone, unless recovered
+	if _, err := NewWithOwner(roots[3], owner, roots[3], db); err == nil {
+		t.Fatalf("flattened state %x still accessible", roots[3])
+	}
+	// Storage tries with other owners must not see the nodes
+	if _, err := New(roots[5], db); err == nil {
+		t.Fatalf("state accessible with the wrong owner")
+	}
+}
+
+// Tests that sibling diff layers forked off below the persistent state are
+// dropped when capping.
+func TestPathSchemeDropForks(t *testing.T) {
+	var (
+		rng    = rand.New(rand.NewSource(3))
+		db     = newPathDatabase(rawdb.NewMemoryDatabase())
+		state  = make(pathTestState)
+		root1  = mutatePathTrie(t, db, common.Hash{}, emptyRoot, state, rng)
+		fork   = state.copy()
+		root2  = mutatePathTrie(t, db, common.Hash{}, root1, state, rng)
+		forked = mutatePathTrie(t, db, common.Hash{}, root1, fork, rng)
+		root3  = mutatePathTrie(t, db, common.Hash{}, root2, state, rng)
+	)
+	checkPathTrie(t, db, common.Hash{}, forked, fork)
+
+	if err := db.CapLayers(root3, 1); err != nil {
+		t.Fatalf("failed to cap layers: %v", err)
+	}
+	if _, ok := db.path.layers[forked]; ok {
+		t.Fatalf("stale fork not dropped")
+	}
+	if _, ok := db.path.layers[root3]; !ok {
+		t.Fatalf("head layer dropped")
+	}
+	checkPathTrie(t, db, common.Hash{}, root3, state)
+}
+
+// Tests that the persistent state can be reverted to older versions using the
+// reverse diffs, and that diffs beyond the retention limit are pruned.
+func TestPathSchemeRecover(t *testing.T) {
+	var (
+		rng    = rand.New(rand.NewSource(4))
+		diskdb = rawdb.NewMemoryDatabase()
+		db     = NewDatabaseWithConfig(diskdb, &Config{Scheme: rawdb.PathScheme, ReverseDiffs: 8})
+		state  = make(pathTestState)
+		root   = emptyRoot
+		roots  = []common.Hash{emptyRoot}
+		states = []pathTestState{state.copy()}
+	)
+	for i := 0; i < 12; i++ {
+		root = mutatePathTrie(t, db, common.Hash{}, root, state, rng)
+		if err := db.CapLayers(root, 0); err != nil {
+			t.Fatalf("failed to flatten state: %v", err)
+		}
+		roots = append(roots, root)
+		states = append(states, state.copy())
+	}
+	if id := rawdb.ReadPersistentStateID(diskdb); id != 12 {
+		t.Fatalf("persistent state id mismatch: have %d, want 12", id)
+	}
+	// The oldest states are beyond the retention limit
+	for i := 0; i < 4; i++ {
+		if db.Recoverable(roots[i]) {
+			t.Fatalf("state %d recoverable beyond the retention limit", i)
+		}
+		if blob := rawdb.ReadReverseDiff(diskdb, uint64(i)); len(blob) != 0 {
+			t.Fatalf("reverse diff %d not pruned", i)
+		}
+	}
+	if !db.Recoverable(roots[4]) {
+		t.Fatalf("state 4 not recoverable")
+	}
+	if err := db.Recover(roots[7]); err != nil {
+		t.Fatalf("failed to recover state: %v", err)
+	}
+	if have := readDiskRoot(diskdb); have != roots[7] {
+		t.Fatalf("disk root mismatch: have %x, want %x", have, roots[7])
+	}
+	checkPathTrie(t, newPathDatabase(diskdb), common.Hash{}, roots[7], states[7])
+
+	// Newer states are gone, older ones are still recoverable
+	if db.Recoverable(roots[9]) {
+		t.Fatalf("reverted state still recoverable")
+	}
+	if err := db.Recover(roots[4]); err != nil {
+		t.Fatalf("failed to recover state: %v", err)
+	}
+	checkPathTrie(t, db, common.Hash{}, roots[4], states[4])
+
+	// Continue building on top of the recovered state
+	root = mutatePathTrie(t, db, common.Hash{}, roots[4], states[4], rng)
+	if err := db.Commit(root, false, nil); err != nil {
+		t.Fatalf("failed to flatten state: %v", err)
+	}
+	checkPathTrie(t, newPathDatabase(diskdb), common.Hash{}, root, states[4])
+	if id := rawdb.ReadPersistentStateID(diskdb); id != 5 {
+		t.Fatalf("persistent state id mismatch: have %d, want 5", id)
+	}
+}
+
+// Tests that the scheme is taken from the config alone, defaulting to the hash
+// scheme without looking at the scheme marker of the database.
+func TestPathSchemeSelection(t *testing.T) {
+	diskdb := rawdb.NewMemoryDatabase()
+	rawdb.WriteStateScheme(diskdb, rawdb.PathScheme)
+
+	if scheme := NewDatabase(diskdb).Scheme(); scheme != rawdb.HashScheme {
+		t.Fatalf("scheme mismatch: have %s, want %s", scheme, rawdb.HashScheme)
+	}
+	if scheme := newPathDatabase(diskdb).Scheme(); scheme != rawdb.PathScheme {
+		t.Fatalf("scheme mismatch: have %s, want %s", scheme, rawdb.PathScheme)
+	}
+	// Make sure the root of an empty state is resolved correctly
+	if root := readDiskRoot(diskdb); root != emptyRoot {
+		t.Fatalf("empty disk root mismatch: have %x, want %x", root, emptyRoot)
+	}
+	blob := []byte{0xc0}
+	rawdb.WriteAccountTrieNode(diskdb, nil, blob)
+	if root := readDiskRoot(diskdb); root != crypto.Keccak256Hash(blob) {
+		t.Fatalf("disk root mismatch: have %x, want %x", root, crypto.Keccak256Hash(blob))
+	}
+}
+
+// Tests that the storage trie of a deleted account is wiped from disk when its
+// layer is flattened, and restored when the state is recovered.
+func TestPathSchemeDeleteStorage(t *testing.T) {
+	var (
+		rng    = rand.New(rand.NewSource(5))
+		diskdb = rawdb.NewMemoryDatabase()
+		db     = NewDatabaseWithConfig(diskdb, &Config{Scheme: rawdb.PathScheme, ReverseDiffs: 8})
+		owner  = common.HexToHash("0xdeadbeef")
+		state  = make(pathTestState)
+		root1  = mutatePathTrie(t, db, owner, emptyRoot, state, rng)
+	)
+	if err := db.Commit(root1, false, nil); err != nil {
+		t.Fatalf("failed to flatten state: %v", err)
+	}
+	countStorage := func() int {
+		it := rawdb.IterateStorageTrieNodes(diskdb, owner)
+		defer it.Release()
+
+		var n int
+		for it.Next() {
+			n++
+		}
+		return n
+	}
+	if countStorage() == 0 {
+		t.Fatalf("storage trie not persisted")
+	}
+	// Delete the storage trie along with an unrelated account trie change
+	tr, _ := New(emptyRoot, db)
+	tr.Update([]byte("account"), []byte("value"))
+	root2, _, nodes, err := tr.CommitNodes(nil)
+	if err != nil {
+		t.Fatalf("failed to commit trie: %v", err)
+	}
+	nodes.DeleteStorage(owner)
+	if err := db.Update(root2, root1, nodes); err != nil {
+		t.Fatalf("failed to update database: %v", err)
+	}
+	if err := db.Commit(root2, false, nil); err != nil {
+		t.Fatalf("failed to flatten state: %v", err)
+	}
+	if n := countStorage(); n != 0 {
+		t.Fatalf("storage trie not wiped: %d nodes left", n)
+	}
+	// Revert the deletion and make sure the storage trie is back in full
+	if err := db.Recover(root1); err != nil {
+		t.Fatalf("failed to recover state: %v", err)
+	}
+	checkPathTrie(t, newPathDatabase(diskdb), owner, root1, state)
+}
+
+// Tests that nodes can't be looked up by hash alone under the path scheme.
+func TestPathSchemeNodeByHash(t *testing.T) {
+	var (
+		rng   = rand.New(rand.NewSource(6))
+		db    = newPathDatabase(rawdb.NewMemoryDatabase())
+		state = make(pathTestState)
+		root  = mutatePathTrie(t, db, common.Hash{}, emptyRoot, state, rng)
+	)
+	if _, err := db.Node(root); err != ErrPathSchemeUnsupported {
+		t.Fatalf("error mismatch: have %v, want %v", err, ErrPathSchemeUnsupported)
+	}
+}
+
+// Tests that tries committed on top of the same state before either transition
+// is sealed don't mix their nodes.
+func TestPathSchemeConcurrentCommits(t *testing.T) {
+	var (
+		rng   = rand.New(rand.NewSource(7))
+		db    = newPathDatabase(rawdb.NewMemoryDatabase())
+		state = make(pathTestState)
+		root  = mutatePathTrie(t, db, common.Hash{}, emptyRoot, state, rng)
+
+		stateA        = state.copy()
+		stateB        = state.copy()
+		rootA, nodesA = commitPathTrie(t, db, common.Hash{}, root, stateA, rng)
+		rootB, nodesB = commitPathTrie(t, db, common.Hash{}, root, stateB, rng)
+	)
+	if err := db.Update(rootB, root, nodesB); err != nil {
+		t.Fatalf("failed to update database: %v", err)
+	}
+	if err := db.Update(rootA, root, nodesA); err != nil {
+		t.Fatalf("failed to update database: %v", err)
+	}
+	checkPathTrie(t, db, common.Hash{}, rootA, stateA)
+	checkPathTrie(t, db, common.Hash{}, rootB, stateB)
+	checkPathTrie(t, db, common.Hash{}, root, state)
+}
+
+// Tests that nodes are resolved from the diff layers of the requested state
+// only, not from unrelated layers tracking the same path.
+func TestPathSchemeLayerOrder(t *testing.T) {
+	var (
+		rng    = rand.New(rand.NewSource(8))
+		db     = newPathDatabase(rawdb.NewMemoryDatabase())
+		owner  = common.HexToHash("0xdeadbeef")
+		state  = make(pathTestState)
+		root1  = mutatePathTrie(t, db, owner, emptyRoot, state, rng)
+		state1 = state.copy()
+		root2  = mutatePathTrie(t, db, owner, root1, state, rng)
+	)
+	// The storage trie of the first state is available as part of its own
+	// state, but not as part of the second one, which overwrote its nodes.
+	if _, err := NewWithOwner(root1, owner, root1, db); err != nil {
+		t.Fatalf("failed to open trie in its own state: %v", err)
+	}
+	if _, err := NewWithOwner(root2, owner, root1, db); err == nil {
+		t.Fatalf("overwritten trie accessible in a later state")
+	}
+	checkPathTrie(t, db, owner, root1, state1)
+	checkPathTrie(t, db, owner, root2, state)
+}
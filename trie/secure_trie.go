@@ -54,10 +54,17 @@ type SecureTrie struct {
 // A new cache generation is created by each call to Commit.
 // cachelimit sets the number of past cache generations to keep.
 func NewSecure(root common.Hash, db *Database) (*SecureTrie, error) {
+	return NewSecureWithOwner(root, common.Hash{}, root, db)
+}
+
+// NewSecureWithOwner creates a secure trie owned by the given account hash as
+// part of the given state, it should be used for opening storage tries. See
+// NewWithOwner for details.
+func NewSecureWithOwner(state common.Hash, owner common.Hash, root common.Hash, db *Database) (*SecureTrie, error) {
 	if db == nil {
 		panic("trie.NewSecure called without a database")
 	}
-	trie, err := New(root, db)
+	trie, err := NewWithOwner(state, owner, root, db)
 	if err != nil {
 		return nil, err
 	}
@@ -162,6 +169,16 @@ func (t *SecureTrie) GetKey(shaKey []byte) []byte {
 // Committing flushes nodes from memory. Subsequent Get calls will load nodes
 // from the database.
 func (t *SecureTrie) Commit(onleaf LeafCallback) (common.Hash, int, error) {
+	if t.trie.db != nil && t.trie.db.path != nil {
+		return common.Hash{}, 0, errPathCommit
+	}
+	root, committed, _, err := t.CommitNodes(onleaf)
+	return root, committed, err
+}
+
+// CommitNodes writes the secure hash pre-images to the trie's database and
+// commits the trie. See Trie.CommitNodes for details.
+func (t *SecureTrie) CommitNodes(onleaf LeafCallback) (common.Hash, int, *NodeSet, error) {
 	// Write all the pre-images to the actual disk database
 	if len(t.getSecKeyCache()) > 0 {
 		if t.trie.db.preimages != nil { // Ugly direct check but avoids the below write lock
@@ -174,7 +191,7 @@ func (t *SecureTrie) Commit(onleaf LeafCallback) (common.Hash, int, error) {
 		t.secKeyCache = make(map[string][]byte)
 	}
 	// Commit the trie to its intermediate node database
-	return t.trie.Commit(onleaf)
+	return t.trie.CommitNodes(onleaf)
 }
 
 // Hash returns the root hash of SecureTrie. It does not write to the
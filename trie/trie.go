@@ -61,8 +61,10 @@ type LeafCallback func(paths [][]byte, hexpath []byte, leaf []byte, parent commo
 //
 // Trie is not safe for concurrent use.
 type Trie struct {
-	db   *Database
-	root node
+	db    *Database
+	root  node
+	owner common.Hash // Owner of the trie, the account hash for storage tries
+	state common.Hash // Root of the state the trie is part of, selecting the node versions of the path scheme
 
 	// Keep track of the number leaves which have been inserted since the last
 	// hashing operation. This number will not directly map to the number of
@@ -84,6 +86,8 @@ func (t *Trie) Copy() *Trie {
 	return &Trie{
 		db:       t.db,
 		root:     t.root,
+		owner:    t.owner,
+		state:    t.state,
 		unhashed: t.unhashed,
 		tracer:   t.tracer.copy(),
 	}
@@ -96,14 +100,34 @@ func (t *Trie) Copy() *Trie {
 // New will panic if db is nil and returns a MissingNodeError if root does
 // not exist in the database. Accessing the trie loads nodes from db on demand.
 func New(root common.Hash, db *Database) (*Trie, error) {
+	return NewWithOwner(root, common.Hash{}, root, db)
+}
+
+// NewWithOwner creates a trie with an existing root node from db, owned by the
+// given account hash and being part of the state with the given root. The owner
+// and the state are only relevant for the path based scheme, where the owner is
+// part of the node keys and the state selects the versions of the nodes. The
+// account trie is owned by the empty hash, its root is the state root.
+func NewWithOwner(state common.Hash, owner common.Hash, root common.Hash, db *Database) (*Trie, error) {
 	if db == nil {
 		panic("trie.New called without a database")
 	}
 	trie := &Trie{
-		db: db,
-		//tracer: newTracer(),
+		db:    db,
+		owner: owner,
+		state: state,
+	}
+	// The path scheme needs to know which nodes were removed from the trie
+	// to delete them from their paths, track them.
+	if db.path != nil {
+		trie.tracer = newTracer()
 	}
 	if root != (common.Hash{}) && root != emptyRoot {
+		// Nodes of overwritten states might linger in the caches of the path
+		// scheme, only open the account tries of the tracked states.
+		if db.path != nil && owner == (common.Hash{}) && !db.hasStateRoot(root) {
+			return nil, &MissingNodeError{NodeHash: root}
+		}
 		rootnode, err := trie.resolveHash(root[:], nil)
 		if err != nil {
 			return nil, err
@@ -221,7 +245,7 @@ func (t *Trie) tryGetNode(origNode node, path []byte, pos int) (item []byte, new
 		if hash == nil {
 			return nil, origNode, 0, errors.New("non-consensus node")
 		}
-		blob, err := t.db.Node(common.BytesToHash(hash))
+		blob, err := t.resolveBlob(hash, path[:pos])
 		return blob, origNode, 1, err
 	}
 	// Path still needs to be traversed, descend into children
@@ -497,7 +521,7 @@ func (t *Trie) delete(n node, prefix, key []byte) (bool, node, error) {
 				// shortNode{..., shortNode{...}}.  Since the entry
 				// might not be loaded yet, resolve it just for this
 				// check.
-				cnode, err := t.resolve(n.Children[pos], prefix)
+				cnode, err := t.resolve(n.Children[pos], append(prefix, byte(pos)))
 				if err != nil {
 					return false, nil, err
 				}
@@ -559,6 +583,12 @@ func (t *Trie) resolve(n node, prefix []byte) (node, error) {
 
 func (t *Trie) resolveHash(n hashNode, prefix []byte) (node, error) {
 	hash := common.BytesToHash(n)
+	if t.db.path != nil {
+		if blob := t.db.pathNodeBlob(t.state, t.owner, prefix, hash); len(blob) != 0 {
+			return mustDecodeNode(n, blob), nil
+		}
+		return nil, &MissingNodeError{NodeHash: hash, Path: prefix}
+	}
 	if node := t.db.node(hash); node != nil {
 		return node, nil
 	}
@@ -567,7 +597,12 @@ func (t *Trie) resolveHash(n hashNode, prefix []byte) (node, error) {
 
 func (t *Trie) resolveBlob(n hashNode, prefix []byte) ([]byte, error) {
 	hash := common.BytesToHash(n)
-	blob, _ := t.db.Node(hash)
+	var blob []byte
+	if t.db.path != nil {
+		blob = t.db.pathNodeBlob(t.state, t.owner, prefix, hash)
+	} else {
+		blob, _ = t.db.Node(hash)
+	}
 	if len(blob) != 0 {
 		return blob, nil
 	}
@@ -583,27 +618,54 @@ func (t *Trie) Hash() common.Hash {
 }
 
 // Commit writes all nodes to the trie's memory database, tracking the internal
-// and external (for account tries) references.
+// and external (for account tries) references. Tries of the path based scheme
+// need to be committed with CommitNodes instead.
 func (t *Trie) Commit(onleaf LeafCallback) (common.Hash, int, error) {
+	if t.db != nil && t.db.path != nil {
+		return common.Hash{}, 0, errPathCommit
+	}
+	root, committed, _, err := t.CommitNodes(onleaf)
+	return root, committed, err
+}
+
+// CommitNodes commits all dirty nodes of the trie. With the hash based scheme,
+// they are written to the trie's memory database, tracking the internal and
+// external (for account tries) references. With the path based scheme, they
+// are returned as a node set instead, which needs to be passed to Update along
+// with the nodes of the other tries changed by the same state transition. The
+// trie can only be used again once the transition is sealed.
+func (t *Trie) CommitNodes(onleaf LeafCallback) (common.Hash, int, *NodeSet, error) {
 	if t.db == nil {
 		panic("commit called on trie with nil database")
 	}
 	defer t.tracer.reset()
 
+	// Nodes removed from the trie are marked as deleted at their paths, the
+	// committed nodes below will overwrite any of them which got recreated.
+	var nodes *NodeSet
+	if t.db.path != nil {
+		nodes = NewNodeSet()
+		for _, path := range t.tracer.deleteList() {
+			nodes.insert(t.owner, path, common.Hash{}, nil)
+		}
+	}
 	if t.root == nil {
-		return emptyRoot, 0, nil
+		if t.owner == (common.Hash{}) {
+			t.state = emptyRoot
+		}
+		return emptyRoot, 0, nodes, nil
 	}
 	// Derive the hash for all dirty nodes first. We hold the assumption
 	// in the following procedure that all nodes are hashed.
 	rootHash := t.Hash()
-	h := newCommitter()
+	h := newCommitter(t.owner, nodes)
 	defer returnCommitterToPool(h)
 
 	// Do a quick check if we really need to commit, before we spin
 	// up goroutines. This can happen e.g. if we load a trie for reading storage
 	// values, but don't write to it.
 	if _, dirty := t.root.cache(); !dirty {
-		return rootHash, 0, nil
+		return rootHash, 0, nodes, nil
 	}
 	var wg sync.WaitGroup
 	if onleaf != nil {
@@ -625,10 +687,15 @@ func (t *Trie) Commit(onleaf LeafCallback) (common.Hash, int, error) {
 		wg.Wait()
 	}
 	if err != nil {
-		return common.Hash{}, 0, err
+		return common.Hash{}, 0, nil, err
 	}
 	t.root = newRoot
-	return rootHash, committed, nil
+
+	// The account trie defines the state, it moves to the new one
+	if t.owner == (common.Hash{}) {
+		t.state = rootHash
+	}
+	return rootHash, committed, nodes, nil
 }
 
 // hashRoot calculates the root hash of the given trie